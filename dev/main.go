@@ -0,0 +1,96 @@
+// dev запускает notifier и sender в одном процессе без Postgres, Redis и
+// RabbitMQ: база, кеш и брокер живут в памяти и пропадают при остановке.
+// Для локальной разработки и демонстраций
+//
+//	cd dev && CONFIG_PATH=../config/config.yml go run .
+//
+// При старте выпускается ключ API со всеми scope для команды по умолчанию,
+// он печатается в лог. Отдельный модуль, чтобы notifier и sender
+// собирались без него
+package main
+
+import (
+	"delayednotifier/inmemory"
+	"fmt"
+	"os"
+	"os/signal"
+	"sender/worker"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/config"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+func main() {
+	zlog.Init()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+
+	cfg := config.New()
+	err := cfg.Load(os.Getenv("CONFIG_PATH"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if os.Getenv("DEBUG") == "false" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	n, err := inmemory.New(cfg, os.Getenv("SERVICE_API_KEY"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	token, scopes, err := n.IssueKey("dev")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	zlog.Logger.Info().Strs("scopes", scopes).Str("key", token).Msg("dev api key")
+
+	c, err := worker.Load(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	w := worker.New(n.Messager(), c)
+	go w.Start()
+	zlog.Logger.Info().Strs("channels", c.Channels()).
+		Msg("start receive messages from queue")
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = cfg.GetString("web_notifier.port")
+	}
+	// страницы и статика notifier ищутся от рабочей директории
+	dir := os.Getenv("NOTIFIER_DIR")
+	if dir == "" {
+		dir = "../notifier"
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	router := ginext.New()
+	router.LoadHTMLGlob("templates/*.html")
+	if err := n.SetRoutes(router); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	zlog.Logger.Info().Msg("start listening port")
+	go func() {
+		err := router.Run(fmt.Sprintf(":%s", port))
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("error on listening")
+		}
+	}()
+
+	<-sig
+	zlog.Logger.Info().Msg("shutdown starting...")
+	// начатые отправки доотправляются, потом закрывается общий брокер
+	w.Stop()
+}
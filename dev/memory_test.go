@@ -0,0 +1,128 @@
+package main
+
+import (
+	"delayednotifier/inmemory"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sender/worker"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/config"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// testConfig sender только с каналом webhook на loopback и без повторов
+const testConfig = `
+sender:
+  channels: "webhook"
+  claim_timeout: "5s"
+  shutdown_timeout: "5s"
+  webhook:
+    allow: "127.0.0.0/8"
+`
+
+// minDelay минимальная задержка уведомления в notifier
+const minDelay = 20 * time.Second
+
+// TestMemoryEnd2End тот же путь уведомления, что и в dev режиме: notifier и
+// sender в одном процессе на базе, кеше и брокере в памяти, без контейнеров
+func TestMemoryEnd2End(t *testing.T) {
+	zlog.Init()
+
+	hooks := make(chan string, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		hooks <- string(b)
+	}))
+	defer hook.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0o600))
+	cfg := config.New()
+	require.NoError(t, cfg.Load(path))
+
+	n, err := inmemory.New(cfg, "")
+	require.NoError(t, err)
+	token, _, err := n.IssueKey("test")
+	require.NoError(t, err)
+
+	c, err := worker.Load(cfg)
+	require.NoError(t, err)
+	w := worker.New(n.Messager(), c)
+	go w.Start()
+	defer w.Stop()
+
+	gin.SetMode(gin.TestMode)
+	g := ginext.New()
+	require.NoError(t, n.SetRoutes(g))
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-API-Key", token)
+		rr := httptest.NewRecorder()
+		g.ServeHTTP(rr, req)
+		t.Log(rr.Body.String())
+		return rr
+	}
+	create := func(message string) {
+		rr := do(http.MethodPost, "/notify", fmt.Sprintf(
+			`{"message": "%s", "webhook_url": "%s", "webhook_secret": "s", "date": "%s"}`,
+			message, hook.URL, time.Now().Add(minDelay+time.Second).UTC().Format(time.RFC3339Nano),
+		))
+		require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	}
+	status := func(id int64) string {
+		rr := do(http.MethodGet, fmt.Sprintf("/notify/%d", id), "")
+		require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+		r := struct {
+			Result struct {
+				Status string
+			}
+		}{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &r))
+		return r.Result.Status
+	}
+
+	// ----------------------- DELAYED DELIVERY ---------------------------
+	/*
+		create two notifications and cancel the second one, sender asks
+		notifier before sending and drops it without calling webhook
+	*/
+	start := time.Now()
+	create("hi")
+	create("bye")
+	rr := do(http.MethodDelete, "/notify/2", "")
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	/*
+		first notification comes to webhook not earlier than its date and
+		becomes sent
+	*/
+	select {
+	case b := <-hooks:
+		require.Contains(t, b, "hi")
+		require.GreaterOrEqual(t, time.Since(start), minDelay)
+	case <-time.After(minDelay + 10*time.Second):
+		t.Fatal("webhook was not called")
+	}
+	require.Eventually(t, func() bool {
+		return status(1) == "sent"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	select {
+	case b := <-hooks:
+		t.Fatalf("cancelled notification was sent: %s", b)
+	case <-time.After(2 * time.Second):
+	}
+	require.Equal(t, "cancelled", status(2))
+	// --------------------------------------------------------------------
+}
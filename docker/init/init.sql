@@ -1,8 +1,157 @@
+create table tenants(
+    id serial primary key,
+    name varchar(255) not null unique,
+    created_at timestamptz not null default now()
+);
+
+insert into tenants (id, name) values (1, 'default');
+select setval('tenants_id_seq', 1);
+
+create table templates(
+    id serial primary key,
+    tenant_id bigint not null references tenants (id),
+    name varchar(255) not null,
+    body text not null,
+    telegram_body text not null default '',
+    email_subject text not null default '',
+    email_body text not null default '',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    unique (tenant_id, name)
+);
+
+create table series(
+    id serial primary key,
+    tenant_id bigint not null references tenants (id),
+    rule varchar(255) not null,
+    until timestamptz,
+    max_count bigint not null default 0,
+    fired bigint not null default 0,
+    status varchar(50) not null default ('active'),
+    last_occurrence_id bigint not null default 0,
+    created_at timestamptz not null default now()
+);
+
+create table contacts(
+    id serial primary key,
+    tenant_id bigint not null references tenants (id),
+    name varchar(255) not null,
+    telegram_id varchar(64) not null default '',
+    email varchar(255) not null default '',
+    preferred_channel varchar(20) not null default '',
+    locale varchar(35) not null default '',
+    timezone varchar(64) not null default ('UTC'),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
 create table notifications(
     id serial primary key,
-    telegram_id bigint,
+    tenant_id bigint not null references tenants (id),
     message text not null,
-    email varchar(255),
     status varchar(50) not null default ('pending'),
-    dt timestamp not null
-);
\ No newline at end of file
+    dt timestamptz not null,
+    timezone varchar(64) not null default ('UTC'),
+    idempotency_key varchar(255),
+    request_hash varchar(64),
+    version bigint not null default 1,
+    cancelled_at timestamptz,
+    cancel_reason text,
+    series_id bigint references series (id) on delete set null,
+    telegram_message text not null default '',
+    email_subject text not null default '',
+    email_message text not null default '',
+    template_id bigint references templates (id) on delete set null,
+    contact_id bigint references contacts (id) on delete set null,
+    webhook_headers jsonb not null default '{}',
+    webhook_secret varchar(255) not null default '',
+    retry_max_attempts int not null default 0,
+    retry_backoff int not null default 0,
+    republished integer not null default 0,
+    republished_at timestamptz,
+    overdue_at timestamptz,
+    dispatched_version bigint not null default 0,
+    unique (tenant_id, idempotency_key)
+);
+
+create table recipients(
+    id serial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    channel varchar(20) not null,
+    address varchar(255) not null,
+    status varchar(50) not null default ('pending'),
+    error text not null default '',
+    updated_at timestamptz not null default now(),
+    unique (notification_id, channel, address)
+);
+
+create table deliveries(
+    id bigserial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    channel varchar(20) not null,
+    address varchar(255) not null,
+    attempt bigint not null default 0,
+    status varchar(50) not null,
+    response text not null default '',
+    error text not null default '',
+    started_at timestamptz not null,
+    finished_at timestamptz not null
+);
+
+create table api_keys(
+    id serial primary key,
+    tenant_id bigint not null references tenants (id),
+    name varchar(255) not null,
+    shown varchar(32) not null,
+    hash varchar(64) not null unique,
+    scopes text[] not null,
+    created_at timestamptz not null default now(),
+    revoked_at timestamptz
+);
+
+create table dead_letters(
+    id bigserial primary key,
+    tenant_id bigint,
+    notification_id bigint,
+    channel varchar(20) not null default '',
+    address varchar(255) not null default '',
+    reason varchar(32) not null,
+    error text not null default '',
+    attempts jsonb not null default '[]',
+    payload bytea not null,
+    failed_at timestamptz not null,
+    created_at timestamptz not null default now()
+);
+
+create table outbox(
+    id bigserial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    payload bytea not null,
+    publish_at timestamptz not null,
+    attempts integer not null default 0,
+    last_error text not null default '',
+    created_at timestamptz not null default now(),
+    sent_at timestamptz
+);
+
+create table retries(
+    id bigserial primary key,
+    payload bytea not null,
+    headers jsonb not null default '{}',
+    due_at timestamptz not null,
+    created_at timestamptz not null default now()
+);
+
+create index notifications_dt_id_idx on notifications (dt, id);
+create index notifications_series_id_idx on notifications (series_id, dt, id);
+create index notifications_contact_id_idx on notifications (contact_id, status);
+create index notifications_tenant_dt_id_idx on notifications (tenant_id, dt, id);
+create index contacts_tenant_id_idx on contacts (tenant_id);
+create index api_keys_tenant_id_idx on api_keys (tenant_id);
+create index dead_letters_tenant_idx on dead_letters (tenant_id, id);
+create index dead_letters_notification_idx on dead_letters (notification_id);
+create index deliveries_notification_id_idx on deliveries (notification_id, id);
+create index outbox_unsent_idx on outbox (id) where sent_at is null;
+create index outbox_sent_at_idx on outbox (sent_at) where sent_at is not null;
+create index retries_due_at_idx on retries (due_at, id);
+create index notifications_pending_dt_idx on notifications (dt, id) where status = 'pending';
@@ -0,0 +1,130 @@
+// apikey выпускает, показывает и отзывает ключи API напрямую в базе.
+// Нужен, чтобы выпустить первый admin ключ, дальше ключами можно управлять
+// через /keys и /tenants
+//
+//	apikey issue -name admin -scopes admin,tenants
+//	apikey issue -tenant 2 -name ops -scopes admin
+//	apikey list -tenant 2
+//	apikey revoke -tenant 2 -id 3
+//	apikey tenant -name billing
+//	apikey tenants
+package main
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/service"
+	"delayednotifier/internal/storage"
+	"delayednotifier/internal/storage/postgres"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wb-go/wbf/config"
+	"github.com/wb-go/wbf/zlog"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey issue [-tenant ID] -name NAME -scopes create,read,cancel,admin,tenants,ops")
+	fmt.Fprintln(os.Stderr, "       apikey list [-tenant ID]")
+	fmt.Fprintln(os.Stderr, "       apikey revoke [-tenant ID] -id ID")
+	fmt.Fprintln(os.Stderr, "       apikey tenant -name NAME")
+	fmt.Fprintln(os.Stderr, "       apikey tenants")
+	os.Exit(2)
+}
+
+func main() {
+	zlog.Init()
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg := config.New()
+	err := cfg.Load(os.Getenv("CONFIG_PATH"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	db := postgres.New(
+		cfg.GetString("postgres.host"), cfg.GetString("postgres.port"),
+		cfg.GetString("postgres.username"), os.Getenv("POSTGRES_PASSWORD"),
+		cfg.GetString("postgres.dbname"), cfg.GetString("postgres.sslmode"),
+	)
+	defer db.Shutdown()
+	// ключи живут только в базе, кеш и очередь не нужны
+	srv := service.New(storage.New(db, nil, nil))
+	// у того, кто запускает утилиту, есть доступ к базе, поэтому он может
+	// выдать любой scope
+	root := apikey.Key{Scopes: apikey.Grantable}
+
+	switch os.Args[1] {
+	case "issue":
+		fs := flag.NewFlagSet("issue", flag.ExitOnError)
+		tid := fs.Int64("tenant", tenant.DefaultID, "tenant id")
+		name := fs.String("name", "", "key name")
+		scopes := fs.String("scopes", "", "comma separated scopes")
+		_ = fs.Parse(os.Args[2:])
+
+		k, token, err := srv.IssueAPIKey(root, *tid, *name, strings.Split(*scopes, ","))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("id: %d\nscopes: %s\nkey: %s\n", k.ID, strings.Join(k.Scopes, ","), token)
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		tid := fs.Int64("tenant", tenant.DefaultID, "tenant id")
+		_ = fs.Parse(os.Args[2:])
+
+		keys, err := srv.APIKeys(*tid)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, k := range keys {
+			state := "active"
+			if k.RevokedAt != nil {
+				state = "revoked " + k.RevokedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf(
+				"%d\t%s\t%s...\t%s\t%s\n",
+				k.ID, k.Name, k.Shown, strings.Join(k.Scopes, ","), state,
+			)
+		}
+	case "revoke":
+		fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+		tid := fs.Int64("tenant", tenant.DefaultID, "tenant id")
+		id := fs.Int64("id", 0, "key id")
+		_ = fs.Parse(os.Args[2:])
+
+		if err := srv.RevokeAPIKey(*tid, *id); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("key %d revoked\n", *id)
+	case "tenant":
+		fs := flag.NewFlagSet("tenant", flag.ExitOnError)
+		name := fs.String("name", "", "tenant name")
+		_ = fs.Parse(os.Args[2:])
+
+		id, err := srv.CreateTenant(*name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("tenant id: %d\n", id)
+	case "tenants":
+		ts, err := srv.Tenants()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, t := range ts {
+			fmt.Printf("%d\t%s\t%s\n", t.ID, t.Name, t.CreatedAt.Format("2006-01-02 15:04"))
+		}
+	default:
+		usage()
+	}
+}
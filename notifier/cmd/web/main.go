@@ -2,10 +2,13 @@ package main
 
 import (
 	"delayednotifier/internal/service"
+	"delayednotifier/internal/settings"
 	"delayednotifier/internal/storage"
 	"delayednotifier/internal/storage/postgres"
 	"delayednotifier/internal/storage/rabbit"
 	"delayednotifier/internal/storage/redis"
+	"delayednotifier/internal/storage/redisqueue"
+	"delayednotifier/internal/storage/scheduler"
 	"delayednotifier/internal/web"
 	"os/signal"
 	"syscall"
@@ -13,6 +16,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	// база зон внутри бинарника, в контейнере может не быть zoneinfo
+	_ "time/tzdata"
 
 	_ "delayednotifier/docs"
 
@@ -27,6 +32,10 @@ import (
 // @description Создает отложенные уведомления в очереди
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Ключ API, также принимается Authorization: Bearer <ключ>
 
 func main() {
 	zlog.Init()
@@ -44,11 +53,16 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	rb := rabbit.New(
-		cfg.GetString("rabbit.username"), os.Getenv("RABBIT_PASSWORD"),
-		cfg.GetString("rabbit.host"), cfg.GetString("rabbit.queue"),
-		cfg.GetString("rabbit.exchanger"), cfg.GetString("rabbit.routing_key"),
-	)
+	sched, err := settings.LoadScheduler(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	qc, err := settings.LoadQueue(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	rdI, err := strconv.Atoi(cfg.GetString("redis.db"))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -59,16 +73,91 @@ func main() {
 		os.Getenv("REDIS_PASSWORD"),
 		rdI,
 	)
+	var (
+		broker      storage.Queue
+		closeBroker func()
+		// повторы sender без отложенного обменника
+		retries scheduler.Retries
+	)
+	switch qc.Kind {
+	case settings.QueueRedis:
+		rq := redisqueue.New(
+			cfg.GetString("redis.addr"), os.Getenv("REDIS_PASSWORD"), rdI, qc.Redis,
+		)
+		broker, closeBroker = rq, rq.Shutdown
+	default:
+		// без отложенного обменника время отправки хранит только база
+		exchanger := cfg.GetString("rabbit.exchanger")
+		if sched.Kind == settings.SchedulerPostgres {
+			exchanger = ""
+		}
+		rb := rabbit.New(
+			cfg.GetString("rabbit.username"), os.Getenv("RABBIT_PASSWORD"),
+			cfg.GetString("rabbit.host"), cfg.GetString("rabbit.queue"),
+			exchanger, cfg.GetString("rabbit.routing_key"),
+			cfg.GetString("rabbit.dead_letter_queue"),
+			cfg.GetString("rabbit.results_queue"), cfg.GetString("rabbit.claims_queue"),
+			cfg.GetString("rabbit.retries_queue"),
+		)
+		broker, closeBroker = rb, rb.Shoutdown
+		if sched.Kind == settings.SchedulerPostgres {
+			retries = rb
+		}
+	}
 	db := postgres.New(
 		cfg.GetString("postgres.host"), cfg.GetString("postgres.port"),
 		cfg.GetString("postgres.username"), os.Getenv("POSTGRES_PASSWORD"),
 		cfg.GetString("postgres.dbname"), cfg.GetString("postgres.sslmode"),
 	)
-	str := storage.New(db, rd, rb)
+	q := broker
+	if sched.Kind == settings.SchedulerPostgres {
+		s := scheduler.New(broker, db)
+		if retries != nil {
+			s.SetRetries(retries)
+			go s.ConsumeRetries()
+		}
+		go s.Run(sched.Interval, sched.Batch)
+		q = s
+	}
+	str := storage.New(db, rd, q)
+	go str.ConsumeDeadLetters()
+	relay, err := settings.LoadRelay(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go str.RelayOutbox(relay.Interval, relay.Batch, relay.Retention)
+	sweep, err := settings.LoadSweeper(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go str.SweepOverdue(sweep.Interval, sweep.Grace, sweep.MaxRepublish, sweep.Batch)
 
 	srv := service.New(str)
+	go str.ConsumeResults(srv.ApplyDelivery)
+	go str.ConsumeClaims(srv.Claim)
+	srv.SetServiceKey(os.Getenv("SERVICE_API_KEY"))
+	limits, err := settings.LoadLimits(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	srv.SetLimits(limits)
+	srv.SetOverdueGrace(sweep.Grace)
 
+	proxies, err := settings.LoadTrustedProxies(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	router := ginext.New()
+	// без списка gin верит X-Forwarded-For от любого клиента и лимит по IP
+	// обходится подменой заголовка
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	router.LoadHTMLGlob("templates/*.html")
 	web.SetRoutes(router, srv)
 
@@ -84,7 +173,7 @@ func main() {
 
 	<-sig
 	zlog.Logger.Info().Msg("shutdown sarting...")
-	rb.Shoutdown()
+	closeBroker()
 	rd.Shutdown()
 	db.Shutdown()
 }
@@ -0,0 +1,90 @@
+// Package inmemory собирает notifier на базе, кеше и брокере в памяти
+// процесса, данные пропадают при остановке. Пакет не internal, чтобы модуль
+// dev в корне репозитория запускал notifier и sender в одном процессе
+package inmemory
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/service"
+	"delayednotifier/internal/settings"
+	"delayednotifier/internal/storage"
+	"delayednotifier/internal/storage/memory"
+	"delayednotifier/internal/web"
+
+	"github.com/wb-go/wbf/config"
+	"github.com/wb-go/wbf/ginext"
+)
+
+type Notifier struct {
+	q       *memory.Queue
+	srv     *service.Service
+	proxies []string
+}
+
+// New собирает notifier с настройками из cfg и запускает его фоновую
+// работу: relay outbox, поиск опоздавших уведомлений, разбор DLQ,
+// результатов и запросов sender. serviceKey - сервисный ключ sender, пустой
+// - выключен
+func New(cfg *config.Config, serviceKey string) (*Notifier, error) {
+	relay, err := settings.LoadRelay(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sweep, err := settings.LoadSweeper(cfg)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := settings.LoadLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := settings.LoadTrustedProxies(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	q := memory.NewQueue()
+	str := storage.New(memory.New(), memory.NewCache(), q)
+	go str.ConsumeDeadLetters()
+	go str.RelayOutbox(relay.Interval, relay.Batch, relay.Retention)
+	go str.SweepOverdue(sweep.Interval, sweep.Grace, sweep.MaxRepublish, sweep.Batch)
+
+	srv := service.New(str)
+	go str.ConsumeResults(srv.ApplyDelivery)
+	go str.ConsumeClaims(srv.Claim)
+	srv.SetServiceKey(serviceKey)
+	srv.SetLimits(limits)
+	srv.SetOverdueGrace(sweep.Grace)
+
+	return &Notifier{q: q, srv: srv, proxies: proxies}, nil
+}
+
+// Messager сторона брокера для sender в том же процессе, ее принимает
+// worker.New. Остановка sender закрывает и брокер
+func (n *Notifier) Messager() *memory.Messager {
+	return n.q.Messager()
+}
+
+// IssueKey выпускает ключ API name со всеми scope для команды по умолчанию:
+// база пустая, без ключа к API не обратиться
+func (n *Notifier) IssueKey(name string) (string, []string, error) {
+	k, token, err := n.srv.IssueAPIKey(
+		apikey.Key{Scopes: apikey.Grantable}, tenant.DefaultID, name, apikey.Grantable,
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, k.Scopes, nil
+}
+
+// SetRoutes задает router доверенные прокси из конфига и регистрирует
+// маршруты notifier, шаблоны страниц router должен загрузить сам
+func (n *Notifier) SetRoutes(router *ginext.Engine) error {
+	if err := router.SetTrustedProxies(n.proxies); err != nil {
+		return err
+	}
+	web.SetRoutes(router, n.srv)
+	return nil
+}
@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeCancel = "cancel"
+	// ScopeAdmin выпуск и отзыв ключей своей команды
+	ScopeAdmin = "admin"
+	// ScopeTenants создание команд и выпуск ключей в любой команде
+	ScopeTenants = "tenants"
+	// ScopeOps разбор DLQ и просроченных уведомлений всех команд, как и
+	// tenants не входит в admin
+	ScopeOps = "ops"
+	// ScopeService внутренние вызовы сервисов. Выдается только сервисному
+	// ключу
+	ScopeService = "service"
+
+	// Prefix начало каждого ключа, по нему ключ легко найти в логах и
+	// конфигах
+	Prefix = "dn_"
+	// ShownLen сколько символов ключа хранится открыто для списка ключей
+	ShownLen = len(Prefix) + 8
+)
+
+// Grantable scope, которые можно выдать ключу
+var Grantable = []string{
+	ScopeCreate, ScopeRead, ScopeCancel, ScopeAdmin, ScopeTenants, ScopeOps,
+}
+
+var ErrWrongScope = errors.New("wrong scope")
+
+// Key выпущенный ключ API. Сам ключ не хранится, только его хеш
+type Key struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	Shown     string     `db:"shown"`
+	Hash      string     `db:"hash" json:"-"`
+	Scopes    []string   `db:"scopes"`
+	TenantID  int64      `db:"tenant_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// Has проверяет, что ключ дает scope. admin дает все scope своей команды,
+// но не tenants, ops и service
+func (k Key) Has(scope string) bool {
+	if slices.Contains(k.Scopes, scope) {
+		return true
+	}
+
+	return scope != ScopeService && scope != ScopeTenants && scope != ScopeOps &&
+		slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// ValidateScopes проверяет список scope для выпуска ключа
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: scopes is empty", ErrWrongScope)
+	}
+	for _, s := range scopes {
+		if !slices.Contains(Grantable, s) {
+			return fmt.Errorf(
+				"%w: %q (\"create\", \"read\", \"cancel\", \"admin\", \"tenants\" or \"ops\" only)",
+				ErrWrongScope, s,
+			)
+		}
+	}
+
+	return nil
+}
+
+// Generate создает новый ключ, возвращает его и хеш для хранения
+func Generate() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := Prefix + hex.EncodeToString(b)
+
+	return token, Hash(token), nil
+}
+
+// Hash хеш ключа. Ключи случайные и длинные, поэтому соль и медленный хеш
+// не нужны, а поиск по хешу остается точным
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,51 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	token, hash, err := Generate()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, Prefix))
+	require.Equal(t, Hash(token), hash)
+	require.NotContains(t, hash, token[len(Prefix):])
+
+	other, _, err := Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+}
+
+func TestHas(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     Key
+		scope string
+		want  bool
+	}{
+		{name: "own scope", k: Key{Scopes: []string{ScopeRead}}, scope: ScopeRead, want: true},
+		{name: "other scope", k: Key{Scopes: []string{ScopeRead}}, scope: ScopeCreate},
+		{name: "admin", k: Key{Scopes: []string{ScopeAdmin}}, scope: ScopeCancel, want: true},
+		{name: "admin not service", k: Key{Scopes: []string{ScopeAdmin}}, scope: ScopeService},
+		{name: "admin not tenants", k: Key{Scopes: []string{ScopeAdmin}}, scope: ScopeTenants},
+		{name: "admin not ops", k: Key{Scopes: []string{ScopeAdmin}}, scope: ScopeOps},
+		{name: "service", k: Key{Scopes: []string{ScopeService}}, scope: ScopeService, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.k.Has(tt.scope))
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	require.NoError(t, ValidateScopes([]string{ScopeCreate, ScopeRead}))
+	require.NoError(t, ValidateScopes([]string{ScopeTenants}))
+	require.NoError(t, ValidateScopes([]string{ScopeOps}))
+	require.ErrorIs(t, ValidateScopes(nil), ErrWrongScope)
+	require.ErrorIs(t, ValidateScopes([]string{ScopeService}), ErrWrongScope)
+	require.ErrorIs(t, ValidateScopes([]string{"write"}), ErrWrongScope)
+}
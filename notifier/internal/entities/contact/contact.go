@@ -0,0 +1,86 @@
+package contact
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrWrongContact = errors.New("wrong contact")
+
+var localeRe = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// Contact сохраненный получатель. Уведомления с contact_id отправляются по
+// адресам контакта на момент отправки. PreferredChannel пустой - во все
+// заданные каналы
+type Contact struct {
+	ID               int64     `db:"id"`
+	Name             string    `db:"name"`
+	TelegramID       string    `db:"telegram_id"`
+	Email            string    `db:"email"`
+	PreferredChannel string    `db:"preferred_channel"`
+	Locale           string    `db:"locale"`
+	Timezone         string    `db:"timezone"`
+	TenantID         int64     `db:"tenant_id"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+// Validate проверяет адреса, канал, локаль и зону контакта
+func (c Contact) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is empty", ErrWrongContact)
+	}
+	if c.TelegramID == "" && c.Email == "" {
+		return fmt.Errorf("%w: both send variant is empty", ErrWrongContact)
+	}
+	if c.TelegramID != "" {
+		v, err := strconv.ParseInt(c.TelegramID, 10, 64)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("%w: telegram_id should be positive numeric", ErrWrongContact)
+		}
+	}
+	if c.Email != "" &&
+		(!strings.Contains(c.Email, "@") || !strings.Contains(c.Email, ".")) {
+		return fmt.Errorf("%w: wrong email format", ErrWrongContact)
+	}
+	switch c.PreferredChannel {
+	case "":
+	case notification.ChannelTelegram, notification.ChannelEmail:
+		if c.Address(c.PreferredChannel) == "" {
+			return fmt.Errorf(
+				"%w: preferred channel %s has no address",
+				ErrWrongContact, c.PreferredChannel,
+			)
+		}
+	default:
+		return fmt.Errorf(
+			"%w: wrong preferred_channel (\"telegram\" or \"email\" only)",
+			ErrWrongContact,
+		)
+	}
+	if c.Locale != "" && !localeRe.MatchString(c.Locale) {
+		return fmt.Errorf("%w: wrong locale, expected like \"ru-RU\"", ErrWrongContact)
+	}
+	if _, err := notification.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("%w: %w", ErrWrongContact, err)
+	}
+
+	return nil
+}
+
+// Address адрес контакта в канале, пустой если канал не задан
+func (c Contact) Address(channel string) string {
+	switch channel {
+	case notification.ChannelTelegram:
+		return c.TelegramID
+	case notification.ChannelEmail:
+		return c.Email
+	}
+
+	return ""
+}
@@ -0,0 +1,72 @@
+package contact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Contact
+		wantErr bool
+	}{
+		{
+			name: "good",
+			c: Contact{
+				Name: "Аня", TelegramID: "123", Email: "a@b.c",
+				PreferredChannel: "telegram", Locale: "ru-RU", Timezone: "Europe/Moscow",
+			},
+		},
+		{
+			name: "email only",
+			c:    Contact{Name: "Аня", Email: "a@b.c"},
+		},
+		{
+			name:    "empty name",
+			c:       Contact{TelegramID: "123"},
+			wantErr: true,
+		},
+		{
+			name:    "no channels",
+			c:       Contact{Name: "Аня"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong telegram id",
+			c:       Contact{Name: "Аня", TelegramID: "-5"},
+			wantErr: true,
+		},
+		{
+			name:    "preferred without address",
+			c:       Contact{Name: "Аня", Email: "a@b.c", PreferredChannel: "telegram"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown preferred",
+			c:       Contact{Name: "Аня", Email: "a@b.c", PreferredChannel: "sms"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong locale",
+			c:       Contact{Name: "Аня", Email: "a@b.c", Locale: "русский"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong timezone",
+			c:       Contact{Name: "Аня", Email: "a@b.c", Timezone: "Moscow"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.c.Validate()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrWrongContact)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package deadletter
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"time"
+)
+
+// Причины, по которым sender отправил сообщение в DLQ
+const (
+	// ReasonUnreadable сообщение очереди не удалось разобрать
+	ReasonUnreadable = "unreadable"
+	// ReasonPermanent отправка не удалась и повтор не поможет
+	ReasonPermanent = "permanent_error"
+	// ReasonExhausted временные ошибки, попытки кончились
+	ReasonExhausted = "retries_exhausted"
+)
+
+// Attempt неудачная попытка отправки, Attempt - ее номер, 0 - первая
+type Attempt struct {
+	Attempt int64     `json:"attempt"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
+
+// Letter сообщение, которое sender не смог доставить. Payload - исходное
+// сообщение очереди, для доставки одному получателю в нем только он. В нем
+// есть секрет webhook, поэтому наружу он не отдается. TenantID и
+// NotificationID нулевые, если сообщение не разобрано
+type Letter struct {
+	ID             int64     `db:"id" json:"id"`
+	TenantID       int64     `db:"tenant_id" json:"tenant_id"`
+	NotificationID int64     `db:"notification_id" json:"notification_id"`
+	Channel        string    `db:"channel" json:"channel"`
+	Address        string    `db:"address" json:"address"`
+	Reason         string    `db:"reason" json:"reason"`
+	Error          string    `db:"error" json:"error"`
+	Attempts       []Attempt `db:"attempts" json:"attempts"`
+	Payload        []byte    `db:"payload" json:"-"`
+	FailedAt       time.Time `db:"failed_at" json:"failed_at"`
+}
+
+// Replay сообщение для повторной публикации. Разобранное уведомление уже
+// отмечено отправленным, поэтому уходит как первый повтор, тогда sender не
+// проверяет статус и заново считает попытки. Неразобранное публикуется как
+// есть
+func (l Letter) Replay() []byte {
+	n := notification.Notification{}
+	if l.Reason == ReasonUnreadable || n.UnmarshalBinary(l.Payload) != nil {
+		return l.Payload
+	}
+	n.Attempt = 1
+	v, err := n.MarshalBinary()
+	if err != nil {
+		return l.Payload
+	}
+
+	return v
+}
+
+// Filter параметры выборки DLQ, After - курсор: id последней записи
+// предыдущей страницы
+type Filter struct {
+	TenantID       int64
+	NotificationID int64
+	Channel        string
+	Reason         string
+	After          int64
+	Limit          int
+}
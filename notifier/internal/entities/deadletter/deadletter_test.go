@@ -0,0 +1,23 @@
+package deadletter
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLetter_Replay(t *testing.T) {
+	n := notification.Notification{ID: 3, Message: "hi", TenantID: 2, Attempt: 4}
+	v, err := n.MarshalBinary()
+	require.NoError(t, err)
+
+	got := notification.Notification{}
+	require.NoError(t, got.UnmarshalBinary(Letter{Reason: ReasonExhausted, Payload: v}.Replay()))
+	require.Equal(t, int64(1), got.Attempt)
+	require.Equal(t, n.ID, got.ID)
+
+	raw := []byte("garbage")
+	require.Equal(t, raw, Letter{Reason: ReasonUnreadable, Payload: raw}.Replay())
+	require.Equal(t, raw, Letter{Reason: ReasonPermanent, Payload: raw}.Replay())
+}
@@ -0,0 +1,72 @@
+package event
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"time"
+)
+
+// Claim запрос sender перед отправкой: уведомление отмечается взятым, если
+// его версия совпадает с версией в сообщении. Совпадает с sender
+type Claim struct {
+	TenantID       int64 `json:"tenant_id"`
+	NotificationID int64 `json:"notification_id"`
+	Version        int64 `json:"version"`
+	ContactID      int64 `json:"contact_id,omitempty"`
+}
+
+// Ответы на Claim
+const (
+	ClaimOK         = "ok"
+	ClaimCancelled  = "cancelled"
+	ClaimSuperseded = "superseded"
+	ClaimNotFound   = "not_found"
+	// ClaimFailed запрос не удалось обработать
+	ClaimFailed = "failed"
+)
+
+// ClaimReply ответ на Claim. Contact - адреса контакта уведомления на момент
+// отправки, Error - почему контакта нет или почему запрос не обработан.
+// Recorded - получатели, результат доставки которым уже записан: сообщение
+// пришло повторно, и им sender не отправляет
+type ClaimReply struct {
+	Result   string           `json:"result"`
+	Error    string           `json:"error,omitempty"`
+	Contact  *contact.Contact `json:"contact,omitempty"`
+	Recorded []Recipient      `json:"recorded,omitempty"`
+}
+
+// Recipient получатель и записанный статус его доставки
+type Recipient struct {
+	Channel string `json:"channel"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
+}
+
+// Delivery результат попытки доставки одному получателю от sender
+type Delivery struct {
+	TenantID       int64     `json:"tenant_id"`
+	NotificationID int64     `json:"notification_id"`
+	Channel        string    `json:"channel"`
+	Address        string    `json:"address"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error"`
+	Attempt        int64     `json:"attempt"`
+	Response       string    `json:"response"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// Delivery попытка доставки для записи в историю
+func (e Delivery) Delivery() notification.Delivery {
+	return notification.Delivery{
+		Channel:    e.Channel,
+		Address:    e.Address,
+		Attempt:    e.Attempt,
+		Status:     e.Status,
+		Response:   e.Response,
+		Error:      e.Error,
+		StartedAt:  e.StartedAt,
+		FinishedAt: e.FinishedAt,
+	}
+}
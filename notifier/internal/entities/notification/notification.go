@@ -2,29 +2,371 @@ package notification
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Notify модель отложенного уведомления
 type Notification struct {
-	ID               int64     `db:"id"`
-	TelegramID       int64     `db:"telegram_id"`
-	Message          string    `db:"message"`
-	Email            string    `db:"email"`
-	Status           string    `db:"status"`
-	Date             time.Time `db:"dt"`
+	ID           int64     `db:"id"`
+	Message      string    `db:"message"`
+	Status       string    `db:"status"`
+	Date         time.Time `db:"dt"`
+	Version      int64     `db:"version"`
+	CancelledAt  time.Time `db:"cancelled_at"`
+	CancelReason string    `db:"cancel_reason"`
+	SeriesID     int64     `db:"series_id"`
+	// Timezone IANA зона получателя, время хранится в UTC, зона нужна для
+	// вывода локального времени и расчета срабатываний серии
+	Timezone string `db:"timezone"`
+	// текст для отдельных каналов, пустой - используется Message
+	TelegramMessage string `db:"telegram_message"`
+	EmailSubject    string `db:"email_subject"`
+	EmailMessage    string `db:"email_message"`
+	TemplateID      int64  `db:"template_id"`
+	// Recipients получатели по каналам, хранятся в отдельной таблице
+	Recipients []Recipient
+	// ContactID сохраненный контакт, его адреса sender берет при отправке
+	ContactID int64 `db:"contact_id"`
+	// TenantID команда, которой принадлежит уведомление
+	TenantID int64 `db:"tenant_id"`
+	// WebhookHeaders дополнительные заголовки запроса в канал webhook,
+	// WebhookSecret - ключ подписи его тела, наружу не отдается
+	WebhookHeaders map[string]string `db:"webhook_headers"`
+	WebhookSecret  string            `db:"webhook_secret" json:"-"`
+	// RetryMaxAttempts, RetryBackoff политика повтора неудачной отправки:
+	// попыток всего и первая пауза в секундах, 0 - настройки sender
+	RetryMaxAttempts int64 `db:"retry_max_attempts"`
+	RetryBackoff     int64 `db:"retry_backoff"`
+	// Attempt номер повтора в сообщении очереди, 0 - первая отправка
+	Attempt int64 `db:"-" json:"-"`
+}
+
+// Recipient получатель уведомления в одном канале, Address - chat id для
+// Telegram, адрес почты или URL для webhook
+type Recipient struct {
+	Channel string `db:"channel"`
+	Address string `db:"address"`
+	Status  string `db:"status"`
+	Error   string `db:"error"`
+}
+
+// Delivery попытка доставки одному получателю. Attempt - номер попытки,
+// 0 - первая, Response - ответ провайдера канала
+type Delivery struct {
+	ID         int64     `db:"id"`
+	Channel    string    `db:"channel"`
+	Address    string    `db:"address"`
+	Attempt    int64     `db:"attempt"`
+	Status     string    `db:"status"`
+	Response   string    `db:"response"`
+	Error      string    `db:"error"`
+	StartedAt  time.Time `db:"started_at"`
+	FinishedAt time.Time `db:"finished_at"`
+}
+
+// Recipient получатель с результатом этой попытки
+func (d Delivery) Recipient() Recipient {
+	return Recipient{
+		Channel: d.Channel, Address: d.Address, Status: d.Status, Error: d.Error,
+	}
+}
+
+// DeliveryStatus общий статус отправленного уведомления по его получателям:
+// sending пока есть ожидающие или повторы, иначе sent, failed или
+// partially_failed
+func DeliveryStatus(rs []Recipient) string {
+	var sent, failed int
+	for _, rc := range rs {
+		switch rc.Status {
+		case RecipientSent:
+			sent++
+		case RecipientFailed:
+			failed++
+		default:
+			return StatusSending
+		}
+	}
+	switch {
+	case sent == 0 && failed == 0:
+		return StatusSending
+	case failed == 0:
+		return StatusSent
+	case sent == 0:
+		return StatusFailed
+	}
+
+	return StatusPartiallyFailed
 }
 
 const (
 	DateLayout = time.RFC3339
+	// LocalDateLayout время без смещения, трактуется в зоне timezone
+	LocalDateLayout = "2006-01-02T15:04:05"
+	DefaultTimezone = "UTC"
 
-	StatusPending  = "pending"
-	StatusComplete = "complete"
+	StatusPending   = "pending"
+	StatusCancelled = "cancelled"
+	// статусы после того, как sender взял уведомление, выводятся из
+	// статусов получателей
+	StatusSending         = "sending"
+	StatusSent            = "sent"
+	StatusPartiallyFailed = "partially_failed"
+	StatusFailed          = "failed"
+
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+
+	RecipientPending = "pending"
+	RecipientSent    = "sent"
+	RecipientFailed  = "failed"
+	// RecipientRetrying отправка не удалась, повтор запланирован
+	RecipientRetrying = "retrying"
+
+	SortByDate = "dt"
+	SortByID   = "id"
 )
 
-func (n Notification) DT() string {
-	return n.Date.Format(time.DateTime)
+var (
+	ErrWrongCursor   = errors.New("wrong cursor value")
+	ErrWrongTimezone = errors.New("wrong timezone (IANA name expected)")
+	// ErrQuotaExceeded у команды уже максимум ожидающих уведомлений
+	ErrQuotaExceeded = errors.New("pending notifications quota exceeded")
+	// ErrCorrupted бинарное представление уведомления повреждено: длина поля
+	// отрицательная или больше оставшихся данных
+	ErrCorrupted = errors.New("corrupted notification binary")
+)
+
+// LoadLocation возвращает зону по IANA имени, пустое имя - UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	// Local зависит от зоны сервера, ради этого поле и вводилось
+	if name == "Local" {
+		return nil, ErrWrongTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrWrongTimezone
+	}
+
+	return loc, nil
+}
+
+// ParseDate разбирает время в формате RFC3339, если смещение не указано -
+// время трактуется в зоне loc. Результат всегда в UTC
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	t, lerr := time.ParseInLocation(LocalDateLayout, s, loc)
+	if lerr != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
+
+// Addresses адреса получателей канала в порядке добавления
+func (n Notification) Addresses(channel string) []string {
+	r := make([]string, 0, len(n.Recipients))
+	for _, rc := range n.Recipients {
+		if rc.Channel == channel {
+			r = append(r, rc.Address)
+		}
+	}
+
+	return r
+}
+
+// SetAddresses заменяет получателей канала, новые получатели в статусе
+// pending, повторы адресов отбрасываются
+func (n *Notification) SetAddresses(channel string, addrs []string) {
+	r := make([]Recipient, 0, len(n.Recipients)+len(addrs))
+	for _, rc := range n.Recipients {
+		if rc.Channel != channel {
+			r = append(r, rc)
+		}
+	}
+	seen := make(map[string]struct{}, len(addrs))
+	for _, a := range addrs {
+		if _, ok := seen[a]; ok {
+			continue
+		}
+		seen[a] = struct{}{}
+		r = append(r, Recipient{
+			Channel: channel, Address: a, Status: RecipientPending,
+		})
+	}
+	n.Recipients = r
+}
+
+// Update изменения уведомления, nil поля остаются без изменений. Telegram и
+// Emails заменяют весь список получателей канала. Version - ожидаемая версия
+// уведомления, 0 - без проверки
+type Update struct {
+	Status   *string
+	Message  *string
+	Telegram *[]string
+	Emails   *[]string
+	Date     *time.Time
+	Timezone *string
+	Version  int64
+}
+
+// HasContent меняет ли обновление содержимое или время отправки, такие
+// изменения требуют новой версии и повторной публикации в очередь
+func (u Update) HasContent() bool {
+	return u.Message != nil || u.Telegram != nil || u.Emails != nil ||
+		u.Date != nil || u.Timezone != nil
+}
+
+func (u Update) Empty() bool {
+	return u.Status == nil && !u.HasContent()
+}
+
+func (u Update) Apply(n Notification) Notification {
+	if u.Status != nil {
+		n.Status = *u.Status
+	}
+	if u.Message != nil {
+		n.Message = *u.Message
+		n.TelegramMessage, n.EmailMessage = "", ""
+	}
+	if u.Telegram != nil {
+		n.SetAddresses(ChannelTelegram, *u.Telegram)
+	}
+	if u.Emails != nil {
+		n.SetAddresses(ChannelEmail, *u.Emails)
+	}
+	if u.Date != nil {
+		n.Date = *u.Date
+	}
+	if u.Timezone != nil {
+		n.Timezone = *u.Timezone
+	}
+
+	return n
+}
+
+// BatchResult результат создания одного уведомления из пачки
+type BatchResult struct {
+	ID  int64
+	Err error
+}
+
+// Filter параметры выборки списка уведомлений
+type Filter struct {
+	TenantID  int64
+	Status    string
+	Channel   string
+	Recipient string
+	Text      string
+	SeriesID  int64
+	From      time.Time
+	To        time.Time
+	Sort      string
+	Desc      bool
+	Limit     int
+	Cursor    *Cursor
+}
+
+// Cursor позиция последнего элемента страницы для keyset пагинации
+type Cursor struct {
+	Date time.Time
+	ID   int64
+}
+
+func (c Cursor) String() string {
+	v := strconv.FormatInt(c.Date.UnixNano(), 10) + ":" +
+		strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(v))
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	v, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrWrongCursor
+	}
+	dt, id, ok := strings.Cut(string(v), ":")
+	if !ok {
+		return Cursor{}, ErrWrongCursor
+	}
+	ns, err := strconv.ParseInt(dt, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrWrongCursor
+	}
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || i <= 0 {
+		return Cursor{}, ErrWrongCursor
+	}
+
+	return Cursor{Date: time.Unix(0, ns).UTC(), ID: i}, nil
+}
+
+// Location зона уведомления, для неизвестной зоны - UTC
+func (n Notification) Location() *time.Location {
+	loc, err := LoadLocation(n.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Local время отправки в зоне уведомления
+func (n Notification) Local() time.Time {
+	return n.Date.In(n.Location())
+}
+
+// Hash отпечаток содержимого уведомления, по нему сравниваются повторные
+// запросы с одним ключом идемпотентности
+func (n Notification) Hash() string {
+	h := sha256.New()
+	_ = binary.Write(h, binary.LittleEndian, n.Date.UnixNano())
+	_ = binary.Write(h, binary.LittleEndian, n.ContactID)
+	_ = binary.Write(h, binary.LittleEndian, n.RetryMaxAttempts)
+	_ = binary.Write(h, binary.LittleEndian, n.RetryBackoff)
+	fields := []string{
+		n.Message, n.Timezone,
+		n.TelegramMessage, n.EmailSubject, n.EmailMessage,
+	}
+	for _, rc := range n.Recipients {
+		fields = append(fields, rc.Channel, rc.Address)
+	}
+	for _, k := range n.webhookHeaderNames() {
+		fields = append(fields, k, n.WebhookHeaders[k])
+	}
+	fields = append(fields, n.WebhookSecret)
+	for _, f := range fields {
+		_ = binary.Write(h, binary.LittleEndian, int32(len(f)))
+		h.Write([]byte(f))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// webhookHeaderNames имена заголовков webhook по порядку, чтобы отпечаток и
+// бинарный вид не зависели от обхода map
+func (n Notification) webhookHeaderNames() []string {
+	r := make([]string, 0, len(n.WebhookHeaders))
+	for k := range n.WebhookHeaders {
+		r = append(r, k)
+	}
+	slices.Sort(r)
+
+	return r
 }
 
 func (n Notification) MarshalBinary() ([]byte, error) {
@@ -34,11 +376,8 @@ func (n Notification) MarshalBinary() ([]byte, error) {
 	if err := binary.Write(b, binary.LittleEndian, n.ID); err != nil {
 		return nil, err
 	}
-	if err := binary.Write(b, binary.LittleEndian, n.TelegramID); err != nil {
-		return nil, err
-	}
 
-	strFields := []string{n.Message, n.Email, n.Status}
+	strFields := []string{n.Message, n.Status}
 	for _, f := range strFields {
 		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
 			return nil, err
@@ -57,6 +396,78 @@ func (n Notification) MarshalBinary() ([]byte, error) {
 	if err := binary.Write(b, binary.LittleEndian, d); err != nil {
 		return nil, err
 	}
+	if err := binary.Write(b, binary.LittleEndian, n.Version); err != nil {
+		return nil, err
+	}
+	c, err := n.CancelledAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range [][]byte{c, []byte(n.CancelReason)} {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.SeriesID); err != nil {
+		return nil, err
+	}
+	channelFields := []string{
+		n.Timezone, n.TelegramMessage, n.EmailSubject, n.EmailMessage,
+	}
+	for _, f := range channelFields {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.TemplateID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, int32(len(n.Recipients))); err != nil {
+		return nil, err
+	}
+	for _, rc := range n.Recipients {
+		for _, f := range []string{rc.Channel, rc.Address, rc.Status, rc.Error} {
+			if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+				return nil, err
+			}
+			if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.ContactID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.TenantID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, int32(len(n.WebhookHeaders))); err != nil {
+		return nil, err
+	}
+	webhookFields := make([]string, 0, 2*len(n.WebhookHeaders)+1)
+	for _, k := range n.webhookHeaderNames() {
+		webhookFields = append(webhookFields, k, n.WebhookHeaders[k])
+	}
+	webhookFields = append(webhookFields, n.WebhookSecret)
+	for _, f := range webhookFields {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []int64{n.RetryMaxAttempts, n.RetryBackoff, n.Attempt} {
+		if err := binary.Write(b, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
 	return b.Bytes(), nil
 }
 
@@ -72,38 +483,152 @@ func (n *Notification) UnmarshalBinary(data []byte) error {
 		return err
 	}
 	n.ID = ID
-	var TelegramID int64
-	if err := binary.Read(b, binary.LittleEndian, &TelegramID); err != nil {
-		return err
-	}
-	n.TelegramID = TelegramID
 
-	strFields := []*string{&n.Message, &n.Email, &n.Status}
+	strFields := []*string{&n.Message, &n.Status}
 	for _, f := range strFields {
-		var l int32
-		if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+		bf, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		*f = string(bf)
+	}
+	t := time.Time{}
+	bf, err := readBytes(b)
+	if err != nil {
+		return err
+	}
+	err = t.UnmarshalBinary(bf)
+	if err != nil {
+		return err
+	}
+	n.Date = t
+	var Version int64
+	if err := binary.Read(b, binary.LittleEndian, &Version); err != nil {
+		return err
+	}
+	n.Version = Version
+	var tail [2][]byte
+	for i := range tail {
+		bf, err := readBytes(b)
+		if err != nil {
 			return err
 		}
-		bf := make([]byte, l)
-		if err := binary.Read(b, binary.LittleEndian, bf); err != nil {
+		tail[i] = bf
+	}
+	if err := n.CancelledAt.UnmarshalBinary(tail[0]); err != nil {
+		return err
+	}
+	n.CancelReason = string(tail[1])
+	var SeriesID int64
+	if err := binary.Read(b, binary.LittleEndian, &SeriesID); err != nil {
+		return err
+	}
+	n.SeriesID = SeriesID
+	channelFields := []*string{
+		&n.Timezone, &n.TelegramMessage, &n.EmailSubject, &n.EmailMessage,
+	}
+	for _, f := range channelFields {
+		bf, err := readBytes(b)
+		if err != nil {
 			return err
 		}
 		*f = string(bf)
 	}
-	t := time.Time{}
-	var l int32
-	if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+	var TemplateID int64
+	if err := binary.Read(b, binary.LittleEndian, &TemplateID); err != nil {
 		return err
 	}
-	bf := make([]byte, l)
-	if err := binary.Read(b, binary.LittleEndian, bf); err != nil {
+	n.TemplateID = TemplateID
+	// у получателя 4 строки, у каждой длина не меньше 4 байт
+	count, err := readCount(b, 16)
+	if err != nil {
+		return err
+	}
+	n.Recipients = nil
+	for i := int32(0); i < count; i++ {
+		rc := Recipient{}
+		for _, f := range []*string{&rc.Channel, &rc.Address, &rc.Status, &rc.Error} {
+			bf, err := readBytes(b)
+			if err != nil {
+				return err
+			}
+			*f = string(bf)
+		}
+		n.Recipients = append(n.Recipients, rc)
+	}
+	var ContactID int64
+	if err := binary.Read(b, binary.LittleEndian, &ContactID); err != nil {
 		return err
 	}
-	err := t.UnmarshalBinary(bf)
+	n.ContactID = ContactID
+	var TenantID int64
+	if err := binary.Read(b, binary.LittleEndian, &TenantID); err != nil {
+		return err
+	}
+	n.TenantID = TenantID
+	headers, err := readCount(b, 8)
 	if err != nil {
 		return err
 	}
-	n.Date = t
+	readString := func() (string, error) {
+		bf, err := readBytes(b)
+		return string(bf), err
+	}
+	n.WebhookHeaders = nil
+	for i := int32(0); i < headers; i++ {
+		k, err := readString()
+		if err != nil {
+			return err
+		}
+		v, err := readString()
+		if err != nil {
+			return err
+		}
+		if n.WebhookHeaders == nil {
+			n.WebhookHeaders = make(map[string]string, headers)
+		}
+		n.WebhookHeaders[k] = v
+	}
+	secret, err := readString()
+	if err != nil {
+		return err
+	}
+	n.WebhookSecret = secret
+	for _, f := range []*int64{&n.RetryMaxAttempts, &n.RetryBackoff, &n.Attempt} {
+		if err := binary.Read(b, binary.LittleEndian, f); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// readBytes читает поле с длиной впереди. Длина проверяется до выделения
+// памяти, иначе поврежденное сообщение из очереди роняет процесс
+func readBytes(b *bytes.Reader) ([]byte, error) {
+	var l int32
+	if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+		return nil, err
+	}
+	if l < 0 || int64(l) > int64(b.Len()) {
+		return nil, ErrCorrupted
+	}
+	bf := make([]byte, l)
+	if _, err := io.ReadFull(b, bf); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// readCount читает число элементов, каждый из которых занимает не меньше
+// size байт
+func readCount(b *bytes.Reader, size int64) (int32, error) {
+	var n int32
+	if err := binary.Read(b, binary.LittleEndian, &n); err != nil {
+		return 0, err
+	}
+	if n < 0 || int64(n)*size > int64(b.Len()) {
+		return 0, ErrCorrupted
+	}
+	return n, nil
+}
@@ -0,0 +1,35 @@
+package notification
+
+import "time"
+
+// Overdue ожидающее уведомление, время которого давно прошло: отложенное
+// сообщение, скорее всего, потеряно. Republished - сколько раз его
+// опубликовали заново, OverdueAt - когда повторы кончились
+type Overdue struct {
+	ID            int64      `db:"id" json:"id"`
+	TenantID      int64      `db:"tenant_id" json:"tenant_id"`
+	Date          time.Time  `db:"dt" json:"date"`
+	Version       int64      `db:"version" json:"version"`
+	Republished   int64      `db:"republished" json:"republished"`
+	RepublishedAt *time.Time `db:"republished_at" json:"republished_at,omitempty"`
+	OverdueAt     *time.Time `db:"overdue_at" json:"overdue_at,omitempty"`
+}
+
+// OverdueFilter параметры отчета о просроченных: ожидающие с временем
+// раньше Before. Flagged - только те, повторы которых кончились. After -
+// курсор: id последнего уведомления предыдущей страницы
+type OverdueFilter struct {
+	TenantID int64
+	Before   time.Time
+	Flagged  bool
+	After    int64
+	Limit    int
+}
+
+// Sweep итог прохода по просроченным. Skipped - проход уже идет в другом
+// экземпляре notifier
+type Sweep struct {
+	Republished []Notification
+	Flagged     int64
+	Skipped     bool
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import "time"
+
+// Message сообщение для отложенного обменника, записывается в одной
+// транзакции с уведомлением и публикуется relay
+type Message struct {
+	ID             int64     `db:"id"`
+	NotificationID int64     `db:"notification_id"`
+	Payload        []byte    `db:"payload"`
+	PublishAt      time.Time `db:"publish_at"`
+	Attempts       int64     `db:"attempts"`
+}
+
+// Delay сколько мс сообщение должно пролежать в обменнике, если публикуется
+// в момент now. Для просроченного - отрицательное, sender получит его сразу
+func (m Message) Delay(now time.Time) int64 {
+	return m.PublishAt.UnixMilli() - now.UnixMilli()
+}
@@ -1,64 +1,718 @@
 package request
 
 import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"fmt"
+	"net/textproto"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// MaxRecipients максимальное количество получателей одного уведомления
+const MaxRecipients = 100
+
+// addresses объединяет одиночное поле и список адресов канала, nil - канал
+// в запросе не задан
+func addresses(one *string, many *[]string) *[]string {
+	if one == nil && many == nil {
+		return nil
+	}
+	r := make([]string, 0, 1)
+	if one != nil && *one != "" {
+		r = append(r, *one)
+	}
+	if many != nil {
+		r = append(r, *many...)
+	}
+
+	return &r
+}
+
+// telegramIDs проверяет chat id и приводит их к каноничному виду
+func telegramIDs(ids []string) ([]string, string) {
+	r := make([]string, 0, len(ids))
+	for _, id := range ids {
+		v, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, "telegram_id, shoud be numeric"
+		} else if v <= 0 {
+			return nil, "wrong telegram_id, shoud be >0"
+		}
+		r = append(r, strconv.FormatInt(v, 10))
+	}
+
+	return r, ""
+}
+
+// MaxWebhookHeaders, MaxWebhookSecretLen ограничения канала webhook
+const (
+	MaxWebhookURLLen    = 255
+	MaxWebhookHeaders   = 20
+	MaxWebhookSecretLen = 255
+)
+
+// webhookReserved заголовки, которые sender ставит сам
+var webhookReserved = map[string]struct{}{
+	"Content-Type":        {},
+	"Content-Length":      {},
+	"Host":                {},
+	"X-Webhook-Signature": {},
+	"X-Webhook-Timestamp": {},
+}
+
+// webhook проверяет URL, заголовки и секрет канала webhook. Имена заголовков
+// приводятся к каноничному виду
+func webhook(u string, headers map[string]string, secret string) (map[string]string, string) {
+	if u == "" {
+		if len(headers) != 0 || secret != "" {
+			return nil, "webhook_headers and webhook_secret can be used only with webhook_url"
+		}
+		return nil, ""
+	}
+	if len(u) > MaxWebhookURLLen {
+		return nil, fmt.Sprintf("webhook_url is longer than %d", MaxWebhookURLLen)
+	}
+	p, err := url.Parse(u)
+	if err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+		return nil, "wrong webhook_url (absolute http or https url expected)"
+	}
+	if secret == "" {
+		return nil, "webhook_secret is empty"
+	}
+	if len(secret) > MaxWebhookSecretLen {
+		return nil, fmt.Sprintf("webhook_secret is longer than %d", MaxWebhookSecretLen)
+	}
+	if len(headers) > MaxWebhookHeaders {
+		return nil, fmt.Sprintf("too many webhook_headers (max %d)", MaxWebhookHeaders)
+	}
+	var r map[string]string
+	for k, v := range headers {
+		name := textproto.CanonicalMIMEHeaderKey(k)
+		if name == "" || strings.ContainsAny(name, " :\r\n") ||
+			strings.ContainsAny(v, "\r\n") {
+			return nil, "wrong webhook header " + strconv.Quote(k)
+		}
+		if _, ok := webhookReserved[name]; ok {
+			return nil, "webhook header " + name + " is set by sender"
+		}
+		if r == nil {
+			r = make(map[string]string, len(headers))
+		}
+		r[name] = v
+	}
+
+	return r, ""
+}
+
+// MaxRetryAttempts, MaxRetryBackoff ограничения политики повтора
+const (
+	MaxRetryAttempts = 20
+	MaxRetryBackoff  = 24 * 60 * 60
+)
+
+// Retry политика повтора неудачной отправки, незаданные поля берутся из
+// настроек sender
+type Retry struct {
+	// MaxAttempts попыток отправки всего, 1 - без повторов
+	MaxAttempts int64 `json:"max_attempts,omitempty"`
+	// BackoffSeconds пауза перед первым повтором, дальше удваивается
+	BackoffSeconds int64 `json:"backoff_seconds,omitempty"`
+}
+
+func (r *Retry) Validate() string {
+	if r.MaxAttempts < 0 || r.MaxAttempts > MaxRetryAttempts {
+		return fmt.Sprintf("wrong retry.max_attempts (0..%d, 0 - sender default)", MaxRetryAttempts)
+	}
+	if r.BackoffSeconds < 0 || r.BackoffSeconds > MaxRetryBackoff {
+		return fmt.Sprintf("wrong retry.backoff_seconds (0..%d, 0 - sender default)", MaxRetryBackoff)
+	}
+
+	return ""
+}
+
+func emails(es []string) ([]string, string) {
+	for _, e := range es {
+		if !strings.Contains(e, "@") || !strings.Contains(e, ".") {
+			return nil, "wrong email format"
+		}
+	}
+
+	return es, ""
+}
+
+// UpdateNotification модель запроса для изменения уведомления, незаданные
+// поля остаются без изменений. Заданный канал (telegram_id/telegram_ids или
+// email/emails) заменяет всех его получателей, пустое значение - очищает.
+// Статус клиент не меняет, он выводится из доставки, поле Status только для
+// понятной ошибки
 type UpdateNotification struct {
-	Status string `json:"status"`
+	Status      *string   `json:"status" swaggerignore:"true"`
+	Message     *string   `json:"message"`
+	TelegramID  *string   `json:"telegram_id"`
+	TelegramIDs *[]string `json:"telegram_ids"`
+	Email       *string   `json:"email"`
+	Emails      *[]string `json:"emails"`
+	Date        *string   `json:"date"`
+	Timezone    *string   `json:"timezone"`
+	Version     int64     `json:"version"`
 }
 
-func (u *UpdateNotification) Validate() (status, msg string) {
-	if u.Status == notification.StatusPending {
-		return notification.StatusPending, ""
+func (u *UpdateNotification) Validate() (notification.Update, string) {
+	r := notification.Update{}
+
+	if u.Status != nil {
+		return notification.Update{}, "status can't be changed, it is set by delivery"
 	}
-	if u.Status == notification.StatusComplete {
-		return notification.StatusComplete, ""
+	if u.Message != nil {
+		if *u.Message == "" {
+			return notification.Update{}, "message is empty"
+		}
+		r.Message = u.Message
+	}
+	if tg := addresses(u.TelegramID, u.TelegramIDs); tg != nil {
+		ids, msg := telegramIDs(*tg)
+		if msg != "" {
+			return notification.Update{}, msg
+		}
+		r.Telegram = &ids
+	}
+	if em := addresses(u.Email, u.Emails); em != nil {
+		es, msg := emails(*em)
+		if msg != "" {
+			return notification.Update{}, msg
+		}
+		r.Emails = &es
+	}
+	count := 0
+	for _, a := range []*[]string{r.Telegram, r.Emails} {
+		if a != nil {
+			count += len(*a)
+		}
+	}
+	if count > MaxRecipients {
+		return notification.Update{}, fmt.Sprintf(
+			"too many recipients (max %d)", MaxRecipients,
+		)
+	}
+	loc := time.UTC
+	if u.Timezone != nil {
+		l, err := notification.LoadLocation(*u.Timezone)
+		if err != nil {
+			return notification.Update{}, err.Error()
+		}
+		tz := l.String()
+		r.Timezone = &tz
+		loc = l
+	}
+	if u.Date != nil {
+		t, err := notification.ParseDate(*u.Date, loc)
+		if err != nil {
+			return notification.Update{}, "wrong date value (format: RFC3339)"
+		}
+		r.Date = &t
+	}
+	if u.Version < 0 {
+		return notification.Update{}, "version should be positive"
+	}
+	r.Version = u.Version
+
+	if r.Empty() {
+		return notification.Update{}, "nothing to update"
 	}
 
-	return "", "wrong status"
+	return r, ""
 }
 
-// CreateNotification модель запроса для создания нового уведомления
+// MaxBatchSize максимальное количество уведомлений в одном запросе
+const MaxBatchSize = 500
+
+// CreateNotification модель запроса для создания нового уведомления,
+// получатели задаются одиночными полями и/или списками
 type CreateNotification struct {
-	Message    string `json:"message"`
-	TelegramID string `json:"telegram_id"`
-	Email      string `json:"email"`
-	Date       string `json:"date"`
+	Message     string   `json:"message"`
+	TelegramID  string   `json:"telegram_id"`
+	TelegramIDs []string `json:"telegram_ids,omitempty"`
+	Email       string   `json:"email"`
+	Emails      []string `json:"emails,omitempty"`
+	// WebhookURL адрес, на который sender отправит POST с подписанным телом,
+	// WebhookSecret обязателен вместе с ним
+	WebhookURL     string            `json:"webhook_url,omitempty"`
+	WebhookHeaders map[string]string `json:"webhook_headers,omitempty"`
+	WebhookSecret  string            `json:"webhook_secret,omitempty"`
+	// ContactID сохраненный контакт, можно вместо адресов или вместе с ними
+	ContactID int64  `json:"contact_id,omitempty"`
+	Date      string `json:"date"`
+	// Timezone IANA зона, например "Europe/Moscow", по умолчанию UTC. Date
+	// без смещения трактуется в этой зоне
+	Timezone string `json:"timezone,omitempty"`
+	// TemplateID шаблон вместо message, Params - значения его переменных
+	TemplateID int64          `json:"template_id,omitempty"`
+	Params     map[string]any `json:"params,omitempty"`
+	// Recurrence правило повторения в формате cron, например "0 9 * * 1"
+	Recurrence      string `json:"recurrence,omitempty"`
+	RecurrenceUntil string `json:"recurrence_until,omitempty"`
+	RecurrenceCount int64  `json:"recurrence_count,omitempty"`
+	// Retry своя политика повтора неудачной отправки
+	Retry *Retry `json:"retry,omitempty"`
+}
+
+// Recurring задано ли правило повторения
+func (c *CreateNotification) Recurring() bool {
+	return c.Recurrence != "" || c.RecurrenceUntil != "" || c.RecurrenceCount != 0
+}
+
+// ValidateSeries проверяет поля повторения, для серии дата первого
+// срабатывания необязательна
+func (c *CreateNotification) ValidateSeries() (series.Series, notification.Notification, string) {
+	sr := series.Series{}
+	if c.Recurrence == "" {
+		return series.Series{}, notification.Notification{}, "recurrence is empty"
+	}
+	if _, err := series.ParseRule(c.Recurrence); err != nil {
+		return series.Series{}, notification.Notification{}, err.Error()
+	}
+	sr.Rule = c.Recurrence
+	loc, err := notification.LoadLocation(c.Timezone)
+	if err != nil {
+		return series.Series{}, notification.Notification{}, err.Error()
+	}
+	if c.RecurrenceUntil != "" {
+		t, err := notification.ParseDate(c.RecurrenceUntil, loc)
+		if err != nil {
+			return series.Series{}, notification.Notification{},
+				"wrong recurrence_until value (format: RFC3339)"
+		}
+		sr.Until = t
+	}
+	if c.RecurrenceCount < 0 {
+		return series.Series{}, notification.Notification{},
+			"recurrence_count should be positive"
+	}
+	sr.Count = c.RecurrenceCount
+
+	tmp := *c
+	if tmp.Date == "" {
+		tmp.Date = time.Time{}.Format(notification.DateLayout)
+	}
+	n, msg := tmp.Validate()
+	if msg != "" {
+		return series.Series{}, notification.Notification{}, msg
+	}
+
+	return sr, n, ""
 }
 
 func (c *CreateNotification) Validate() (notification.Notification, string) {
 	r := notification.Notification{}
-	if c.Message == "" {
+	if c.TemplateID != 0 {
+		if c.Message != "" {
+			return notification.Notification{}, "message and template_id can't be used together"
+		}
+		if c.TemplateID < 0 {
+			return notification.Notification{}, "template_id should be positive"
+		}
+	} else if c.Message == "" {
 		return notification.Notification{}, "message is empty"
+	} else if len(c.Params) != 0 {
+		return notification.Notification{}, "params can be used only with template_id"
 	}
 	r.Message = c.Message
-	if c.TelegramID == "" && c.Email == "" {
+	ids, msg := telegramIDs(*addresses(&c.TelegramID, &c.TelegramIDs))
+	if msg != "" {
+		return notification.Notification{}, msg
+	}
+	es, msg := emails(*addresses(&c.Email, &c.Emails))
+	if msg != "" {
+		return notification.Notification{}, msg
+	}
+	r.SetAddresses(notification.ChannelTelegram, ids)
+	r.SetAddresses(notification.ChannelEmail, es)
+	headers, msg := webhook(c.WebhookURL, c.WebhookHeaders, c.WebhookSecret)
+	if msg != "" {
+		return notification.Notification{}, msg
+	}
+	if c.WebhookURL != "" {
+		r.SetAddresses(notification.ChannelWebhook, []string{c.WebhookURL})
+		r.WebhookHeaders = headers
+		r.WebhookSecret = c.WebhookSecret
+	}
+	if c.ContactID < 0 {
+		return notification.Notification{}, "contact_id should be positive"
+	}
+	r.ContactID = c.ContactID
+	if c.Retry != nil {
+		if msg := c.Retry.Validate(); msg != "" {
+			return notification.Notification{}, msg
+		}
+		r.RetryMaxAttempts = c.Retry.MaxAttempts
+		r.RetryBackoff = c.Retry.BackoffSeconds
+	}
+	if len(r.Recipients) == 0 && r.ContactID == 0 {
 		return notification.Notification{}, "both send variant is empty"
 	}
-	if c.TelegramID != "" {
-		tID, err := strconv.ParseInt(c.TelegramID, 10, 64)
+	if len(r.Recipients) > MaxRecipients {
+		return notification.Notification{}, fmt.Sprintf(
+			"too many recipients (max %d)", MaxRecipients,
+		)
+	}
+	loc, err := notification.LoadLocation(c.Timezone)
+	if err != nil {
+		return notification.Notification{}, err.Error()
+	}
+	r.Timezone = loc.String()
+	t, err := notification.ParseDate(c.Date, loc)
+	if err != nil {
+		return notification.Notification{}, "wrong date value (format: RFC3339)"
+	}
+	r.Date = t
+
+	return r, ""
+}
+
+// ListNotifications модель запроса для получения списка уведомлений
+type ListNotifications struct {
+	Status    string `form:"status"`
+	Channel   string `form:"channel"`
+	Recipient string `form:"recipient"`
+	Text      string `form:"text"`
+	From      string `form:"from"`
+	To        string `form:"to"`
+	Sort      string `form:"sort"`
+	Order     string `form:"order"`
+	Limit     string `form:"limit"`
+	Cursor    string `form:"cursor"`
+}
+
+func (l *ListNotifications) Validate() (notification.Filter, string) {
+	f := notification.Filter{}
+
+	switch l.Status {
+	case "", notification.StatusPending, notification.StatusSending,
+		notification.StatusSent, notification.StatusPartiallyFailed,
+		notification.StatusFailed, notification.StatusCancelled:
+		f.Status = l.Status
+	default:
+		return notification.Filter{}, "wrong status"
+	}
+	switch l.Channel {
+	case "", notification.ChannelTelegram, notification.ChannelEmail,
+		notification.ChannelWebhook:
+		f.Channel = l.Channel
+	default:
+		return notification.Filter{}, "wrong channel (\"telegram\", \"email\" or \"webhook\" only)"
+	}
+	f.Recipient = l.Recipient
+	f.Text = l.Text
+
+	if l.From != "" {
+		t, err := time.Parse(notification.DateLayout, l.From)
 		if err != nil {
-			return notification.Notification{}, "telegram_id, shoud be numeric"
-		} else if tID <= 0 {
-			return notification.Notification{}, "wrong telegram_id, shoud be >0"
+			return notification.Filter{}, "wrong from value (format: RFC3339)"
+		}
+		f.From = t
+	}
+	if l.To != "" {
+		t, err := time.Parse(notification.DateLayout, l.To)
+		if err != nil {
+			return notification.Filter{}, "wrong to value (format: RFC3339)"
+		}
+		f.To = t
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.From.After(f.To) {
+		return notification.Filter{}, "from should be before to"
+	}
+
+	switch l.Sort {
+	case "", notification.SortByDate:
+		f.Sort = notification.SortByDate
+	case notification.SortByID:
+		f.Sort = notification.SortByID
+	default:
+		return notification.Filter{}, "wrong sort (\"dt\" or \"id\" only)"
+	}
+	switch l.Order {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		return notification.Filter{}, "wrong order (\"asc\" or \"desc\" only)"
+	}
+
+	if l.Limit != "" {
+		lm, err := strconv.Atoi(l.Limit)
+		if err != nil || lm <= 0 {
+			return notification.Filter{}, "limit should be positive numeric"
 		}
-		r.TelegramID = tID
+		f.Limit = lm
 	}
-	if c.Email != "" {
-		if !strings.Contains(c.Email, "@") || !strings.Contains(c.Email, ".") {
-			return notification.Notification{}, "wrong email format"
+	if l.Cursor != "" {
+		c, err := notification.ParseCursor(l.Cursor)
+		if err != nil {
+			return notification.Filter{}, err.Error()
 		}
+		f.Cursor = &c
 	}
-	r.Email = c.Email
-	t, err := time.Parse(notification.DateLayout, c.Date)
+
+	return f, ""
+}
+
+// PurgeNotifications модель запроса для удаления отмененных уведомлений
+type PurgeNotifications struct {
+	Before string `json:"before"`
+}
+
+func (p *PurgeNotifications) Validate() (time.Time, string) {
+	t, err := time.Parse(notification.DateLayout, p.Before)
 	if err != nil {
-		return notification.Notification{}, "wrong date value (format: 2006-01-02 15:04)"
+		return time.Time{}, "wrong before value (format: RFC3339)"
+	}
+
+	return t, ""
+}
+
+// UpdateSeries модель запроса для изменения статуса серии
+type UpdateSeries struct {
+	Status string `json:"status"`
+}
+
+func (u *UpdateSeries) Validate() (string, string) {
+	switch u.Status {
+	case series.StatusActive, series.StatusPaused, series.StatusStopped:
+		return u.Status, ""
+	default:
+		return "", "wrong status (\"active\", \"paused\" or \"stopped\" only)"
+	}
+}
+
+// Template модель запроса для создания и изменения шаблона сообщения,
+// переменные в формате text/template: {{.name}}
+type Template struct {
+	Name         string `json:"name"`
+	Body         string `json:"body"`
+	TelegramBody string `json:"telegram_body"`
+	EmailSubject string `json:"email_subject"`
+	EmailBody    string `json:"email_body"`
+}
+
+func (t *Template) Validate() (template.Template, string) {
+	if t.Name == "" {
+		return template.Template{}, "name is empty"
+	}
+	r := template.Template{
+		Name:         t.Name,
+		Body:         t.Body,
+		TelegramBody: t.TelegramBody,
+		EmailSubject: t.EmailSubject,
+		EmailBody:    t.EmailBody,
+	}
+	if err := r.Validate(); err != nil {
+		return template.Template{}, err.Error()
+	}
+
+	return r, ""
+}
+
+// Contact модель запроса для создания и изменения контакта
+type Contact struct {
+	Name             string `json:"name"`
+	TelegramID       string `json:"telegram_id"`
+	Email            string `json:"email"`
+	PreferredChannel string `json:"preferred_channel"`
+	Locale           string `json:"locale"`
+	Timezone         string `json:"timezone"`
+}
+
+func (c *Contact) Validate() (contact.Contact, string) {
+	r := contact.Contact{
+		Name:             c.Name,
+		TelegramID:       c.TelegramID,
+		Email:            c.Email,
+		PreferredChannel: c.PreferredChannel,
+		Locale:           c.Locale,
+		Timezone:         c.Timezone,
+	}
+	if r.TelegramID != "" {
+		ids, msg := telegramIDs([]string{r.TelegramID})
+		if msg != "" {
+			return contact.Contact{}, msg
+		}
+		r.TelegramID = ids[0]
+	}
+	if r.Timezone == "" {
+		r.Timezone = notification.DefaultTimezone
+	}
+	if err := r.Validate(); err != nil {
+		return contact.Contact{}, err.Error()
 	}
-	r.Date = t
 
 	return r, ""
 }
+
+type APIKey struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (k *APIKey) Validate() (APIKey, string) {
+	if k.Name == "" {
+		return APIKey{}, "name is empty"
+	}
+	if err := apikey.ValidateScopes(k.Scopes); err != nil {
+		return APIKey{}, err.Error()
+	}
+
+	return *k, ""
+}
+
+type Tenant struct {
+	Name string `json:"name"`
+}
+
+func (t *Tenant) Validate() (string, string) {
+	name := strings.TrimSpace(t.Name)
+	if name == "" {
+		return "", "name is empty"
+	}
+
+	return name, ""
+}
+
+// ListDeadLetters модель запроса для получения записей DLQ, cursor - id
+// последней записи предыдущей страницы
+type ListDeadLetters struct {
+	TenantID       string `form:"tenant_id"`
+	NotificationID string `form:"notification_id"`
+	Channel        string `form:"channel"`
+	Reason         string `form:"reason"`
+	Limit          string `form:"limit"`
+	Cursor         string `form:"cursor"`
+}
+
+// positive разбирает необязательный положительный параметр запроса
+func positive(name, v string) (int64, string) {
+	if v == "" {
+		return 0, ""
+	}
+	r, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || r <= 0 {
+		return 0, name + " should be positive numeric"
+	}
+
+	return r, ""
+}
+
+func deadLetterReason(reason string) string {
+	switch reason {
+	case "", deadletter.ReasonUnreadable, deadletter.ReasonPermanent,
+		deadletter.ReasonExhausted:
+		return ""
+	}
+
+	return "wrong reason (\"unreadable\", \"permanent_error\" or \"retries_exhausted\" only)"
+}
+
+func (l *ListDeadLetters) Validate() (deadletter.Filter, string) {
+	f := deadletter.Filter{Channel: l.Channel, Reason: l.Reason}
+	var msg string
+	if f.TenantID, msg = positive("tenant_id", l.TenantID); msg != "" {
+		return deadletter.Filter{}, msg
+	}
+	if f.NotificationID, msg = positive("notification_id", l.NotificationID); msg != "" {
+		return deadletter.Filter{}, msg
+	}
+	if f.After, msg = positive("cursor", l.Cursor); msg != "" {
+		return deadletter.Filter{}, msg
+	}
+	limit, msg := positive("limit", l.Limit)
+	if msg != "" {
+		return deadletter.Filter{}, msg
+	}
+	f.Limit = int(limit)
+	if msg := deadLetterReason(l.Reason); msg != "" {
+		return deadletter.Filter{}, msg
+	}
+
+	return f, ""
+}
+
+// ListOverdue модель запроса отчета о просроченных уведомлениях, cursor - id
+// последнего уведомления предыдущей страницы
+type ListOverdue struct {
+	TenantID string `form:"tenant_id"`
+	Flagged  string `form:"flagged"`
+	Limit    string `form:"limit"`
+	Cursor   string `form:"cursor"`
+}
+
+func (l *ListOverdue) Validate() (notification.OverdueFilter, string) {
+	f := notification.OverdueFilter{}
+	var msg string
+	if f.TenantID, msg = positive("tenant_id", l.TenantID); msg != "" {
+		return notification.OverdueFilter{}, msg
+	}
+	if f.After, msg = positive("cursor", l.Cursor); msg != "" {
+		return notification.OverdueFilter{}, msg
+	}
+	limit, msg := positive("limit", l.Limit)
+	if msg != "" {
+		return notification.OverdueFilter{}, msg
+	}
+	f.Limit = int(limit)
+	if l.Flagged != "" {
+		flagged, err := strconv.ParseBool(l.Flagged)
+		if err != nil {
+			return notification.OverdueFilter{}, "flagged should be true or false"
+		}
+		f.Flagged = flagged
+	}
+
+	return f, ""
+}
+
+// DeadLetterBulk модель запроса для массового replay или discard: либо
+// список ids, либо all с необязательным фильтром
+type DeadLetterBulk struct {
+	IDs            []int64 `json:"ids,omitempty"`
+	All            bool    `json:"all,omitempty"`
+	TenantID       int64   `json:"tenant_id,omitempty"`
+	NotificationID int64   `json:"notification_id,omitempty"`
+	Channel        string  `json:"channel,omitempty"`
+	Reason         string  `json:"reason,omitempty"`
+}
+
+func (b *DeadLetterBulk) Validate() ([]int64, deadletter.Filter, string) {
+	filtered := b.TenantID != 0 || b.NotificationID != 0 || b.Channel != "" ||
+		b.Reason != ""
+	if len(b.IDs) != 0 {
+		if b.All || filtered {
+			return nil, deadletter.Filter{}, "ids can't be used with all or filter"
+		}
+		for _, id := range b.IDs {
+			if id <= 0 {
+				return nil, deadletter.Filter{}, "ids should be positive"
+			}
+		}
+		return b.IDs, deadletter.Filter{}, ""
+	}
+	if !b.All {
+		return nil, deadletter.Filter{}, "ids or all is required"
+	}
+	if b.TenantID < 0 || b.NotificationID < 0 {
+		return nil, deadletter.Filter{}, "tenant_id and notification_id should be positive"
+	}
+	if msg := deadLetterReason(b.Reason); msg != "" {
+		return nil, deadletter.Filter{}, msg
+	}
+
+	return nil, deadletter.Filter{
+		TenantID: b.TenantID, NotificationID: b.NotificationID,
+		Channel: b.Channel, Reason: b.Reason,
+	}, ""
+}
@@ -1,15 +1,34 @@
 package request
 
 import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/entities/notification"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
+func manyIDs(n int) []string {
+	r := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		r = append(r, strconv.Itoa(i))
+	}
+
+	return r
+}
+
 func TestCreateNotify_Validate(t *testing.T) {
 	type fields struct {
-		Message    string
-		TelegramID string
-		Email      string
-		Date       string
+		Message     string
+		TelegramID  string
+		TelegramIDs []string
+		Email       string
+		Emails      []string
+		ContactID   int64
+		Date        string
+		Timezone    string
 	}
 	tests := []struct {
 		name    string
@@ -123,14 +142,94 @@ func TestCreateNotify_Validate(t *testing.T) {
 			},
 			wantMsg: true,
 		},
+		{
+			name: "local date with timezone",
+			fields: fields{
+				Message:    "haha",
+				TelegramID: "123",
+				Date:       "2000-12-22T15:06:00",
+				Timezone:   "Europe/Moscow",
+			},
+			wantMsg: false,
+		},
+		{
+			name: "wrong timezone",
+			fields: fields{
+				Message:    "haha",
+				TelegramID: "123",
+				Date:       "2000-12-22T15:06:00Z",
+				Timezone:   "Moscow",
+			},
+			wantMsg: true,
+		},
+		{
+			name: "recipient lists",
+			fields: fields{
+				Message:     "haha",
+				TelegramID:  "123",
+				TelegramIDs: []string{"456", "789"},
+				Emails:      []string{"a@asd.com", "b@asd.com"},
+				Date:        "2000-12-22T15:06:00Z",
+			},
+			wantMsg: false,
+		},
+		{
+			name: "wrong email in list",
+			fields: fields{
+				Message: "haha",
+				Emails:  []string{"a@asd.com", "basd.com"},
+				Date:    "2000-12-22T15:06:00Z",
+			},
+			wantMsg: true,
+		},
+		{
+			name: "wrong tg id in list",
+			fields: fields{
+				Message:     "haha",
+				TelegramIDs: []string{"123", "abc"},
+				Date:        "2000-12-22T15:06:00Z",
+			},
+			wantMsg: true,
+		},
+		{
+			name: "contact only",
+			fields: fields{
+				Message:   "haha",
+				ContactID: 3,
+				Date:      "2000-12-22T15:06:00Z",
+			},
+			wantMsg: false,
+		},
+		{
+			name: "negative contact",
+			fields: fields{
+				Message:   "haha",
+				ContactID: -3,
+				Date:      "2000-12-22T15:06:00Z",
+			},
+			wantMsg: true,
+		},
+		{
+			name: "too many recipients",
+			fields: fields{
+				Message:     "haha",
+				TelegramIDs: manyIDs(MaxRecipients + 1),
+				Date:        "2000-12-22T15:06:00Z",
+			},
+			wantMsg: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &CreateNotification{
-				Message:    tt.fields.Message,
-				TelegramID: tt.fields.TelegramID,
-				Email:      tt.fields.Email,
-				Date:       tt.fields.Date,
+				Message:     tt.fields.Message,
+				TelegramID:  tt.fields.TelegramID,
+				TelegramIDs: tt.fields.TelegramIDs,
+				Email:       tt.fields.Email,
+				Emails:      tt.fields.Emails,
+				ContactID:   tt.fields.ContactID,
+				Date:        tt.fields.Date,
+				Timezone:    tt.fields.Timezone,
 			}
 			_, got := c.Validate()
 			if (tt.wantMsg && got == "") || (!tt.wantMsg && got != "") {
@@ -139,3 +238,342 @@ func TestCreateNotify_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestListNotifications_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    ListNotifications
+		wantMsg bool
+	}{
+		{
+			name:    "empty",
+			data:    ListNotifications{},
+			wantMsg: false,
+		},
+		{
+			name: "all filters",
+			data: ListNotifications{
+				Status: "pending", Channel: "telegram", Recipient: "123",
+				Text: "hi", From: "2000-12-22T15:06:00.000Z",
+				To: "2000-12-23T15:06:00.000Z", Sort: "id", Order: "desc",
+				Limit: "10",
+			},
+			wantMsg: false,
+		},
+		{
+			name:    "wrong status",
+			data:    ListNotifications{Status: "test"},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong channel",
+			data:    ListNotifications{Channel: "sms"},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong from",
+			data:    ListNotifications{From: "2000-12-22 15:06"},
+			wantMsg: true,
+		},
+		{
+			name: "from after to",
+			data: ListNotifications{
+				From: "2000-12-23T15:06:00.000Z", To: "2000-12-22T15:06:00.000Z",
+			},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong sort",
+			data:    ListNotifications{Sort: "message"},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong order",
+			data:    ListNotifications{Order: "up"},
+			wantMsg: true,
+		},
+		{
+			name:    "negative limit",
+			data:    ListNotifications{Limit: "-1"},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong cursor",
+			data:    ListNotifications{Cursor: "haha"},
+			wantMsg: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := tt.data.Validate()
+			if (tt.wantMsg && got == "") || (!tt.wantMsg && got != "") {
+				t.Errorf("ListNotifications.Validate() got = %v, want %t", got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestUpdateNotification_Validate(t *testing.T) {
+	str := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		data    UpdateNotification
+		wantMsg bool
+	}{
+		{
+			name:    "status",
+			data:    UpdateNotification{Status: str("sending")},
+			wantMsg: true,
+		},
+		{
+			name:    "back to pending",
+			data:    UpdateNotification{Message: str("hi"), Status: str("pending")},
+			wantMsg: true,
+		},
+		{
+			name: "content",
+			data: UpdateNotification{
+				Message: str("hi"), TelegramID: str("123"), Email: str(""),
+				Date: str("2000-12-22T15:06:00.000Z"), Version: 1,
+			},
+			wantMsg: false,
+		},
+		{
+			name:    "empty",
+			data:    UpdateNotification{},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong status",
+			data:    UpdateNotification{Status: str("test")},
+			wantMsg: true,
+		},
+		{
+			name:    "empty message",
+			data:    UpdateNotification{Message: str("")},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong tg id",
+			data:    UpdateNotification{TelegramID: str("-1")},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong email",
+			data:    UpdateNotification{Email: str("asdasd.com")},
+			wantMsg: true,
+		},
+		{
+			name:    "wrong email in list",
+			data:    UpdateNotification{Emails: &[]string{"a@asd.com", "asdasd.com"}},
+			wantMsg: true,
+		},
+		{
+			name:    "clear channel",
+			data:    UpdateNotification{TelegramIDs: &[]string{}},
+			wantMsg: false,
+		},
+		{
+			name:    "wrong date",
+			data:    UpdateNotification{Date: str("2000-12-22 15:06")},
+			wantMsg: true,
+		},
+		{
+			name:    "negative version",
+			data:    UpdateNotification{Message: str("hi"), Version: -1},
+			wantMsg: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := tt.data.Validate()
+			if (tt.wantMsg && got == "") || (!tt.wantMsg && got != "") {
+				t.Errorf("UpdateNotification.Validate() got = %v, want %t", got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestCreateNotify_Recipients(t *testing.T) {
+	c := &CreateNotification{
+		Message:     "haha",
+		TelegramID:  "0123",
+		TelegramIDs: []string{"123", "456"},
+		Email:       "a@asd.com",
+		Emails:      []string{"b@asd.com"},
+		Date:        "2000-12-22T15:06:00Z",
+	}
+	n, msg := c.Validate()
+	require.Empty(t, msg)
+	require.Equal(t, []string{"123", "456"}, n.Addresses(notification.ChannelTelegram))
+	require.Equal(t, []string{"a@asd.com", "b@asd.com"}, n.Addresses(notification.ChannelEmail))
+	for _, rc := range n.Recipients {
+		require.Equal(t, notification.RecipientPending, rc.Status)
+	}
+}
+
+func TestCreateNotify_Webhook(t *testing.T) {
+	base := CreateNotification{Message: "haha", Date: "2000-12-22T15:06:00Z"}
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		secret  string
+		wantMsg bool
+	}{
+		{
+			name:    "good",
+			url:     "https://example.com/hook?a=1",
+			headers: map[string]string{"x-source": "crm"},
+			secret:  "s3cret",
+		},
+		{name: "no headers", url: "http://localhost:8081/hook", secret: "s3cret"},
+		{name: "no secret", url: "https://example.com/hook", wantMsg: true},
+		{name: "relative url", url: "/hook", secret: "s3cret", wantMsg: true},
+		{name: "ftp url", url: "ftp://example.com/hook", secret: "s3cret", wantMsg: true},
+		{
+			name: "long url", secret: "s3cret", wantMsg: true,
+			url: "https://example.com/" + strings.Repeat("a", MaxWebhookURLLen),
+		},
+		{name: "secret without url", secret: "s3cret", wantMsg: true},
+		{
+			name: "reserved header", url: "https://example.com/hook", secret: "s3cret",
+			headers: map[string]string{"x-webhook-signature": "fake"},
+			wantMsg: true,
+		},
+		{
+			name: "header injection", url: "https://example.com/hook", secret: "s3cret",
+			headers: map[string]string{"X-Source": "crm\r\nX-Other: 1"},
+			wantMsg: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			c.WebhookURL, c.WebhookHeaders, c.WebhookSecret = tt.url, tt.headers, tt.secret
+			n, msg := c.Validate()
+			if tt.wantMsg {
+				require.NotEmpty(t, msg)
+				return
+			}
+			require.Empty(t, msg)
+			require.Equal(t, []string{tt.url}, n.Addresses(notification.ChannelWebhook))
+			require.Equal(t, tt.secret, n.WebhookSecret)
+			for k := range n.WebhookHeaders {
+				require.Equal(t, "X-Source", k)
+			}
+		})
+	}
+}
+
+func TestContact_Validate(t *testing.T) {
+	c := Contact{Name: "Аня", TelegramID: "0123"}
+	r, msg := c.Validate()
+	require.Empty(t, msg)
+	require.Equal(t, "123", r.TelegramID)
+	require.Equal(t, notification.DefaultTimezone, r.Timezone)
+
+	c = Contact{Name: "Аня", TelegramID: "abc"}
+	_, msg = c.Validate()
+	require.NotEmpty(t, msg)
+
+	c = Contact{Name: "Аня", Email: "a@b.c", PreferredChannel: "telegram"}
+	_, msg = c.Validate()
+	require.NotEmpty(t, msg)
+}
+
+func TestCreateNotify_Retry(t *testing.T) {
+	tests := []struct {
+		name        string
+		retry       *Retry
+		wantMsg     bool
+		wantAttempt int64
+		wantBackoff int64
+	}{
+		{name: "default"},
+		{name: "override", retry: &Retry{MaxAttempts: 5, BackoffSeconds: 30}, wantAttempt: 5, wantBackoff: 30},
+		{name: "only attempts", retry: &Retry{MaxAttempts: 1}, wantAttempt: 1},
+		{name: "negative attempts", retry: &Retry{MaxAttempts: -1}, wantMsg: true},
+		{name: "too many attempts", retry: &Retry{MaxAttempts: MaxRetryAttempts + 1}, wantMsg: true},
+		{name: "long backoff", retry: &Retry{BackoffSeconds: MaxRetryBackoff + 1}, wantMsg: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CreateNotification{
+				Message: "haha", Email: "a@b.c", Date: "2000-12-22T15:06:00Z", Retry: tt.retry,
+			}
+			n, msg := c.Validate()
+			if tt.wantMsg {
+				require.NotEmpty(t, msg)
+				return
+			}
+			require.Empty(t, msg)
+			require.Equal(t, tt.wantAttempt, n.RetryMaxAttempts)
+			require.Equal(t, tt.wantBackoff, n.RetryBackoff)
+		})
+	}
+}
+
+func TestDeadLetterBulk_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       DeadLetterBulk
+		wantMsg bool
+		wantIDs int
+		wantF   deadletter.Filter
+	}{
+		{name: "ids", b: DeadLetterBulk{IDs: []int64{1, 2}}, wantIDs: 2},
+		{name: "all", b: DeadLetterBulk{All: true}},
+		{
+			name:  "all by filter",
+			b:     DeadLetterBulk{All: true, TenantID: 2, Reason: deadletter.ReasonPermanent},
+			wantF: deadletter.Filter{TenantID: 2, Reason: deadletter.ReasonPermanent},
+		},
+		{name: "nothing", b: DeadLetterBulk{}, wantMsg: true},
+		{name: "ids with filter", b: DeadLetterBulk{IDs: []int64{1}, Channel: "email"}, wantMsg: true},
+		{name: "negative id", b: DeadLetterBulk{IDs: []int64{-1}}, wantMsg: true},
+		{name: "wrong reason", b: DeadLetterBulk{All: true, Reason: "lost"}, wantMsg: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, f, msg := tt.b.Validate()
+			if tt.wantMsg {
+				require.NotEmpty(t, msg)
+				return
+			}
+			require.Empty(t, msg)
+			require.Len(t, ids, tt.wantIDs)
+			require.Equal(t, tt.wantF, f)
+		})
+	}
+}
+
+func TestListOverdue_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		l       ListOverdue
+		wantMsg bool
+		wantF   notification.OverdueFilter
+	}{
+		{name: "empty", l: ListOverdue{}},
+		{
+			name:  "all params",
+			l:     ListOverdue{TenantID: "2", Flagged: "true", Limit: "5", Cursor: "10"},
+			wantF: notification.OverdueFilter{TenantID: 2, Flagged: true, Limit: 5, After: 10},
+		},
+		{name: "wrong flagged", l: ListOverdue{Flagged: "maybe"}, wantMsg: true},
+		{name: "wrong tenant", l: ListOverdue{TenantID: "-2"}, wantMsg: true},
+		{name: "wrong limit", l: ListOverdue{Limit: "abc"}, wantMsg: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, msg := tt.l.Validate()
+			if tt.wantMsg {
+				require.NotEmpty(t, msg)
+				return
+			}
+			require.Empty(t, msg)
+			require.Equal(t, tt.wantF, f)
+		})
+	}
+}
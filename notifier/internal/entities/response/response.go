@@ -1,5 +1,10 @@
 package response
 
+import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/entities/notification"
+)
+
 // Error модель ответа в случае ошибки
 // type Error struct {
 // 	Error string `json:"error"`
@@ -35,3 +40,80 @@ func OK(result any) Response {
 		Result: result,
 	}
 }
+
+// Page модель ответа со страницей списка
+type Page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// BatchItem модель результата создания одного уведомления из пачки
+type BatchItem struct {
+	Index int    `json:"index"`
+	ID    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// SeriesCreated модель ответа при создании серии уведомлений
+type SeriesCreated struct {
+	SeriesID int64 `json:"series_id"`
+	ID       int64 `json:"id"`
+}
+
+// Notification модель уведомления в ответе, Date в UTC, LocalDate - то же
+// время в зоне Timezone
+type Notification struct {
+	notification.Notification
+	LocalDate string
+	// Deliveries история попыток доставки, только при запросе по ID
+	Deliveries []notification.Delivery `json:"Deliveries,omitempty"`
+}
+
+func NewNotification(n notification.Notification) Notification {
+	n.Date = n.Date.UTC()
+
+	return Notification{
+		Notification: n,
+		LocalDate:    n.Local().Format(notification.DateLayout),
+	}
+}
+
+func NewNotifications(ns []notification.Notification) []Notification {
+	r := make([]Notification, 0, len(ns))
+	for _, n := range ns {
+		r = append(r, NewNotification(n))
+	}
+
+	return r
+}
+
+// IssuedKey модель ответа при выпуске ключа. Key показывается один раз
+type IssuedKey struct {
+	ID     int64    `json:"id"`
+	Key    string   `json:"key"`
+	Scopes []string `json:"scopes"`
+}
+
+// DeadLetter модель записи DLQ при просмотре, Notification - разобранное
+// сообщение, если его удалось прочитать
+type DeadLetter struct {
+	deadletter.Letter
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+func NewDeadLetter(l deadletter.Letter) DeadLetter {
+	r := DeadLetter{Letter: l}
+	n := notification.Notification{}
+	if err := n.UnmarshalBinary(l.Payload); err == nil && len(l.Payload) != 0 {
+		v := NewNotification(n)
+		r.Notification = &v
+	}
+
+	return r
+}
+
+// DeadLetterItem модель результата replay или discard одной записи DLQ
+type DeadLetterItem struct {
+	ID    int64  `json:"id"`
+	Error string `json:"error,omitempty"`
+}
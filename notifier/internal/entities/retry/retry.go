@@ -0,0 +1,13 @@
+package retry
+
+import "time"
+
+// Retry повтор отправки от sender, который ждет своего времени в базе, когда
+// отложенного обменника нет (scheduler.type postgres). Payload - сообщение
+// очереди, Headers - его строковые заголовки, кроме x-delay
+type Retry struct {
+	ID      int64             `db:"id"`
+	Payload []byte            `db:"payload"`
+	Headers map[string]string `db:"headers"`
+	DueAt   time.Time         `db:"due_at"`
+}
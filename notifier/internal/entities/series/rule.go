@@ -0,0 +1,177 @@
+package series
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWrongRule = errors.New("wrong recurrence rule")
+)
+
+// searchLimit ограничение поиска следующего срабатывания, правило вроде
+// "0 0 30 2 *" никогда не сработает
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct {
+	min, max int
+}
+
+var fieldBounds = [5]bounds{
+	{0, 59}, // минута
+	{0, 23}, // час
+	{1, 31}, // день месяца
+	{1, 12}, // месяц
+	{0, 7},  // день недели, 0 и 7 - воскресенье
+}
+
+// Rule правило повторения в формате cron из 5 полей:
+// минута час день_месяца месяц день_недели
+type Rule struct {
+	expr string
+
+	minute, hour, dom, month, dow uint64
+	// если ограничены и день месяца, и день недели, достаточно совпадения
+	// любого из них, как в классическом cron
+	domAny, dowAny bool
+}
+
+func ParseRule(expr string) (Rule, error) {
+	expr = strings.TrimSpace(expr)
+	src := expr
+	if m, ok := macros[expr]; ok {
+		src = m
+	}
+
+	fields := strings.Fields(src)
+	if len(fields) != len(fieldBounds) {
+		return Rule{}, fmt.Errorf(
+			"%w: expected %d fields", ErrWrongRule, len(fieldBounds),
+		)
+	}
+
+	var sets [5]uint64
+	for i, f := range fields {
+		v, err := parseField(f, fieldBounds[i])
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %q: %s", ErrWrongRule, f, err)
+		}
+		sets[i] = v
+	}
+	// 7 это тоже воскресенье
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return Rule{
+		expr:   expr,
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseField(f string, b bounds) (uint64, error) {
+	var r uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			v, err := strconv.Atoi(stepStr)
+			if err != nil || v <= 0 {
+				return 0, errors.New("wrong step")
+			}
+			step = v
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, z, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, errors.New("wrong range")
+			}
+			if hi, err = strconv.Atoi(z); err != nil {
+				return 0, errors.New("wrong range")
+			}
+		default:
+			v, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, errors.New("wrong value")
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = b.max
+			}
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("out of range %d-%d", b.min, b.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			r |= 1 << uint(v)
+		}
+	}
+
+	return r, nil
+}
+
+func (r Rule) String() string {
+	return r.expr
+}
+
+func (r Rule) dayMatches(t time.Time) bool {
+	dom := r.dom&(1<<uint(t.Day())) != 0
+	dow := r.dow&(1<<uint(t.Weekday())) != 0
+	if r.domAny || r.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next возвращает первое срабатывание строго после t в зоне t, нулевое время
+// если правило не срабатывает в обозримом будущем
+func (r Rule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(searchLimit)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		if r.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !r.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if r.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if r.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,113 @@
+package series
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "monday morning", expr: "0 9 * * 1"},
+		{name: "lists and steps", expr: "0,30 */2 1-15/7 1,6 *"},
+		{name: "sunday as 7", expr: "0 9 * * 7"},
+		{name: "macro", expr: "@monthly"},
+		{name: "empty", expr: "", wantErr: true},
+		{name: "too few fields", expr: "0 9 * *", wantErr: true},
+		{name: "out of range", expr: "60 9 * * *", wantErr: true},
+		{name: "wrong step", expr: "*/0 * * * *", wantErr: true},
+		{name: "wrong range", expr: "0 9 10-5 * *", wantErr: true},
+		{name: "not numeric", expr: "0 9 * * mon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRule(tt.expr)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("ParseRule() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrWrongRule) {
+				t.Errorf("ParseRule() error = %v, want ErrWrongRule", err)
+			}
+		})
+	}
+}
+
+func TestRule_Next(t *testing.T) {
+	parse := func(v string) time.Time {
+		tm, err := time.Parse(time.RFC3339, v)
+		require.NoError(t, err)
+		return tm
+	}
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{
+			name: "every monday at 09:00",
+			expr: "0 9 * * 1",
+			from: "2025-09-03T10:00:00Z", // среда
+			want: "2025-09-08T09:00:00Z",
+		},
+		{
+			name: "strictly after",
+			expr: "0 9 * * 1",
+			from: "2025-09-08T09:00:00Z",
+			want: "2025-09-15T09:00:00Z",
+		},
+		{
+			name: "first day of month",
+			expr: "@monthly",
+			from: "2025-12-15T10:00:00Z",
+			want: "2026-01-01T00:00:00Z",
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 12 13 * 5",
+			from: "2025-09-01T00:00:00Z",
+			want: "2025-09-05T12:00:00Z",
+		},
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			from: "2025-09-01T10:16:30Z",
+			want: "2025-09-01T10:30:00Z",
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: "2025-03-01T00:00:00Z",
+			want: "2028-02-29T00:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.expr)
+			require.NoError(t, err)
+			got := r.Next(parse(tt.from))
+			require.True(t, got.Equal(parse(tt.want)), "got %s", got)
+		})
+	}
+
+	r, err := ParseRule("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, r.Next(parse("2025-01-01T00:00:00Z")).IsZero())
+}
+
+func TestSeries_Exhausted(t *testing.T) {
+	now := time.Now()
+	require.False(t, Series{}.Exhausted(now))
+	require.True(t, Series{}.Exhausted(time.Time{}))
+	require.True(t, Series{Until: now}.Exhausted(now.Add(time.Minute)))
+	require.False(t, Series{Until: now}.Exhausted(now.Add(-time.Minute)))
+	require.True(t, Series{Count: 3, Fired: 3}.Exhausted(now))
+	require.False(t, Series{Count: 3, Fired: 2}.Exhausted(now))
+}
@@ -0,0 +1,36 @@
+package series
+
+import "time"
+
+const (
+	StatusActive   = "active"
+	StatusPaused   = "paused"
+	StatusStopped  = "stopped"
+	StatusFinished = "finished"
+)
+
+// Series модель серии повторяющихся уведомлений. Каждое срабатывание - это
+// отдельное уведомление с series_id, следующее создается копированием
+// предыдущего после его отправки
+type Series struct {
+	ID               int64     `db:"id"`
+	Rule             string    `db:"rule"`
+	Until            time.Time `db:"until"`
+	Count            int64     `db:"max_count"`
+	Fired            int64     `db:"fired"`
+	Status           string    `db:"status"`
+	LastOccurrenceID int64     `db:"last_occurrence_id"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+// Exhausted достигнуты ли ограничения серии для срабатывания в момент next
+func (s Series) Exhausted(next time.Time) bool {
+	if next.IsZero() {
+		return true
+	}
+	if !s.Until.IsZero() && next.After(s.Until) {
+		return true
+	}
+
+	return s.Count != 0 && s.Fired >= s.Count
+}
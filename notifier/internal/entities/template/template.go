@@ -0,0 +1,103 @@
+package template
+
+import (
+	"bytes"
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"fmt"
+	texttemplate "text/template"
+	"time"
+)
+
+var (
+	ErrWrongTemplate = errors.New("wrong template")
+	ErrRender        = errors.New("can't render template")
+)
+
+// Template именованный шаблон сообщения. Body - текст по умолчанию, варианты
+// для каналов необязательны и заменяют его для своего канала
+type Template struct {
+	ID           int64     `db:"id"`
+	Name         string    `db:"name"`
+	Body         string    `db:"body"`
+	TelegramBody string    `db:"telegram_body"`
+	EmailSubject string    `db:"email_subject"`
+	EmailBody    string    `db:"email_body"`
+	TenantID     int64     `db:"tenant_id"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+func (t Template) parts() map[string]string {
+	return map[string]string{
+		"body":          t.Body,
+		"telegram_body": t.TelegramBody,
+		"email_subject": t.EmailSubject,
+		"email_body":    t.EmailBody,
+	}
+}
+
+func parse(name, text string) (*texttemplate.Template, error) {
+	return texttemplate.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Validate проверяет что все части шаблона разбираются
+func (t Template) Validate() error {
+	if t.Body == "" {
+		return fmt.Errorf("%w: body is empty", ErrWrongTemplate)
+	}
+	for name, text := range t.parts() {
+		if _, err := parse(name, text); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrWrongTemplate, name, err)
+		}
+	}
+
+	return nil
+}
+
+func render(name, text string, params map[string]any) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tm, err := parse(name, text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrWrongTemplate, name, err)
+	}
+	b := new(bytes.Buffer)
+	if err := tm.Execute(b, params); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrRender, err)
+	}
+
+	return b.String(), nil
+}
+
+// Apply заполняет текст уведомления по шаблону. Отсутствующий в params
+// ключ - ошибка, а не пустая строка
+func (t Template) Apply(n notification.Notification, params map[string]any) (notification.Notification, error) {
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	fields := []struct {
+		name, text string
+		dst        *string
+	}{
+		{"body", t.Body, &n.Message},
+		{"telegram_body", t.TelegramBody, &n.TelegramMessage},
+		{"email_subject", t.EmailSubject, &n.EmailSubject},
+		{"email_body", t.EmailBody, &n.EmailMessage},
+	}
+	for _, f := range fields {
+		v, err := render(f.name, f.text, params)
+		if err != nil {
+			return n, err
+		}
+		*f.dst = v
+	}
+	if n.Message == "" {
+		return n, fmt.Errorf("%w: %s", ErrRender, "message is empty")
+	}
+	n.TemplateID = t.ID
+
+	return n, nil
+}
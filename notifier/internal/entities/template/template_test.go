@@ -0,0 +1,68 @@
+package template
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Template
+		wantErr bool
+	}{
+		{
+			name: "good",
+			t: Template{
+				Body: "Привет, {{.name}}", TelegramBody: "{{.name}}!",
+				EmailSubject: "Встреча {{.date}}",
+			},
+		},
+		{
+			name:    "empty body",
+			t:       Template{TelegramBody: "hi"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong syntax",
+			t:       Template{Body: "hi", EmailBody: "{{.name"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.t.Validate()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrWrongTemplate)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tm := Template{
+		ID:           3,
+		Body:         "Привет, {{.name}}! Встреча {{.date}}",
+		TelegramBody: "{{.name}}, встреча {{.date}}",
+		EmailSubject: "Встреча {{.date}}",
+	}
+	n := notification.Notification{}
+	n.SetAddresses(notification.ChannelTelegram, []string{"1"})
+
+	got, err := tm.Apply(n, map[string]any{"name": "Аня", "date": "01.02"})
+	require.NoError(t, err)
+	require.Equal(t, "Привет, Аня! Встреча 01.02", got.Message)
+	require.Equal(t, "Аня, встреча 01.02", got.TelegramMessage)
+	require.Equal(t, "Встреча 01.02", got.EmailSubject)
+	require.Equal(t, "", got.EmailMessage)
+	require.Equal(t, int64(3), got.TemplateID)
+
+	_, err = tm.Apply(n, map[string]any{"name": "Аня"})
+	require.ErrorIs(t, err, ErrRender)
+	_, err = tm.Apply(n, nil)
+	require.ErrorIs(t, err, ErrRender)
+}
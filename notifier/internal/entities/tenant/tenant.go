@@ -0,0 +1,15 @@
+package tenant
+
+import "time"
+
+// DefaultID команда, которой отданы данные, созданные до разделения на
+// команды
+const DefaultID = 1
+
+// Tenant команда. Уведомления, серии, шаблоны, контакты и ключи видны
+// только внутри своей команды
+type Tenant struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
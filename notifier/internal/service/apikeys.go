@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crypto/subtle"
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+)
+
+// serviceKeyName имя, под которым в логах виден сервисный ключ sender
+const serviceKeyName = "service"
+
+// SetServiceKey задает ключ, которым sender ходит во внутренние ручки.
+// Ключ не хранится в базе и дает все scope, включая service. Сервисный ключ
+// не привязан к команде, команду запроса sender передает сам. Пустой ключ
+// отключает сервисный доступ
+func (s *Service) SetServiceKey(token string) {
+	s.serviceHash = ""
+	if token != "" {
+		s.serviceHash = apikey.Hash(token)
+	}
+}
+
+// Authenticate возвращает действующий ключ по его значению, неизвестный
+// или отозванный ключ - ErrUnauthorized
+func (s *Service) Authenticate(token string) (apikey.Key, error) {
+	const op = "internal.service.Authenticate"
+
+	if token == "" {
+		return apikey.Key{}, fmt.Errorf("%w: %s", ErrUnauthorized, "api key is empty")
+	}
+	hash := apikey.Hash(token)
+	if s.serviceHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(s.serviceHash)) == 1 {
+		return apikey.Key{
+			Name: serviceKeyName,
+			Scopes: []string{
+				apikey.ScopeCreate, apikey.ScopeRead, apikey.ScopeCancel,
+				apikey.ScopeService,
+			},
+		}, nil
+	}
+
+	k, err := s.str.APIKeyByHash(hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return k, fmt.Errorf("%w: %s", ErrUnauthorized, "unknown or revoked api key")
+	} else if err != nil {
+		return k, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return k, nil
+}
+
+// IssueAPIKey выпускает ключ команды tenantID от имени ключа issuer. Ключ
+// не может выдать scope, которого нет у него самого. Значение ключа
+// возвращается только здесь, в базе остается хеш
+func (s *Service) IssueAPIKey(issuer apikey.Key, tenantID int64, name string, scopes []string) (apikey.Key, string, error) {
+	const op = "internal.service.IssueAPIKey"
+
+	if name == "" {
+		return apikey.Key{}, "", fmt.Errorf(
+			"%w: %s", ErrNotValidData, "key name is empty",
+		)
+	}
+	if len(name) > MaxAPIKeyNameLen {
+		return apikey.Key{}, "", fmt.Errorf(
+			"%w: key name is longer than %d", ErrNotValidData, MaxAPIKeyNameLen,
+		)
+	}
+	if err := apikey.ValidateScopes(scopes); err != nil {
+		return apikey.Key{}, "", fmt.Errorf("%w: %w", ErrNotValidData, err)
+	}
+	for _, scope := range scopes {
+		if !issuer.Has(scope) {
+			return apikey.Key{}, "", fmt.Errorf(
+				"%w: can't grant scope %s", ErrForbidden, scope,
+			)
+		}
+	}
+	if _, err := s.Tenant(tenantID); errors.Is(err, ErrNotFound) {
+		return apikey.Key{}, "", fmt.Errorf(
+			"%w: %s", ErrNotValidData, "tenant not found",
+		)
+	} else if err != nil {
+		return apikey.Key{}, "", err
+	}
+
+	token, hash, err := apikey.Generate()
+	if err != nil {
+		return apikey.Key{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	k := apikey.Key{
+		Name:     name,
+		Shown:    token[:apikey.ShownLen],
+		Hash:     hash,
+		Scopes:   scopes,
+		TenantID: tenantID,
+	}
+
+	k.ID, err = s.str.CreateAPIKey(k)
+	if err != nil {
+		return apikey.Key{}, "", fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return k, token, nil
+}
+
+func (s *Service) APIKeys(tenantID int64) ([]apikey.Key, error) {
+	const op = "internal.service.APIKeys"
+
+	r, err := s.str.APIKeys(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
+
+// RevokeAPIKey отзывает ключ, ключ перестает работать сразу
+func (s *Service) RevokeAPIKey(tenantID, id int64) error {
+	const op = "internal.service.RevokeAPIKey"
+
+	if id <= 0 {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "key id is negative or == 0")
+	}
+
+	err := s.str.RevokeAPIKey(tenantID, id)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/storage"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_IssueAPIKey(t *testing.T) {
+	admin := apikey.Key{Scopes: []string{apikey.ScopeAdmin}, TenantID: 1}
+	tests := []struct {
+		name    string
+		issuer  apikey.Key
+		tenant  int64
+		key     string
+		scopes  []string
+		err     error
+		wantErr error
+	}{
+		{
+			name:   "good",
+			key:    "crm",
+			scopes: []string{apikey.ScopeCreate, apikey.ScopeRead},
+		},
+		{
+			name:    "empty name",
+			scopes:  []string{apikey.ScopeRead},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "long name",
+			key:     strings.Repeat("a", MaxAPIKeyNameLen+1),
+			scopes:  []string{apikey.ScopeRead},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "no scopes",
+			key:     "crm",
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "service scope",
+			key:     "crm",
+			scopes:  []string{apikey.ScopeService},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "scope not granted",
+			issuer:  apikey.Key{Scopes: []string{apikey.ScopeRead}},
+			key:     "crm",
+			scopes:  []string{apikey.ScopeCreate},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "admin can't grant tenants",
+			key:     "crm",
+			scopes:  []string{apikey.ScopeTenants},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:   "tenants to other tenant",
+			issuer: apikey.Key{Scopes: []string{apikey.ScopeAdmin, apikey.ScopeTenants}},
+			tenant: 2,
+			key:    "crm",
+			scopes: []string{apikey.ScopeAdmin},
+		},
+		{
+			name:    "tenant not found",
+			tenant:  3,
+			key:     "crm",
+			scopes:  []string{apikey.ScopeRead},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "unknown",
+			key:     "crm",
+			scopes:  []string{apikey.ScopeRead},
+			err:     errors.New("unknown"),
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored apikey.Key
+			s := New(&StorageMock{
+				addKF: func(k apikey.Key) (int64, error) {
+					stored = k
+					return 1, tt.err
+				},
+				getTnF: func(id int64) (tenant.Tenant, error) {
+					if id > 2 {
+						return tenant.Tenant{}, storage.ErrNotFound
+					}
+					return tenant.Tenant{ID: id}, nil
+				},
+			})
+			if tt.issuer.Scopes == nil {
+				tt.issuer = admin
+			}
+			if tt.tenant == 0 {
+				tt.tenant = 1
+			}
+			k, token, err := s.IssueAPIKey(tt.issuer, tt.tenant, tt.key, tt.scopes)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(1), k.ID)
+			require.Equal(t, tt.tenant, stored.TenantID)
+			require.Equal(t, apikey.Hash(token), stored.Hash)
+			require.True(t, strings.HasPrefix(token, stored.Shown))
+			require.NotEqual(t, token, stored.Shown)
+		})
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	const (
+		userToken    = "dn_user"
+		serviceToken = "dn_service"
+	)
+	s := New(&StorageMock{
+		getKF: func(hash string) (apikey.Key, error) {
+			if hash == apikey.Hash("dn_broken") {
+				return apikey.Key{}, errors.New("unknown")
+			}
+			if hash != apikey.Hash(userToken) {
+				return apikey.Key{}, storage.ErrNotFound
+			}
+			return apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}}, nil
+		},
+	})
+
+	k, err := s.Authenticate(userToken)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), k.ID)
+
+	_, err = s.Authenticate("")
+	require.ErrorIs(t, err, ErrUnauthorized)
+	_, err = s.Authenticate("dn_other")
+	require.ErrorIs(t, err, ErrUnauthorized)
+	_, err = s.Authenticate("dn_broken")
+	require.ErrorIs(t, err, ErrStorageInternal)
+
+	_, err = s.Authenticate(serviceToken)
+	require.ErrorIs(t, err, ErrUnauthorized)
+	s.SetServiceKey(serviceToken)
+	k, err = s.Authenticate(serviceToken)
+	require.NoError(t, err)
+	require.True(t, k.Has(apikey.ScopeService))
+}
+
+func TestService_RevokeAPIKey(t *testing.T) {
+	s := New(&StorageMock{
+		revokeKF: func(id int64) error {
+			if id != 1 {
+				return storage.ErrNotAffected
+			}
+			return nil
+		},
+	})
+
+	require.NoError(t, s.RevokeAPIKey(1, 1))
+	require.ErrorIs(t, s.RevokeAPIKey(1, 2), ErrNotAffected)
+	require.ErrorIs(t, s.RevokeAPIKey(1, 0), ErrNotValidData)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+)
+
+func validateContact(c contact.Contact) error {
+	if len(c.Name) > MaxContactNameLen {
+		return fmt.Errorf(
+			"%w: contact name longer than %d", ErrNotValidData, MaxContactNameLen,
+		)
+	}
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotValidData, err)
+	}
+
+	return nil
+}
+
+// checkContact проверяет что контакт уведомления существует в его команде
+func (s *Service) checkContact(n notification.Notification) error {
+	const op = "internal.service.checkContact"
+
+	if n.ContactID == 0 {
+		return nil
+	}
+	_, err := s.str.Contact(n.TenantID, n.ContactID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "contact not found")
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+func (s *Service) CreateContact(tenantID int64, c contact.Contact) (int64, error) {
+	const op = "internal.service.CreateContact"
+
+	c.TenantID = tenantID
+	if err := validateContact(c); err != nil {
+		return 0, err
+	}
+
+	id, err := s.str.CreateContact(c)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return id, nil
+}
+
+func (s *Service) Contact(tenantID, id int64) (contact.Contact, error) {
+	const op = "internal.service.Contact"
+
+	if id <= 0 {
+		return contact.Contact{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "contact id is negative or == 0",
+		)
+	}
+
+	c, err := s.str.Contact(tenantID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c, fmt.Errorf("%w: %w", ErrNotFound, err)
+	} else if err != nil {
+		return c, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return c, nil
+}
+
+func (s *Service) Contacts(tenantID int64) ([]contact.Contact, error) {
+	const op = "internal.service.Contacts"
+
+	r, err := s.str.Contacts(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
+
+// UpdateContact заменяет контакт, новые адреса подхватят и уже
+// запланированные уведомления
+func (s *Service) UpdateContact(tenantID int64, c contact.Contact) (contact.Contact, error) {
+	const op = "internal.service.UpdateContact"
+
+	c.TenantID = tenantID
+	if c.ID <= 0 {
+		return contact.Contact{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "contact id is negative or == 0",
+		)
+	}
+	if err := validateContact(c); err != nil {
+		return contact.Contact{}, err
+	}
+
+	r, err := s.str.UpdateContact(c)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return r, fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return r, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
+
+// DeleteContact удаляет контакт, пока на него запланированы уведомления
+// удалить его нельзя
+func (s *Service) DeleteContact(tenantID, id int64) error {
+	const op = "internal.service.DeleteContact"
+
+	if id <= 0 {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData, "contact id is negative or == 0",
+		)
+	}
+
+	err := s.str.DeleteContact(tenantID, id)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if errors.Is(err, storage.ErrConflict) {
+		return fmt.Errorf(
+			"%w: %s", ErrConflict, "contact has pending notifications",
+		)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/storage"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateContact(t *testing.T) {
+	good := contact.Contact{Name: "Аня", Email: "a@b.c", Timezone: "UTC"}
+	tests := []struct {
+		name    string
+		str     storager
+		c       contact.Contact
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				addCF: func(c contact.Contact) (int64, error) {
+					return 1, nil
+				},
+			},
+			c: good,
+		},
+		{
+			name:    "not valid",
+			str:     &StorageMock{},
+			c:       contact.Contact{Name: "Аня"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				addCF: func(c contact.Contact) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			c:       good,
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			_, err := s.CreateContact(1, tt.c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.CreateContact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_DeleteContact(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "good"},
+		{name: "not found", err: storage.ErrNotAffected, wantErr: ErrNotAffected},
+		{name: "has pending", err: storage.ErrConflict, wantErr: ErrConflict},
+		{name: "unknown", err: errors.New("unknown"), wantErr: ErrStorageInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&StorageMock{
+				deleteCF: func(id int64) error {
+					return tt.err
+				},
+			})
+			err := s.DeleteContact(1, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.DeleteContact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_CreateNotificationForContact(t *testing.T) {
+	n := notification.Notification{
+		Message: "hi", ContactID: 7, Date: time.Now().Add(time.Hour),
+	}
+	created := false
+	s := New(&StorageMock{
+		getCF: func(id int64) (contact.Contact, error) {
+			if id != 7 {
+				return contact.Contact{}, storage.ErrNotFound
+			}
+			return contact.Contact{ID: 7}, nil
+		},
+		addNF: func(n notification.Notification) (int64, error) {
+			created = true
+			return 1, nil
+		},
+	})
+
+	_, err := s.CreateNotification(1, n)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	n.ContactID = 8
+	_, err = s.CreateNotification(1, n)
+	require.ErrorIs(t, err, ErrNotValidData)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// MaxDeadLetterBulk сколько записей DLQ обрабатывает один массовый запрос
+const MaxDeadLetterBulk = 1000
+
+// DeadLetterResult итог replay или discard одной записи DLQ
+type DeadLetterResult struct {
+	ID  int64
+	Err error
+}
+
+// DeadLetters возвращает страницу DLQ и курсор следующей страницы
+func (s *Service) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, string, error) {
+	const op = "internal.service.DeadLetters"
+
+	if f.Limit == 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxListLimit {
+		return nil, "", fmt.Errorf(
+			"%w: limit should be in range 1..%d", ErrNotValidData, MaxListLimit,
+		)
+	}
+	limit := f.Limit
+	f.Limit++
+
+	r, err := s.str.DeadLetters(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	if len(r) <= limit {
+		return r, "", nil
+	}
+	r = r[:limit]
+
+	return r, strconv.FormatInt(r[len(r)-1].ID, 10), nil
+}
+
+func validateDeadLetterID(id int64) error {
+	if id <= 0 {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData, "dead letter id is negative or == 0",
+		)
+	}
+
+	return nil
+}
+
+// DeadLetter запись DLQ с исходным сообщением
+func (s *Service) DeadLetter(id int64) (deadletter.Letter, error) {
+	const op = "internal.service.DeadLetter"
+
+	if err := validateDeadLetterID(id); err != nil {
+		return deadletter.Letter{}, err
+	}
+
+	l, err := s.str.DeadLetter(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return l, fmt.Errorf("%w: %w", ErrNotFound, err)
+	} else if err != nil {
+		return l, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return l, nil
+}
+
+// ReplayDeadLetter отправляет сообщение записи в очередь заново и удаляет
+// запись
+func (s *Service) ReplayDeadLetter(id int64) error {
+	const op = "internal.service.ReplayDeadLetter"
+
+	if err := validateDeadLetterID(id); err != nil {
+		return err
+	}
+
+	err := s.str.ReplayDeadLetter(id)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+// DiscardDeadLetter удаляет запись без отправки
+func (s *Service) DiscardDeadLetter(id int64) error {
+	const op = "internal.service.DiscardDeadLetter"
+
+	if err := validateDeadLetterID(id); err != nil {
+		return err
+	}
+
+	err := s.str.DiscardDeadLetter(id)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+// ReplayDeadLetters повторяет записи ids, без ids - первые MaxDeadLetterBulk
+// записей под фильтр f
+func (s *Service) ReplayDeadLetters(ids []int64, f deadletter.Filter) ([]DeadLetterResult, error) {
+	return s.bulkDeadLetters(ids, f, s.ReplayDeadLetter)
+}
+
+// DiscardDeadLetters удаляет записи ids, без ids - первые MaxDeadLetterBulk
+// записей под фильтр f
+func (s *Service) DiscardDeadLetters(ids []int64, f deadletter.Filter) ([]DeadLetterResult, error) {
+	return s.bulkDeadLetters(ids, f, s.DiscardDeadLetter)
+}
+
+func (s *Service) bulkDeadLetters(ids []int64, f deadletter.Filter, do func(id int64) error) ([]DeadLetterResult, error) {
+	const op = "internal.service.bulkDeadLetters"
+
+	if len(ids) > MaxDeadLetterBulk {
+		return nil, fmt.Errorf(
+			"%w: ids should be at most %d", ErrNotValidData, MaxDeadLetterBulk,
+		)
+	}
+	if len(ids) == 0 {
+		f.After, f.Limit = 0, MaxDeadLetterBulk
+		ls, err := s.str.DeadLetters(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+		}
+		for _, l := range ls {
+			ids = append(ids, l.ID)
+		}
+	}
+
+	r := make([]DeadLetterResult, 0, len(ids))
+	for _, id := range ids {
+		r = append(r, DeadLetterResult{ID: id, Err: do(id)})
+	}
+
+	return r, nil
+}
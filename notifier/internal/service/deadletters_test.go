@@ -0,0 +1,157 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/storage"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_DeadLetters(t *testing.T) {
+	letters := func(n int) []deadletter.Letter {
+		r := make([]deadletter.Letter, n)
+		for i := range r {
+			r[i].ID = int64(i + 1)
+		}
+		return r
+	}
+	tests := []struct {
+		name     string
+		str      storager
+		f        deadletter.Filter
+		wantLen  int
+		wantNext string
+		wantErr  error
+	}{
+		{
+			name: "last page",
+			str: &StorageMock{
+				listDF: func(f deadletter.Filter) ([]deadletter.Letter, error) {
+					return letters(2), nil
+				},
+			},
+			wantLen: 2,
+		},
+		{
+			name: "has next page",
+			str: &StorageMock{
+				listDF: func(f deadletter.Filter) ([]deadletter.Letter, error) {
+					return letters(f.Limit), nil
+				},
+			},
+			f:        deadletter.Filter{Limit: 3},
+			wantLen:  3,
+			wantNext: "3",
+		},
+		{
+			name:    "wrong limit",
+			str:     &StorageMock{},
+			f:       deadletter.Filter{Limit: MaxListLimit + 1},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				listDF: func(f deadletter.Filter) ([]deadletter.Letter, error) {
+					return nil, errors.New("unknown")
+				},
+			},
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			r, next, err := s.DeadLetters(tt.f)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, r, tt.wantLen)
+			require.Equal(t, tt.wantNext, next)
+		})
+	}
+}
+
+func TestService_ReplayDeadLetter(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     storager
+		id      int64
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				replayDF: func(id int64) error {
+					return nil
+				},
+			},
+			id: 1,
+		},
+		{
+			name:    "wrong id",
+			str:     &StorageMock{},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "not found",
+			str: &StorageMock{
+				replayDF: func(id int64) error {
+					return storage.ErrNotAffected
+				},
+			},
+			id:      1,
+			wantErr: ErrNotAffected,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				replayDF: func(id int64) error {
+					return errors.New("unknown")
+				},
+			},
+			id:      1,
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			err := s.ReplayDeadLetter(tt.id)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_BulkDeadLetters(t *testing.T) {
+	discarded := []int64{}
+	s := New(&StorageMock{
+		listDF: func(f deadletter.Filter) ([]deadletter.Letter, error) {
+			require.Equal(t, "email", f.Channel)
+			require.Equal(t, MaxDeadLetterBulk, f.Limit)
+			return []deadletter.Letter{{ID: 4}, {ID: 5}}, nil
+		},
+		discardDF: func(id int64) error {
+			if id == 5 {
+				return storage.ErrNotAffected
+			}
+			discarded = append(discarded, id)
+			return nil
+		},
+	})
+
+	r, err := s.DiscardDeadLetters(nil, deadletter.Filter{Channel: "email"})
+	require.NoError(t, err)
+	require.Len(t, r, 2)
+	require.NoError(t, r[0].Err)
+	require.ErrorIs(t, r[1].Err, ErrNotAffected)
+
+	r, err = s.DiscardDeadLetters([]int64{7, 0}, deadletter.Filter{})
+	require.NoError(t, err)
+	require.NoError(t, r[0].Err)
+	require.ErrorIs(t, r[1].Err, ErrNotValidData)
+	require.Equal(t, []int64{4, 7}, discarded)
+
+	_, err = s.ReplayDeadLetters(make([]int64, MaxDeadLetterBulk+1), deadletter.Filter{})
+	require.ErrorIs(t, err, ErrNotValidData)
+}
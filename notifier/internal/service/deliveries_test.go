@@ -0,0 +1,153 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/storage"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_RecordDelivery(t *testing.T) {
+	sent := notification.Delivery{
+		Channel: notification.ChannelEmail, Address: "a@b.c",
+		Status: notification.RecipientSent,
+	}
+	tests := []struct {
+		name    string
+		str     storager
+		id      int64
+		d       notification.Delivery
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return nil
+				},
+			},
+			id: 1,
+			d:  sent,
+		},
+		{
+			name: "webhook",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return nil
+				},
+			},
+			id: 1,
+			d: notification.Delivery{
+				Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+				Status: notification.RecipientFailed, Error: "wrong status code on request: 500",
+			},
+		},
+		{
+			name: "retrying",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return nil
+				},
+			},
+			id: 1,
+			d: notification.Delivery{
+				Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+				Status: notification.RecipientRetrying, Attempt: 2,
+				Error: "wrong status code on request: 503 (retry 3 in 2m0s)",
+			},
+		},
+		{
+			name:    "wrong id",
+			str:     &StorageMock{},
+			id:      0,
+			d:       sent,
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "wrong status",
+			str:  &StorageMock{},
+			id:   1,
+			d: notification.Delivery{
+				Channel: notification.ChannelEmail, Address: "a@b.c",
+				Status: notification.RecipientPending,
+			},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "negative attempt",
+			str:  &StorageMock{},
+			id:   1,
+			d: notification.Delivery{
+				Channel: notification.ChannelEmail, Address: "a@b.c",
+				Status: notification.RecipientSent, Attempt: -1,
+			},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown channel",
+			str:  &StorageMock{},
+			id:   1,
+			d: notification.Delivery{
+				Channel: "sms", Address: "123", Status: notification.RecipientSent,
+			},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown notification",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return storage.ErrNotAffected
+				},
+			},
+			id:      1,
+			d:       sent,
+			wantErr: ErrNotAffected,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return errors.New("unknown")
+				},
+			},
+			id:      1,
+			d:       sent,
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			err := s.RecordDelivery(1, tt.id, tt.d)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.RecordDelivery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_RecordDeliveryNormalizes(t *testing.T) {
+	var got notification.Delivery
+	s := New(&StorageMock{
+		dlvF: func(id int64, d notification.Delivery) error {
+			got = d
+			return nil
+		},
+	})
+	d := notification.Delivery{
+		Channel: notification.ChannelTelegram, Address: "123",
+		Status:   notification.RecipientFailed,
+		Error:    strings.Repeat("x", MaxDeliveryErrorLen+10),
+		Response: strings.Repeat("y", MaxDeliveryResponseLen+10),
+	}
+
+	require.NoError(t, s.RecordDelivery(1, 1, d))
+	require.Len(t, got.Error, MaxDeliveryErrorLen)
+	require.Len(t, got.Response, MaxDeliveryResponseLen)
+	require.WithinDuration(t, time.Now(), got.FinishedAt, time.Second)
+	require.Equal(t, got.FinishedAt, got.StartedAt)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Limits ограничения клиентов API, нулевое значение - без ограничения
+type Limits struct {
+	// Rate запросов в секунду на ключ, Burst - сколько запросов можно
+	// сделать подряд после простоя
+	Rate  float64
+	Burst int64
+	// IPRate и IPBurst то же на IP, считаются до проверки ключа, так
+	// перебор ключей тоже ограничен
+	IPRate  float64
+	IPBurst int64
+	// MaxPending ожидающих отправки уведомлений на команду, проверяется
+	// хранилищем при вставке, чтобы параллельные запросы не превысили его.
+	// Срабатывания серий не проверяются, они заменяют уже отправленные
+	MaxPending int64
+}
+
+func (s *Service) SetLimits(l Limits) {
+	s.limits = l
+	s.str.SetMaxPending(l.MaxPending)
+}
+
+// Allow учитывает запрос с ключом keyID. Сверх лимита возвращает
+// ErrRateLimited и время, через которое стоит повторить запрос
+func (s *Service) Allow(keyID int64) (time.Duration, error) {
+	const op = "internal.service.Allow"
+
+	wait, err := s.allow("key:"+strconv.FormatInt(keyID, 10), s.limits.Rate, s.limits.Burst)
+	if err != nil {
+		return wait, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return 0, nil
+}
+
+// AllowIP учитывает запрос с адреса ip так же, как Allow
+func (s *Service) AllowIP(ip string) (time.Duration, error) {
+	const op = "internal.service.AllowIP"
+
+	wait, err := s.allow("ip:"+ip, s.limits.IPRate, s.limits.IPBurst)
+	if err != nil {
+		return wait, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return 0, nil
+}
+
+// allow расходует токен из корзины client
+func (s *Service) allow(client string, rate float64, burst int64) (time.Duration, error) {
+	if rate <= 0 {
+		return 0, nil
+	}
+
+	ok, wait, err := s.str.Allow(client, rate, max(burst, 1))
+	if err != nil {
+		return 0, fmt.Errorf("%w -> %w", ErrStorageInternal, err)
+	}
+	if !ok {
+		return wait, fmt.Errorf("%w: retry after %s", ErrRateLimited, wait)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Allow(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   Limits
+		allowF   func(client string, rate float64, burst int64) (bool, time.Duration, error)
+		wantWait time.Duration
+		wantErr  error
+	}{
+		{
+			name:   "no limit",
+			limits: Limits{},
+		},
+		{
+			name:   "allowed",
+			limits: Limits{Rate: 5, Burst: 10},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				return true, 0, nil
+			},
+		},
+		{
+			name:   "limited",
+			limits: Limits{Rate: 5, Burst: 10},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				return false, 200 * time.Millisecond, nil
+			},
+			wantWait: 200 * time.Millisecond,
+			wantErr:  ErrRateLimited,
+		},
+		{
+			name:   "by key",
+			limits: Limits{Rate: 5, IPRate: 1},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				if client != "key:1" || rate != 5 {
+					return false, time.Second, nil
+				}
+				return true, 0, nil
+			},
+		},
+		{
+			name:   "zero burst",
+			limits: Limits{Rate: 5},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				if burst != 1 {
+					return false, time.Second, nil
+				}
+				return true, 0, nil
+			},
+		},
+		{
+			name:   "unknown error",
+			limits: Limits{Rate: 5, Burst: 10},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				return false, 0, errors.New("unknown")
+			},
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&StorageMock{allowF: tt.allowF})
+			s.SetLimits(tt.limits)
+			wait, err := s.Allow(1)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantWait, wait)
+		})
+	}
+}
+
+func TestService_AllowIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		allowF  func(client string, rate float64, burst int64) (bool, time.Duration, error)
+		wantErr error
+	}{
+		{
+			name:   "no limit",
+			limits: Limits{Rate: 5},
+		},
+		{
+			name:   "by ip",
+			limits: Limits{Rate: 5, IPRate: 20, IPBurst: 40},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				if client != "ip:192.0.2.1" || rate != 20 || burst != 40 {
+					return false, time.Second, nil
+				}
+				return true, 0, nil
+			},
+		},
+		{
+			name:   "limited",
+			limits: Limits{IPRate: 20},
+			allowF: func(client string, rate float64, burst int64) (bool, time.Duration, error) {
+				return false, time.Second, nil
+			},
+			wantErr: ErrRateLimited,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&StorageMock{allowF: tt.allowF})
+			s.SetLimits(tt.limits)
+			_, err := s.AllowIP("192.0.2.1")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_Quota(t *testing.T) {
+	n := notification.Notification{
+		Message: "hi", Recipients: to("a@b.c"), Date: time.Now().Add(time.Hour),
+	}
+	quota := fmt.Errorf("%w: 11 of 10 pending notifications", notification.ErrQuotaExceeded)
+	str := &StorageMock{
+		addNF: func(n notification.Notification) (int64, error) {
+			if n.TenantID == 2 {
+				return 0, quota
+			}
+			return 1, nil
+		},
+		batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+			if len(ns) > 1 {
+				return nil, quota
+			}
+			return make([]notification.BatchResult, len(ns)), nil
+		},
+	}
+	s := New(str)
+	s.SetLimits(Limits{MaxPending: 10})
+	// квоту проверяет хранилище в транзакции вставки
+	require.Equal(t, int64(10), str.maxPending)
+
+	_, err := s.CreateNotification(1, n)
+	require.NoError(t, err)
+	_, err = s.CreateNotification(2, n)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.NotErrorIs(t, err, ErrStorageInternal)
+
+	_, err = s.CreateNotifications(1, []notification.Notification{n})
+	require.NoError(t, err)
+	_, err = s.CreateNotifications(1, []notification.Notification{n, n})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.NotErrorIs(t, err, ErrStorageInternal)
+
+	// невалидные элементы пачки в хранилище не попадают
+	bad := n
+	bad.Recipients = nil
+	_, err = s.CreateNotifications(1, []notification.Notification{n, bad})
+	require.NoError(t, err)
+}
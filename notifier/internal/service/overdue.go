@@ -0,0 +1,51 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultOverdueGrace на сколько ожидающее уведомление может опоздать, пока
+// его не сочтут потерянным
+const DefaultOverdueGrace = 10 * time.Minute
+
+// SetOverdueGrace задает, на сколько ожидающее уведомление может опоздать,
+// должно совпадать с настройкой прохода storage.SweepOverdue
+func (s *Service) SetOverdueGrace(d time.Duration) {
+	s.overdueGrace = d
+}
+
+// Overdue отчет об ожидающих уведомлениях всех команд, опоздавших больше
+// чем на grace, с курсором следующей страницы
+func (s *Service) Overdue(f notification.OverdueFilter) ([]notification.Overdue, string, error) {
+	const op = "internal.service.Overdue"
+
+	if f.Limit == 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxListLimit {
+		return nil, "", fmt.Errorf(
+			"%w: limit should be in range 1..%d", ErrNotValidData, MaxListLimit,
+		)
+	}
+	grace := s.overdueGrace
+	if grace == 0 {
+		grace = DefaultOverdueGrace
+	}
+	f.Before = time.Now().Add(-grace)
+	limit := f.Limit
+	f.Limit++
+
+	r, err := s.str.OverdueNotifications(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	if len(r) <= limit {
+		return r, "", nil
+	}
+	r = r[:limit]
+
+	return r, strconv.FormatInt(r[len(r)-1].ID, 10), nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Overdue(t *testing.T) {
+	overdue := func(n int) []notification.Overdue {
+		r := make([]notification.Overdue, n)
+		for i := range r {
+			r[i].ID = int64(i + 1)
+		}
+		return r
+	}
+	tests := []struct {
+		name     string
+		str      storager
+		grace    time.Duration
+		f        notification.OverdueFilter
+		wantLen  int
+		wantNext string
+		wantErr  error
+	}{
+		{
+			name: "default grace",
+			str: &StorageMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, error) {
+					if time.Since(f.Before) < DefaultOverdueGrace {
+						return nil, errors.New("wrong before")
+					}
+					return overdue(2), nil
+				},
+			},
+			wantLen: 2,
+		},
+		{
+			name: "has next page",
+			str: &StorageMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, error) {
+					if time.Since(f.Before) < time.Hour {
+						return nil, errors.New("wrong before")
+					}
+					return overdue(f.Limit), nil
+				},
+			},
+			grace:    time.Hour,
+			f:        notification.OverdueFilter{Limit: 3},
+			wantLen:  3,
+			wantNext: "3",
+		},
+		{
+			name:    "wrong limit",
+			str:     &StorageMock{},
+			f:       notification.OverdueFilter{Limit: MaxListLimit + 1},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, error) {
+					return nil, errors.New("unknown")
+				},
+			},
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			s.SetOverdueGrace(tt.grace)
+			r, next, err := s.Overdue(tt.f)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, r, tt.wantLen)
+			require.Equal(t, tt.wantNext, next)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/event"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+)
+
+// Claim отмечает уведомление взятым sender в отправку. Сообщение с другой
+// версией устарело. Повторное сообщение уже взятого уведомления принимается,
+// но в ответе получатели с записанным результатом, чтобы sender не отправил
+// им еще раз. Для уведомления с контактом возвращает адреса контакта на
+// момент отправки
+func (s *Service) Claim(c event.Claim) event.ClaimReply {
+	sending := notification.StatusSending
+	err := s.UpdateNotification(c.TenantID, c.NotificationID, notification.Update{
+		Status: &sending, Version: c.Version,
+	})
+	if errors.Is(err, ErrNotAffected) {
+		return event.ClaimReply{Result: event.ClaimNotFound}
+	} else if errors.Is(err, ErrCancelled) {
+		return event.ClaimReply{Result: event.ClaimCancelled}
+	} else if errors.Is(err, ErrConflict) {
+		return event.ClaimReply{Result: event.ClaimSuperseded}
+	} else if err != nil {
+		return event.ClaimReply{Result: event.ClaimFailed, Error: err.Error()}
+	}
+
+	// без состояния получателей повторное сообщение продублирует отправку,
+	// лучше вернуть его в очередь
+	n, err := s.str.GetNotification(c.TenantID, c.NotificationID)
+	if err != nil {
+		return event.ClaimReply{Result: event.ClaimFailed, Error: err.Error()}
+	}
+	r := event.ClaimReply{Result: event.ClaimOK}
+	for _, rc := range n.Recipients {
+		if rc.Status != notification.RecipientPending {
+			r.Recorded = append(r.Recorded, event.Recipient{
+				Channel: rc.Channel, Address: rc.Address, Status: rc.Status,
+			})
+		}
+	}
+	if c.ContactID == 0 {
+		return r
+	}
+	// уведомление уже взято, без контакта sender отправит по своим адресам
+	ct, err := s.Contact(c.TenantID, c.ContactID)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Contact = &ct
+
+	return r
+}
+
+// ApplyDelivery записывает результат доставки от sender. Ошибка базы
+// оборачивается в storage.ErrRetryLater, чтобы результат вернулся в очередь
+func (s *Service) ApplyDelivery(e event.Delivery) error {
+	const op = "internal.service.ApplyDelivery"
+
+	err := s.RecordDelivery(e.TenantID, e.NotificationID, e.Delivery())
+	if errors.Is(err, ErrStorageInternal) {
+		return fmt.Errorf("%s: %w: %w", op, storage.ErrRetryLater, err)
+	}
+
+	return err
+}
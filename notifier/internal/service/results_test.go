@@ -0,0 +1,243 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/event"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/storage"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Claim(t *testing.T) {
+	claimed := func(id int64, u notification.Update) (notification.Notification, error) {
+		return notification.Notification{ID: id, Status: *u.Status}, nil
+	}
+	pending := func(id int64) (notification.Notification, error) {
+		return notification.Notification{ID: id, Recipients: []notification.Recipient{{
+			Channel: notification.ChannelEmail, Address: "a@b.c",
+			Status: notification.RecipientPending,
+		}}}, nil
+	}
+	tests := []struct {
+		name         string
+		str          storager
+		c            event.Claim
+		want         string
+		wantContact  bool
+		wantError    bool
+		wantRecorded []event.Recipient
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+					if *u.Status != notification.StatusSending || u.Version != 2 {
+						return notification.Notification{}, errors.New("wrong update")
+					}
+					return claimed(id, u)
+				},
+				getF: pending,
+			},
+			c:    event.Claim{NotificationID: 1, Version: 2},
+			want: event.ClaimOK,
+		},
+		{
+			name: "claimed again",
+			str: &StorageMock{
+				updateF: claimed,
+				getF: func(id int64) (notification.Notification, error) {
+					return notification.Notification{ID: id, Recipients: []notification.Recipient{
+						{
+							Channel: notification.ChannelEmail, Address: "a@b.c",
+							Status: notification.RecipientSent,
+						},
+						{
+							Channel: notification.ChannelTelegram, Address: "1",
+							Status: notification.RecipientPending,
+						},
+						{
+							Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+							Status: notification.RecipientRetrying,
+						},
+					}}, nil
+				},
+			},
+			c:    event.Claim{NotificationID: 1, Version: 1},
+			want: event.ClaimOK,
+			wantRecorded: []event.Recipient{
+				{
+					Channel: notification.ChannelEmail, Address: "a@b.c",
+					Status: notification.RecipientSent,
+				},
+				{
+					Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+					Status: notification.RecipientRetrying,
+				},
+			},
+		},
+		{
+			name: "recipients not loaded",
+			str: &StorageMock{
+				updateF: claimed,
+				getF: func(id int64) (notification.Notification, error) {
+					return notification.Notification{}, errors.New("connection refused")
+				},
+			},
+			c:         event.Claim{NotificationID: 1, Version: 1},
+			want:      event.ClaimFailed,
+			wantError: true,
+		},
+		{
+			name: "with contact",
+			str: &StorageMock{
+				updateF: claimed,
+				getF:    pending,
+				getCF: func(id int64) (contact.Contact, error) {
+					return contact.Contact{ID: id, Email: "a@b.c"}, nil
+				},
+			},
+			c:           event.Claim{NotificationID: 1, Version: 1, ContactID: 3},
+			want:        event.ClaimOK,
+			wantContact: true,
+		},
+		{
+			name: "contact not found",
+			str: &StorageMock{
+				updateF: claimed,
+				getF:    pending,
+				getCF: func(id int64) (contact.Contact, error) {
+					return contact.Contact{}, storage.ErrNotFound
+				},
+			},
+			c:         event.Claim{NotificationID: 1, Version: 1, ContactID: 3},
+			want:      event.ClaimOK,
+			wantError: true,
+		},
+		{
+			name: "not found",
+			str: &StorageMock{
+				updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+					return notification.Notification{}, storage.ErrNotAffected
+				},
+			},
+			c:    event.Claim{NotificationID: 1, Version: 1},
+			want: event.ClaimNotFound,
+		},
+		{
+			name: "cancelled",
+			str: &StorageMock{
+				updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+					return notification.Notification{}, storage.ErrCancelled
+				},
+			},
+			c:    event.Claim{NotificationID: 1, Version: 1},
+			want: event.ClaimCancelled,
+		},
+		{
+			name: "superseded",
+			str: &StorageMock{
+				updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+					return notification.Notification{}, storage.ErrNotEditable
+				},
+			},
+			c:    event.Claim{NotificationID: 1, Version: 1},
+			want: event.ClaimSuperseded,
+		},
+		{
+			name:      "wrong id",
+			str:       &StorageMock{},
+			c:         event.Claim{Version: 1},
+			want:      event.ClaimFailed,
+			wantError: true,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+					return notification.Notification{}, errors.New("unknown")
+				},
+			},
+			c:         event.Claim{NotificationID: 1, Version: 1},
+			want:      event.ClaimFailed,
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			r := s.Claim(tt.c)
+			require.Equal(t, tt.want, r.Result)
+			require.Equal(t, tt.wantContact, r.Contact != nil)
+			require.Equal(t, tt.wantError, r.Error != "")
+			require.Equal(t, tt.wantRecorded, r.Recorded)
+		})
+	}
+}
+
+func TestService_ApplyDelivery(t *testing.T) {
+	e := event.Delivery{
+		NotificationID: 1, Channel: notification.ChannelEmail, Address: "a@b.c",
+		Status: notification.RecipientSent, Attempt: 1,
+	}
+	tests := []struct {
+		name    string
+		str     storager
+		e       event.Delivery
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					if id != e.NotificationID || d.Address != e.Address {
+						return errors.New("wrong delivery")
+					}
+					return nil
+				},
+			},
+			e: e,
+		},
+		{
+			name: "not valid",
+			str:  &StorageMock{},
+			e: event.Delivery{
+				NotificationID: 1, Channel: "sms", Address: "1",
+				Status: notification.RecipientSent,
+			},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "not found",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return storage.ErrNotAffected
+				},
+			},
+			e:       e,
+			wantErr: ErrNotAffected,
+		},
+		{
+			name: "retry later",
+			str: &StorageMock{
+				dlvF: func(id int64, d notification.Delivery) error {
+					return errors.New("connection refused")
+				},
+			},
+			e:       e,
+			wantErr: storage.ErrRetryLater,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			err := s.ApplyDelivery(tt.e)
+			require.ErrorIs(t, err, tt.wantErr)
+			if !errors.Is(tt.wantErr, storage.ErrRetryLater) {
+				require.NotErrorIs(t, err, storage.ErrRetryLater)
+			}
+		})
+	}
+}
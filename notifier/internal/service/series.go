@@ -0,0 +1,220 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const seriesCancelReason = "series "
+
+// nextOccurrence возвращает первое срабатывание правила после from, но не
+// раньше чем через MinDelay от текущего момента. Правило считается в зоне
+// loc, результат в UTC
+func nextOccurrence(rule series.Rule, from time.Time, loc *time.Location) time.Time {
+	min := time.Now().UTC().Add(MinDelay)
+	if from.Before(min) {
+		from = min.Add(-time.Nanosecond)
+	}
+
+	return rule.Next(from.In(loc)).UTC()
+}
+
+// CreateSeries создает серию повторяющихся уведомлений и ее первое
+// срабатывание. Если у n задана дата, первое срабатывание ищется начиная
+// с нее, иначе с текущего момента. Возвращает id серии и id уведомления
+func (s *Service) CreateSeries(tenantID int64, sr series.Series, n notification.Notification) (int64, int64, error) {
+	const op = "internal.service.CreateSeries"
+
+	n.TenantID = tenantID
+	rule, err := series.ParseRule(sr.Rule)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %w", ErrNotValidData, err)
+	}
+	if sr.Count < 0 {
+		return 0, 0, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "recurrence count can't be negative",
+		)
+	}
+
+	from := n.Date
+	if !from.IsZero() {
+		from = from.Add(-time.Nanosecond)
+	}
+	next := nextOccurrence(rule, from, n.Location())
+	if next.IsZero() || (!sr.Until.IsZero() && next.After(sr.Until)) {
+		return 0, 0, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "recurrence rule has no occurrences",
+		)
+	}
+	n.Date = next
+	if err := validateNotification(n); err != nil {
+		return 0, 0, err
+	}
+	if err := s.checkContact(n); err != nil {
+		return 0, 0, err
+	}
+	seriesID, id, err := s.str.CreateSeries(sr, n)
+	if errors.Is(err, ErrQuotaExceeded) {
+		return 0, 0, err
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return seriesID, id, nil
+}
+
+// scheduleNext создает следующее срабатывание серии после prev, ищет его
+// начиная с from. Повторный вызов для того же prev ничего не создает
+func (s *Service) scheduleNext(prev notification.Notification, from time.Time) error {
+	const op = "internal.service.scheduleNext"
+
+	if prev.SeriesID == 0 {
+		return nil
+	}
+
+	sr, err := s.str.Series(prev.TenantID, prev.SeriesID)
+	if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	if sr.Status != series.StatusActive || sr.LastOccurrenceID != prev.ID {
+		return nil
+	}
+
+	rule, err := series.ParseRule(sr.Rule)
+	if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	next := nextOccurrence(rule, from, prev.Location())
+
+	if sr.Exhausted(next) {
+		_, err = s.str.UpdateSeriesStatus(prev.TenantID, sr.ID, series.StatusFinished)
+		if err != nil && !errors.Is(err, storage.ErrNotEditable) {
+			return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+		}
+		return nil
+	}
+
+	n := notification.Notification{
+		Message:  prev.Message,
+		Status:   notification.StatusPending,
+		Date:     next,
+		Timezone: prev.Timezone,
+		SeriesID: sr.ID,
+
+		TelegramMessage: prev.TelegramMessage,
+		EmailSubject:    prev.EmailSubject,
+		EmailMessage:    prev.EmailMessage,
+		TemplateID:      prev.TemplateID,
+		ContactID:       prev.ContactID,
+		TenantID:        prev.TenantID,
+		WebhookHeaders:  prev.WebhookHeaders,
+		WebhookSecret:   prev.WebhookSecret,
+
+		RetryMaxAttempts: prev.RetryMaxAttempts,
+		RetryBackoff:     prev.RetryBackoff,
+	}
+	// статусы доставки прошлого срабатывания не переносятся
+	n.SetAddresses(notification.ChannelTelegram, prev.Addresses(notification.ChannelTelegram))
+	n.SetAddresses(notification.ChannelEmail, prev.Addresses(notification.ChannelEmail))
+	n.SetAddresses(notification.ChannelWebhook, prev.Addresses(notification.ChannelWebhook))
+	_, err = s.str.CreateOccurrence(sr.ID, prev.ID, n)
+	if err != nil && !errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+func (s *Service) Series(tenantID, id int64) (series.Series, error) {
+	const op = "internal.service.Series"
+
+	if id <= 0 {
+		return series.Series{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "series id is negative or == 0",
+		)
+	}
+
+	sr, err := s.str.Series(tenantID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return sr, fmt.Errorf("%w: %w", ErrNotFound, err)
+	} else if err != nil {
+		return sr, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return sr, nil
+}
+
+// SeriesOccurrences возвращает страницу срабатываний серии
+func (s *Service) SeriesOccurrences(tenantID, id int64, f notification.Filter) ([]notification.Notification, string, error) {
+	if _, err := s.Series(tenantID, id); err != nil {
+		return nil, "", err
+	}
+	f.SeriesID = id
+
+	return s.Notifications(tenantID, f)
+}
+
+// UpdateSeriesStatus ставит серию на паузу, возобновляет или останавливает
+// ее. При паузе и остановке ожидающее срабатывание отменяется, при
+// возобновлении следующее срабатывание планируется от текущего момента
+func (s *Service) UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error) {
+	const op = "internal.service.UpdateSeriesStatus"
+
+	if id <= 0 {
+		return series.Series{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "series id is negative or == 0",
+		)
+	}
+	switch status {
+	case series.StatusActive, series.StatusPaused, series.StatusStopped:
+	default:
+		return series.Series{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData,
+			"wrong status value (\"active\", \"paused\" or \"stopped\" only)",
+		)
+	}
+
+	sr, err := s.str.UpdateSeriesStatus(tenantID, id, status)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return sr, fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if errors.Is(err, storage.ErrNotEditable) {
+		return sr, fmt.Errorf(
+			"%w: %s", ErrConflict, "stopped or finished series can't be changed",
+		)
+	} else if err != nil {
+		return sr, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	if sr.LastOccurrenceID == 0 {
+		return sr, nil
+	}
+
+	last, err := s.str.GetNotification(tenantID, sr.LastOccurrenceID)
+	if err != nil {
+		return sr, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	if status == series.StatusActive {
+		if last.Status == notification.StatusPending {
+			return sr, nil
+		}
+		err = s.scheduleNext(last, time.Now().UTC())
+		if err != nil {
+			return sr, err
+		}
+		return s.Series(tenantID, id)
+	}
+
+	if last.Status == notification.StatusPending {
+		err = s.str.CancelNotification(tenantID, last.ID, seriesCancelReason+status)
+		if err != nil && !errors.Is(err, storage.ErrNotEditable) &&
+			!errors.Is(err, storage.ErrCancelled) {
+			return sr, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+		}
+	}
+
+	return sr, nil
+}
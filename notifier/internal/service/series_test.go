@@ -0,0 +1,296 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/storage"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateSeries(t *testing.T) {
+	n := notification.Notification{Message: "hi", Recipients: to("1")}
+	tests := []struct {
+		name    string
+		sr      series.Series
+		date    time.Time
+		wantErr error
+	}{
+		{
+			name: "good",
+			sr:   series.Series{Rule: "0 9 * * 1"},
+		},
+		{
+			name: "date in future",
+			sr:   series.Series{Rule: "@daily", Count: 3},
+			date: time.Date(3000, 1, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "wrong rule",
+			sr:      series.Series{Rule: "every day"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "until in past",
+			sr:      series.Series{Rule: "@daily", Until: time.Now().Add(-time.Hour)},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "never fires",
+			sr:      series.Series{Rule: "0 0 30 2 *"},
+			wantErr: ErrNotValidData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got notification.Notification
+			s := New(&StorageMock{
+				addSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					got = n
+					return 1, 2, nil
+				},
+			})
+			n.Date = tt.date
+			seriesID, id, err := s.CreateSeries(1, tt.sr, n)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(1), seriesID)
+			require.Equal(t, int64(2), id)
+			require.True(t, got.Date.After(time.Now().Add(MinDelay)))
+			if !tt.date.IsZero() {
+				require.Equal(t, tt.date.Add(14*time.Hour), got.Date)
+			}
+		})
+	}
+}
+
+func TestService_scheduleNext(t *testing.T) {
+	prevDate := time.Date(3000, 1, 1, 9, 0, 0, 0, time.UTC)
+	prev := notification.Notification{
+		ID: 10, SeriesID: 1, Message: "hi", Recipients: to("1"),
+		Status: notification.StatusSending, Date: prevDate,
+	}
+	prev.Recipients[0].Status = notification.RecipientSent
+	tests := []struct {
+		name     string
+		sr       series.Series
+		wantNext bool
+		wantStat string
+	}{
+		{
+			name:     "next occurrence",
+			sr:       series.Series{ID: 1, Rule: "0 9 * * *", Status: series.StatusActive, LastOccurrenceID: 10},
+			wantNext: true,
+		},
+		{
+			name: "paused",
+			sr:   series.Series{ID: 1, Rule: "0 9 * * *", Status: series.StatusPaused, LastOccurrenceID: 10},
+		},
+		{
+			name: "already scheduled",
+			sr:   series.Series{ID: 1, Rule: "0 9 * * *", Status: series.StatusActive, LastOccurrenceID: 11},
+		},
+		{
+			name: "count reached",
+			sr: series.Series{
+				ID: 1, Rule: "0 9 * * *", Status: series.StatusActive,
+				LastOccurrenceID: 10, Count: 2, Fired: 2,
+			},
+			wantStat: series.StatusFinished,
+		},
+		{
+			name: "until reached",
+			sr: series.Series{
+				ID: 1, Rule: "0 9 * * *", Status: series.StatusActive,
+				LastOccurrenceID: 10, Until: prevDate.Add(time.Hour),
+			},
+			wantStat: series.StatusFinished,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				next   notification.Notification
+				status string
+			)
+			s := New(&StorageMock{
+				seriesF: func(id int64) (series.Series, error) {
+					return tt.sr, nil
+				},
+				nextF: func(seriesID, prevID int64, n notification.Notification) (int64, error) {
+					next = n
+					return 11, nil
+				},
+				sStatusF: func(id int64, st string) (series.Series, error) {
+					status = st
+					return tt.sr, nil
+				},
+			})
+			require.NoError(t, s.scheduleNext(prev, prev.Date))
+			require.Equal(t, tt.wantStat, status)
+			if !tt.wantNext {
+				require.Zero(t, next.Date)
+				return
+			}
+			require.Equal(t, prevDate.Add(24*time.Hour), next.Date)
+			require.Equal(t, prev.Message, next.Message)
+			require.Equal(t, notification.StatusPending, next.Status)
+			require.Equal(t, to("1"), next.Recipients)
+		})
+	}
+}
+
+func TestService_UpdateNotificationSchedulesNext(t *testing.T) {
+	created := false
+	sending := notification.StatusSending
+	s := New(&StorageMock{
+		updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+			return notification.Notification{
+				ID: id, SeriesID: 1, Status: notification.StatusSending,
+				Date: time.Now(),
+			}, nil
+		},
+		seriesF: func(id int64) (series.Series, error) {
+			return series.Series{
+				ID: id, Rule: "@hourly", Status: series.StatusActive,
+				LastOccurrenceID: 5,
+			}, nil
+		},
+		nextF: func(seriesID, prevID int64, n notification.Notification) (int64, error) {
+			created = true
+			return 0, storage.ErrNotAffected
+		},
+	})
+
+	err := s.UpdateNotification(1, 5, notification.Update{Status: &sending})
+	require.NoError(t, err)
+	require.True(t, created)
+}
+
+func TestService_UpdateSeriesStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		last       string
+		updateErr  error
+		wantCancel bool
+		wantNext   bool
+		wantErr    error
+	}{
+		{
+			name:       "pause cancels pending",
+			status:     series.StatusPaused,
+			last:       notification.StatusPending,
+			wantCancel: true,
+		},
+		{
+			name:   "stop after sent",
+			status: series.StatusStopped,
+			last:   notification.StatusSending,
+		},
+		{
+			name:     "resume schedules next",
+			status:   series.StatusActive,
+			last:     notification.StatusCancelled,
+			wantNext: true,
+		},
+		{
+			name:   "resume with pending",
+			status: series.StatusActive,
+			last:   notification.StatusPending,
+		},
+		{
+			name:    "wrong status",
+			status:  series.StatusFinished,
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:      "stopped",
+			status:    series.StatusActive,
+			updateErr: storage.ErrNotEditable,
+			wantErr:   ErrConflict,
+		},
+		{
+			name:      "not found",
+			status:    series.StatusPaused,
+			updateErr: storage.ErrNotAffected,
+			wantErr:   ErrNotAffected,
+		},
+		{
+			name:      "unknown",
+			status:    series.StatusPaused,
+			updateErr: errors.New("unknown"),
+			wantErr:   ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cancelled, next bool
+			sr := series.Series{ID: 1, Rule: "@daily", LastOccurrenceID: 5}
+			s := New(&StorageMock{
+				sStatusF: func(id int64, status string) (series.Series, error) {
+					sr.Status = status
+					return sr, tt.updateErr
+				},
+				seriesF: func(id int64) (series.Series, error) {
+					return sr, nil
+				},
+				getF: func(id int64) (notification.Notification, error) {
+					return notification.Notification{
+						ID: id, SeriesID: 1, Status: tt.last,
+						Date: time.Now().Add(-time.Hour),
+					}, nil
+				},
+				cancelF: func(id int64, reason string) error {
+					cancelled = true
+					return nil
+				},
+				nextF: func(seriesID, prevID int64, n notification.Notification) (int64, error) {
+					next = true
+					return 6, nil
+				},
+			})
+
+			_, err := s.UpdateSeriesStatus(1, 1, tt.status)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantCancel, cancelled)
+			require.Equal(t, tt.wantNext, next)
+		})
+	}
+}
+
+func TestService_scheduleNextInTimezone(t *testing.T) {
+	// 09:00 по Москве - 06:00 UTC
+	prev := notification.Notification{
+		ID: 10, SeriesID: 1, Message: "hi", Recipients: to("1"),
+		Status: notification.StatusSending, Timezone: "Europe/Moscow",
+		Date: time.Date(3000, 1, 1, 6, 0, 0, 0, time.UTC),
+	}
+	var next notification.Notification
+	s := New(&StorageMock{
+		seriesF: func(id int64) (series.Series, error) {
+			return series.Series{
+				ID: 1, Rule: "0 9 * * *", Status: series.StatusActive,
+				LastOccurrenceID: 10,
+			}, nil
+		},
+		nextF: func(seriesID, prevID int64, n notification.Notification) (int64, error) {
+			next = n
+			return 11, nil
+		},
+	})
+
+	require.NoError(t, s.scheduleNext(prev, prev.Date))
+	require.Equal(t, time.Date(3000, 1, 2, 6, 0, 0, 0, time.UTC), next.Date)
+	require.Equal(t, prev.Timezone, next.Timezone)
+}
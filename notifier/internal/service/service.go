@@ -1,23 +1,76 @@
 package service
 
 import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
 	"delayednotifier/internal/storage"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type storager interface {
 	CreateNotification(n notification.Notification) (int64, error)
-	GetNotification(id int64) (notification.Notification, error)
-	DeleteNotification(id int64) error
-	UpdateNotificationStatus(status string, id int64) error
+	CreateNotifications(ns []notification.Notification) ([]notification.BatchResult, error)
+	CreateNotificationIdempotent(n notification.Notification, key string) (int64, error)
+	GetNotification(tenantID, id int64) (notification.Notification, error)
+	SetMaxPending(n int64)
+	Notifications(f notification.Filter) ([]notification.Notification, error)
+	CancelNotification(tenantID, id int64, reason string) error
+	PurgeNotifications(tenantID int64, before time.Time) (int64, error)
+	UpdateNotification(tenantID, id int64, u notification.Update) (notification.Notification, error)
+	RecordDelivery(tenantID, id int64, d notification.Delivery) error
+	Deliveries(tenantID, id int64) ([]notification.Delivery, error)
+	OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error)
+
+	CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error)
+	CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error)
+	Series(tenantID, id int64) (series.Series, error)
+	UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error)
+
+	CreateTemplate(t template.Template) (int64, error)
+	Template(tenantID, id int64) (template.Template, error)
+	Templates(tenantID int64) ([]template.Template, error)
+	UpdateTemplate(t template.Template) (template.Template, error)
+	DeleteTemplate(tenantID, id int64) error
+
+	CreateContact(c contact.Contact) (int64, error)
+	Contact(tenantID, id int64) (contact.Contact, error)
+	Contacts(tenantID int64) ([]contact.Contact, error)
+	UpdateContact(c contact.Contact) (contact.Contact, error)
+	DeleteContact(tenantID, id int64) error
+
+	CreateAPIKey(k apikey.Key) (int64, error)
+	APIKeyByHash(hash string) (apikey.Key, error)
+	APIKeys(tenantID int64) ([]apikey.Key, error)
+	RevokeAPIKey(tenantID, id int64) error
+
+	CreateTenant(t tenant.Tenant) (int64, error)
+	Tenant(id int64) (tenant.Tenant, error)
+	Tenants() ([]tenant.Tenant, error)
+
+	DeadLetter(id int64) (deadletter.Letter, error)
+	DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error)
+	ReplayDeadLetter(id int64) error
+	DiscardDeadLetter(id int64) error
+
+	Allow(client string, rate float64, burst int64) (bool, time.Duration, error)
 }
 
 type Service struct {
 	str storager
+	// serviceHash хеш сервисного ключа sender, пустой - доступа нет
+	serviceHash string
+	limits      Limits
+	// overdueGrace на сколько ожидающее уведомление может опоздать
+	overdueGrace time.Duration
 }
 
 func New(s storager) *Service {
@@ -26,46 +79,172 @@ func New(s storager) *Service {
 	}
 }
 
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	MaxIdempotencyKeyLen   = 255
+	MaxCancelReasonLen     = 1024
+	MaxTemplateNameLen     = 255
+	MaxRecipients          = 100
+	MaxContactNameLen      = 255
+	MaxDeliveryErrorLen    = 1024
+	MaxDeliveryResponseLen = 1024
+	MaxAPIKeyNameLen       = 255
+	MaxTenantNameLen       = 255
+
+	// MinDelay минимальный отступ времени отправки от текущего момента
+	MinDelay = time.Second * 20
+)
+
 var (
 	ErrNotValidData    = errors.New("not valid data")
 	ErrStorageInternal = errors.New("internal error in storage")
 	ErrNotFound        = errors.New("not found")
 	ErrNotAffected     = errors.New("no one didn't be affected")
+	ErrConflict        = errors.New("conflict")
+	ErrCancelled       = errors.New("notification cancelled")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrQuotaExceeded   = notification.ErrQuotaExceeded
 )
 
-func (s *Service) CreateNotification(n notification.Notification) (int64, error) {
-	const op = "internal.service.CreateNotification"
-
-	if n.Date.Unix() < time.Now().UTC().Add(time.Second*20).Unix() {
-		return 0, fmt.Errorf(
+func validateNotification(n notification.Notification) error {
+	if n.Date.Unix() < time.Now().UTC().Add(MinDelay).Unix() {
+		return fmt.Errorf(
 			"%w: %s", ErrNotValidData, "date in past",
 		)
 	}
-	if n.TelegramID != 0 {
-		if n.TelegramID <= 0 {
-			return 0, fmt.Errorf(
-				"%w: %s", ErrNotValidData, "telegram_id can't be <= 0",
-			)
-		}
+	if len(n.Recipients) == 0 && n.ContactID == 0 {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "both send variant is empty")
 	}
-	if n.Email != "" {
-		if !strings.Contains(n.Email, "@") || !strings.Contains(n.Email, ".") {
-			return 0, fmt.Errorf(
-				"%w: %s", ErrNotValidData, "not valid email format",
-			)
+	if n.ContactID < 0 {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "contact id is negative")
+	}
+	if len(n.Recipients) > MaxRecipients {
+		return fmt.Errorf(
+			"%w: recipients more than %d", ErrNotValidData, MaxRecipients,
+		)
+	}
+	for _, rc := range n.Recipients {
+		switch rc.Channel {
+		case notification.ChannelTelegram:
+			if id, err := strconv.ParseInt(rc.Address, 10, 64); err != nil || id <= 0 {
+				return fmt.Errorf(
+					"%w: %s", ErrNotValidData, "telegram_id can't be <= 0",
+				)
+			}
+		case notification.ChannelEmail:
+			if !strings.Contains(rc.Address, "@") || !strings.Contains(rc.Address, ".") {
+				return fmt.Errorf(
+					"%w: %s", ErrNotValidData, "not valid email format",
+				)
+			}
+		case notification.ChannelWebhook:
+			// URL уже проверен при разборе запроса
+			if rc.Address == "" {
+				return fmt.Errorf("%w: %s", ErrNotValidData, "webhook_url is empty")
+			}
+		default:
+			return fmt.Errorf("%w: unknown channel %q", ErrNotValidData, rc.Channel)
 		}
-
 	}
 
+	return nil
+}
+
+func (s *Service) CreateNotification(tenantID int64, n notification.Notification) (int64, error) {
+	const op = "internal.service.CreateNotification"
+
+	n.TenantID = tenantID
+	if err := validateNotification(n); err != nil {
+		return 0, err
+	}
+	if err := s.checkContact(n); err != nil {
+		return 0, err
+	}
 	id, err := s.str.CreateNotification(n)
-	if err != nil {
+	if errors.Is(err, ErrQuotaExceeded) {
+		return 0, err
+	} else if err != nil {
 		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
 	}
 
 	return id, nil
 }
 
-func (s *Service) Notification(id int64) (notification.Notification, error) {
+// CreateNotificationIdempotent создает уведомление с ключом идемпотентности,
+// повторный запрос с тем же ключом и телом возвращает исходный id
+func (s *Service) CreateNotificationIdempotent(tenantID int64, n notification.Notification, key string) (int64, error) {
+	const op = "internal.service.CreateNotificationIdempotent"
+
+	n.TenantID = tenantID
+	if err := validateNotification(n); err != nil {
+		return 0, err
+	}
+	if err := s.checkContact(n); err != nil {
+		return 0, err
+	}
+	if key == "" || len(key) > MaxIdempotencyKeyLen {
+		return 0, fmt.Errorf(
+			"%w: idempotency key length should be in range 1..%d",
+			ErrNotValidData, MaxIdempotencyKeyLen,
+		)
+	}
+	id, err := s.str.CreateNotificationIdempotent(n, key)
+	if errors.Is(err, storage.ErrConflict) {
+		return 0, fmt.Errorf("%w: %w", ErrConflict, err)
+	} else if errors.Is(err, ErrQuotaExceeded) {
+		return 0, err
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return id, nil
+}
+
+// CreateNotifications создает пачку уведомлений, невалидные элементы
+// получают ошибку в результате и не мешают сохранению остальных
+func (s *Service) CreateNotifications(tenantID int64, ns []notification.Notification) ([]notification.BatchResult, error) {
+	const op = "internal.service.CreateNotifications"
+
+	r := make([]notification.BatchResult, len(ns))
+	valid := make([]notification.Notification, 0, len(ns))
+	idx := make([]int, 0, len(ns))
+	for i, n := range ns {
+		n.TenantID = tenantID
+		if err := validateNotification(n); err != nil {
+			r[i].Err = err
+			continue
+		}
+		if err := s.checkContact(n); err != nil {
+			r[i].Err = err
+			continue
+		}
+		valid = append(valid, n)
+		idx = append(idx, i)
+	}
+	if len(valid) == 0 {
+		return r, nil
+	}
+	created, err := s.str.CreateNotifications(valid)
+	if errors.Is(err, ErrQuotaExceeded) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	for i, c := range created {
+		r[idx[i]] = c
+		if c.Err != nil {
+			r[idx[i]].Err = fmt.Errorf("%w -> %w", ErrStorageInternal, c.Err)
+		}
+	}
+
+	return r, nil
+}
+
+func (s *Service) Notification(tenantID, id int64) (notification.Notification, error) {
 	const op = "internal.service.CreateNotification"
 
 	if id <= 0 {
@@ -74,7 +253,7 @@ func (s *Service) Notification(id int64) (notification.Notification, error) {
 		)
 	}
 
-	n, err := s.str.GetNotification(id)
+	n, err := s.str.GetNotification(tenantID, id)
 	if errors.Is(err, storage.ErrNotFound) {
 		return n, fmt.Errorf("%w: %w", ErrNotFound, err)
 	} else if err != nil {
@@ -84,46 +263,232 @@ func (s *Service) Notification(id int64) (notification.Notification, error) {
 	return n, nil
 }
 
-func (s *Service) DeleteNotification(id int64) error {
-	const op = "internal.service.CreateNotification"
+// Notifications возвращает страницу уведомлений и курсор следующей страницы
+func (s *Service) Notifications(tenantID int64, f notification.Filter) ([]notification.Notification, string, error) {
+	const op = "internal.service.Notifications"
+
+	f.TenantID = tenantID
+	if f.Limit == 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxListLimit {
+		return nil, "", fmt.Errorf(
+			"%w: limit should be in range 1..%d", ErrNotValidData, MaxListLimit,
+		)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.From.After(f.To) {
+		return nil, "", fmt.Errorf(
+			"%w: %s", ErrNotValidData, "from should be before to",
+		)
+	}
+	limit := f.Limit
+	// берем на один элемент больше, чтобы понять есть ли следующая страница
+	f.Limit++
+
+	r, err := s.str.Notifications(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+	if len(r) <= limit {
+		return r, "", nil
+	}
+
+	r = r[:limit]
+	last := r[len(r)-1]
+	next := notification.Cursor{Date: last.Date, ID: last.ID}
+
+	return r, next.String(), nil
+}
+
+// CancelNotification отменяет pending уведомление, sender не станет его
+// отправлять. Запись остается в базе до очистки через PurgeNotifications
+func (s *Service) CancelNotification(tenantID, id int64, reason string) error {
+	const op = "internal.service.CancelNotification"
 
 	if id <= 0 {
 		return fmt.Errorf(
 			"%w: %s", ErrNotValidData, "notification id is negative or == 0",
 		)
 	}
+	if len(reason) > MaxCancelReasonLen {
+		return fmt.Errorf(
+			"%w: reason longer than %d", ErrNotValidData, MaxCancelReasonLen,
+		)
+	}
 
-	err := s.str.DeleteNotification(id)
+	err := s.str.CancelNotification(tenantID, id, reason)
 	if errors.Is(err, storage.ErrNotAffected) {
 		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if errors.Is(err, storage.ErrCancelled) {
+		return fmt.Errorf("%w: %w", ErrCancelled, err)
+	} else if errors.Is(err, storage.ErrNotEditable) {
+		return fmt.Errorf("%w: %s", ErrConflict, "only pending notification can be cancelled")
 	} else if err != nil {
 		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
 	}
 
-	return nil
+	// отмена срабатывания серии не останавливает ее, планируем следующее
+	n, err := s.str.GetNotification(tenantID, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return s.scheduleNext(n, n.Date)
 }
 
-func (s *Service) UpdateNotificationStatus(status string, id int64) error {
-	const op = "internal.service.CreateNotification"
+// PurgeNotifications удаляет отмененные раньше before уведомления команды и
+// возвращает их количество
+func (s *Service) PurgeNotifications(tenantID int64, before time.Time) (int64, error) {
+	const op = "internal.service.PurgeNotifications"
 
-	if status != notification.StatusComplete && status != notification.StatusPending {
-		return fmt.Errorf(
-			"%w: %s", ErrNotValidData,
-			"wrong status value (\"pending\" or \"complete\" only)",
+	if before.IsZero() || before.After(time.Now()) {
+		return 0, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "before should be in past",
 		)
 	}
+
+	n, err := s.str.PurgeNotifications(tenantID, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return n, nil
+}
+
+// UpdateNotification меняет статус и/или содержимое уведомления. Текст, время
+// и получателей можно менять только пока уведомление в статусе pending
+func (s *Service) UpdateNotification(tenantID, id int64, u notification.Update) error {
+	const op = "internal.service.UpdateNotification"
+
 	if id <= 0 {
 		return fmt.Errorf(
 			"%w: %s", ErrNotValidData, "notification id is negative or == 0",
 		)
 	}
+	if u.Empty() {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "nothing to update")
+	}
+	// статус выводится из доставки, сам меняется только при claim
+	if u.Status != nil && *u.Status != notification.StatusSending {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData, "status can't be changed",
+		)
+	}
+	if u.HasContent() {
+		cur, err := s.str.GetNotification(tenantID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%w: %w", ErrNotAffected, err)
+		} else if err != nil {
+			return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+		}
+		if cur.Status == notification.StatusCancelled {
+			return ErrCancelled
+		}
+		if cur.Status != notification.StatusPending {
+			return fmt.Errorf(
+				"%w: %s", ErrConflict, "only pending notification can be edited",
+			)
+		}
+		if u.Version == 0 {
+			u.Version = cur.Version
+		}
+
+		n := u.Apply(cur)
+		if n.Message == "" {
+			return fmt.Errorf("%w: %s", ErrNotValidData, "message is empty")
+		}
+		if err := validateNotification(n); err != nil {
+			return err
+		}
+	}
 
-	err := s.str.UpdateNotificationStatus(status, id)
+	n, err := s.str.UpdateNotification(tenantID, id, u)
 	if errors.Is(err, storage.ErrNotAffected) {
 		return ErrNotAffected
+	} else if errors.Is(err, storage.ErrCancelled) {
+		return fmt.Errorf("%w: %w", ErrCancelled, err)
+	} else if errors.Is(err, storage.ErrNotEditable) {
+		return fmt.Errorf("%w: %w", ErrConflict, err)
 	} else if err != nil {
 		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
 	}
 
+	// после отправки срабатывания серии планируем следующее
+	if n.Status == notification.StatusSending {
+		return s.scheduleNext(n, n.Date)
+	}
+
 	return nil
 }
+
+// RecordDelivery сохраняет попытку доставки получателю, вызывается при
+// разборе результатов sender из очереди. Общий статус уведомления выводится из статусов
+// получателей
+func (s *Service) RecordDelivery(tenantID, id int64, d notification.Delivery) error {
+	const op = "internal.service.RecordDelivery"
+
+	if id <= 0 {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData, "notification id is negative or == 0",
+		)
+	}
+	if d.Channel != notification.ChannelTelegram &&
+		d.Channel != notification.ChannelEmail &&
+		d.Channel != notification.ChannelWebhook {
+		return fmt.Errorf("%w: unknown channel %q", ErrNotValidData, d.Channel)
+	}
+	if d.Address == "" {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "address is empty")
+	}
+	if d.Status != notification.RecipientSent &&
+		d.Status != notification.RecipientFailed &&
+		d.Status != notification.RecipientRetrying {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData,
+			"wrong status value (\"sent\", \"failed\" or \"retrying\" only)",
+		)
+	}
+	if d.Attempt < 0 {
+		return fmt.Errorf("%w: %s", ErrNotValidData, "attempt is negative")
+	}
+	if len(d.Error) > MaxDeliveryErrorLen {
+		d.Error = d.Error[:MaxDeliveryErrorLen]
+	}
+	if len(d.Response) > MaxDeliveryResponseLen {
+		d.Response = d.Response[:MaxDeliveryResponseLen]
+	}
+	// старый sender не присылает время попытки
+	if d.FinishedAt.IsZero() {
+		d.FinishedAt = time.Now().UTC()
+	}
+	if d.StartedAt.IsZero() {
+		d.StartedAt = d.FinishedAt
+	}
+
+	err := s.str.RecordDelivery(tenantID, id, d)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+// Deliveries история попыток доставки уведомления
+func (s *Service) Deliveries(tenantID, id int64) ([]notification.Delivery, error) {
+	const op = "internal.service.Deliveries"
+
+	if id <= 0 {
+		return nil, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "notification id is negative or == 0",
+		)
+	}
+
+	r, err := s.str.Deliveries(tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
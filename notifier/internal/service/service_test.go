@@ -1,10 +1,18 @@
 package service
 
 import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
 	"delayednotifier/internal/storage"
 	"errors"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,24 +21,217 @@ import (
 
 type StorageMock struct {
 	addNF   func(n notification.Notification) (int64, error)
+	batchF  func(ns []notification.Notification) ([]notification.BatchResult, error)
+	idemF   func(n notification.Notification, key string) (int64, error)
 	getF    func(id int64) (notification.Notification, error)
-	deleteF func(id int64) error
-	updateF func(status string, id int64) error
+	listF   func(f notification.Filter) ([]notification.Notification, error)
+	cancelF func(id int64, reason string) error
+	purgeF  func(before time.Time) (int64, error)
+	updateF func(id int64, u notification.Update) (notification.Notification, error)
+	dlvF    func(id int64, d notification.Delivery) error
+	dlvsF   func(id int64) ([]notification.Delivery, error)
+	overF   func(f notification.OverdueFilter) ([]notification.Overdue, error)
+
+	addSF    func(sr series.Series, n notification.Notification) (int64, int64, error)
+	nextF    func(seriesID, prevID int64, n notification.Notification) (int64, error)
+	seriesF  func(id int64) (series.Series, error)
+	sStatusF func(id int64, status string) (series.Series, error)
+
+	addTF    func(t template.Template) (int64, error)
+	getTF    func(id int64) (template.Template, error)
+	listTF   func() ([]template.Template, error)
+	updateTF func(t template.Template) (template.Template, error)
+	deleteTF func(id int64) error
+
+	addCF    func(c contact.Contact) (int64, error)
+	getCF    func(id int64) (contact.Contact, error)
+	listCF   func() ([]contact.Contact, error)
+	updateCF func(c contact.Contact) (contact.Contact, error)
+	deleteCF func(id int64) error
+
+	addKF    func(k apikey.Key) (int64, error)
+	getKF    func(hash string) (apikey.Key, error)
+	listKF   func() ([]apikey.Key, error)
+	revokeKF func(id int64) error
+
+	addTnF  func(t tenant.Tenant) (int64, error)
+	getTnF  func(id int64) (tenant.Tenant, error)
+	listTnF func() ([]tenant.Tenant, error)
+
+	getDF     func(id int64) (deadletter.Letter, error)
+	listDF    func(f deadletter.Filter) ([]deadletter.Letter, error)
+	replayDF  func(id int64) error
+	discardDF func(id int64) error
+
+	maxPending int64
+	allowF     func(client string, rate float64, burst int64) (bool, time.Duration, error)
 }
 
 func (sm *StorageMock) CreateNotification(n notification.Notification) (int64, error) {
 	return sm.addNF(n)
 }
 
-func (sm *StorageMock) GetNotification(id int64) (notification.Notification, error) {
+func (sm *StorageMock) CreateNotifications(ns []notification.Notification) ([]notification.BatchResult, error) {
+	return sm.batchF(ns)
+}
+
+func (sm *StorageMock) CreateNotificationIdempotent(n notification.Notification, key string) (int64, error) {
+	return sm.idemF(n, key)
+}
+
+func (sm *StorageMock) GetNotification(_, id int64) (notification.Notification, error) {
 	return sm.getF(id)
 }
 
-func (sm *StorageMock) DeleteNotification(id int64) error {
-	return sm.deleteF(id)
+func (sm *StorageMock) Notifications(f notification.Filter) ([]notification.Notification, error) {
+	return sm.listF(f)
+}
+
+func (sm *StorageMock) CancelNotification(_, id int64, reason string) error {
+	return sm.cancelF(id, reason)
+}
+
+func (sm *StorageMock) PurgeNotifications(_ int64, before time.Time) (int64, error) {
+	return sm.purgeF(before)
+}
+func (sm *StorageMock) UpdateNotification(_, id int64, u notification.Update) (notification.Notification, error) {
+	return sm.updateF(id, u)
+}
+
+func (sm *StorageMock) RecordDelivery(_, id int64, d notification.Delivery) error {
+	return sm.dlvF(id, d)
+}
+
+func (sm *StorageMock) Deliveries(_, id int64) ([]notification.Delivery, error) {
+	return sm.dlvsF(id)
+}
+
+func (sm *StorageMock) OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error) {
+	return sm.overF(f)
 }
-func (sm *StorageMock) UpdateNotificationStatus(status string, id int64) error {
-	return sm.updateF(status, id)
+
+// to получатели по адресам: числовой адрес - Telegram, остальные - почта
+func to(addrs ...string) []notification.Recipient {
+	n := notification.Notification{}
+	for _, a := range addrs {
+		ch := notification.ChannelEmail
+		if _, err := strconv.ParseInt(a, 10, 64); err == nil {
+			ch = notification.ChannelTelegram
+		}
+		n.Recipients = append(n.Recipients, notification.Recipient{
+			Channel: ch, Address: a, Status: notification.RecipientPending,
+		})
+	}
+
+	return n.Recipients
+}
+
+func (sm *StorageMock) CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error) {
+	return sm.addSF(sr, n)
+}
+
+func (sm *StorageMock) CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error) {
+	return sm.nextF(seriesID, prevID, n)
+}
+
+func (sm *StorageMock) Series(_, id int64) (series.Series, error) {
+	return sm.seriesF(id)
+}
+
+func (sm *StorageMock) UpdateSeriesStatus(_, id int64, status string) (series.Series, error) {
+	return sm.sStatusF(id, status)
+}
+
+func (sm *StorageMock) CreateTemplate(t template.Template) (int64, error) {
+	return sm.addTF(t)
+}
+
+func (sm *StorageMock) Template(_, id int64) (template.Template, error) {
+	return sm.getTF(id)
+}
+
+func (sm *StorageMock) Templates(_ int64) ([]template.Template, error) {
+	return sm.listTF()
+}
+
+func (sm *StorageMock) UpdateTemplate(t template.Template) (template.Template, error) {
+	return sm.updateTF(t)
+}
+
+func (sm *StorageMock) DeleteTemplate(_, id int64) error {
+	return sm.deleteTF(id)
+}
+
+func (sm *StorageMock) CreateContact(c contact.Contact) (int64, error) {
+	return sm.addCF(c)
+}
+
+func (sm *StorageMock) Contact(_, id int64) (contact.Contact, error) {
+	return sm.getCF(id)
+}
+
+func (sm *StorageMock) Contacts(_ int64) ([]contact.Contact, error) {
+	return sm.listCF()
+}
+
+func (sm *StorageMock) UpdateContact(c contact.Contact) (contact.Contact, error) {
+	return sm.updateCF(c)
+}
+
+func (sm *StorageMock) DeleteContact(_, id int64) error {
+	return sm.deleteCF(id)
+}
+
+func (sm *StorageMock) CreateAPIKey(k apikey.Key) (int64, error) {
+	return sm.addKF(k)
+}
+
+func (sm *StorageMock) APIKeyByHash(hash string) (apikey.Key, error) {
+	return sm.getKF(hash)
+}
+
+func (sm *StorageMock) APIKeys(_ int64) ([]apikey.Key, error) {
+	return sm.listKF()
+}
+
+func (sm *StorageMock) RevokeAPIKey(_, id int64) error {
+	return sm.revokeKF(id)
+}
+
+func (sm *StorageMock) CreateTenant(t tenant.Tenant) (int64, error) {
+	return sm.addTnF(t)
+}
+
+func (sm *StorageMock) Tenant(id int64) (tenant.Tenant, error) {
+	return sm.getTnF(id)
+}
+
+func (sm *StorageMock) Tenants() ([]tenant.Tenant, error) {
+	return sm.listTnF()
+}
+
+func (sm *StorageMock) DeadLetter(id int64) (deadletter.Letter, error) {
+	return sm.getDF(id)
+}
+
+func (sm *StorageMock) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error) {
+	return sm.listDF(f)
+}
+
+func (sm *StorageMock) ReplayDeadLetter(id int64) error {
+	return sm.replayDF(id)
+}
+
+func (sm *StorageMock) DiscardDeadLetter(id int64) error {
+	return sm.discardDF(id)
+}
+
+func (sm *StorageMock) SetMaxPending(n int64) {
+	sm.maxPending = n
+}
+
+func (sm *StorageMock) Allow(client string, rate float64, burst int64) (bool, time.Duration, error) {
+	return sm.allowF(client, rate, burst)
 }
 
 func TestService_CreateNotification(t *testing.T) {
@@ -62,8 +263,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
-					Email:      "asd@asd.com",
+					Recipients: to("123", "asd@asd.com"),
 					Date:       goodTM,
 				},
 			},
@@ -80,9 +280,9 @@ func TestService_CreateNotification(t *testing.T) {
 			},
 			args: args{
 				n: notification.Notification{
-					Message: "test",
-					Email:   "asd@asd.com",
-					Date:    goodTM,
+					Message:    "test",
+					Recipients: to("asd@asd.com"),
+					Date:       goodTM,
 				},
 			},
 			want: nil,
@@ -99,7 +299,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
+					Recipients: to("123"),
 					Date:       goodTM,
 				},
 			},
@@ -117,12 +317,33 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
+					Recipients: to("123"),
 					Date:       goodTM,
 				},
 			},
 			want: nil,
 		},
+		{
+			name: "webhook",
+			fields: fields{
+				s: &StorageMock{
+					addNF: func(n notification.Notification) (int64, error) {
+						return 0, nil
+					},
+				},
+			},
+			args: args{
+				n: notification.Notification{
+					Message: "test",
+					Recipients: []notification.Recipient{{
+						Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+						Status: notification.RecipientPending,
+					}},
+					Date: goodTM,
+				},
+			},
+			want: nil,
+		},
 		{
 			name: "date in past",
 			fields: fields{
@@ -135,8 +356,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
-					Email:      "asd@asd.com",
+					Recipients: to("123", "asd@asd.com"),
 					Date:       badTM,
 				},
 			},
@@ -154,8 +374,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: -1,
-					Email:      "asd@asd.com",
+					Recipients: to("-1", "asd@asd.com"),
 					Date:       goodTM,
 				},
 			},
@@ -173,8 +392,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
-					Email:      "asd@asd.com",
+					Recipients: to("123", "asd@asd.com"),
 					Date:       goodTM,
 				},
 			},
@@ -192,8 +410,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
-					Email:      "asd@asd.com",
+					Recipients: to("123", "asd@asd.com"),
 					Date:       goodTM,
 				},
 			},
@@ -211,8 +428,7 @@ func TestService_CreateNotification(t *testing.T) {
 			args: args{
 				n: notification.Notification{
 					Message:    "test",
-					TelegramID: 123,
-					Email:      "asdasd.com",
+					Recipients: to("123", "asdasd.com"),
 					Date:       goodTM,
 				},
 			},
@@ -224,13 +440,174 @@ func TestService_CreateNotification(t *testing.T) {
 			s := New(
 				tt.fields.s,
 			)
-			if _, err := s.CreateNotification(tt.args.n); !errors.Is(err, tt.want) {
+			if _, err := s.CreateNotification(1, tt.args.n); !errors.Is(err, tt.want) {
 				t.Errorf("Service.CreateNotification() error = %v, wantErr %v", err, tt.want)
 			}
 		})
 	}
 }
 
+func TestService_CreateNotifications(t *testing.T) {
+	goodTM, err := time.Parse(notification.DateLayout, "3000-12-22T15:20:00.000Z")
+	require.NoError(t, err)
+	badTM, err := time.Parse(notification.DateLayout, "1969-12-22T15:20:00.000Z")
+	require.NoError(t, err)
+
+	stored := func(ns []notification.Notification) ([]notification.BatchResult, error) {
+		r := make([]notification.BatchResult, len(ns))
+		for i := range r {
+			r[i].ID = int64(i + 1)
+		}
+		return r, nil
+	}
+	tests := []struct {
+		name     string
+		str      storager
+		ns       []notification.Notification
+		wantErrs []error
+		wantErr  error
+	}{
+		{
+			name: "good",
+			str:  &StorageMock{batchF: stored},
+			ns: []notification.Notification{
+				{Message: "test", Recipients: to("123"), Date: goodTM},
+				{Message: "test", Recipients: to("asd@asd.com"), Date: goodTM},
+			},
+			wantErrs: []error{nil, nil},
+		},
+		{
+			name: "partial",
+			str:  &StorageMock{batchF: stored},
+			ns: []notification.Notification{
+				{Message: "test", Recipients: to("123"), Date: badTM},
+				{Message: "test", Recipients: to("asd@asd.com"), Date: goodTM},
+				{Message: "test", Recipients: to("asdasd.com"), Date: goodTM},
+			},
+			wantErrs: []error{ErrNotValidData, nil, ErrNotValidData},
+		},
+		{
+			name: "all invalid",
+			str:  &StorageMock{},
+			ns: []notification.Notification{
+				{Message: "test", Recipients: to("-1"), Date: goodTM},
+			},
+			wantErrs: []error{ErrNotValidData},
+		},
+		{
+			name: "publish error",
+			str: &StorageMock{
+				batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+					return []notification.BatchResult{{ID: 1, Err: errors.New("unknown")}}, nil
+				},
+			},
+			ns: []notification.Notification{
+				{Message: "test", Recipients: to("123"), Date: goodTM},
+			},
+			wantErrs: []error{ErrStorageInternal},
+		},
+		{
+			name: "storage error",
+			str: &StorageMock{
+				batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+					return nil, errors.New("unknown")
+				},
+			},
+			ns: []notification.Notification{
+				{Message: "test", Recipients: to("123"), Date: goodTM},
+			},
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			got, err := s.CreateNotifications(1, tt.ns)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.CreateNotifications() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Len(t, got, len(tt.wantErrs))
+			for i, want := range tt.wantErrs {
+				if !errors.Is(got[i].Err, want) {
+					t.Errorf("Service.CreateNotifications()[%d] error = %v, wantErr %v", i, got[i].Err, want)
+				}
+				if want == nil && got[i].ID == 0 {
+					t.Errorf("Service.CreateNotifications()[%d] id is empty", i)
+				}
+			}
+		})
+	}
+}
+
+func TestService_CreateNotificationIdempotent(t *testing.T) {
+	goodTM, err := time.Parse(notification.DateLayout, "3000-12-22T15:20:00.000Z")
+	require.NoError(t, err)
+	n := notification.Notification{Message: "test", Recipients: to("123"), Date: goodTM}
+
+	tests := []struct {
+		name    string
+		str     storager
+		key     string
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				idemF: func(n notification.Notification, key string) (int64, error) {
+					return 7, nil
+				},
+			},
+			key:  "abc",
+			want: 7,
+		},
+		{
+			name:    "empty key",
+			str:     &StorageMock{},
+			key:     "",
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "too long key",
+			str:     &StorageMock{},
+			key:     strings.Repeat("a", MaxIdempotencyKeyLen+1),
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "conflict",
+			str: &StorageMock{
+				idemF: func(n notification.Notification, key string) (int64, error) {
+					return 0, storage.ErrConflict
+				},
+			},
+			key:     "abc",
+			wantErr: ErrConflict,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				idemF: func(n notification.Notification, key string) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			key:     "abc",
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			got, err := s.CreateNotificationIdempotent(1, n, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.CreateNotificationIdempotent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestService_Notification(t *testing.T) {
 	goodTM, _ := time.Parse(notification.DateLayout, "3000-12-22 15:20")
 	type fields struct {
@@ -254,8 +631,7 @@ func TestService_Notification(t *testing.T) {
 						return notification.Notification{
 							ID:         1,
 							Message:    "test",
-							TelegramID: 123,
-							Email:      "asd@asd.com",
+							Recipients: to("123", "asd@asd.com"),
 							Date:       goodTM,
 						}, nil
 					},
@@ -267,8 +643,7 @@ func TestService_Notification(t *testing.T) {
 			want: notification.Notification{
 				ID:         1,
 				Message:    "test",
-				TelegramID: 123,
-				Email:      "asd@asd.com",
+				Recipients: to("123", "asd@asd.com"),
 				Date:       goodTM,
 			},
 			wantErr: nil,
@@ -324,7 +699,7 @@ func TestService_Notification(t *testing.T) {
 			s := New(
 				tt.fields.s,
 			)
-			got, err := s.Notification(tt.args.id)
+			got, err := s.Notification(1, tt.args.id)
 			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("Service.Notification() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -336,12 +711,13 @@ func TestService_Notification(t *testing.T) {
 	}
 }
 
-func TestService_DeleteNotification(t *testing.T) {
+func TestService_CancelNotification(t *testing.T) {
 	type fields struct {
 		s storager
 	}
 	type args struct {
-		id int64
+		id     int64
+		reason string
 	}
 	tests := []struct {
 		name    string
@@ -353,9 +729,14 @@ func TestService_DeleteNotification(t *testing.T) {
 			name: "good",
 			fields: fields{
 				s: &StorageMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return nil
 					},
+					getF: func(id int64) (notification.Notification, error) {
+						return notification.Notification{
+							ID: id, Status: notification.StatusCancelled,
+						}, nil
+					},
 				},
 			},
 			args: args{
@@ -367,7 +748,7 @@ func TestService_DeleteNotification(t *testing.T) {
 			name: "not affected",
 			fields: fields{
 				s: &StorageMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return storage.ErrNotAffected
 					},
 				},
@@ -381,7 +762,7 @@ func TestService_DeleteNotification(t *testing.T) {
 			name: "unknown error",
 			fields: fields{
 				s: &StorageMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return errors.New("unknown")
 					},
 				},
@@ -395,7 +776,7 @@ func TestService_DeleteNotification(t *testing.T) {
 			name: "bad id",
 			fields: fields{
 				s: &StorageMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return nil
 					},
 				},
@@ -405,27 +786,130 @@ func TestService_DeleteNotification(t *testing.T) {
 			},
 			wantErr: ErrNotValidData,
 		},
+		{
+			name: "already cancelled",
+			fields: fields{
+				s: &StorageMock{
+					cancelF: func(id int64, reason string) error {
+						return storage.ErrCancelled
+					},
+				},
+			},
+			args: args{
+				id: 1,
+			},
+			wantErr: ErrCancelled,
+		},
+		{
+			name: "already sent",
+			fields: fields{
+				s: &StorageMock{
+					cancelF: func(id int64, reason string) error {
+						return storage.ErrNotEditable
+					},
+				},
+			},
+			args: args{
+				id:     1,
+				reason: "changed plans",
+			},
+			wantErr: ErrConflict,
+		},
+		{
+			name: "too long reason",
+			fields: fields{
+				s: &StorageMock{},
+			},
+			args: args{
+				id:     1,
+				reason: strings.Repeat("a", MaxCancelReasonLen+1),
+			},
+			wantErr: ErrNotValidData,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := New(
 				tt.fields.s,
 			)
-			err := s.DeleteNotification(tt.args.id)
+			err := s.CancelNotification(1, tt.args.id, tt.args.reason)
 			if !errors.Is(err, tt.wantErr) {
-				t.Errorf("Service.DeleteNotification() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("Service.CancelNotification() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestService_UpdateNotificationStatus(t *testing.T) {
+func TestService_PurgeNotifications(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     storager
+		before  time.Time
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				purgeF: func(before time.Time) (int64, error) {
+					return 3, nil
+				},
+			},
+			before: time.Now().Add(-time.Hour),
+			want:   3,
+		},
+		{
+			name:    "future",
+			str:     &StorageMock{},
+			before:  time.Now().Add(time.Hour),
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				purgeF: func(before time.Time) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			before:  time.Now().Add(-time.Hour),
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			got, err := s.PurgeNotifications(1, tt.before)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.PurgeNotifications() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestService_UpdateNotification(t *testing.T) {
+	goodTM, err := time.Parse(notification.DateLayout, "3000-12-22T15:20:00.000Z")
+	require.NoError(t, err)
+	badTM, err := time.Parse(notification.DateLayout, "1969-12-22T15:20:00.000Z")
+	require.NoError(t, err)
+	str := func(v string) *string { return &v }
+	pending := func(id int64) (notification.Notification, error) {
+		return notification.Notification{
+			ID: id, Message: "test", Recipients: to("123"),
+			Status: notification.StatusPending, Date: goodTM, Version: 3,
+		}, nil
+	}
+	updated := func(id int64, u notification.Update) (notification.Notification, error) {
+		return notification.Notification{}, nil
+	}
+
 	type fields struct {
 		str storager
 	}
 	type args struct {
-		status string
-		id     int64
+		id int64
+		u  notification.Update
 	}
 	tests := []struct {
 		name   string
@@ -437,28 +921,37 @@ func TestService_UpdateNotificationStatus(t *testing.T) {
 			name: "good",
 			fields: fields{
 				str: &StorageMock{
-					updateF: func(status string, id int64) error {
-						return nil
-					},
+					updateF: updated,
 				},
 			},
 			args: args{
-				status: "pending",
-				id:     100,
+				u:  notification.Update{Status: str("sending")},
+				id: 100,
 			},
 		},
 		{
 			name: "negative id",
 			fields: fields{
 				str: &StorageMock{
-					updateF: func(status string, id int64) error {
-						return nil
-					},
+					updateF: updated,
 				},
 			},
 			args: args{
-				status: "pending",
-				id:     -100,
+				u:  notification.Update{Status: str("sending")},
+				id: -100,
+			},
+			want: ErrNotValidData,
+		},
+		{
+			name: "back to pending",
+			fields: fields{
+				str: &StorageMock{
+					updateF: updated,
+				},
+			},
+			args: args{
+				u:  notification.Update{Status: str("pending")},
+				id: 1,
 			},
 			want: ErrNotValidData,
 		},
@@ -466,56 +959,176 @@ func TestService_UpdateNotificationStatus(t *testing.T) {
 			name: "unknown status",
 			fields: fields{
 				str: &StorageMock{
-					updateF: func(status string, id int64) error {
-						return nil
-					},
+					updateF: updated,
 				},
 			},
 			args: args{
-				status: "test",
-				id:     1,
+				u:  notification.Update{Status: str("test")},
+				id: 1,
 			},
 			want: ErrNotValidData,
 		},
 		{
-			name: "good",
+			name: "empty update",
 			fields: fields{
 				str: &StorageMock{
-					updateF: func(status string, id int64) error {
-						return storage.ErrNotAffected
+					updateF: updated,
+				},
+			},
+			args: args{
+				id: 1,
+			},
+			want: ErrNotValidData,
+		},
+		{
+			name: "not affected",
+			fields: fields{
+				str: &StorageMock{
+					updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+						return notification.Notification{}, storage.ErrNotAffected
 					},
 				},
 			},
 			args: args{
-				status: "pending",
-				id:     100,
+				u:  notification.Update{Status: str("sending")},
+				id: 100,
 			},
 			want: ErrNotAffected,
 		},
 		{
-			name: "good",
+			name: "unknown error",
 			fields: fields{
 				str: &StorageMock{
-					updateF: func(status string, id int64) error {
-						return errors.New("test")
+					updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+						return notification.Notification{}, errors.New("test")
 					},
 				},
 			},
 			args: args{
-				status: "pending",
-				id:     100,
+				u:  notification.Update{Status: str("sending")},
+				id: 100,
 			},
 			want: ErrStorageInternal,
 		},
+		{
+			name: "stale version",
+			fields: fields{
+				str: &StorageMock{
+					updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+						return notification.Notification{}, storage.ErrNotEditable
+					},
+				},
+			},
+			args: args{
+				u:  notification.Update{Status: str("sending"), Version: 1},
+				id: 100,
+			},
+			want: ErrConflict,
+		},
+		{
+			name: "reschedule",
+			fields: fields{
+				str: &StorageMock{
+					getF: pending,
+					updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+						if u.Version != 3 {
+							return notification.Notification{}, storage.ErrNotEditable
+						}
+						return notification.Notification{}, nil
+					},
+				},
+			},
+			args: args{
+				u:  notification.Update{Message: str("new"), Date: &goodTM},
+				id: 100,
+			},
+		},
+		{
+			name: "reschedule to past",
+			fields: fields{
+				str: &StorageMock{
+					getF:    pending,
+					updateF: updated,
+				},
+			},
+			args: args{
+				u:  notification.Update{Date: &badTM},
+				id: 100,
+			},
+			want: ErrNotValidData,
+		},
+		{
+			name: "remove last recipient",
+			fields: fields{
+				str: &StorageMock{
+					getF:    pending,
+					updateF: updated,
+				},
+			},
+			args: args{
+				u:  notification.Update{Telegram: &[]string{}},
+				id: 100,
+			},
+			want: ErrNotValidData,
+		},
+		{
+			name: "edit sending",
+			fields: fields{
+				str: &StorageMock{
+					getF: func(id int64) (notification.Notification, error) {
+						return notification.Notification{
+							ID: id, Status: notification.StatusSending,
+						}, nil
+					},
+					updateF: updated,
+				},
+			},
+			args: args{
+				u:  notification.Update{Message: str("new")},
+				id: 100,
+			},
+			want: ErrConflict,
+		},
+		{
+			name: "status of cancelled",
+			fields: fields{
+				str: &StorageMock{
+					updateF: func(id int64, u notification.Update) (notification.Notification, error) {
+						return notification.Notification{}, storage.ErrCancelled
+					},
+				},
+			},
+			args: args{
+				u:  notification.Update{Status: str("sending"), Version: 1},
+				id: 100,
+			},
+			want: ErrCancelled,
+		},
+		{
+			name: "edit not found",
+			fields: fields{
+				str: &StorageMock{
+					getF: func(id int64) (notification.Notification, error) {
+						return notification.Notification{}, storage.ErrNotFound
+					},
+					updateF: updated,
+				},
+			},
+			args: args{
+				u:  notification.Update{Message: str("new")},
+				id: 100,
+			},
+			want: ErrNotAffected,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Service{
 				str: tt.fields.str,
 			}
-			err := s.UpdateNotificationStatus(tt.args.status, tt.args.id)
+			err := s.UpdateNotification(1, tt.args.id, tt.args.u)
 			if !errors.Is(err, tt.want) {
-				t.Errorf("Service.UpdateNotificationStatus() error = %v, wantErr %v", err, tt.want)
+				t.Errorf("Service.UpdateNotification() error = %v, wantErr %v", err, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+)
+
+func validateTemplate(t template.Template) error {
+	if t.Name == "" || len(t.Name) > MaxTemplateNameLen {
+		return fmt.Errorf(
+			"%w: template name length should be in range 1..%d",
+			ErrNotValidData, MaxTemplateNameLen,
+		)
+	}
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotValidData, err)
+	}
+
+	return nil
+}
+
+func (s *Service) CreateTemplate(tenantID int64, t template.Template) (int64, error) {
+	const op = "internal.service.CreateTemplate"
+
+	t.TenantID = tenantID
+	if err := validateTemplate(t); err != nil {
+		return 0, err
+	}
+
+	id, err := s.str.CreateTemplate(t)
+	if errors.Is(err, storage.ErrConflict) {
+		return 0, fmt.Errorf("%w: %s", ErrConflict, "template name already used")
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return id, nil
+}
+
+func (s *Service) Template(tenantID, id int64) (template.Template, error) {
+	const op = "internal.service.Template"
+
+	if id <= 0 {
+		return template.Template{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "template id is negative or == 0",
+		)
+	}
+
+	t, err := s.str.Template(tenantID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return t, fmt.Errorf("%w: %w", ErrNotFound, err)
+	} else if err != nil {
+		return t, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return t, nil
+}
+
+func (s *Service) Templates(tenantID int64) ([]template.Template, error) {
+	const op = "internal.service.Templates"
+
+	r, err := s.str.Templates(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
+
+func (s *Service) UpdateTemplate(tenantID int64, t template.Template) (template.Template, error) {
+	const op = "internal.service.UpdateTemplate"
+
+	t.TenantID = tenantID
+	if t.ID <= 0 {
+		return template.Template{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "template id is negative or == 0",
+		)
+	}
+	if err := validateTemplate(t); err != nil {
+		return template.Template{}, err
+	}
+
+	r, err := s.str.UpdateTemplate(t)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return r, fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if errors.Is(err, storage.ErrConflict) {
+		return r, fmt.Errorf("%w: %s", ErrConflict, "template name already used")
+	} else if err != nil {
+		return r, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
+
+// DeleteTemplate удаляет шаблон, уже созданные по нему уведомления хранят
+// готовый текст и не меняются
+func (s *Service) DeleteTemplate(tenantID, id int64) error {
+	const op = "internal.service.DeleteTemplate"
+
+	if id <= 0 {
+		return fmt.Errorf(
+			"%w: %s", ErrNotValidData, "template id is negative or == 0",
+		)
+	}
+
+	err := s.str.DeleteTemplate(tenantID, id)
+	if errors.Is(err, storage.ErrNotAffected) {
+		return fmt.Errorf("%w: %w", ErrNotAffected, err)
+	} else if err != nil {
+		return fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return nil
+}
+
+// ApplyTemplate заполняет текст уведомления по шаблону templateID. Текст
+// рендерится один раз при создании, отсутствующий параметр - ошибка
+func (s *Service) ApplyTemplate(tenantID int64, n notification.Notification, templateID int64, params map[string]any) (notification.Notification, error) {
+	const op = "internal.service.ApplyTemplate"
+
+	if templateID <= 0 {
+		return n, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "template id is negative or == 0",
+		)
+	}
+
+	t, err := s.str.Template(tenantID, templateID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return n, fmt.Errorf("%w: %s", ErrNotValidData, "template not found")
+	} else if err != nil {
+		return n, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	r, err := t.Apply(n, params)
+	if err != nil {
+		return n, fmt.Errorf("%w: %w", ErrNotValidData, err)
+	}
+
+	return r, nil
+}
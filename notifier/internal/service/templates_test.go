@@ -0,0 +1,157 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/storage"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       template.Template
+		err     error
+		wantErr error
+	}{
+		{
+			name: "good",
+			t:    template.Template{Name: "meeting", Body: "hi {{.name}}"},
+		},
+		{
+			name:    "empty name",
+			t:       template.Template{Body: "hi"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "long name",
+			t:       template.Template{Name: strings.Repeat("a", MaxTemplateNameLen+1), Body: "hi"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "wrong syntax",
+			t:       template.Template{Name: "meeting", Body: "hi {{"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "name used",
+			t:       template.Template{Name: "meeting", Body: "hi"},
+			err:     storage.ErrConflict,
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "unknown",
+			t:       template.Template{Name: "meeting", Body: "hi"},
+			err:     errors.New("unknown"),
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&StorageMock{
+				addTF: func(tm template.Template) (int64, error) {
+					return 1, tt.err
+				},
+			})
+			_, err := s.CreateTemplate(1, tt.t)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestService_UpdateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       template.Template
+		err     error
+		wantErr error
+	}{
+		{
+			name: "good",
+			t:    template.Template{ID: 1, Name: "meeting", Body: "hi"},
+		},
+		{
+			name:    "bad id",
+			t:       template.Template{Name: "meeting", Body: "hi"},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "not found",
+			t:       template.Template{ID: 1, Name: "meeting", Body: "hi"},
+			err:     storage.ErrNotAffected,
+			wantErr: ErrNotAffected,
+		},
+		{
+			name:    "name used",
+			t:       template.Template{ID: 1, Name: "meeting", Body: "hi"},
+			err:     storage.ErrConflict,
+			wantErr: ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&StorageMock{
+				updateTF: func(tm template.Template) (template.Template, error) {
+					return tm, tt.err
+				},
+			})
+			_, err := s.UpdateTemplate(1, tt.t)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestService_DeleteTemplate(t *testing.T) {
+	s := New(&StorageMock{
+		deleteTF: func(id int64) error {
+			if id != 1 {
+				return storage.ErrNotAffected
+			}
+			return nil
+		},
+	})
+
+	require.NoError(t, s.DeleteTemplate(1, 1))
+	require.ErrorIs(t, s.DeleteTemplate(1, 2), ErrNotAffected)
+	require.ErrorIs(t, s.DeleteTemplate(1, 0), ErrNotValidData)
+}
+
+func TestService_ApplyTemplate(t *testing.T) {
+	s := New(&StorageMock{
+		getTF: func(id int64) (template.Template, error) {
+			if id != 1 {
+				return template.Template{}, storage.ErrNotFound
+			}
+			return template.Template{
+				ID: 1, Name: "meeting", Body: "hi {{.name}}",
+				EmailSubject: "for {{.name}}",
+			}, nil
+		},
+	})
+	n := notification.Notification{Recipients: to("a@b.c")}
+
+	got, err := s.ApplyTemplate(1, n, 1, map[string]any{"name": "Bob"})
+	require.NoError(t, err)
+	require.Equal(t, "hi Bob", got.Message)
+	require.Equal(t, "for Bob", got.EmailSubject)
+	require.Equal(t, int64(1), got.TemplateID)
+
+	_, err = s.ApplyTemplate(1, n, 1, nil)
+	require.ErrorIs(t, err, ErrNotValidData)
+	_, err = s.ApplyTemplate(1, n, 2, nil)
+	require.ErrorIs(t, err, ErrNotValidData)
+	_, err = s.ApplyTemplate(1, n, 0, nil)
+	require.ErrorIs(t, err, ErrNotValidData)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/storage"
+	"errors"
+	"fmt"
+)
+
+// CreateTenant создает команду, ключи для нее выпускает IssueAPIKey
+func (s *Service) CreateTenant(name string) (int64, error) {
+	const op = "internal.service.CreateTenant"
+
+	if name == "" || len(name) > MaxTenantNameLen {
+		return 0, fmt.Errorf(
+			"%w: tenant name length should be in range 1..%d",
+			ErrNotValidData, MaxTenantNameLen,
+		)
+	}
+
+	id, err := s.str.CreateTenant(tenant.Tenant{Name: name})
+	if errors.Is(err, storage.ErrConflict) {
+		return 0, fmt.Errorf("%w: %s", ErrConflict, "tenant name already used")
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return id, nil
+}
+
+func (s *Service) Tenant(id int64) (tenant.Tenant, error) {
+	const op = "internal.service.Tenant"
+
+	if id <= 0 {
+		return tenant.Tenant{}, fmt.Errorf(
+			"%w: %s", ErrNotValidData, "tenant id is negative or == 0",
+		)
+	}
+
+	t, err := s.str.Tenant(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return t, fmt.Errorf("%w: %w", ErrNotFound, err)
+	} else if err != nil {
+		return t, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return t, nil
+}
+
+func (s *Service) Tenants() ([]tenant.Tenant, error) {
+	const op = "internal.service.Tenants"
+
+	r, err := s.str.Tenants()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w -> %w", op, ErrStorageInternal, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/storage"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateTenant(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     storager
+		tenant  string
+		wantErr error
+	}{
+		{
+			name: "good",
+			str: &StorageMock{
+				addTnF: func(t tenant.Tenant) (int64, error) {
+					return 2, nil
+				},
+			},
+			tenant: "billing",
+		},
+		{
+			name:    "empty name",
+			str:     &StorageMock{},
+			wantErr: ErrNotValidData,
+		},
+		{
+			name:    "long name",
+			str:     &StorageMock{},
+			tenant:  strings.Repeat("a", MaxTenantNameLen+1),
+			wantErr: ErrNotValidData,
+		},
+		{
+			name: "name used",
+			str: &StorageMock{
+				addTnF: func(t tenant.Tenant) (int64, error) {
+					return 0, storage.ErrConflict
+				},
+			},
+			tenant:  "billing",
+			wantErr: ErrConflict,
+		},
+		{
+			name: "unknown error",
+			str: &StorageMock{
+				addTnF: func(t tenant.Tenant) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			tenant:  "billing",
+			wantErr: ErrStorageInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.str)
+			_, err := s.CreateTenant(tt.tenant)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.CreateTenant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Команда запроса всегда перекрывает команду, пришедшую в данных
+func TestService_TenantIsolation(t *testing.T) {
+	var (
+		nTenant int64
+		cTenant int64
+		tTenant int64
+		fTenant int64
+	)
+	s := New(&StorageMock{
+		addNF: func(n notification.Notification) (int64, error) {
+			nTenant = n.TenantID
+			return 1, nil
+		},
+		addCF: func(c contact.Contact) (int64, error) {
+			cTenant = c.TenantID
+			return 1, nil
+		},
+		addTF: func(t template.Template) (int64, error) {
+			tTenant = t.TenantID
+			return 1, nil
+		},
+		listF: func(f notification.Filter) ([]notification.Notification, error) {
+			fTenant = f.TenantID
+			return nil, nil
+		},
+	})
+
+	n := notification.Notification{
+		Message: "hi", Recipients: to("a@b.c"), Date: time.Now().Add(time.Hour),
+		TenantID: 5,
+	}
+	_, err := s.CreateNotification(2, n)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), nTenant)
+
+	_, err = s.CreateContact(2, contact.Contact{
+		Name: "Аня", Email: "a@b.c", Timezone: "UTC", TenantID: 5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cTenant)
+
+	_, err = s.CreateTemplate(2, template.Template{
+		Name: "t", Body: "hi", TenantID: 5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), tTenant)
+
+	_, _, err = s.Notifications(2, notification.Filter{TenantID: 5})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), fTenant)
+}
@@ -0,0 +1,228 @@
+// Package settings читает из конфига настройки notifier для cmd/web и
+// inmemory
+package settings
+
+import (
+	"delayednotifier/internal/service"
+	"delayednotifier/internal/storage/redisqueue"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/config"
+)
+
+// LoadLimits читает ограничения клиентов, пустое значение - без ограничения
+func LoadLimits(cfg *config.Config) (service.Limits, error) {
+	var (
+		l   service.Limits
+		err error
+	)
+	if v := cfg.GetString("limits.rps"); v != "" {
+		if l.Rate, err = strconv.ParseFloat(v, 64); err != nil {
+			return l, fmt.Errorf("limits.rps: %w", err)
+		}
+	}
+	if v := cfg.GetString("limits.burst"); v != "" {
+		if l.Burst, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return l, fmt.Errorf("limits.burst: %w", err)
+		}
+	}
+	if v := cfg.GetString("limits.ip_rps"); v != "" {
+		if l.IPRate, err = strconv.ParseFloat(v, 64); err != nil {
+			return l, fmt.Errorf("limits.ip_rps: %w", err)
+		}
+	}
+	if v := cfg.GetString("limits.ip_burst"); v != "" {
+		if l.IPBurst, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return l, fmt.Errorf("limits.ip_burst: %w", err)
+		}
+	}
+	if v := cfg.GetString("limits.max_pending"); v != "" {
+		if l.MaxPending, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return l, fmt.Errorf("limits.max_pending: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// Relay настройки публикации outbox
+type Relay struct {
+	Interval  time.Duration
+	Batch     int
+	Retention time.Duration
+}
+
+// LoadRelay читает outbox из конфига, пустое значение - по умолчанию
+func LoadRelay(cfg *config.Config) (Relay, error) {
+	r := Relay{Interval: time.Second, Batch: 100, Retention: 24 * time.Hour}
+	var err error
+	if v := cfg.GetString("outbox.interval"); v != "" {
+		if r.Interval, err = time.ParseDuration(v); err != nil || r.Interval <= 0 {
+			return r, fmt.Errorf("outbox.interval: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("outbox.batch"); v != "" {
+		if r.Batch, err = strconv.Atoi(v); err != nil || r.Batch <= 0 {
+			return r, fmt.Errorf("outbox.batch: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("outbox.retention"); v != "" {
+		if r.Retention, err = time.ParseDuration(v); err != nil || r.Retention <= 0 {
+			return r, fmt.Errorf("outbox.retention: wrong value %q", v)
+		}
+	}
+
+	return r, nil
+}
+
+// Sweeper настройки прохода по просроченным уведомлениям
+type Sweeper struct {
+	Interval     time.Duration
+	Grace        time.Duration
+	MaxRepublish int
+	Batch        int
+}
+
+// LoadSweeper читает sweeper из конфига, пустое значение - по умолчанию
+func LoadSweeper(cfg *config.Config) (Sweeper, error) {
+	r := Sweeper{
+		Interval: time.Minute, Grace: service.DefaultOverdueGrace,
+		MaxRepublish: 3, Batch: 100,
+	}
+	var err error
+	if v := cfg.GetString("sweeper.interval"); v != "" {
+		if r.Interval, err = time.ParseDuration(v); err != nil || r.Interval <= 0 {
+			return r, fmt.Errorf("sweeper.interval: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("sweeper.grace"); v != "" {
+		if r.Grace, err = time.ParseDuration(v); err != nil || r.Grace <= 0 {
+			return r, fmt.Errorf("sweeper.grace: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("sweeper.max_republish"); v != "" {
+		if r.MaxRepublish, err = strconv.Atoi(v); err != nil || r.MaxRepublish < 0 {
+			return r, fmt.Errorf("sweeper.max_republish: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("sweeper.batch"); v != "" {
+		if r.Batch, err = strconv.Atoi(v); err != nil || r.Batch <= 0 {
+			return r, fmt.Errorf("sweeper.batch: wrong value %q", v)
+		}
+	}
+
+	return r, nil
+}
+
+// LoadTrustedProxies читает web_notifier.trusted_proxies: адреса или сети
+// прокси через запятую, которым можно верить в X-Forwarded-For. Пусто - не
+// верить никому, адрес клиента берется из соединения
+func LoadTrustedProxies(cfg *config.Config) ([]string, error) {
+	v := cfg.GetString("web_notifier.trusted_proxies")
+	if v == "" {
+		return nil, nil
+	}
+	var r []string
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if _, err := netip.ParsePrefix(p); err != nil {
+			if _, err := netip.ParseAddr(p); err != nil {
+				return nil, fmt.Errorf("web_notifier.trusted_proxies: wrong value %q", p)
+			}
+		}
+		r = append(r, p)
+	}
+
+	return r, nil
+}
+
+// Откуда берется время отправки: отложенный обменник RabbitMQ или таблица
+// уведомлений
+const (
+	SchedulerRabbit   = "rabbit"
+	SchedulerPostgres = "postgres"
+)
+
+// Scheduler настройки планировщика, Interval и Batch - только для postgres
+type Scheduler struct {
+	Kind     string
+	Interval time.Duration
+	Batch    int
+}
+
+// LoadScheduler читает scheduler из конфига, пустое значение - по умолчанию
+func LoadScheduler(cfg *config.Config) (Scheduler, error) {
+	r := Scheduler{Kind: SchedulerRabbit, Interval: time.Second, Batch: 100}
+	var err error
+	switch v := cfg.GetString("scheduler.type"); v {
+	case "", SchedulerRabbit:
+	case SchedulerPostgres:
+		r.Kind = v
+	default:
+		return r, fmt.Errorf("scheduler.type: wrong value %q (rabbit or postgres)", v)
+	}
+	if v := cfg.GetString("scheduler.interval"); v != "" {
+		if r.Interval, err = time.ParseDuration(v); err != nil || r.Interval <= 0 {
+			return r, fmt.Errorf("scheduler.interval: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("scheduler.batch"); v != "" {
+		if r.Batch, err = strconv.Atoi(v); err != nil || r.Batch <= 0 {
+			return r, fmt.Errorf("scheduler.batch: wrong value %q", v)
+		}
+	}
+
+	return r, nil
+}
+
+// Брокер между notifier и sender
+const (
+	QueueRabbit = "rabbit"
+	QueueRedis  = "redis"
+)
+
+// Queue настройки брокера, Redis - только для очереди в redis
+type Queue struct {
+	Kind  string
+	Redis redisqueue.Options
+}
+
+// LoadQueue читает queue из конфига, пустое значение - по умолчанию
+func LoadQueue(cfg *config.Config) (Queue, error) {
+	r := Queue{Kind: QueueRabbit, Redis: redisqueue.Options{
+		Prefix: "delayednotifier", Visibility: 5 * time.Minute,
+		PollInterval: 100 * time.Millisecond, Batch: 16,
+	}}
+	var err error
+	switch v := cfg.GetString("queue.type"); v {
+	case "", QueueRabbit:
+	case QueueRedis:
+		r.Kind = v
+	default:
+		return r, fmt.Errorf("queue.type: wrong value %q (rabbit or redis)", v)
+	}
+	if v := cfg.GetString("queue.redis.prefix"); v != "" {
+		r.Redis.Prefix = v
+	}
+	if v := cfg.GetString("queue.redis.visibility_timeout"); v != "" {
+		if r.Redis.Visibility, err = time.ParseDuration(v); err != nil || r.Redis.Visibility <= 0 {
+			return r, fmt.Errorf("queue.redis.visibility_timeout: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("queue.redis.poll_interval"); v != "" {
+		if r.Redis.PollInterval, err = time.ParseDuration(v); err != nil || r.Redis.PollInterval <= 0 {
+			return r, fmt.Errorf("queue.redis.poll_interval: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("queue.redis.batch"); v != "" {
+		if r.Redis.Batch, err = strconv.Atoi(v); err != nil || r.Redis.Batch <= 0 {
+			return r, fmt.Errorf("queue.redis.batch: wrong value %q", v)
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,162 @@
+package settings
+
+import (
+	"delayednotifier/internal/storage/redisqueue"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/config"
+)
+
+// load конфиг из текста yaml
+func load(t *testing.T, yml string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(yml), 0o600))
+	cfg := config.New()
+	require.NoError(t, cfg.Load(path))
+	return cfg
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		yml     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			yml:  "web_notifier:\n  port: \"8080\"\n",
+			want: nil,
+		},
+		{
+			name: "list",
+			yml:  "web_notifier:\n  trusted_proxies: \"10.0.0.0/8, 127.0.0.1,::1\"\n",
+			want: []string{"10.0.0.0/8", "127.0.0.1", "::1"},
+		},
+		{
+			name:    "wrong",
+			yml:     "web_notifier:\n  trusted_proxies: \"10.0.0.0/8,proxy\"\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadTrustedProxies(load(t, tt.yml))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadScheduler(t *testing.T) {
+	tests := []struct {
+		name    string
+		yml     string
+		want    Scheduler
+		wantErr bool
+	}{
+		{
+			name: "default",
+			yml:  "debug: true\n",
+			want: Scheduler{Kind: SchedulerRabbit, Interval: time.Second, Batch: 100},
+		},
+		{
+			name: "postgres",
+			yml:  "scheduler:\n  type: \"postgres\"\n  interval: \"500ms\"\n  batch: 10\n",
+			want: Scheduler{Kind: SchedulerPostgres, Interval: 500 * time.Millisecond, Batch: 10},
+		},
+		{
+			name:    "wrong type",
+			yml:     "scheduler:\n  type: \"cron\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong interval",
+			yml:     "scheduler:\n  interval: \"-1s\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong batch",
+			yml:     "scheduler:\n  batch: 0\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadScheduler(load(t, tt.yml))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadQueue(t *testing.T) {
+	def := redisqueue.Options{
+		Prefix: "delayednotifier", Visibility: 5 * time.Minute,
+		PollInterval: 100 * time.Millisecond, Batch: 16,
+	}
+	tests := []struct {
+		name    string
+		yml     string
+		want    Queue
+		wantErr bool
+	}{
+		{
+			name: "default",
+			yml:  "debug: true\n",
+			want: Queue{Kind: QueueRabbit, Redis: def},
+		},
+		{
+			name: "redis",
+			yml: "queue:\n  type: \"redis\"\n  redis:\n    prefix: \"dn\"\n" +
+				"    visibility_timeout: \"1m\"\n    poll_interval: \"1s\"\n    batch: 4\n",
+			want: Queue{Kind: QueueRedis, Redis: redisqueue.Options{
+				Prefix: "dn", Visibility: time.Minute, PollInterval: time.Second, Batch: 4,
+			}},
+		},
+		{
+			name:    "wrong type",
+			yml:     "queue:\n  type: \"kafka\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong visibility",
+			yml:     "queue:\n  redis:\n    visibility_timeout: \"soon\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong poll interval",
+			yml:     "queue:\n  redis:\n    poll_interval: \"0s\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong batch",
+			yml:     "queue:\n  redis:\n    batch: -1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadQueue(load(t, tt.yml))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
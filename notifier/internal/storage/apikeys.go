@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/apikey"
+	"errors"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+func (s *Storage) CreateAPIKey(k apikey.Key) (int64, error) {
+	const op = "internal.storage.CreateAPIKey"
+
+	id, err := s.db.CreateAPIKey(k)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	if id < 1 {
+		return id, ErrDontHaveID
+	}
+
+	return id, nil
+}
+
+// APIKeyByHash действующий ключ по хешу, нет ключа или он отозван -
+// ErrNotFound
+func (s *Storage) APIKeyByHash(hash string) (apikey.Key, error) {
+	const op = "internal.storage.APIKeyByHash"
+
+	k, err := s.db.APIKeyByHash(hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return k, err
+	}
+
+	return k, nil
+}
+
+func (s *Storage) APIKeys(tenantID int64) ([]apikey.Key, error) {
+	const op = "internal.storage.APIKeys"
+
+	r, err := s.db.APIKeys(tenantID)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// RevokeAPIKey отзывает ключ, нет ключа или он уже отозван - ErrNotAffected
+func (s *Storage) RevokeAPIKey(tenantID, id int64) error {
+	const op = "internal.storage.RevokeAPIKey"
+
+	affected, err := s.db.RevokeAPIKey(tenantID, id)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/contact"
+	"errors"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+func (s *Storage) CreateContact(c contact.Contact) (int64, error) {
+	const op = "internal.storage.CreateContact"
+
+	id, err := s.db.CreateContact(c)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	if id < 1 {
+		return id, ErrDontHaveID
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Contact(tenantID, id int64) (contact.Contact, error) {
+	const op = "internal.storage.Contact"
+
+	c, err := s.db.Contact(tenantID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return c, err
+	}
+
+	return c, nil
+}
+
+func (s *Storage) Contacts(tenantID int64) ([]contact.Contact, error) {
+	const op = "internal.storage.Contacts"
+
+	r, err := s.db.Contacts(tenantID)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// UpdateContact заменяет контакт, нет контакта - ErrNotAffected
+func (s *Storage) UpdateContact(c contact.Contact) (contact.Contact, error) {
+	const op = "internal.storage.UpdateContact"
+
+	r, err := s.db.UpdateContact(c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotAffected
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return r, err
+	}
+
+	return r, nil
+}
+
+// DeleteContact удаляет контакт, нет контакта - ErrNotAffected, есть
+// запланированные на него уведомления - ErrConflict
+func (s *Storage) DeleteContact(tenantID, id int64) error {
+	const op = "internal.storage.DeleteContact"
+
+	affected, err := s.db.DeleteContact(tenantID, id)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+	if affected == 0 {
+		_, err = s.db.Contact(tenantID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotAffected
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return err
+		}
+		return ErrConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/deadletter"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// ConsumeDeadLetters переносит письма из DLQ в базу, пока очередь открыта.
+// Письмо подтверждается только после записи, при ошибке базы оно
+// возвращается в очередь
+func (s *Storage) ConsumeDeadLetters() {
+	const op = "internal.storage.ConsumeDeadLetters"
+
+	for msg := range s.q.DeadLetters() {
+		l := deadletter.Letter{}
+		if err := json.Unmarshal(msg.Body, &l); err != nil {
+			l = deadletter.Letter{
+				Reason:  deadletter.ReasonUnreadable,
+				Error:   "unreadable dead letter: " + err.Error(),
+				Payload: msg.Body,
+			}
+		}
+		if l.FailedAt.IsZero() {
+			l.FailedAt = time.Now().UTC()
+		}
+
+		_, err := s.db.CreateDeadLetter(l)
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			time.Sleep(time.Second)
+			if err := msg.Nack(false, true); err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+			}
+			continue
+		}
+		if err := msg.Ack(false); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	}
+}
+
+func (s *Storage) DeadLetter(id int64) (deadletter.Letter, error) {
+	const op = "internal.storage.DeadLetter"
+
+	l, err := s.db.DeadLetter(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return l, err
+	}
+
+	return l, nil
+}
+
+func (s *Storage) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error) {
+	const op = "internal.storage.DeadLetters"
+
+	r, err := s.db.DeadLetters(f)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ReplayDeadLetter публикует сообщение записи заново без задержки и удаляет
+// запись, нет записи - ErrNotAffected
+func (s *Storage) ReplayDeadLetter(id int64) error {
+	const op = "internal.storage.ReplayDeadLetter"
+
+	l, err := s.db.DeadLetter(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotAffected
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	err = s.q.Send(l.Replay())
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	// сообщение уже в очереди, повторный replay отправит его еще раз, поэтому
+	// ошибку удаления только логируем
+	if _, err := s.db.DeleteDeadLetter(id); err != nil {
+		zlog.Logger.Error().AnErr("err", err).Int64("id", id).Msg(op)
+	}
+
+	return nil
+}
+
+// DiscardDeadLetter удаляет запись без отправки, нет записи - ErrNotAffected
+func (s *Storage) DiscardDeadLetter(id int64) error {
+	const op = "internal.storage.DiscardDeadLetter"
+
+	affected, err := s.db.DeleteDeadLetter(id)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Allow списывает запрос клиента из его корзины токенов в кеше
+func (s *Storage) Allow(client string, rate float64, burst int64) (bool, time.Duration, error) {
+	const op = "internal.storage.Allow"
+
+	ok, wait, err := s.c.Allow(client, rate, burst)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return false, 0, err
+	}
+
+	return ok, wait, nil
+}
+
+// SetMaxPending задает, сколько ожидающих уведомлений может быть у команды,
+// 0 - без ограничения. Проверяется в транзакции вставки
+func (s *Storage) SetMaxPending(n int64) {
+	s.db.SetMaxPending(n)
+}
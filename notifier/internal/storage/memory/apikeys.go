@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/apikey"
+	"fmt"
+	"slices"
+)
+
+func (db *DB) CreateAPIKey(k apikey.Key) (int64, error) {
+	const op = "internal.storage.memory.CreateAPIKey"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.hasTenant(k.TenantID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, cur := range db.apiKeys {
+		if cur.Hash == k.Hash {
+			return 0, fmt.Errorf("%s: %w: hash", op, ErrDuplicate)
+		}
+	}
+	k.ID = db.next("api_keys")
+	k.Scopes = slices.Clone(k.Scopes)
+	k.CreatedAt, k.RevokedAt = now(), nil
+	db.apiKeys[k.ID] = k
+
+	return k.ID, nil
+}
+
+// APIKeyByHash ищет действующий ключ по хешу во всех командах, отозванные
+// ключи не возвращаются
+func (db *DB) APIKeyByHash(hash string) (apikey.Key, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, k := range db.apiKeys {
+		if k.Hash == hash && k.RevokedAt == nil {
+			return k, nil
+		}
+	}
+
+	return apikey.Key{}, sql.ErrNoRows
+}
+
+func (db *DB) APIKeys(tenantID int64) ([]apikey.Key, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := make([]apikey.Key, 0)
+	for _, k := range db.apiKeys {
+		if k.TenantID == tenantID {
+			r = append(r, k)
+		}
+	}
+	slices.SortFunc(r, func(a, b apikey.Key) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return r, nil
+}
+
+// RevokeAPIKey отзывает ключ. Возвращает количество измененных ключей,
+// повторный отзыв ничего не меняет
+func (db *DB) RevokeAPIKey(tenantID, id int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	k, ok := db.apiKeys[id]
+	if !ok || k.TenantID != tenantID || k.RevokedAt != nil {
+		return 0, nil
+	}
+	t := now()
+	k.RevokedAt = &t
+	db.apiKeys[id] = k
+
+	return 1, nil
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotencyTTL сколько хранится ключ идемпотентности, как в redis
+const IdempotencyTTL = 24 * time.Hour
+
+type cacheKey struct {
+	tenantID int64
+	id       int64
+}
+
+type idempotencyKey struct {
+	tenantID int64
+	key      string
+}
+
+type idempotencyValue struct {
+	id      int64
+	hash    string
+	expires time.Time
+}
+
+// bucket корзина токенов клиента
+type bucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// Cache реализация storage.Cache в памяти. Промах возвращает redis.Nil,
+// как redis, уведомления хранятся в том же бинарном виде
+type Cache struct {
+	mu            sync.Mutex
+	notifications map[cacheKey][]byte
+	idempotency   map[idempotencyKey]idempotencyValue
+	buckets       map[string]bucket
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		notifications: map[cacheKey][]byte{},
+		idempotency:   map[idempotencyKey]idempotencyValue{},
+		buckets:       map[string]bucket{},
+	}
+}
+
+func (c *Cache) AddNotification(n notification.Notification) error {
+	v, err := n.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notifications[cacheKey{n.TenantID, n.ID}] = v
+
+	return nil
+}
+
+func (c *Cache) GetNotification(tenantID, id int64) (notification.Notification, error) {
+	var n notification.Notification
+
+	c.mu.Lock()
+	v, ok := c.notifications[cacheKey{tenantID, id}]
+	c.mu.Unlock()
+	if !ok {
+		return n, redis.Nil
+	}
+	err := n.UnmarshalBinary(v)
+
+	return n, err
+}
+
+func (c *Cache) DeleteNotification(tenantID, id int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := cacheKey{tenantID, id}
+	if _, ok := c.notifications[k]; !ok {
+		return 0, nil
+	}
+	delete(c.notifications, k)
+
+	return 1, nil
+}
+
+func (c *Cache) AddIdempotencyKey(tenantID int64, key string, id int64, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.idempotency[idempotencyKey{tenantID, key}] = idempotencyValue{
+		id: id, hash: hash, expires: time.Now().Add(IdempotencyTTL),
+	}
+
+	return nil
+}
+
+func (c *Cache) IdempotencyKey(tenantID int64, key string) (int64, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := idempotencyKey{tenantID, key}
+	v, ok := c.idempotency[k]
+	if !ok {
+		return 0, "", redis.Nil
+	}
+	if time.Now().After(v.expires) {
+		delete(c.idempotency, k)
+		return 0, "", redis.Nil
+	}
+
+	return v.id, v.hash, nil
+}
+
+// Allow списывает запрос клиента из его корзины токенов так же, как
+// скрипт redis. Если запрос сверх лимита, возвращает false и время, через
+// которое появится следующий токен
+func (c *Cache) Allow(client string, rate float64, burst int64) (bool, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	b, ok := c.buckets[client]
+	if !ok {
+		b = bucket{tokens: float64(burst), ts: now}
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.ts).Seconds()*rate)
+	b.ts = now
+	if b.tokens >= 1 {
+		b.tokens--
+		c.buckets[client] = b
+		return true, 0, nil
+	}
+	c.buckets[client] = b
+	wait := math.Ceil((1 - b.tokens) * 1000 / rate)
+
+	return false, time.Duration(max(wait, 1)) * time.Millisecond, nil
+}
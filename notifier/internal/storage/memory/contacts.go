@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+	"slices"
+)
+
+func (db *DB) CreateContact(c contact.Contact) (int64, error) {
+	const op = "internal.storage.memory.CreateContact"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.hasTenant(c.TenantID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	c.ID = db.next("contacts")
+	c.CreatedAt = now()
+	c.UpdatedAt = c.CreatedAt
+	db.contacts[c.ID] = c
+
+	return c.ID, nil
+}
+
+func (db *DB) Contact(tenantID, id int64) (contact.Contact, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	c, ok := db.contacts[id]
+	if !ok || c.TenantID != tenantID {
+		return contact.Contact{}, sql.ErrNoRows
+	}
+
+	return c, nil
+}
+
+func (db *DB) Contacts(tenantID int64) ([]contact.Contact, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := make([]contact.Contact, 0)
+	for _, c := range db.contacts {
+		if c.TenantID == tenantID {
+			r = append(r, c)
+		}
+	}
+	slices.SortFunc(r, func(a, b contact.Contact) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return r, nil
+}
+
+// UpdateContact заменяет контакт целиком, если контакта нет - возвращается
+// sql.ErrNoRows
+func (db *DB) UpdateContact(c contact.Contact) (contact.Contact, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	cur, ok := db.contacts[c.ID]
+	if !ok || cur.TenantID != c.TenantID {
+		return contact.Contact{}, sql.ErrNoRows
+	}
+	c.CreatedAt = cur.CreatedAt
+	c.UpdatedAt = now()
+	db.contacts[c.ID] = c
+
+	return c, nil
+}
+
+// DeleteContact удаляет контакт, если на него нет pending уведомлений.
+// Возвращает количество удаленных контактов
+func (db *DB) DeleteContact(tenantID, id int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	c, ok := db.contacts[id]
+	if !ok || c.TenantID != tenantID {
+		return 0, nil
+	}
+	for _, r := range db.notifications {
+		if r.n.ContactID == id && r.n.Status == notification.StatusPending {
+			return 0, nil
+		}
+	}
+	delete(db.contacts, id)
+	for _, r := range db.notifications {
+		if r.n.ContactID == id {
+			r.n.ContactID = 0
+		}
+	}
+
+	return 1, nil
+}
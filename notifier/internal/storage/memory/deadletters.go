@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/deadletter"
+	"slices"
+)
+
+func (db *DB) CreateDeadLetter(l deadletter.Letter) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	l.ID = db.next("dead_letters")
+	l.Attempts = slices.Clone(l.Attempts)
+	if l.Attempts == nil {
+		l.Attempts = []deadletter.Attempt{}
+	}
+	l.Payload = slices.Clone(l.Payload)
+	l.FailedAt = ts(l.FailedAt)
+	db.deadLetters[l.ID] = l
+
+	return l.ID, nil
+}
+
+// DeadLetter запись DLQ вместе с исходным сообщением
+func (db *DB) DeadLetter(id int64) (deadletter.Letter, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	l, ok := db.deadLetters[id]
+	if !ok {
+		return deadletter.Letter{}, sql.ErrNoRows
+	}
+
+	return l, nil
+}
+
+// DeadLetters страница записей DLQ по возрастанию id, без исходных сообщений
+func (db *DB) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := make([]deadletter.Letter, 0)
+	for _, l := range db.deadLetters {
+		if l.ID <= f.After ||
+			(f.TenantID != 0 && l.TenantID != f.TenantID) ||
+			(f.NotificationID != 0 && l.NotificationID != f.NotificationID) ||
+			(f.Channel != "" && l.Channel != f.Channel) ||
+			(f.Reason != "" && l.Reason != f.Reason) {
+			continue
+		}
+		l.Payload = nil
+		r = append(r, l)
+	}
+	slices.SortFunc(r, func(a, b deadletter.Letter) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(r) > f.Limit {
+		r = r[:f.Limit]
+	}
+
+	return r, nil
+}
+
+// DeleteDeadLetter удаляет запись DLQ, возвращает количество удаленных
+func (db *DB) DeleteDeadLetter(id int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.deadLetters[id]; !ok {
+		return 0, nil
+	}
+	delete(db.deadLetters, id)
+
+	return 1, nil
+}
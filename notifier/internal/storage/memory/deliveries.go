@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"slices"
+)
+
+// RecordDelivery сохраняет попытку доставки, результат получателю и общий
+// статус уведомления. Неизвестный получатель добавляется. Возвращает
+// количество измененных строк, 0 - нет уведомления
+func (db *DB) RecordDelivery(tenantID, id int64, d notification.Delivery) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.notifications[id]
+	if !ok || r.n.TenantID != tenantID {
+		return 0, nil
+	}
+
+	rc := d.Recipient()
+	i := slices.IndexFunc(r.n.Recipients, func(v notification.Recipient) bool {
+		return v.Channel == rc.Channel && v.Address == rc.Address
+	})
+	if i < 0 {
+		r.n.Recipients = append(r.n.Recipients, rc)
+	} else {
+		r.n.Recipients[i] = rc
+	}
+
+	d.ID = db.next("deliveries")
+	d.StartedAt, d.FinishedAt = ts(d.StartedAt), ts(d.FinishedAt)
+	r.deliveries = append(r.deliveries, d)
+
+	// отмененное и еще не взятое уведомление свой статус сохраняет
+	if r.n.Status != notification.StatusPending && r.n.Status != notification.StatusCancelled {
+		r.n.Status = notification.DeliveryStatus(r.n.Recipients)
+	}
+
+	return 1, nil
+}
+
+// Deliveries история попыток доставки уведомления по порядку
+func (db *DB) Deliveries(tenantID, id int64) ([]notification.Delivery, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.notifications[id]
+	if !ok || r.n.TenantID != tenantID {
+		return []notification.Delivery{}, nil
+	}
+
+	return append([]notification.Delivery{}, r.deliveries...), nil
+}
@@ -0,0 +1,130 @@
+// Package memory хранилище, кеш и брокер в памяти процесса для режима
+// разработки и тестов без Postgres, Redis и RabbitMQ. Повторяет поведение
+// настоящих реализаций: те же условия изменения строк и те же ошибки, по
+// которым storage понимает, что строки нет. Данные пропадают при остановке
+package memory
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoTenant нарушение внешнего ключа: команды нет
+	ErrNoTenant = errors.New("tenant doesn't exist")
+	// ErrDuplicate нарушение уникальности
+	ErrDuplicate = errors.New("duplicate key value")
+)
+
+// row уведомление вместе с колонками, которые наружу не отдаются
+type row struct {
+	n              notification.Notification
+	idempotencyKey string
+	requestHash    string
+	republished    int64
+	republishedAt  time.Time
+	overdueAt      time.Time
+	deliveries     []notification.Delivery
+}
+
+// seriesRow серия и ее команда, в series.Series команды нет
+type seriesRow struct {
+	s        series.Series
+	tenantID int64
+}
+
+// outboxRow сообщение outbox, sentAt нулевое - еще не опубликовано
+type outboxRow struct {
+	id             int64
+	notificationID int64
+	payload        []byte
+	publishAt      time.Time
+	attempts       int64
+	lastError      string
+	sentAt         time.Time
+}
+
+// DB реализация storage.DB в памяти. Все изменения идут под одной
+// блокировкой, поэтому каждое из них атомарно, как транзакция postgres
+type DB struct {
+	mu sync.Mutex
+	// relay не дает двум RelayOutbox опубликовать одно сообщение дважды
+	relay sync.Mutex
+
+	seq           map[string]int64
+	notifications map[int64]*row
+	series        map[int64]*seriesRow
+	templates     map[int64]template.Template
+	contacts      map[int64]contact.Contact
+	apiKeys       map[int64]apikey.Key
+	tenants       map[int64]tenant.Tenant
+	deadLetters   map[int64]deadletter.Letter
+	outbox        []outboxRow
+	maxPending    int64
+}
+
+// New пустая база с командой по умолчанию, как после миграций
+func New() *DB {
+	db := &DB{
+		seq:           map[string]int64{},
+		notifications: map[int64]*row{},
+		series:        map[int64]*seriesRow{},
+		templates:     map[int64]template.Template{},
+		contacts:      map[int64]contact.Contact{},
+		apiKeys:       map[int64]apikey.Key{},
+		tenants:       map[int64]tenant.Tenant{},
+		deadLetters:   map[int64]deadletter.Letter{},
+	}
+	db.tenants[tenant.DefaultID] = tenant.Tenant{
+		ID: tenant.DefaultID, Name: "default", CreatedAt: now(),
+	}
+	db.seq["tenants"] = tenant.DefaultID
+
+	return db
+}
+
+// next следующее значение последовательности таблицы
+func (db *DB) next(table string) int64 {
+	db.seq[table]++
+	return db.seq[table]
+}
+
+// now текущее время с точностью postgres
+func now() time.Time {
+	return ts(time.Now())
+}
+
+// ts время так, как его вернет timestamptz: UTC, микросекунды
+func ts(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// hasTenant проверяет внешний ключ на команду
+func (db *DB) hasTenant(id int64) error {
+	if _, ok := db.tenants[id]; !ok {
+		return ErrNoTenant
+	}
+
+	return nil
+}
+
+// clone копия уведомления, которую можно менять снаружи
+func clone(n notification.Notification) notification.Notification {
+	n.Recipients = slices.Clone(n.Recipients)
+	n.WebhookHeaders = maps.Clone(n.WebhookHeaders)
+	if len(n.WebhookHeaders) == 0 {
+		n.WebhookHeaders = nil
+	}
+
+	return n
+}
@@ -0,0 +1,332 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// insert добавляет уведомление и кладет его сообщение в outbox
+func (db *DB) insert(n notification.Notification, seriesID int64) (int64, error) {
+	if err := db.hasTenant(n.TenantID); err != nil {
+		return 0, err
+	}
+
+	n = clone(n)
+	n.ID, n.Version, n.SeriesID = db.next("notifications"), 1, seriesID
+	n.Status = notification.StatusPending
+	n.Date = ts(n.Date)
+	n.CancelledAt, n.CancelReason, n.Attempt = time.Time{}, "", 0
+	for i, rc := range n.Recipients {
+		if rc.Status == "" {
+			n.Recipients[i].Status = notification.RecipientPending
+		}
+	}
+	db.notifications[n.ID] = &row{n: n}
+	if err := db.insertOutbox(n); err != nil {
+		delete(db.notifications, n.ID)
+		return 0, err
+	}
+
+	return n.ID, nil
+}
+
+func (db *DB) CreateNotification(n notification.Notification) (int64, error) {
+	const op = "internal.storage.memory.CreateNotification"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.checkPending(n.TenantID, 1); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := db.insert(n, 0)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// CreateNotificationIdempotent вставляет уведомление с ключом идемпотентности,
+// если ключ уже занят, уведомление не создается и возвращается false
+func (db *DB) CreateNotificationIdempotent(n notification.Notification, key, hash string) (int64, bool, error) {
+	const op = "internal.storage.memory.CreateNotificationIdempotent"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.byIdempotencyKey(n.TenantID, key); ok {
+		return 0, false, nil
+	}
+	if err := db.checkPending(n.TenantID, 1); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := db.insert(n, 0)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	r := db.notifications[id]
+	r.idempotencyKey, r.requestHash = key, hash
+
+	return id, true, nil
+}
+
+func (db *DB) byIdempotencyKey(tenantID int64, key string) (*row, bool) {
+	for _, r := range db.notifications {
+		if r.n.TenantID == tenantID && r.idempotencyKey == key {
+			return r, true
+		}
+	}
+
+	return nil, false
+}
+
+// IdempotencyKey возвращает id и отпечаток запроса, сохраненные с ключом
+func (db *DB) IdempotencyKey(tenantID int64, key string) (int64, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.byIdempotencyKey(tenantID, key)
+	if !ok {
+		return 0, "", sql.ErrNoRows
+	}
+
+	return r.n.ID, r.requestHash, nil
+}
+
+// CreateNotifications сохраняет пачку целиком или не сохраняет ничего
+func (db *DB) CreateNotifications(ns []notification.Notification) ([]int64, error) {
+	const op = "internal.storage.memory.CreateNotifications"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, n := range ns {
+		if err := db.hasTenant(n.TenantID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	// пачка всегда от одной команды
+	if len(ns) != 0 {
+		if err := db.checkPending(ns[0].TenantID, int64(len(ns))); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	ids := make([]int64, 0, len(ns))
+	for _, n := range ns {
+		id, err := db.insert(n, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (db *DB) SetMaxPending(n int64) {
+	db.mu.Lock()
+	db.maxPending = n
+	db.mu.Unlock()
+}
+
+// checkPending проверяет, что после вставки add уведомлений у команды будет
+// не больше maxPending ожидающих, вызывается под блокировкой
+func (db *DB) checkPending(tenantID, add int64) error {
+	if db.maxPending <= 0 {
+		return nil
+	}
+
+	n := add
+	for _, r := range db.notifications {
+		if r.n.TenantID == tenantID && r.n.Status == notification.StatusPending {
+			n++
+		}
+	}
+	if n > db.maxPending {
+		return fmt.Errorf(
+			"%w: %d of %d pending notifications", notification.ErrQuotaExceeded,
+			n, db.maxPending,
+		)
+	}
+
+	return nil
+}
+
+func (db *DB) Notification(tenantID, id int64) (notification.Notification, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.notifications[id]
+	if !ok || r.n.TenantID != tenantID {
+		return notification.Notification{}, sql.ErrNoRows
+	}
+
+	return clone(r.n), nil
+}
+
+// match подходит ли уведомление под условия выборки, кроме курсора
+func match(n notification.Notification, f notification.Filter) bool {
+	if n.TenantID != f.TenantID {
+		return false
+	}
+	if f.Status != "" && n.Status != f.Status {
+		return false
+	}
+	if f.Channel != "" || f.Recipient != "" {
+		found := slices.ContainsFunc(n.Recipients, func(rc notification.Recipient) bool {
+			return (f.Channel == "" || rc.Channel == f.Channel) &&
+				(f.Recipient == "" || rc.Address == f.Recipient)
+		})
+		if !found {
+			return false
+		}
+	}
+	if f.SeriesID != 0 && n.SeriesID != f.SeriesID {
+		return false
+	}
+	if f.Text != "" &&
+		!strings.Contains(strings.ToLower(n.Message), strings.ToLower(f.Text)) {
+		return false
+	}
+	if !f.From.IsZero() && n.Date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && n.Date.After(f.To) {
+		return false
+	}
+
+	return true
+}
+
+// compare порядок уведомлений по id или по (dt, id)
+func compare(a, b notification.Notification, byDate bool) int {
+	if byDate {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(a.ID, b.ID)
+}
+
+func (db *DB) Notifications(f notification.Filter) ([]notification.Notification, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	byDate := f.Sort == notification.SortByDate
+	sign := 1
+	if f.Desc {
+		sign = -1
+	}
+
+	r := make([]notification.Notification, 0, f.Limit)
+	for _, row := range db.notifications {
+		if !match(row.n, f) {
+			continue
+		}
+		if f.Cursor != nil {
+			cur := notification.Notification{ID: f.Cursor.ID, Date: f.Cursor.Date}
+			if sign*compare(row.n, cur, byDate) <= 0 {
+				continue
+			}
+		}
+		r = append(r, clone(row.n))
+	}
+	slices.SortFunc(r, func(a, b notification.Notification) int {
+		return sign * compare(a, b, byDate)
+	})
+	if len(r) > f.Limit {
+		r = r[:f.Limit]
+	}
+
+	return r, nil
+}
+
+// UpdateNotification применяет изменения к уведомлению и возвращает его
+// новое состояние, условия те же, что у postgres. Если уведомление под
+// условия не подошло, возвращается sql.ErrNoRows
+func (db *DB) UpdateNotification(tenantID, id int64, u notification.Update) (notification.Notification, error) {
+	const op = "internal.storage.memory.UpdateNotification"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.notifications[id]
+	if !ok || r.n.TenantID != tenantID {
+		return notification.Notification{}, sql.ErrNoRows
+	}
+	if u.Version != 0 && r.n.Version != u.Version {
+		return notification.Notification{}, sql.ErrNoRows
+	}
+	status := r.n.Status
+	if u.HasContent() {
+		ok = status == notification.StatusPending
+	} else {
+		// статус меняет только claim: pending -> sending, повторное
+		// сообщение не возвращает отправленное в sending
+		ok = status == notification.StatusPending || status == notification.StatusSending
+	}
+	if !ok {
+		return notification.Notification{}, sql.ErrNoRows
+	}
+
+	n := u.Apply(clone(r.n))
+	n.Date = ts(n.Date)
+	if u.HasContent() {
+		// новое сообщение уходит в outbox, счет повторов начинается заново
+		n.Version++
+		if err := db.insertOutbox(n); err != nil {
+			return notification.Notification{}, fmt.Errorf("%s: %w", op, err)
+		}
+		r.republished, r.republishedAt, r.overdueAt = 0, time.Time{}, time.Time{}
+	}
+	r.n = n
+
+	return clone(n), nil
+}
+
+// CancelNotification переводит pending уведомление в статус cancelled
+func (db *DB) CancelNotification(tenantID, id int64, reason string) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, ok := db.notifications[id]
+	if !ok || r.n.TenantID != tenantID || r.n.Status != notification.StatusPending {
+		return 0, nil
+	}
+	r.n.Status = notification.StatusCancelled
+	r.n.CancelledAt, r.n.CancelReason = now(), reason
+
+	return 1, nil
+}
+
+// PurgeNotifications удаляет отмененные до before уведомления команды
+// вместе с получателями, попытками и outbox и возвращает их id
+func (db *DB) PurgeNotifications(tenantID int64, before time.Time) ([]int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	ids := make([]int64, 0)
+	for id, r := range db.notifications {
+		if r.n.TenantID == tenantID && r.n.Status == notification.StatusCancelled &&
+			r.n.CancelledAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		delete(db.notifications, id)
+	}
+	db.outbox = slices.DeleteFunc(db.outbox, func(m outboxRow) bool {
+		_, ok := db.notifications[m.notificationID]
+		return !ok
+	})
+
+	return ids, nil
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/tenant"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newNotification() notification.Notification {
+	return notification.Notification{
+		TenantID: tenant.DefaultID,
+		Message:  "hi",
+		Date:     time.Now().Add(time.Hour),
+		Recipients: []notification.Recipient{{
+			Channel: notification.ChannelEmail, Address: "a@b.co",
+		}},
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestDB_CreateNotification(t *testing.T) {
+	db := New()
+
+	id, err := db.CreateNotification(newNotification())
+	require.NoError(t, err)
+	n, err := db.Notification(tenant.DefaultID, id)
+	require.NoError(t, err)
+	require.Equal(t, notification.StatusPending, n.Status)
+	require.Equal(t, int64(1), n.Version)
+	require.Equal(t, notification.RecipientPending, n.Recipients[0].Status)
+
+	_, err = db.Notification(tenant.DefaultID+1, id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	n = newNotification()
+	n.TenantID = 42
+	_, err = db.CreateNotification(n)
+	require.ErrorIs(t, err, ErrNoTenant)
+}
+
+func TestDB_CreateNotificationIdempotent(t *testing.T) {
+	db := New()
+
+	id, created, err := db.CreateNotificationIdempotent(newNotification(), "k", "h")
+	require.NoError(t, err)
+	require.True(t, created)
+
+	_, created, err = db.CreateNotificationIdempotent(newNotification(), "k", "other")
+	require.NoError(t, err)
+	require.False(t, created)
+
+	got, hash, err := db.IdempotencyKey(tenant.DefaultID, "k")
+	require.NoError(t, err)
+	require.Equal(t, id, got)
+	require.Equal(t, "h", hash)
+}
+
+func TestDB_MaxPending(t *testing.T) {
+	db := New()
+	db.SetMaxPending(3)
+
+	// параллельные вставки не превышают квоту
+	errs := make(chan error, 5)
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.CreateNotification(newNotification())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	exceeded := 0
+	for err := range errs {
+		if err != nil {
+			require.ErrorIs(t, err, notification.ErrQuotaExceeded)
+			exceeded++
+		}
+	}
+	require.Equal(t, 2, exceeded)
+
+	_, err := db.CreateNotifications([]notification.Notification{newNotification()})
+	require.ErrorIs(t, err, notification.ErrQuotaExceeded)
+}
+
+func TestDB_UpdateNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		u       notification.Update
+		version int64
+		wantErr error
+	}{
+		{
+			name:    "new message",
+			status:  notification.StatusPending,
+			u:       notification.Update{Message: ptr("new"), Version: 1},
+			version: 2,
+		},
+		{
+			name:    "stale version",
+			status:  notification.StatusPending,
+			u:       notification.Update{Message: ptr("new"), Version: 5},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:    "message after sending",
+			status:  notification.StatusSending,
+			u:       notification.Update{Message: ptr("new")},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:    "sending again",
+			status:  notification.StatusSending,
+			u:       notification.Update{Status: ptr(notification.StatusSending)},
+			version: 1,
+		},
+		{
+			name:    "sent back to sending",
+			status:  notification.StatusSent,
+			u:       notification.Update{Status: ptr(notification.StatusSending)},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:    "cancelled",
+			status:  notification.StatusCancelled,
+			u:       notification.Update{Status: ptr(notification.StatusSent)},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := New()
+			id, err := db.CreateNotification(newNotification())
+			require.NoError(t, err)
+			db.notifications[id].n.Status = tt.status
+
+			n, err := db.UpdateNotification(tenant.DefaultID, id, tt.u)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.version, n.Version)
+			// новое содержимое снова уходит в очередь через outbox
+			require.Len(t, db.outbox, int(tt.version))
+		})
+	}
+}
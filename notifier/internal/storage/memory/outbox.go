@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"cmp"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/outbox"
+	"slices"
+	"time"
+)
+
+// insertOutbox кладет сообщение уведомления в outbox, вызывается под
+// блокировкой вместе с изменением уведомления
+func (db *DB) insertOutbox(n notification.Notification) error {
+	v, err := n.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	db.outbox = append(db.outbox, outboxRow{
+		id:             db.next("outbox"),
+		notificationID: n.ID,
+		payload:        v,
+		publishAt:      n.Date,
+	})
+
+	return nil
+}
+
+// RelayOutbox передает в publish до limit неопубликованных сообщений по
+// порядку и отмечает опубликованные. На первой ошибке публикации попытка
+// сохраняется и обход останавливается, возвращается число опубликованных
+// и ошибка publish
+func (db *DB) RelayOutbox(limit int, publish func(m outbox.Message) error) (int, error) {
+	db.relay.Lock()
+	defer db.relay.Unlock()
+
+	// publish идет без блокировки базы, иначе медленный брокер остановит
+	// все запросы
+	db.mu.Lock()
+	ms := make([]outbox.Message, 0, limit)
+	for _, m := range db.outbox {
+		if len(ms) == limit {
+			break
+		}
+		if m.sentAt.IsZero() {
+			ms = append(ms, outbox.Message{
+				ID: m.id, NotificationID: m.notificationID, Payload: m.payload,
+				PublishAt: m.publishAt, Attempts: m.attempts,
+			})
+		}
+	}
+	db.mu.Unlock()
+
+	sent := 0
+	var pubErr error
+	for _, m := range ms {
+		pubErr = publish(m)
+		db.mu.Lock()
+		// уведомление могли удалить, пока сообщение публиковалось
+		if i := db.outboxIndex(m.ID); i >= 0 {
+			if pubErr != nil {
+				db.outbox[i].attempts++
+				db.outbox[i].lastError = pubErr.Error()
+			} else {
+				db.outbox[i].sentAt, db.outbox[i].lastError = now(), ""
+			}
+		}
+		db.mu.Unlock()
+		if pubErr != nil {
+			break
+		}
+		sent++
+	}
+
+	return sent, pubErr
+}
+
+func (db *DB) outboxIndex(id int64) int {
+	i, ok := slices.BinarySearchFunc(db.outbox, id, func(m outboxRow, id int64) int {
+		return cmp.Compare(m.id, id)
+	})
+	if !ok {
+		return -1
+	}
+
+	return i
+}
+
+// PurgeOutbox удаляет сообщения, опубликованные раньше before
+func (db *DB) PurgeOutbox(before time.Time) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	l := len(db.outbox)
+	db.outbox = slices.DeleteFunc(db.outbox, func(m outboxRow) bool {
+		return !m.sentAt.IsZero() && m.sentAt.Before(before)
+	})
+
+	return int64(l - len(db.outbox)), nil
+}
+
+// unsent есть ли у уведомления неопубликованное сообщение
+func (db *DB) unsent(id int64) bool {
+	return slices.ContainsFunc(db.outbox, func(m outboxRow) bool {
+		return m.notificationID == id && m.sentAt.IsZero()
+	})
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"cmp"
+	"delayednotifier/internal/entities/notification"
+	"slices"
+	"time"
+)
+
+// overdue ожидающее уведомление со временем раньше before, еще не
+// отмеченное и не публиковавшееся заново в этом проходе
+func overdue(r *row, before time.Time) bool {
+	return r.n.Status == notification.StatusPending && r.n.Date.Before(before) &&
+		r.overdueAt.IsZero() &&
+		(r.republishedAt.IsZero() || r.republishedAt.Before(before))
+}
+
+// SweepOverdue публикует заново до limit ожидающих уведомлений со временем
+// раньше before, условия те же, что у postgres. Процесс один, поэтому
+// проход никогда не пропускается
+func (db *DB) SweepOverdue(before time.Time, maxRepublish, limit int) (notification.Sweep, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := notification.Sweep{}
+	due := make([]*row, 0)
+	for _, row := range db.notifications {
+		// уже ждущее в outbox сообщение не потеряно, его опубликует relay
+		if overdue(row, before) && row.republished < int64(maxRepublish) &&
+			!db.unsent(row.n.ID) {
+			due = append(due, row)
+		}
+	}
+	slices.SortFunc(due, func(a, b *row) int {
+		return compare(a.n, b.n, true)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, row := range due {
+		row.n.Version++
+		row.republished++
+		row.republishedAt = now()
+		if err := db.insertOutbox(row.n); err != nil {
+			return notification.Sweep{}, err
+		}
+		r.Republished = append(r.Republished, clone(row.n))
+	}
+
+	for _, row := range db.notifications {
+		if overdue(row, before) && row.republished >= int64(maxRepublish) {
+			row.overdueAt = now()
+			r.Flagged++
+		}
+	}
+
+	return r, nil
+}
+
+// OverdueNotifications ожидающие уведомления всех команд со временем раньше
+// f.Before по возрастанию id
+func (db *DB) OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := []notification.Overdue{}
+	for _, row := range db.notifications {
+		n := row.n
+		if n.Status != notification.StatusPending || !n.Date.Before(f.Before) {
+			continue
+		}
+		if f.TenantID != 0 && n.TenantID != f.TenantID {
+			continue
+		}
+		if f.Flagged && row.overdueAt.IsZero() {
+			continue
+		}
+		if n.ID <= f.After {
+			continue
+		}
+		o := notification.Overdue{
+			ID: n.ID, TenantID: n.TenantID, Date: n.Date, Version: n.Version,
+			Republished: row.republished,
+		}
+		if !row.republishedAt.IsZero() {
+			t := row.republishedAt
+			o.RepublishedAt = &t
+		}
+		if !row.overdueAt.IsZero() {
+			t := row.overdueAt
+			o.OverdueAt = &t
+		}
+		r = append(r, o)
+	}
+	slices.SortFunc(r, func(a, b notification.Overdue) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(r) > f.Limit {
+		r = r[:f.Limit]
+	}
+
+	return r, nil
+}
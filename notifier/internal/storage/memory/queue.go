@@ -0,0 +1,425 @@
+package memory
+
+import (
+	"container/heap"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrClosed  = errors.New("queue is closed")
+	ErrTimeout = errors.New("no reply in time")
+)
+
+// fifo одна очередь брокера. push не блокируется, сообщения отдаются в out
+// по порядку отдельной горутиной, поэтому медленный потребитель одной
+// очереди не держит остальные. Неподтвержденное сообщение ждет Ack или
+// Nack, с requeue оно встает в конец очереди
+type fifo struct {
+	mu      sync.Mutex
+	items   []amqp091.Delivery
+	unacked map[uint64]amqp091.Delivery
+	tag     uint64
+
+	ready chan struct{}
+	out   chan amqp091.Delivery
+	stop  chan struct{}
+	once  sync.Once
+}
+
+func newFifo() *fifo {
+	f := &fifo{
+		unacked: map[uint64]amqp091.Delivery{},
+		ready:   make(chan struct{}, 1),
+		out:     make(chan amqp091.Delivery),
+		stop:    make(chan struct{}),
+	}
+	go f.run()
+
+	return f
+}
+
+func (f *fifo) push(d amqp091.Delivery) {
+	f.mu.Lock()
+	f.items = append(f.items, d)
+	f.mu.Unlock()
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run отдает сообщения в out, пока очередь не закрыта. Сообщение с
+// истекшим Expiration отбрасывается, как в RabbitMQ
+func (f *fifo) run() {
+	defer close(f.out)
+	for {
+		f.mu.Lock()
+		if len(f.items) == 0 {
+			f.mu.Unlock()
+			select {
+			case <-f.ready:
+				continue
+			case <-f.stop:
+				return
+			}
+		}
+		d := f.items[0]
+		f.items = f.items[1:]
+		f.tag++
+		d.DeliveryTag, d.Acknowledger = f.tag, f
+		f.unacked[d.DeliveryTag] = d
+		f.mu.Unlock()
+
+		ttl, ok := expiration(d)
+		if ok && ttl <= 0 {
+			_ = f.Reject(d.DeliveryTag, false)
+			continue
+		}
+		var (
+			expire <-chan time.Time
+			t      *time.Timer
+		)
+		if ok {
+			t = time.NewTimer(ttl)
+			expire = t.C
+		}
+		select {
+		case f.out <- d:
+		case <-expire:
+			// сообщение истекло, пока его никто не забирал
+			_ = f.Reject(d.DeliveryTag, false)
+		case <-f.stop:
+			f.unshift(d)
+			return
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// unshift возвращает неотданное сообщение в начало очереди
+func (f *fifo) unshift(d amqp091.Delivery) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.unacked, d.DeliveryTag)
+	f.items = append([]amqp091.Delivery{d}, f.items...)
+}
+
+// close перестает отдавать сообщения и закрывает out, недоставленные
+// остаются в очереди
+func (f *fifo) close() {
+	f.once.Do(func() {
+		close(f.stop)
+	})
+}
+
+// expiration сколько сообщению осталось жить, false - срок не задан
+func expiration(d amqp091.Delivery) (time.Duration, bool) {
+	if d.Expiration == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(d.Expiration, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(ms)*time.Millisecond - time.Since(d.Timestamp), true
+}
+
+// Ack подтверждает сообщение tag, multiple не поддерживается
+func (f *fifo) Ack(tag uint64, multiple bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.unacked, tag)
+
+	return nil
+}
+
+// Nack отклоняет сообщение tag, с requeue оно придет снова
+func (f *fifo) Nack(tag uint64, multiple bool, requeue bool) error {
+	return f.Reject(tag, requeue)
+}
+
+func (f *fifo) Reject(tag uint64, requeue bool) error {
+	f.mu.Lock()
+	d, ok := f.unacked[tag]
+	delete(f.unacked, tag)
+	f.mu.Unlock()
+	if ok && requeue {
+		d.Redelivered = true
+		f.push(d)
+	}
+
+	return nil
+}
+
+// timer сообщение, ждущее своего времени. seq сохраняет порядок
+// публикации сообщений с одним временем
+type timer struct {
+	at  time.Time
+	seq uint64
+	d   amqp091.Delivery
+}
+
+// timers минимальная куча по времени отправки
+type timers []timer
+
+func (t timers) Len() int { return len(t) }
+
+func (t timers) Less(i, j int) bool {
+	if t[i].at.Equal(t[j].at) {
+		return t[i].seq < t[j].seq
+	}
+
+	return t[i].at.Before(t[j].at)
+}
+
+func (t timers) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+
+func (t *timers) Push(x any) { *t = append(*t, x.(timer)) }
+
+func (t *timers) Pop() any {
+	old := *t
+	x := old[len(old)-1]
+	*t = old[:len(old)-1]
+
+	return x
+}
+
+// Queue брокер в памяти вместо RabbitMQ с плагином отложенных сообщений.
+// Сам Queue - сторона notifier (storage.Queue), Messager - сторона sender.
+// Отложенные сообщения ждут в куче таймеров и переходят в очередь
+// уведомлений, когда наступит их время
+type Queue struct {
+	mu     sync.Mutex
+	timers timers
+	seq    uint64
+	closed bool
+
+	wake chan struct{}
+	stop chan struct{}
+	once sync.Once
+
+	messages    *fifo
+	deadLetters *fifo
+	results     *fifo
+	claims      *fifo
+
+	replyMu sync.Mutex
+	replies map[string]chan []byte
+	corr    atomic.Uint64
+}
+
+func NewQueue() *Queue {
+	q := &Queue{
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		messages:    newFifo(),
+		deadLetters: newFifo(),
+		results:     newFifo(),
+		claims:      newFifo(),
+		replies:     map[string]chan []byte{},
+	}
+	go q.schedule()
+
+	return q
+}
+
+// schedule переносит наступившие сообщения в очередь уведомлений и спит до
+// ближайшего следующего или до новой публикации
+func (q *Queue) schedule() {
+	t := time.NewTimer(time.Hour)
+	defer t.Stop()
+	for {
+		q.mu.Lock()
+		now := time.Now()
+		for len(q.timers) > 0 && !q.timers[0].at.After(now) {
+			q.messages.push(heap.Pop(&q.timers).(timer).d)
+		}
+		wait := time.Hour
+		if len(q.timers) > 0 {
+			wait = q.timers[0].at.Sub(now)
+		}
+		q.mu.Unlock()
+
+		t.Reset(wait)
+		select {
+		case <-t.C:
+		case <-q.wake:
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// delay кладет сообщение в кучу таймеров, оно придет в очередь уведомлений
+// через d мс
+func (q *Queue) delay(val []byte, d int64, headers amqp091.Table) error {
+	h := amqp091.Table{}
+	for k, v := range headers {
+		h[k] = v
+	}
+	h["x-delay"] = d
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrClosed
+	}
+	q.seq++
+	heap.Push(&q.timers, timer{
+		at:  time.Now().Add(time.Duration(d) * time.Millisecond),
+		seq: q.seq,
+		d:   amqp091.Delivery{Headers: h, ContentType: "text/plain", Body: val},
+	})
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// send кладет сообщение в очередь f сразу
+func (q *Queue) send(f *fifo, d amqp091.Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	f.push(d)
+
+	return nil
+}
+
+// Publish публикует сообщение, которое придет в очередь через d мс
+func (q *Queue) Publish(val []byte, d int64) error {
+	return q.delay(val, d, nil)
+}
+
+// Send кладет сообщение в очередь уведомлений без задержки
+func (q *Queue) Send(val []byte) error {
+	return q.send(q.messages, amqp091.Delivery{ContentType: "text/plain", Body: val})
+}
+
+func (q *Queue) DeadLetters() <-chan amqp091.Delivery {
+	return q.deadLetters.out
+}
+
+func (q *Queue) Results() <-chan amqp091.Delivery {
+	return q.results.out
+}
+
+func (q *Queue) Claims() <-chan amqp091.Delivery {
+	return q.claims.out
+}
+
+// Reply отвечает на запрос msg, ответ на запрос, который уже не ждут,
+// отбрасывается
+func (q *Queue) Reply(msg amqp091.Delivery, val []byte) error {
+	q.replyMu.Lock()
+	reply, ok := q.replies[msg.CorrelationId]
+	q.replyMu.Unlock()
+	if ok {
+		select {
+		case reply <- val:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Shutdown закрывает все очереди, повторный вызов ничего не делает
+func (q *Queue) Shutdown() {
+	q.once.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+		close(q.stop)
+		for _, f := range []*fifo{q.messages, q.deadLetters, q.results, q.claims} {
+			f.close()
+		}
+	})
+}
+
+// Messager сторона sender того же брокера
+func (q *Queue) Messager() *Messager {
+	return &Messager{q: q}
+}
+
+// Messager реализация storage.Messager sender поверх Queue
+type Messager struct {
+	q *Queue
+}
+
+func (m *Messager) Channel() <-chan amqp091.Delivery {
+	return m.q.messages.out
+}
+
+// Cancel перестает отдавать уведомления, канал Channel закрывается,
+// недоставленные сообщения остаются в очереди
+func (m *Messager) Cancel() error {
+	m.q.messages.close()
+	return nil
+}
+
+// Publish публикует повтор, который придет в очередь через d мс
+func (m *Messager) Publish(val []byte, d int64, headers amqp091.Table) error {
+	return m.q.delay(val, d, headers)
+}
+
+func (m *Messager) DeadLetter(val []byte) error {
+	return m.q.send(m.q.deadLetters, amqp091.Delivery{ContentType: "application/json", Body: val})
+}
+
+func (m *Messager) Result(val []byte) error {
+	return m.q.send(m.q.results, amqp091.Delivery{ContentType: "application/json", Body: val})
+}
+
+// Claim отправляет запрос в очередь claims и ждет ответ не дольше timeout.
+// Запрос живет в очереди столько же, сколько его ждут
+func (m *Messager) Claim(val []byte, timeout time.Duration) ([]byte, error) {
+	id := strconv.FormatUint(m.q.corr.Add(1), 10)
+	reply := make(chan []byte, 1)
+	m.q.replyMu.Lock()
+	m.q.replies[id] = reply
+	m.q.replyMu.Unlock()
+	defer func() {
+		m.q.replyMu.Lock()
+		delete(m.q.replies, id)
+		m.q.replyMu.Unlock()
+	}()
+
+	err := m.q.send(m.q.claims, amqp091.Delivery{
+		ContentType:   "application/json",
+		CorrelationId: id,
+		ReplyTo:       "memory",
+		Expiration:    strconv.FormatInt(timeout.Milliseconds(), 10),
+		Timestamp:     time.Now(),
+		Body:          val,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case v := <-reply:
+		return v, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// Shutdown закрывает брокер, он общий с notifier
+func (m *Messager) Shutdown() {
+	m.q.Shutdown()
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan amqp091.Delivery) amqp091.Delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message in queue")
+	}
+
+	return amqp091.Delivery{}
+}
+
+func TestQueue_Publish(t *testing.T) {
+	q := NewQueue()
+	defer q.Shutdown()
+	m := q.Messager()
+
+	start := time.Now()
+	require.NoError(t, q.Publish([]byte("late"), 300))
+	require.NoError(t, q.Publish([]byte("early"), 100))
+	require.NoError(t, q.Send([]byte("now")))
+
+	// сообщения приходят по времени отправки, а не по порядку публикации
+	for _, want := range []string{"now", "early", "late"} {
+		d := receive(t, m.Channel())
+		require.Equal(t, want, string(d.Body))
+		require.NoError(t, d.Ack(false))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestQueue_Headers(t *testing.T) {
+	q := NewQueue()
+	defer q.Shutdown()
+	m := q.Messager()
+
+	require.NoError(t, m.Publish([]byte("retry"), 0, amqp091.Table{"x-attempts": int64(2)}))
+	d := receive(t, m.Channel())
+	require.Equal(t, int64(2), d.Headers["x-attempts"])
+	require.Equal(t, int64(0), d.Headers["x-delay"])
+}
+
+func TestQueue_Nack(t *testing.T) {
+	q := NewQueue()
+	defer q.Shutdown()
+	m := q.Messager()
+
+	require.NoError(t, q.Send([]byte("a")))
+	d := receive(t, m.Channel())
+	require.False(t, d.Redelivered)
+	require.NoError(t, d.Nack(false, true))
+
+	d = receive(t, m.Channel())
+	require.Equal(t, "a", string(d.Body))
+	require.True(t, d.Redelivered)
+	require.NoError(t, d.Nack(false, false))
+
+	select {
+	case d := <-m.Channel():
+		t.Fatalf("rejected message came again: %s", d.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	q := NewQueue()
+	defer q.Shutdown()
+	m := q.Messager()
+
+	require.NoError(t, m.Cancel())
+	_, ok := <-m.Channel()
+	require.False(t, ok)
+	// остальные очереди продолжают работать
+	require.NoError(t, m.Result([]byte("r")))
+	require.Equal(t, "r", string(receive(t, q.Results()).Body))
+}
+
+func TestQueue_Claim(t *testing.T) {
+	q := NewQueue()
+	defer q.Shutdown()
+	m := q.Messager()
+
+	go func() {
+		msg := <-q.Claims()
+		_ = q.Reply(msg, append([]byte("re: "), msg.Body...))
+		_ = msg.Ack(false)
+	}()
+	v, err := m.Claim([]byte("claim"), time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "re: claim", string(v))
+
+	// запрос, на который не ответили вовремя, истекает в очереди
+	_, err = m.Claim([]byte("late"), 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrTimeout)
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, m.Result([]byte("r")))
+	select {
+	case msg := <-q.Claims():
+		t.Fatalf("expired claim came: %s", msg.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQueue_Shutdown(t *testing.T) {
+	q := NewQueue()
+	m := q.Messager()
+	q.Shutdown()
+	m.Shutdown()
+
+	require.ErrorIs(t, q.Publish([]byte("a"), 0), ErrClosed)
+	require.ErrorIs(t, q.Send([]byte("a")), ErrClosed)
+	require.ErrorIs(t, m.DeadLetter([]byte("a")), ErrClosed)
+	_, ok := <-q.DeadLetters()
+	require.False(t, ok)
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"fmt"
+)
+
+// CreateSeries создает серию вместе с первым срабатыванием
+func (db *DB) CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error) {
+	const op = "internal.storage.memory.CreateSeries"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.hasTenant(n.TenantID); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := db.checkPending(n.TenantID, 1); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s := series.Series{
+		ID: db.next("series"), Rule: sr.Rule, Count: sr.Count,
+		Status: series.StatusActive, CreatedAt: now(),
+	}
+	if !sr.Until.IsZero() {
+		s.Until = ts(sr.Until)
+	}
+	id, err := db.insert(n, s.ID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s.Fired, s.LastOccurrenceID = 1, id
+	db.series[s.ID] = &seriesRow{s: s, tenantID: n.TenantID}
+
+	return s.ID, id, nil
+}
+
+// CreateOccurrence создает следующее срабатывание серии, только если серия
+// активна и prevID последнее ее срабатывание, иначе возвращается
+// sql.ErrNoRows
+func (db *DB) CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error) {
+	const op = "internal.storage.memory.CreateOccurrence"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	sr, ok := db.series[seriesID]
+	if !ok || sr.tenantID != n.TenantID || sr.s.Status != series.StatusActive ||
+		sr.s.LastOccurrenceID != prevID {
+		return 0, sql.ErrNoRows
+	}
+	id, err := db.insert(n, seriesID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	sr.s.Fired++
+	sr.s.LastOccurrenceID = id
+
+	return id, nil
+}
+
+func (db *DB) Series(tenantID, id int64) (series.Series, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	sr, ok := db.series[id]
+	if !ok || sr.tenantID != tenantID {
+		return series.Series{}, sql.ErrNoRows
+	}
+
+	return sr.s, nil
+}
+
+// UpdateSeriesStatus меняет статус серии, остановленная или завершенная
+// серия не меняется. Если серия не подошла, возвращается sql.ErrNoRows
+func (db *DB) UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	sr, ok := db.series[id]
+	if !ok || sr.tenantID != tenantID || sr.s.Status == series.StatusStopped ||
+		sr.s.Status == series.StatusFinished {
+		return series.Series{}, sql.ErrNoRows
+	}
+	sr.s.Status = status
+
+	return sr.s, nil
+}
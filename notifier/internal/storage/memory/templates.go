@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/template"
+	"fmt"
+	"slices"
+)
+
+// templateByName шаблон команды с именем name, кроме шаблона except
+func (db *DB) templateByName(tenantID int64, name string, except int64) bool {
+	for _, t := range db.templates {
+		if t.TenantID == tenantID && t.Name == name && t.ID != except {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateTemplate сохраняет шаблон, если имя уже занято - шаблон не
+// создается и возвращается false
+func (db *DB) CreateTemplate(t template.Template) (int64, bool, error) {
+	const op = "internal.storage.memory.CreateTemplate"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.hasTenant(t.TenantID); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if db.templateByName(t.TenantID, t.Name, 0) {
+		return 0, false, nil
+	}
+	t.ID = db.next("templates")
+	t.CreatedAt = now()
+	t.UpdatedAt = t.CreatedAt
+	db.templates[t.ID] = t
+
+	return t.ID, true, nil
+}
+
+func (db *DB) Template(tenantID, id int64) (template.Template, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, ok := db.templates[id]
+	if !ok || t.TenantID != tenantID {
+		return template.Template{}, sql.ErrNoRows
+	}
+
+	return t, nil
+}
+
+func (db *DB) Templates(tenantID int64) ([]template.Template, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := make([]template.Template, 0)
+	for _, t := range db.templates {
+		if t.TenantID == tenantID {
+			r = append(r, t)
+		}
+	}
+	slices.SortFunc(r, func(a, b template.Template) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return r, nil
+}
+
+// UpdateTemplate заменяет шаблон целиком. Если шаблона нет или имя занято
+// другим шаблоном, возвращается sql.ErrNoRows
+func (db *DB) UpdateTemplate(t template.Template) (template.Template, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	cur, ok := db.templates[t.ID]
+	if !ok || cur.TenantID != t.TenantID || db.templateByName(t.TenantID, t.Name, t.ID) {
+		return template.Template{}, sql.ErrNoRows
+	}
+	cur.Name, cur.Body, cur.TelegramBody = t.Name, t.Body, t.TelegramBody
+	cur.EmailSubject, cur.EmailBody = t.EmailSubject, t.EmailBody
+	cur.UpdatedAt = now()
+	db.templates[t.ID] = cur
+
+	return cur, nil
+}
+
+// DeleteTemplate удаляет шаблон, созданные по нему уведомления остаются
+func (db *DB) DeleteTemplate(tenantID, id int64) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, ok := db.templates[id]
+	if !ok || t.TenantID != tenantID {
+		return 0, nil
+	}
+	delete(db.templates, id)
+	for _, r := range db.notifications {
+		if r.n.TemplateID == id {
+			r.n.TemplateID = 0
+		}
+	}
+
+	return 1, nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"cmp"
+	"database/sql"
+	"delayednotifier/internal/entities/tenant"
+	"slices"
+)
+
+// CreateTenant создает команду, если имя уже занято - команда не создается
+// и возвращается false
+func (db *DB) CreateTenant(t tenant.Tenant) (int64, bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, cur := range db.tenants {
+		if cur.Name == t.Name {
+			return 0, false, nil
+		}
+	}
+	t.ID = db.next("tenants")
+	t.CreatedAt = now()
+	db.tenants[t.ID] = t
+
+	return t.ID, true, nil
+}
+
+func (db *DB) Tenant(id int64) (tenant.Tenant, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, ok := db.tenants[id]
+	if !ok {
+		return tenant.Tenant{}, sql.ErrNoRows
+	}
+
+	return t, nil
+}
+
+func (db *DB) Tenants() ([]tenant.Tenant, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r := make([]tenant.Tenant, 0, len(db.tenants))
+	for _, t := range db.tenants {
+		r = append(r, t)
+	}
+	slices.SortFunc(r, func(a, b tenant.Tenant) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return r, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"delayednotifier/internal/entities/outbox"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// wakeRelay сообщает RelayOutbox о новом сообщении, чтобы не ждать тика
+func (s *Storage) wakeRelay() {
+	select {
+	case s.outbox <- struct{}{}:
+	default:
+	}
+}
+
+// RelayOutbox публикует сообщения outbox в отложенный обменник пачками по
+// batch: сразу после записи нового уведомления и каждые interval, пока
+// брокер не примет все. Опубликованные сообщения хранятся retention
+func (s *Storage) RelayOutbox(interval time.Duration, batch int, retention time.Duration) {
+	const op = "internal.storage.RelayOutbox"
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	purged := time.Now()
+	for {
+		select {
+		case <-tick.C:
+		case <-s.outbox:
+		}
+
+		for {
+			n, err := s.relayOutbox(batch)
+			if err != nil {
+				// брокер или база недоступны, повторим на следующем тике
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+				break
+			}
+			if n < batch {
+				break
+			}
+		}
+
+		if time.Since(purged) < retention {
+			continue
+		}
+		purged = time.Now()
+		if _, err := s.db.PurgeOutbox(purged.Add(-retention)); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	}
+}
+
+// relayOutbox публикует одну пачку, возвращает число опубликованных
+func (s *Storage) relayOutbox(batch int) (int, error) {
+	return s.db.RelayOutbox(batch, func(m outbox.Message) error {
+		return s.q.Publish(m.Payload, m.Delay(time.Now()))
+	})
+}
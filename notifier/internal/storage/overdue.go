@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// SweepOverdue каждые interval ищет ожидающие уведомления, время которых
+// прошло больше чем на grace, и публикует их заново через outbox не больше
+// maxRepublish раз, потом отмечает просроченными. Проход идет в одном
+// экземпляре notifier, остальные его пропускают
+func (s *Storage) SweepOverdue(interval, grace time.Duration, maxRepublish, batch int) {
+	const op = "internal.storage.SweepOverdue"
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for range tick.C {
+		for {
+			r, err := s.sweepOverdue(time.Now().Add(-grace), maxRepublish, batch)
+			if err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+				break
+			}
+			if len(r.Republished) > 0 || r.Flagged > 0 {
+				zlog.Logger.Warn().Fields(map[string]any{
+					"op": op, "republished": len(r.Republished), "flagged": r.Flagged,
+				}).Msg("overdue pending notifications")
+			}
+			if r.Skipped || len(r.Republished) < batch {
+				break
+			}
+		}
+	}
+}
+
+// sweepOverdue один проход, у опубликованных заново меняется версия,
+// поэтому они убираются из кеша
+func (s *Storage) sweepOverdue(before time.Time, maxRepublish, batch int) (notification.Sweep, error) {
+	const op = "internal.storage.sweepOverdue"
+
+	r, err := s.db.SweepOverdue(before, maxRepublish, batch)
+	if err != nil {
+		return r, err
+	}
+	for _, n := range r.Republished {
+		_, err := s.c.DeleteNotification(n.TenantID, n.ID)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			zlog.Logger.Error().AnErr("err", err).Int64("id", n.ID).Msg(op)
+		}
+	}
+	if len(r.Republished) > 0 {
+		s.wakeRelay()
+	}
+
+	return r, nil
+}
+
+func (s *Storage) OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error) {
+	const op = "internal.storage.OverdueNotifications"
+
+	r, err := s.db.OverdueNotifications(f)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/apikey"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `id, name, shown, hash, scopes, tenant_id, created_at,
+	revoked_at`
+
+func scanAPIKey(row scanner) (apikey.Key, error) {
+	var (
+		r       apikey.Key
+		revoked sql.NullTime
+	)
+
+	err := row.Scan(
+		&r.ID, &r.Name, &r.Shown, &r.Hash, pq.Array(&r.Scopes), &r.TenantID,
+		&r.CreatedAt, &revoked,
+	)
+	r.CreatedAt = r.CreatedAt.UTC()
+	if revoked.Valid {
+		t := revoked.Time.UTC()
+		r.RevokedAt = &t
+	}
+
+	return r, err
+}
+
+func (p *Postgres) CreateAPIKey(k apikey.Key) (int64, error) {
+	const op = "internal.storage.postgres.CreateAPIKey"
+
+	var id int64
+
+	q := fmt.Sprintf(
+		`insert into %s (name, shown, hash, scopes, tenant_id)
+		values ($1, $2, $3, $4, $5) returning id;`,
+		APIKeyTable,
+	)
+
+	err := p.db.Master.QueryRowContext(
+		context.Background(), q, k.Name, k.Shown, k.Hash, pq.Array(k.Scopes),
+		k.TenantID,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// APIKeyByHash ищет действующий ключ по хешу, отозванные ключи не
+// возвращаются. Команда запроса определяется по найденному ключу, поэтому
+// поиск идет по всем командам
+func (p *Postgres) APIKeyByHash(hash string) (apikey.Key, error) {
+	const op = "internal.storage.postgres.APIKeyByHash"
+
+	q := fmt.Sprintf(
+		"select %s from %s where hash = $1 and revoked_at is null;",
+		apiKeyColumns, APIKeyTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, hash)
+	r, err := scanAPIKey(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, err
+}
+
+func (p *Postgres) APIKeys(tenantID int64) ([]apikey.Key, error) {
+	const op = "internal.storage.postgres.APIKeys"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 order by id;",
+		apiKeyColumns, APIKeyTable,
+	)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), q, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := make([]apikey.Key, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// RevokeAPIKey отзывает ключ. Возвращает количество измененных строк,
+// повторный отзыв ничего не меняет
+func (p *Postgres) RevokeAPIKey(tenantID, id int64) (int64, error) {
+	const op = "internal.storage.postgres.RevokeAPIKey"
+
+	q := fmt.Sprintf(
+		`update %s set revoked_at = now()
+		where tenant_id = $1 and id = $2 and revoked_at is null;`,
+		APIKeyTable,
+	)
+
+	r, err := p.db.ExecContext(context.Background(), q, tenantID, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"fmt"
+)
+
+const contactColumns = `id, name, telegram_id, email, preferred_channel,
+	locale, timezone, tenant_id, created_at, updated_at`
+
+func scanContact(row scanner) (contact.Contact, error) {
+	var r contact.Contact
+
+	err := row.Scan(
+		&r.ID, &r.Name, &r.TelegramID, &r.Email, &r.PreferredChannel,
+		&r.Locale, &r.Timezone, &r.TenantID, &r.CreatedAt, &r.UpdatedAt,
+	)
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.UpdatedAt = r.UpdatedAt.UTC()
+
+	return r, err
+}
+
+func (p *Postgres) CreateContact(c contact.Contact) (int64, error) {
+	const op = "internal.storage.postgres.CreateContact"
+
+	var id int64
+
+	q := fmt.Sprintf(
+		`insert into %s
+		(name, telegram_id, email, preferred_channel, locale, timezone, tenant_id)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id;`,
+		ContactTable,
+	)
+
+	err := p.db.Master.QueryRowContext(
+		context.Background(), q, c.Name, c.TelegramID, c.Email,
+		c.PreferredChannel, c.Locale, c.Timezone, c.TenantID,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (p *Postgres) Contact(tenantID, id int64) (contact.Contact, error) {
+	const op = "internal.storage.postgres.Contact"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 and id = $2;",
+		contactColumns, ContactTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, tenantID, id)
+	if row.Err() != nil {
+		return contact.Contact{}, fmt.Errorf("%s: %w", op, row.Err())
+	}
+
+	return scanContact(row)
+}
+
+func (p *Postgres) Contacts(tenantID int64) ([]contact.Contact, error) {
+	const op = "internal.storage.postgres.Contacts"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 order by name, id;",
+		contactColumns, ContactTable,
+	)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), q, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := make([]contact.Contact, 0)
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// UpdateContact заменяет контакт целиком, если контакта нет - возвращается
+// sql.ErrNoRows
+func (p *Postgres) UpdateContact(c contact.Contact) (contact.Contact, error) {
+	const op = "internal.storage.postgres.UpdateContact"
+
+	q := fmt.Sprintf(
+		`update %s set name = $1, telegram_id = $2, email = $3,
+		preferred_channel = $4, locale = $5, timezone = $6, updated_at = now()
+		where id = $7 and tenant_id = $8 returning %s;`,
+		ContactTable, contactColumns,
+	)
+
+	row := p.db.Master.QueryRowContext(
+		context.Background(), q, c.Name, c.TelegramID, c.Email,
+		c.PreferredChannel, c.Locale, c.Timezone, c.ID, c.TenantID,
+	)
+	r, err := scanContact(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, err
+}
+
+// DeleteContact удаляет контакт, если на него нет pending уведомлений.
+// Возвращает количество удаленных строк
+func (p *Postgres) DeleteContact(tenantID, id int64) (int64, error) {
+	const op = "internal.storage.postgres.DeleteContact"
+
+	q := fmt.Sprintf(
+		`delete from %s where tenant_id = $1 and id = $2 and not exists (
+			select 1 from %s where contact_id = $2 and status = $3
+		);`,
+		ContactTable, NotificationTable,
+	)
+
+	r, err := p.db.ExecContext(
+		context.Background(), q, tenantID, id, notification.StatusPending,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
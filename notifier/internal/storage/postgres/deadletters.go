@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/deadletter"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const deadLetterColumns = `id, tenant_id, notification_id, channel, address,
+	reason, error, attempts, failed_at`
+
+func scanDeadLetter(row scanner, dest ...any) (deadletter.Letter, error) {
+	var (
+		r              deadletter.Letter
+		tenantID       sql.NullInt64
+		notificationID sql.NullInt64
+		attempts       []byte
+	)
+
+	err := row.Scan(append([]any{
+		&r.ID, &tenantID, &notificationID, &r.Channel, &r.Address,
+		&r.Reason, &r.Error, &attempts, &r.FailedAt,
+	}, dest...)...)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(attempts, &r.Attempts); err != nil {
+		return r, err
+	}
+	r.TenantID = tenantID.Int64
+	r.NotificationID = notificationID.Int64
+	r.FailedAt = r.FailedAt.UTC()
+
+	return r, nil
+}
+
+func (p *Postgres) CreateDeadLetter(l deadletter.Letter) (int64, error) {
+	const op = "internal.storage.postgres.CreateDeadLetter"
+
+	if l.Attempts == nil {
+		l.Attempts = []deadletter.Attempt{}
+	}
+	attempts, err := json.Marshal(l.Attempts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var id int64
+
+	q := fmt.Sprintf(
+		`insert into %s
+		(tenant_id, notification_id, channel, address, reason, error,
+		attempts, payload, failed_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id;`,
+		DeadLetterTable,
+	)
+
+	err = p.db.Master.QueryRowContext(
+		context.Background(), q,
+		sql.NullInt64{Int64: l.TenantID, Valid: l.TenantID != 0},
+		sql.NullInt64{Int64: l.NotificationID, Valid: l.NotificationID != 0},
+		l.Channel, l.Address, l.Reason, l.Error, string(attempts), l.Payload,
+		l.FailedAt.UTC(),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// DeadLetter запись DLQ вместе с исходным сообщением
+func (p *Postgres) DeadLetter(id int64) (deadletter.Letter, error) {
+	const op = "internal.storage.postgres.DeadLetter"
+
+	q := fmt.Sprintf(
+		"select %s, payload from %s where id = $1;",
+		deadLetterColumns, DeadLetterTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, id)
+	if row.Err() != nil {
+		return deadletter.Letter{}, fmt.Errorf("%s: %w", op, row.Err())
+	}
+
+	var payload []byte
+	l, err := scanDeadLetter(row, &payload)
+	l.Payload = payload
+
+	return l, err
+}
+
+// DeadLetters страница записей DLQ по возрастанию id, без исходных сообщений
+func (p *Postgres) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error) {
+	const op = "internal.storage.postgres.DeadLetters"
+
+	where := []string{"id > $1"}
+	args := []any{f.After}
+	if f.TenantID != 0 {
+		args = append(args, f.TenantID)
+		where = append(where, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if f.NotificationID != 0 {
+		args = append(args, f.NotificationID)
+		where = append(where, fmt.Sprintf("notification_id = $%d", len(args)))
+	}
+	if f.Channel != "" {
+		args = append(args, f.Channel)
+		where = append(where, fmt.Sprintf("channel = $%d", len(args)))
+	}
+	if f.Reason != "" {
+		args = append(args, f.Reason)
+		where = append(where, fmt.Sprintf("reason = $%d", len(args)))
+	}
+	args = append(args, f.Limit)
+
+	q := fmt.Sprintf(
+		"select %s from %s where %s order by id limit $%d;",
+		deadLetterColumns, DeadLetterTable, strings.Join(where, " and "),
+		len(args),
+	)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := make([]deadletter.Letter, 0)
+	for rows.Next() {
+		l, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// DeleteDeadLetter удаляет запись DLQ, возвращает количество удаленных строк
+func (p *Postgres) DeleteDeadLetter(id int64) (int64, error) {
+	const op = "internal.storage.postgres.DeleteDeadLetter"
+
+	q := fmt.Sprintf("delete from %s where id = $1;", DeadLetterTable)
+
+	r, err := p.db.ExecContext(context.Background(), q, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
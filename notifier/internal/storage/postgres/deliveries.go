@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"errors"
+	"fmt"
+)
+
+// RecordDelivery сохраняет попытку доставки, результат получателю и общий
+// статус уведомления. Адреса контакта sender узнает при отправке, поэтому
+// неизвестный получатель добавляется. Возвращает количество измененных
+// строк, 0 - нет уведомления
+func (p *Postgres) RecordDelivery(tenantID, id int64, d notification.Delivery) (int64, error) {
+	const op = "internal.storage.postgres.RecordDelivery"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// попытки разных получателей приходят параллельно, блокировка строки
+	// уведомления не дает им затереть общий статус друг друга
+	var status string
+	q := fmt.Sprintf(
+		"select status from %s where id = $1 and tenant_id = $2 for update;",
+		NotificationTable,
+	)
+	err = tx.QueryRowContext(context.Background(), q, id, tenantID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	q = fmt.Sprintf(
+		`insert into %s (notification_id, channel, address, status, error)
+		values ($1, $2, $3, $4, $5)
+		on conflict (notification_id, channel, address) do update
+		set status = excluded.status, error = excluded.error, updated_at = now();`,
+		RecipientTable,
+	)
+	_, err = tx.ExecContext(
+		context.Background(), q, id, d.Channel, d.Address, d.Status, d.Error,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	q = fmt.Sprintf(
+		`insert into %s (notification_id, channel, address, attempt, status,
+		response, error, started_at, finished_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`,
+		DeliveryTable,
+	)
+	_, err = tx.ExecContext(
+		context.Background(), q, id, d.Channel, d.Address, d.Attempt, d.Status,
+		d.Response, d.Error, d.StartedAt.UTC(), d.FinishedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// отмененное и еще не взятое уведомление свой статус сохраняет
+	if status != notification.StatusPending && status != notification.StatusCancelled {
+		ns := []notification.Notification{{ID: id}}
+		if err := loadRecipients(tx, ns); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		q = fmt.Sprintf("update %s set status = $1 where id = $2;", NotificationTable)
+		_, err = tx.ExecContext(
+			context.Background(), q,
+			notification.DeliveryStatus(ns[0].Recipients), id,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return 1, nil
+}
+
+// Deliveries история попыток доставки уведомления по порядку
+func (p *Postgres) Deliveries(tenantID, id int64) ([]notification.Delivery, error) {
+	const op = "internal.storage.postgres.Deliveries"
+
+	q := fmt.Sprintf(
+		`select d.id, d.channel, d.address, d.attempt, d.status, d.response,
+		d.error, d.started_at, d.finished_at
+		from %s d join %s n on n.id = d.notification_id
+		where n.tenant_id = $1 and d.notification_id = $2 order by d.id;`,
+		DeliveryTable, NotificationTable,
+	)
+	rows, err := p.db.Master.QueryContext(context.Background(), q, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := []notification.Delivery{}
+	for rows.Next() {
+		var d notification.Delivery
+		err := rows.Scan(
+			&d.ID, &d.Channel, &d.Address, &d.Attempt, &d.Status, &d.Response,
+			&d.Error, &d.StartedAt, &d.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		d.StartedAt, d.FinishedAt = d.StartedAt.UTC(), d.FinishedAt.UTC()
+		r = append(r, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+)
+
+// DispatchDue передает в send до limit ожидающих уведомлений, время которых
+// наступило, а текущая версия еще не передана, и отмечает переданные.
+// Строки блокируются до конца транзакции, другие экземпляры notifier их
+// пропускают. На первой ошибке send обход останавливается, возвращается
+// число переданных и ошибка send
+func (p *Postgres) DispatchDue(limit int, send func(n notification.Notification) error) (int, error) {
+	const op = "internal.storage.postgres.DispatchDue"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(context.Background(), fmt.Sprintf(
+		`select %s from %s
+		where status = $1 and dt <= now() and dispatched_version <> version
+		order by dt, id limit $2 for update skip locked;`,
+		notificationColumns, NotificationTable,
+	), notification.StatusPending, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	ns := []notification.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		ns = append(ns, n)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := loadRecipients(tx, ns); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sent := 0
+	var sendErr error
+	for _, n := range ns {
+		if sendErr = send(n); sendErr != nil {
+			break
+		}
+		_, err := tx.ExecContext(context.Background(), fmt.Sprintf(
+			"update %s set dispatched_version = version where id = $1;",
+			NotificationTable,
+		), n.ID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sent, sendErr
+}
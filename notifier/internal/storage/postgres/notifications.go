@@ -2,88 +2,545 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"delayednotifier/internal/entities/notification"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
+const notificationColumns = `id, message, status, dt, version,
+	cancelled_at, cancel_reason, series_id, timezone, telegram_message,
+	email_subject, email_message, template_id, contact_id, tenant_id,
+	webhook_headers, webhook_secret, retry_max_attempts, retry_backoff`
+
+// notificationInsertColumns колонки новой строки, значения дает insertArgs.
+// Получатели вставляются отдельно через insertRecipients
+const notificationInsertColumns = `message, dt, timezone,
+	telegram_message, email_subject, email_message, template_id, contact_id,
+	tenant_id, webhook_headers, webhook_secret, retry_max_attempts,
+	retry_backoff`
+
+func insertArgs(n notification.Notification) []any {
+	templateID := sql.NullInt64{Int64: n.TemplateID, Valid: n.TemplateID != 0}
+	contactID := sql.NullInt64{Int64: n.ContactID, Valid: n.ContactID != 0}
+	// map[string]string всегда сериализуется, ошибки здесь быть не может
+	headers, _ := json.Marshal(n.WebhookHeaders)
+	if n.WebhookHeaders == nil {
+		headers = []byte("{}")
+	}
+
+	return []any{
+		n.Message, n.Date.UTC(), n.Timezone,
+		n.TelegramMessage, n.EmailSubject, n.EmailMessage, templateID,
+		contactID, n.TenantID, string(headers), n.WebhookSecret,
+		n.RetryMaxAttempts, n.RetryBackoff,
+	}
+}
+
+// placeholders возвращает "$from, ..., $(from+count-1)"
+func placeholders(from, count int) string {
+	r := make([]string, 0, count)
+	for i := from; i < from+count; i++ {
+		r = append(r, fmt.Sprintf("$%d", i))
+	}
+
+	return strings.Join(r, ", ")
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNotification(row scanner) (notification.Notification, error) {
+	var (
+		r            notification.Notification
+		cancelledAt  sql.NullTime
+		cancelReason sql.NullString
+		seriesID     sql.NullInt64
+		templateID   sql.NullInt64
+		contactID    sql.NullInt64
+		headers      []byte
+	)
+
+	err := row.Scan(
+		&r.ID, &r.Message, &r.Status, &r.Date,
+		&r.Version, &cancelledAt, &cancelReason, &seriesID, &r.Timezone,
+		&r.TelegramMessage, &r.EmailSubject, &r.EmailMessage, &templateID,
+		&contactID, &r.TenantID, &headers, &r.WebhookSecret,
+		&r.RetryMaxAttempts, &r.RetryBackoff,
+	)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(headers, &r.WebhookHeaders); err != nil {
+		return r, err
+	}
+	if len(r.WebhookHeaders) == 0 {
+		r.WebhookHeaders = nil
+	}
+	r.TemplateID = templateID.Int64
+	r.ContactID = contactID.Int64
+	// драйвер отдает время в зоне сессии, наружу отдаем UTC
+	r.Date = r.Date.UTC()
+	if cancelledAt.Valid {
+		r.CancelledAt = cancelledAt.Time.UTC()
+	}
+	r.CancelReason = cancelReason.String
+	r.SeriesID = seriesID.Int64
+
+	return r, err
+}
+
 func (p *Postgres) CreateNotification(n notification.Notification) (int64, error) {
 	const op = "internal.storage.postgres.CreateNotification"
 
 	var id int64
 
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	args := insertArgs(n)
 	q := fmt.Sprintf(
-		`insert into %s 
-		(telegram_id, message, email, dt)
-		values ($1, $2, $3, $4) returning id;`,
+		"insert into %s (%s) values (%s) returning id;",
+		NotificationTable, notificationInsertColumns,
+		placeholders(1, len(args)),
+	)
+
+	err = tx.QueryRowContext(context.Background(), q, args...).Scan(&id)
+	if err != nil {
+		return id, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertRecipients(tx, id, n.Recipients); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	n.ID, n.Version = id, 1
+	if err := insertOutbox(tx, n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := p.checkPending(tx, n.TenantID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// CreateNotificationIdempotent вставляет уведомление с ключом идемпотентности,
+// если ключ уже занят, строка не создается и возвращается false
+func (p *Postgres) CreateNotificationIdempotent(n notification.Notification, key, hash string) (int64, bool, error) {
+	const op = "internal.storage.postgres.CreateNotificationIdempotent"
+
+	var id int64
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	args := append(insertArgs(n), key, hash)
+	q := fmt.Sprintf(
+		`insert into %s (%s, idempotency_key, request_hash) values (%s)
+		on conflict (tenant_id, idempotency_key) do nothing returning id;`,
+		NotificationTable, notificationInsertColumns,
+		placeholders(1, len(args)),
+	)
+
+	err = tx.QueryRowContext(context.Background(), q, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertRecipients(tx, id, n.Recipients); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	n.ID, n.Version = id, 1
+	if err := insertOutbox(tx, n); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := p.checkPending(tx, n.TenantID); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, true, nil
+}
+
+// IdempotencyKey возвращает id и отпечаток запроса, сохраненные с ключом
+func (p *Postgres) IdempotencyKey(tenantID int64, key string) (int64, string, error) {
+	const op = "internal.storage.postgres.IdempotencyKey"
+
+	var (
+		id   int64
+		hash sql.NullString
+	)
+
+	q := fmt.Sprintf(
+		`select id, request_hash from %s
+		where tenant_id = $1 and idempotency_key = $2;`,
 		NotificationTable,
 	)
 
-	row := p.db.Master.QueryRowContext(
-		context.Background(),
-		q, n.TelegramID, n.Message, n.Email, n.DT(),
+	err := p.db.Master.QueryRowContext(
+		context.Background(), q, tenantID, key,
+	).Scan(&id, &hash)
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, hash.String, nil
+}
+
+func (p *Postgres) CreateNotifications(ns []notification.Notification) ([]int64, error) {
+	const op = "internal.storage.postgres.CreateNotifications"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	q := fmt.Sprintf(
+		"insert into %s (%s) values (%s) returning id;",
+		NotificationTable, notificationInsertColumns,
+		placeholders(1, len(insertArgs(notification.Notification{}))),
 	)
-	if row.Err() != nil {
-		return id, row.Err()
+	stmt, err := tx.PrepareContext(context.Background(), q)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ids := make([]int64, 0, len(ns))
+	for _, n := range ns {
+		var id int64
+		err := stmt.QueryRowContext(
+			context.Background(), insertArgs(n)...,
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := insertRecipients(tx, id, n.Recipients); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		n.ID, n.Version = id, 1
+		if err := insertOutbox(tx, n); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	// пачка всегда от одной команды
+	if len(ns) != 0 {
+		if err := p.checkPending(tx, ns[0].TenantID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	err := row.Scan(&id)
+
+	return ids, nil
+}
+
+func (p *Postgres) SetMaxPending(n int64) {
+	p.maxPending = n
+}
+
+// checkPending проверяет квоту ожидающих уведомлений команды после вставки
+// в tx. Строка команды блокируется до конца транзакции, поэтому
+// параллельные вставки той же команды считают по очереди и видят друг друга.
+// for update конфликтует с key share, который вставка уже взяла по внешнему
+// ключу, и две вставки ждали бы друг друга, no key update с ним совместим
+func (p *Postgres) checkPending(tx *sql.Tx, tenantID int64) error {
+	if p.maxPending <= 0 {
+		return nil
+	}
+
+	q := fmt.Sprintf("select id from %s where id = $1 for no key update;", TenantTable)
+	_, err := tx.ExecContext(context.Background(), q, tenantID)
 	if err != nil {
-		return id, fmt.Errorf("%s: %w", op, err)
+		return err
 	}
 
-	return id, nil
+	var n int64
+	q = fmt.Sprintf(
+		"select count(*) from %s where tenant_id = $1 and status = $2;",
+		NotificationTable,
+	)
+	err = tx.QueryRowContext(
+		context.Background(), q, tenantID, notification.StatusPending,
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n > p.maxPending {
+		return fmt.Errorf(
+			"%w: %d of %d pending notifications", notification.ErrQuotaExceeded,
+			n, p.maxPending,
+		)
+	}
+
+	return nil
 }
 
-func (p *Postgres) Notification(id int64) (notification.Notification, error) {
+func (p *Postgres) Notification(tenantID, id int64) (notification.Notification, error) {
 	const op = "internal.storage.postgres.Notification"
 
-	var r notification.Notification
-
-	q := fmt.Sprintf("select * from %s where id = $1;", NotificationTable)
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 and id = $2;",
+		notificationColumns, NotificationTable,
+	)
 
 	row := p.db.Master.QueryRowContext(
-		context.Background(), q, id,
+		context.Background(), q, tenantID, id,
 	)
 	if row.Err() != nil {
-		return r, fmt.Errorf("%s: %w", op, row.Err())
+		return notification.Notification{}, fmt.Errorf("%s: %w", op, row.Err())
 	}
-	err := row.Scan(
-		&r.ID, &r.TelegramID, &r.Message, &r.Email, &r.Status, &r.Date,
-	)
+	r, err := scanNotification(row)
 	if err != nil {
 		return r, err
 	}
+	rs := []notification.Notification{r}
+	if err := loadRecipients(p.db.Master, rs); err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
 
-	return r, nil
+	return rs[0], nil
 }
 
-func (p *Postgres) UpdateNotificationStatus(status string, id int64) (int64, error) {
-	const op = "internal.storage.postgres.UpdateNotificationStatus"
+// likeEscaper экранирует спецсимволы like, чтобы они искались как есть
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likePattern шаблон like для поиска подстроки s, экранирующий символ - \
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+func (p *Postgres) Notifications(f notification.Filter) ([]notification.Notification, error) {
+	const op = "internal.storage.postgres.Notifications"
+
+	where := make([]string, 0, 8)
+	args := make([]any, 0, 8)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where = append(where, "tenant_id = "+arg(f.TenantID))
+	if f.Status != "" {
+		where = append(where, "status = "+arg(f.Status))
+	}
+	if f.Channel != "" || f.Recipient != "" {
+		cond := []string{"r.notification_id = " + NotificationTable + ".id"}
+		if f.Channel != "" {
+			cond = append(cond, "r.channel = "+arg(f.Channel))
+		}
+		if f.Recipient != "" {
+			cond = append(cond, "r.address = "+arg(f.Recipient))
+		}
+		where = append(where, fmt.Sprintf(
+			"exists (select 1 from %s r where %s)",
+			RecipientTable, strings.Join(cond, " and "),
+		))
+	}
+	if f.SeriesID != 0 {
+		where = append(where, "series_id = "+arg(f.SeriesID))
+	}
+	if f.Text != "" {
+		where = append(where, "message ilike "+arg(likePattern(f.Text))+` escape '\'`)
+	}
+	if !f.From.IsZero() {
+		where = append(where, "dt >= "+arg(f.From.UTC()))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "dt <= "+arg(f.To.UTC()))
+	}
+
+	cmp, order := ">", "asc"
+	if f.Desc {
+		cmp, order = "<", "desc"
+	}
+	orderBy := fmt.Sprintf("id %s", order)
+	if f.Sort == notification.SortByDate {
+		orderBy = fmt.Sprintf("dt %s, id %s", order, order)
+	}
+	if f.Cursor != nil {
+		if f.Sort == notification.SortByDate {
+			where = append(where, fmt.Sprintf(
+				"(dt, id) %s (%s, %s)", cmp,
+				arg(f.Cursor.Date.UTC()), arg(f.Cursor.ID),
+			))
+		} else {
+			where = append(where, fmt.Sprintf("id %s %s", cmp, arg(f.Cursor.ID)))
+		}
+	}
 
 	q := fmt.Sprintf(
-		"update %s set status = $1 where id = $2;", NotificationTable,
+		"select %s from %s where %s", notificationColumns, NotificationTable,
+		strings.Join(where, " and "),
 	)
+	q += fmt.Sprintf(" order by %s limit %s;", orderBy, arg(f.Limit))
 
-	r, err := p.db.ExecContext(context.Background(), q, status, id)
+	rows, err := p.db.Master.QueryContext(context.Background(), q, args...)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	affected, err := r.RowsAffected()
+	r := make([]notification.Notification, 0, f.Limit)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := loadRecipients(p.db.Master, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// UpdateNotification применяет изменения к уведомлению и возвращает его
+// новое состояние. Изменение содержимого возможно только у pending
+// уведомления и увеличивает версию. Если ни одна строка не подошла под
+// условия, возвращается sql.ErrNoRows
+func (p *Postgres) UpdateNotification(tenantID, id int64, u notification.Update) (notification.Notification, error) {
+	const op = "internal.storage.postgres.UpdateNotification"
+
+	set := make([]string, 0, 6)
+	args := make([]any, 0, 8)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if u.Status != nil {
+		set = append(set, "status = "+arg(*u.Status))
+	}
+	if u.Message != nil {
+		// новый текст заменяет и варианты для каналов
+		set = append(set, "message = "+arg(*u.Message),
+			"telegram_message = ''", "email_message = ''")
+	}
+	if u.Date != nil {
+		set = append(set, "dt = "+arg(u.Date.UTC()))
+	}
+	if u.Timezone != nil {
+		set = append(set, "timezone = "+arg(*u.Timezone))
+	}
+	if u.HasContent() {
+		// новое сообщение уходит в outbox, счет повторов начинается заново
+		set = append(set, "version = version + 1", "republished = 0",
+			"republished_at = null", "overdue_at = null")
+	}
+
+	where := []string{"tenant_id = " + arg(tenantID), "id = " + arg(id)}
+	if u.Version != 0 {
+		where = append(where, "version = "+arg(u.Version))
+	}
+	if u.HasContent() {
+		where = append(where, "status = "+arg(notification.StatusPending))
+	} else {
+		// статус меняет только claim: pending -> sending, повторное
+		// сообщение не возвращает отправленное в sending
+		where = append(where, fmt.Sprintf(
+			"status in (%s, %s)",
+			arg(notification.StatusPending), arg(notification.StatusSending),
+		))
+	}
+
+	q := fmt.Sprintf(
+		"update %s set %s where %s returning %s;", NotificationTable,
+		strings.Join(set, ", "), strings.Join(where, " and "),
+		notificationColumns,
+	)
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return notification.Notification{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	return affected, nil
+	row := tx.QueryRowContext(context.Background(), q, args...)
+	n, err := scanNotification(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return n, err
+	} else if err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+	channels := map[string]*[]string{
+		notification.ChannelTelegram: u.Telegram,
+		notification.ChannelEmail:    u.Emails,
+	}
+	for channel, addrs := range channels {
+		if addrs == nil {
+			continue
+		}
+		if err := replaceRecipients(tx, id, channel, *addrs); err != nil {
+			return n, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	rs := []notification.Notification{n}
+	if err := loadRecipients(tx, rs); err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+	// новая версия уходит в очередь, старое сообщение отбросит sender
+	if u.HasContent() {
+		if err := insertOutbox(tx, rs[0]); err != nil {
+			return n, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rs[0], nil
 }
 
-func (p *Postgres) DeleteNotification(id int64) (int64, error) {
-	const op = "internal.storage.postgres.UpdateNotificationStatus"
+// CancelNotification переводит pending уведомление в статус cancelled
+func (p *Postgres) CancelNotification(tenantID, id int64, reason string) (int64, error) {
+	const op = "internal.storage.postgres.CancelNotification"
 
 	q := fmt.Sprintf(
-		"delete from %s where id = $1;", NotificationTable,
+		`update %s set status = $1, cancelled_at = $2, cancel_reason = $3
+		where tenant_id = $4 and id = $5 and status = $6;`, NotificationTable,
 	)
 
-	r, err := p.db.ExecContext(context.Background(), q, id)
+	r, err := p.db.ExecContext(
+		context.Background(), q, notification.StatusCancelled,
+		time.Now().UTC(), reason, tenantID, id,
+		notification.StatusPending,
+	)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -94,3 +551,40 @@ func (p *Postgres) DeleteNotification(id int64) (int64, error) {
 
 	return affected, nil
 }
+
+// PurgeNotifications удаляет отмененные до before уведомления команды и
+// возвращает их id
+func (p *Postgres) PurgeNotifications(tenantID int64, before time.Time) ([]int64, error) {
+	const op = "internal.storage.postgres.PurgeNotifications"
+
+	q := fmt.Sprintf(
+		`delete from %s where tenant_id = $1 and status = $2 and cancelled_at < $3
+		returning id;`,
+		NotificationTable,
+	)
+
+	rows, err := p.db.Master.QueryContext(
+		context.Background(), q, tenantID, notification.StatusCancelled,
+		before.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "plain", s: "hello", want: "%hello%"},
+		{name: "percent", s: "100%", want: `%100\%%`},
+		{name: "underscore", s: "a_b", want: `%a\_b%`},
+		{name: "backslash", s: `c:\tmp`, want: `%c:\\tmp%`},
+		{name: "escaped already", s: `\%`, want: `%\\\%%`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, likePattern(tt.s))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/outbox"
+	"fmt"
+	"time"
+)
+
+// insertOutbox кладет сообщение уведомления в outbox в транзакции tx, его
+// опубликует RelayOutbox после коммита
+func insertOutbox(tx *sql.Tx, n notification.Notification) error {
+	v, err := n.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf(
+		`insert into %s (notification_id, payload, publish_at)
+		values ($1, $2, $3);`, OutboxTable,
+	)
+	_, err = tx.ExecContext(context.Background(), q, n.ID, v, n.Date.UTC())
+
+	return err
+}
+
+// RelayOutbox передает в publish до limit неопубликованных сообщений по
+// порядку и отмечает опубликованные. Строки блокируются до конца
+// транзакции, другие экземпляры notifier их пропускают. На первой ошибке
+// публикации попытка сохраняется и обход останавливается, возвращается
+// число опубликованных и ошибка publish
+func (p *Postgres) RelayOutbox(limit int, publish func(m outbox.Message) error) (int, error) {
+	const op = "internal.storage.postgres.RelayOutbox"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(context.Background(), fmt.Sprintf(
+		`select id, notification_id, payload, publish_at, attempts from %s
+		where sent_at is null order by id limit $1 for update skip locked;`,
+		OutboxTable,
+	), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	ms := []outbox.Message{}
+	for rows.Next() {
+		m := outbox.Message{}
+		err := rows.Scan(&m.ID, &m.NotificationID, &m.Payload, &m.PublishAt, &m.Attempts)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		ms = append(ms, m)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sent := 0
+	var pubErr error
+	for _, m := range ms {
+		if pubErr = publish(m); pubErr != nil {
+			_, err := tx.ExecContext(context.Background(), fmt.Sprintf(
+				`update %s set attempts = attempts + 1, last_error = $2
+				where id = $1;`, OutboxTable,
+			), m.ID, pubErr.Error())
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			break
+		}
+		_, err := tx.ExecContext(context.Background(), fmt.Sprintf(
+			"update %s set sent_at = now(), last_error = '' where id = $1;",
+			OutboxTable,
+		), m.ID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sent, pubErr
+}
+
+// PurgeOutbox удаляет сообщения, опубликованные раньше before
+func (p *Postgres) PurgeOutbox(before time.Time) (int64, error) {
+	const op = "internal.storage.postgres.PurgeOutbox"
+
+	res, err := p.db.Master.ExecContext(context.Background(), fmt.Sprintf(
+		"delete from %s where sent_at < $1;", OutboxTable,
+	), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
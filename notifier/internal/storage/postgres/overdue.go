@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sweepLock ключ advisory lock прохода по просроченным, общий для всех
+// экземпляров notifier
+const sweepLock = 0x73776565
+
+// SweepOverdue публикует заново до limit ожидающих уведомлений со временем
+// раньше before. Версия увеличивается, поэтому старое сообщение, если оно
+// все же придет, sender отбросит. Уведомление публикуется не чаще раза за
+// проход и не больше maxRepublish раз, потом отмечается просроченным.
+// Если проход уже идет в другом экземпляре, возвращает Skipped
+func (p *Postgres) SweepOverdue(before time.Time, maxRepublish, limit int) (notification.Sweep, error) {
+	const op = "internal.storage.postgres.SweepOverdue"
+
+	r := notification.Sweep{}
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var locked bool
+	err = tx.QueryRowContext(
+		context.Background(), "select pg_try_advisory_xact_lock($1);", sweepLock,
+	).Scan(&locked)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	if !locked {
+		r.Skipped = true
+		return r, nil
+	}
+
+	// уже ждущее в outbox сообщение не потеряно, его опубликует relay
+	rows, err := tx.QueryContext(context.Background(), fmt.Sprintf(
+		`update %[1]s set version = version + 1, republished = republished + 1,
+		republished_at = now()
+		where id in (
+			select n.id from %[1]s n
+			where n.status = $1 and n.dt < $2 and n.overdue_at is null
+			and n.republished < $3
+			and (n.republished_at is null or n.republished_at < $2)
+			and not exists (
+				select 1 from %[2]s o where o.notification_id = n.id and o.sent_at is null
+			)
+			order by n.dt, n.id limit $4 for update skip locked
+		) returning %[3]s;`,
+		NotificationTable, OutboxTable, notificationColumns,
+	), notification.StatusPending, before.UTC(), maxRepublish, limit)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			_ = rows.Close()
+			return r, fmt.Errorf("%s: %w", op, err)
+		}
+		r.Republished = append(r.Republished, n)
+	}
+	if err := rows.Close(); err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rows.Err(); err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := loadRecipients(tx, r.Republished); err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, n := range r.Republished {
+		if err := insertOutbox(tx, n); err != nil {
+			return r, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(context.Background(), fmt.Sprintf(
+		`update %s set overdue_at = now()
+		where status = $1 and dt < $2 and overdue_at is null
+		and republished >= $3
+		and (republished_at is null or republished_at < $2);`,
+		NotificationTable,
+	), notification.StatusPending, before.UTC(), maxRepublish)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+	if r.Flagged, err = res.RowsAffected(); err != nil {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return notification.Sweep{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// OverdueNotifications ожидающие уведомления всех команд со временем раньше
+// f.Before по возрастанию id
+func (p *Postgres) OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error) {
+	const op = "internal.storage.postgres.OverdueNotifications"
+
+	args := []any{notification.StatusPending, f.Before.UTC()}
+	where := []string{"status = $1", "dt < $2"}
+	if f.TenantID != 0 {
+		args = append(args, f.TenantID)
+		where = append(where, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if f.Flagged {
+		where = append(where, "overdue_at is not null")
+	}
+	if f.After != 0 {
+		args = append(args, f.After)
+		where = append(where, fmt.Sprintf("id > $%d", len(args)))
+	}
+	args = append(args, f.Limit)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), fmt.Sprintf(
+		`select id, tenant_id, dt, version, republished, republished_at, overdue_at
+		from %s where %s order by id limit $%d;`,
+		NotificationTable, strings.Join(where, " and "), len(args),
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := []notification.Overdue{}
+	for rows.Next() {
+		var (
+			o             notification.Overdue
+			republishedAt sql.NullTime
+			overdueAt     sql.NullTime
+		)
+		err := rows.Scan(
+			&o.ID, &o.TenantID, &o.Date, &o.Version, &o.Republished,
+			&republishedAt, &overdueAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		o.Date = o.Date.UTC()
+		if republishedAt.Valid {
+			t := republishedAt.Time.UTC()
+			o.RepublishedAt = &t
+		}
+		if overdueAt.Valid {
+			t := overdueAt.Time.UTC()
+			o.OverdueAt = &t
+		}
+		r = append(r, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
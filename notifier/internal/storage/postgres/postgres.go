@@ -11,10 +11,23 @@ import (
 
 const (
 	NotificationTable = "notifications"
+	SeriesTable       = "series"
+	TemplateTable     = "templates"
+	RecipientTable    = "recipients"
+	ContactTable      = "contacts"
+	APIKeyTable       = "api_keys"
+	TenantTable       = "tenants"
+	DeadLetterTable   = "dead_letters"
+	DeliveryTable     = "deliveries"
+	OutboxTable       = "outbox"
+	RetryTable        = "retries"
 )
 
 type Postgres struct {
 	db *dbpg.DB
+	// maxPending сколько ожидающих уведомлений может быть у команды, 0 - без
+	// ограничения
+	maxPending int64
 }
 
 func New(host, port, username, password, dbname, sslmode string) *Postgres {
@@ -36,7 +49,7 @@ func New(host, port, username, password, dbname, sslmode string) *Postgres {
 		panic(err)
 	}
 
-	return &Postgres{db}
+	return &Postgres{db: db}
 }
 
 func (r *Postgres) Shutdown() {
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// insertRecipients добавляет получателей уведомления id одним запросом
+func insertRecipients(tx *sql.Tx, id int64, rs []notification.Recipient) error {
+	if len(rs) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(rs))
+	args := make([]any, 0, len(rs)*3+1)
+	args = append(args, id)
+	for _, rc := range rs {
+		status := rc.Status
+		if status == "" {
+			status = notification.RecipientPending
+		}
+		args = append(args, rc.Channel, rc.Address, status)
+		values = append(values, fmt.Sprintf(
+			"($1, %s)", placeholders(len(args)-2, 3),
+		))
+	}
+
+	q := fmt.Sprintf(
+		"insert into %s (notification_id, channel, address, status) values %s;",
+		RecipientTable, strings.Join(values, ", "),
+	)
+	_, err := tx.ExecContext(context.Background(), q, args...)
+
+	return err
+}
+
+// replaceRecipients заменяет получателей канала channel
+func replaceRecipients(tx *sql.Tx, id int64, channel string, addrs []string) error {
+	q := fmt.Sprintf(
+		"delete from %s where notification_id = $1 and channel = $2;",
+		RecipientTable,
+	)
+	_, err := tx.ExecContext(context.Background(), q, id, channel)
+	if err != nil {
+		return err
+	}
+
+	n := notification.Notification{}
+	n.SetAddresses(channel, addrs)
+
+	return insertRecipients(tx, id, n.Recipients)
+}
+
+// loadRecipients заполняет получателей у уведомлений ns
+func loadRecipients(q querier, ns []notification.Notification) error {
+	if len(ns) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(ns))
+	idx := make(map[int64]int, len(ns))
+	for i, n := range ns {
+		ids = append(ids, n.ID)
+		idx[n.ID] = i
+	}
+
+	rows, err := q.QueryContext(context.Background(), fmt.Sprintf(
+		`select notification_id, channel, address, status, error from %s
+		where notification_id = any($1) order by id;`, RecipientTable,
+	), pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var (
+			id int64
+			rc notification.Recipient
+		)
+		err := rows.Scan(&id, &rc.Channel, &rc.Address, &rc.Status, &rc.Error)
+		if err != nil {
+			return err
+		}
+		i := idx[id]
+		ns[i].Recipients = append(ns[i].Recipients, rc)
+	}
+
+	return rows.Err()
+}
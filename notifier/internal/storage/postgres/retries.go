@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"delayednotifier/internal/entities/retry"
+	"encoding/json"
+	"fmt"
+)
+
+// AddRetry сохраняет повтор sender до его времени
+func (p *Postgres) AddRetry(r retry.Retry) error {
+	const op = "internal.storage.postgres.AddRetry"
+
+	h, err := json.Marshal(r.Headers)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	q := fmt.Sprintf(
+		"insert into %s (payload, headers, due_at) values ($1, $2, $3);",
+		RetryTable,
+	)
+	_, err = p.db.Master.ExecContext(context.Background(), q, r.Payload, h, r.DueAt.UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DispatchRetries передает в send до limit повторов, время которых
+// наступило, и удаляет переданные. Строки блокируются до конца транзакции,
+// другие экземпляры notifier их пропускают. На первой ошибке send обход
+// останавливается, возвращается число переданных и ошибка send
+func (p *Postgres) DispatchRetries(limit int, send func(r retry.Retry) error) (int, error) {
+	const op = "internal.storage.postgres.DispatchRetries"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(context.Background(), fmt.Sprintf(
+		`select id, payload, headers, due_at from %s
+		where due_at <= now() order by due_at, id limit $1 for update skip locked;`,
+		RetryTable,
+	), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	rs := []retry.Retry{}
+	for rows.Next() {
+		r := retry.Retry{}
+		var h []byte
+		if err := rows.Scan(&r.ID, &r.Payload, &h, &r.DueAt); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(h, &r.Headers); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		rs = append(rs, r)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sent := 0
+	var sendErr error
+	for _, r := range rs {
+		if sendErr = send(r); sendErr != nil {
+			break
+		}
+		_, err := tx.ExecContext(context.Background(), fmt.Sprintf(
+			"delete from %s where id = $1;", RetryTable,
+		), r.ID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sent, sendErr
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"fmt"
+	"time"
+)
+
+const seriesColumns = `id, rule, until, max_count, fired, status,
+	last_occurrence_id, created_at`
+
+func scanSeries(row scanner) (series.Series, error) {
+	var (
+		r     series.Series
+		until sql.NullTime
+	)
+
+	err := row.Scan(
+		&r.ID, &r.Rule, &until, &r.Count, &r.Fired, &r.Status,
+		&r.LastOccurrenceID, &r.CreatedAt,
+	)
+	if until.Valid {
+		r.Until = until.Time.UTC()
+	}
+	r.CreatedAt = r.CreatedAt.UTC()
+
+	return r, err
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{
+		Time: t.UTC(), Valid: true,
+	}
+}
+
+func insertOccurrence(tx *sql.Tx, seriesID int64, n notification.Notification) (int64, error) {
+	var id int64
+
+	args := append(insertArgs(n), seriesID)
+	q := fmt.Sprintf(
+		"insert into %s (%s, series_id) values (%s) returning id;",
+		NotificationTable, notificationInsertColumns,
+		placeholders(1, len(args)),
+	)
+
+	err := tx.QueryRowContext(context.Background(), q, args...).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := insertRecipients(tx, id, n.Recipients); err != nil {
+		return 0, err
+	}
+	n.ID, n.SeriesID, n.Version = id, seriesID, 1
+
+	return id, insertOutbox(tx, n)
+}
+
+// CreateSeries создает серию вместе с первым срабатыванием одной транзакцией
+func (p *Postgres) CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error) {
+	const op = "internal.storage.postgres.CreateSeries"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var seriesID int64
+	q := fmt.Sprintf(
+		`insert into %s (rule, until, max_count, status, tenant_id)
+		values ($1, $2, $3, $4, $5) returning id;`,
+		SeriesTable,
+	)
+	err = tx.QueryRowContext(
+		context.Background(), q,
+		sr.Rule, nullTime(sr.Until), sr.Count, series.StatusActive, n.TenantID,
+	).Scan(&seriesID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := insertOccurrence(tx, seriesID, n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	q = fmt.Sprintf(
+		"update %s set fired = 1, last_occurrence_id = $1 where id = $2;",
+		SeriesTable,
+	)
+	_, err = tx.ExecContext(context.Background(), q, id, seriesID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := p.checkPending(tx, n.TenantID); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return seriesID, id, nil
+}
+
+// CreateOccurrence создает следующее срабатывание серии. Срабатывание
+// создается только если серия активна и prevID последнее ее срабатывание,
+// иначе возвращается sql.ErrNoRows, так повторные вызовы не плодят дубли
+func (p *Postgres) CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error) {
+	const op = "internal.storage.postgres.CreateOccurrence"
+
+	tx, err := p.db.Master.BeginTx(context.Background(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var tmp int64
+	q := fmt.Sprintf(
+		`select id from %s
+		where id = $1 and status = $2 and last_occurrence_id = $3
+		and tenant_id = $4
+		for update;`,
+		SeriesTable,
+	)
+	err = tx.QueryRowContext(
+		context.Background(), q, seriesID, series.StatusActive, prevID,
+		n.TenantID,
+	).Scan(&tmp)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := insertOccurrence(tx, seriesID, n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	q = fmt.Sprintf(
+		`update %s set fired = fired + 1, last_occurrence_id = $1
+		where id = $2;`,
+		SeriesTable,
+	)
+	_, err = tx.ExecContext(context.Background(), q, id, seriesID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (p *Postgres) Series(tenantID, id int64) (series.Series, error) {
+	const op = "internal.storage.postgres.Series"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 and id = $2;",
+		seriesColumns, SeriesTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, tenantID, id)
+	if row.Err() != nil {
+		return series.Series{}, fmt.Errorf("%s: %w", op, row.Err())
+	}
+	r, err := scanSeries(row)
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// UpdateSeriesStatus меняет статус серии, остановленная или завершенная
+// серия не меняется. Если строка не подошла, возвращается sql.ErrNoRows
+func (p *Postgres) UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error) {
+	const op = "internal.storage.postgres.UpdateSeriesStatus"
+
+	q := fmt.Sprintf(
+		`update %s set status = $1
+		where id = $2 and status not in ($3, $4) and tenant_id = $5
+		returning %s;`,
+		SeriesTable, seriesColumns,
+	)
+
+	row := p.db.Master.QueryRowContext(
+		context.Background(), q, status, id,
+		series.StatusStopped, series.StatusFinished, tenantID,
+	)
+	r, err := scanSeries(row)
+	if err != nil && err != sql.ErrNoRows {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, err
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/template"
+	"errors"
+	"fmt"
+)
+
+const templateColumns = `id, name, body, telegram_body, email_subject,
+	email_body, tenant_id, created_at, updated_at`
+
+func scanTemplate(row scanner) (template.Template, error) {
+	var r template.Template
+
+	err := row.Scan(
+		&r.ID, &r.Name, &r.Body, &r.TelegramBody, &r.EmailSubject,
+		&r.EmailBody, &r.TenantID, &r.CreatedAt, &r.UpdatedAt,
+	)
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.UpdatedAt = r.UpdatedAt.UTC()
+
+	return r, err
+}
+
+// CreateTemplate сохраняет шаблон, если имя уже занято - строка не
+// создается и возвращается false
+func (p *Postgres) CreateTemplate(t template.Template) (int64, bool, error) {
+	const op = "internal.storage.postgres.CreateTemplate"
+
+	var id int64
+
+	q := fmt.Sprintf(
+		`insert into %s
+		(name, body, telegram_body, email_subject, email_body, tenant_id)
+		values ($1, $2, $3, $4, $5, $6)
+		on conflict (tenant_id, name) do nothing returning id;`,
+		TemplateTable,
+	)
+
+	err := p.db.Master.QueryRowContext(
+		context.Background(), q,
+		t.Name, t.Body, t.TelegramBody, t.EmailSubject, t.EmailBody, t.TenantID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, true, nil
+}
+
+func (p *Postgres) Template(tenantID, id int64) (template.Template, error) {
+	const op = "internal.storage.postgres.Template"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 and id = $2;",
+		templateColumns, TemplateTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, tenantID, id)
+	if row.Err() != nil {
+		return template.Template{}, fmt.Errorf("%s: %w", op, row.Err())
+	}
+
+	return scanTemplate(row)
+}
+
+func (p *Postgres) Templates(tenantID int64) ([]template.Template, error) {
+	const op = "internal.storage.postgres.Templates"
+
+	q := fmt.Sprintf(
+		"select %s from %s where tenant_id = $1 order by name;",
+		templateColumns, TemplateTable,
+	)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), q, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := make([]template.Template, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// UpdateTemplate заменяет шаблон целиком. Если шаблона нет или имя занято
+// другим шаблоном, возвращается sql.ErrNoRows
+func (p *Postgres) UpdateTemplate(t template.Template) (template.Template, error) {
+	const op = "internal.storage.postgres.UpdateTemplate"
+
+	q := fmt.Sprintf(
+		`update %[1]s set name = $1, body = $2, telegram_body = $3,
+		email_subject = $4, email_body = $5, updated_at = now()
+		where id = $6 and tenant_id = $7 and not exists (
+			select 1 from %[1]s where tenant_id = $7 and name = $1 and id <> $6
+		) returning %[2]s;`,
+		TemplateTable, templateColumns,
+	)
+
+	row := p.db.Master.QueryRowContext(
+		context.Background(), q,
+		t.Name, t.Body, t.TelegramBody, t.EmailSubject, t.EmailBody, t.ID,
+		t.TenantID,
+	)
+	r, err := scanTemplate(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, err
+}
+
+// DeleteTemplate удаляет шаблон, созданные по нему уведомления остаются
+func (p *Postgres) DeleteTemplate(tenantID, id int64) (int64, error) {
+	const op = "internal.storage.postgres.DeleteTemplate"
+
+	q := fmt.Sprintf(
+		"delete from %s where tenant_id = $1 and id = $2;", TemplateTable,
+	)
+
+	r, err := p.db.ExecContext(context.Background(), q, tenantID, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
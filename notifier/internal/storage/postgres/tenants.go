@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"delayednotifier/internal/entities/tenant"
+	"errors"
+	"fmt"
+)
+
+const tenantColumns = "id, name, created_at"
+
+func scanTenant(row scanner) (tenant.Tenant, error) {
+	var r tenant.Tenant
+
+	err := row.Scan(&r.ID, &r.Name, &r.CreatedAt)
+	r.CreatedAt = r.CreatedAt.UTC()
+
+	return r, err
+}
+
+// CreateTenant создает команду, если имя уже занято - строка не создается
+// и возвращается false
+func (p *Postgres) CreateTenant(t tenant.Tenant) (int64, bool, error) {
+	const op = "internal.storage.postgres.CreateTenant"
+
+	var id int64
+
+	q := fmt.Sprintf(
+		`insert into %s (name) values ($1)
+		on conflict (name) do nothing returning id;`,
+		TenantTable,
+	)
+
+	err := p.db.Master.QueryRowContext(
+		context.Background(), q, t.Name,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, true, nil
+}
+
+func (p *Postgres) Tenant(id int64) (tenant.Tenant, error) {
+	const op = "internal.storage.postgres.Tenant"
+
+	q := fmt.Sprintf(
+		"select %s from %s where id = $1;", tenantColumns, TenantTable,
+	)
+
+	row := p.db.Master.QueryRowContext(context.Background(), q, id)
+	r, err := scanTenant(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, err
+}
+
+func (p *Postgres) Tenants() ([]tenant.Tenant, error) {
+	const op = "internal.storage.postgres.Tenants"
+
+	q := fmt.Sprintf(
+		"select %s from %s order by id;", tenantColumns, TenantTable,
+	)
+
+	rows, err := p.db.Master.QueryContext(context.Background(), q)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	r := make([]tenant.Tenant, 0)
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		r = append(r, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
@@ -1,18 +1,30 @@
 package rabbit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/zlog"
 )
 
+// confirmTimeout сколько ждать подтверждение публикации от брокера
+const confirmTimeout = 5 * time.Second
+
+var ErrNotConfirmed = errors.New("message isn't confirmed by broker")
+
 type Rabbit struct {
 	ch   *amqp091.Channel
 	conn *amqp091.Connection
 	q    amqp091.Queue
 	ex   string
 	key  string
+	dlq  <-chan amqp091.Delivery
+	res  <-chan amqp091.Delivery
+	clm  <-chan amqp091.Delivery
+	rtr  <-chan amqp091.Delivery
 }
 
 func (r *Rabbit) Shoutdown() {
@@ -28,7 +40,12 @@ func (r *Rabbit) Shoutdown() {
 	}
 }
 
-func New(username, password, addr, queue, ex, key string) *Rabbit {
+// New подключается к брокеру. Уведомления публикуются в отложенный обменник
+// ex, пустой ex - без обменника и плагина, сообщения идут в очередь queue
+// только через Send, а повторы sender приходят в очередь retries.
+// Недоставленные sender сообщения читаются из очереди dlq, результаты
+// доставки - из results, запросы sender перед отправкой - из claims
+func New(username, password, addr, queue, ex, key, dlq, results, claims, retries string) *Rabbit {
 	r := &Rabbit{}
 
 	conn, err := amqp091.Dial(
@@ -47,6 +64,13 @@ func New(username, password, addr, queue, ex, key string) *Rabbit {
 	}
 	r.ch = ch
 
+	// Publish ждет подтверждение брокера, иначе outbox отметит
+	// отправленным потерянное сообщение
+	err = ch.Confirm(false)
+	if err != nil {
+		panic(err)
+	}
+
 	q, err := ch.QueueDeclare(
 		queue,
 		false,
@@ -60,35 +84,78 @@ func New(username, password, addr, queue, ex, key string) *Rabbit {
 	}
 	r.q = q
 
-	err = ch.ExchangeDeclare(
-		ex,
-		"x-delayed-message",
-		true,
-		false,
-		false,
-		false,
-		amqp091.Table{
-			"x-delayed-type": "direct",
-		},
-	)
-	if err != nil {
-		panic(err)
+	if ex != "" {
+		err = ch.ExchangeDeclare(
+			ex,
+			"x-delayed-message",
+			true,
+			false,
+			false,
+			false,
+			amqp091.Table{
+				"x-delayed-type": "direct",
+			},
+		)
+		if err != nil {
+			panic(err)
+		}
+		err = ch.QueueBind(q.Name, key, ex, false, nil)
+		if err != nil {
+			panic(err)
+		}
 	}
 	r.ex = ex
 	r.key = key
 
-	err = ch.QueueBind(q.Name, key, ex, false, nil)
+	_, err = ch.QueueDeclare(dlq, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	// подтверждение после записи в базу, иначе письмо вернется в очередь
+	r.dlq, err = ch.Consume(dlq, "", false, false, false, false, nil)
 	if err != nil {
 		panic(err)
 	}
 
+	_, err = ch.QueueDeclare(results, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	r.res, err = ch.Consume(results, "", false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = ch.QueueDeclare(claims, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	r.clm, err = ch.Consume(claims, "", false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	if ex == "" {
+		_, err = ch.QueueDeclare(retries, true, false, false, false, nil)
+		if err != nil {
+			panic(err)
+		}
+		// подтверждение после записи в базу, иначе повтор потеряется
+		r.rtr, err = ch.Consume(retries, "", false, false, false, false, nil)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	return r
 }
 
+// Publish публикует сообщение в отложенный обменник, sender получит его
+// через d мс
 func (r *Rabbit) Publish(val []byte, d int64) error {
 	const op = "internal.storage.rabbit.Publish"
 
-	err := r.ch.Publish(r.ex, r.key, true, false, amqp091.Publishing{
+	err := r.publish(r.ex, r.key, amqp091.Publishing{
 		Headers: amqp091.Table{
 			"x-delay": d,
 		},
@@ -102,3 +169,97 @@ func (r *Rabbit) Publish(val []byte, d int64) error {
 
 	return nil
 }
+
+// Send публикует сообщение сразу в очередь sender, минуя отложенный обменник
+func (r *Rabbit) Send(val []byte) error {
+	const op = "internal.storage.rabbit.Send"
+
+	err := r.publish("", r.q.Name, amqp091.Publishing{
+		ContentType: "text/plain",
+		Body:        val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// SendRetry публикует повтор sender сразу в его очередь с заголовками
+// исходного сообщения
+func (r *Rabbit) SendRetry(val []byte, headers amqp091.Table) error {
+	const op = "internal.storage.rabbit.SendRetry"
+
+	err := r.publish("", r.q.Name, amqp091.Publishing{
+		Headers:     headers,
+		ContentType: "text/plain",
+		Body:        val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// publish публикует сообщение и ждет подтверждение брокера
+func (r *Rabbit) publish(ex, key string, msg amqp091.Publishing) error {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	conf, err := r.ch.PublishWithDeferredConfirmWithContext(
+		ctx, ex, key, true, false, msg,
+	)
+	if err != nil {
+		return err
+	}
+	ok, err := conf.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotConfirmed, err)
+	}
+	if !ok {
+		return ErrNotConfirmed
+	}
+
+	return nil
+}
+
+// DeadLetters сообщения DLQ, каждое нужно подтвердить или вернуть
+func (r *Rabbit) DeadLetters() <-chan amqp091.Delivery {
+	return r.dlq
+}
+
+// Results результаты доставки от sender, каждый нужно подтвердить или вернуть
+func (r *Rabbit) Results() <-chan amqp091.Delivery {
+	return r.res
+}
+
+// Claims запросы sender перед отправкой, на каждый нужно ответить через Reply
+func (r *Rabbit) Claims() <-chan amqp091.Delivery {
+	return r.clm
+}
+
+// Retries повторы sender, когда отложенного обменника нет, каждый нужно
+// подтвердить или вернуть. С обменником канал nil
+func (r *Rabbit) Retries() <-chan amqp091.Delivery {
+	return r.rtr
+}
+
+// Reply отвечает на запрос msg в его очередь ответа
+func (r *Rabbit) Reply(msg amqp091.Delivery, val []byte) error {
+	const op = "internal.storage.rabbit.Reply"
+
+	err := r.ch.Publish("", msg.ReplyTo, false, false, amqp091.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: msg.CorrelationId,
+		Body:          val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// tokenBucket списывает один токен из корзины KEYS[1]. ARGV[1] - токенов в
+// секунду, ARGV[2] - размер корзины. Время берется у redis, поэтому у всех
+// экземпляров notifier одни и те же часы. Возвращает {1, 0}, если токен
+// есть, и {0, мс до следующего токена}, если нет
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local b = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+
+return {allowed, wait}
+`)
+
+// Allow списывает запрос клиента из его корзины токенов. Если запрос сверх
+// лимита, возвращает false и время, через которое появится следующий токен
+func (r *Redis) Allow(client string, rate float64, burst int64) (bool, time.Duration, error) {
+	const op = "internal.storage.redis.Allow"
+
+	res, err := tokenBucket.Run(
+		context.Background(), r.rd.Client, []string{rateLimitPrefix + client},
+		strconv.FormatFloat(rate, 'f', -1, 64), burst,
+	).Int64Slice()
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		zlog.Logger.Error().AnErr("err", ErrWrongValue).Msg(op)
+		return false, 0, ErrWrongValue
+	}
+	if res[0] == 1 {
+		return true, 0, nil
+	}
+
+	return false, time.Duration(max(res[1], 1)) * time.Millisecond, nil
+}
@@ -3,13 +3,35 @@ package redis
 import (
 	"context"
 	"delayednotifier/internal/entities/notification"
+	"errors"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	wbfRedis "github.com/wb-go/wbf/redis"
 	"github.com/wb-go/wbf/zlog"
 )
 
+const (
+	idempotencyPrefix = "idempotency:"
+	IdempotencyTTL    = 24 * time.Hour
+)
+
+// tenantKey ключ кеша внутри команды, одинаковые id и ключи идемпотентности
+// разных команд не пересекаются
+func tenantKey(tenantID int64, key string) string {
+	return "tenant:" + strconv.FormatInt(tenantID, 10) + ":" + key
+}
+
+func notificationKey(tenantID, id int64) string {
+	return tenantKey(tenantID, strconv.FormatInt(id, 10))
+}
+
+var (
+	ErrWrongValue = errors.New("wrong value in cache")
+)
+
 type Redis struct {
 	rd *wbfRedis.Client
 }
@@ -45,7 +67,7 @@ func (r *Redis) Shutdown() {
 func (r *Redis) AddNotification(n notification.Notification) error {
 	const op = "internal.storage.redis.AddNotification"
 
-	err := r.rd.Set(context.Background(), strconv.Itoa(int(n.ID)), n)
+	err := r.rd.Set(context.Background(), notificationKey(n.TenantID, n.ID), n)
 	if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return err
@@ -54,11 +76,11 @@ func (r *Redis) AddNotification(n notification.Notification) error {
 	return nil
 }
 
-func (r *Redis) GetNotification(id int64) (notification.Notification, error) {
+func (r *Redis) GetNotification(tenantID, id int64) (notification.Notification, error) {
 	const op = "internal.storage.redis.GetNotification"
 
 	var n notification.Notification
-	s, err := r.rd.Get(context.Background(), strconv.Itoa(int(id)))
+	s, err := r.rd.Get(context.Background(), notificationKey(tenantID, id))
 	if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return n, err
@@ -71,10 +93,12 @@ func (r *Redis) GetNotification(id int64) (notification.Notification, error) {
 	return n, nil
 }
 
-func (r *Redis) DeleteNotification(id int64) (int64, error) {
+func (r *Redis) DeleteNotification(tenantID, id int64) (int64, error) {
 	const op = "internal.storage.redis.DeleteNotification"
 
-	res, err := r.rd.Del(context.Background(), strconv.Itoa(int(id))).Result()
+	res, err := r.rd.Del(
+		context.Background(), notificationKey(tenantID, id),
+	).Result()
 	if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return 0, err
@@ -82,3 +106,41 @@ func (r *Redis) DeleteNotification(id int64) (int64, error) {
 
 	return res, nil
 }
+
+func (r *Redis) AddIdempotencyKey(tenantID int64, key string, id int64, hash string) error {
+	const op = "internal.storage.redis.AddIdempotencyKey"
+
+	err := r.rd.Client.Set(
+		context.Background(), tenantKey(tenantID, idempotencyPrefix+key),
+		strconv.FormatInt(id, 10)+":"+hash, IdempotencyTTL,
+	).Err()
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Redis) IdempotencyKey(tenantID int64, key string) (int64, string, error) {
+	const op = "internal.storage.redis.IdempotencyKey"
+
+	s, err := r.rd.Get(
+		context.Background(), tenantKey(tenantID, idempotencyPrefix+key),
+	)
+	if err != nil {
+		return 0, "", err
+	}
+	v, hash, ok := strings.Cut(s, ":")
+	if !ok {
+		zlog.Logger.Error().AnErr("err", ErrWrongValue).Msg(op)
+		return 0, "", ErrWrongValue
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, "", ErrWrongValue
+	}
+
+	return id, hash, nil
+}
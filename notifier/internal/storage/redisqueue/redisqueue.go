@@ -0,0 +1,160 @@
+// Package redisqueue брокер на Redis вместо RabbitMQ: каждая очередь -
+// ZSET id сообщений по времени, когда они станут видны, поэтому отложенные
+// сообщения не требуют плагина. Очередь из общего модуля zsetqueue, ключи
+// те же у sender
+package redisqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+	"zsetqueue"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Имена очередей после префикса, те же у sender
+const (
+	notificationsQueue = "notifications"
+	deadLettersQueue   = "dead_letters"
+	resultsQueue       = "results"
+	claimsQueue        = "claims"
+)
+
+// replyTTL сколько живет ответ на запрос sender, который никто не забрал
+const replyTTL = time.Minute
+
+// Options настройки очередей
+type Options struct {
+	// Prefix начало всех ключей, одинаковое у notifier и sender
+	Prefix string
+	// Visibility на сколько выданное сообщение прячется от других
+	// получателей, не подтвержденное за это время выдается снова
+	Visibility time.Duration
+	// PollInterval как часто искать наступившие сообщения
+	PollInterval time.Duration
+	// Batch сколько сообщений забирать за раз
+	Batch int
+}
+
+// Queue реализация storage.Queue поверх redis
+type Queue struct {
+	rd            *redis.Client
+	notifications *zsetqueue.Queue
+
+	stop chan struct{}
+	once sync.Once
+	dlq  <-chan amqp091.Delivery
+	res  <-chan amqp091.Delivery
+	clm  <-chan amqp091.Delivery
+}
+
+// New подключается к redis и начинает разбирать недоставленные сообщения,
+// результаты доставки и запросы sender
+func New(addr, password string, db int, o Options) *Queue {
+	rd := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	if err := rd.Ping(context.Background()).Err(); err != nil {
+		panic(err)
+	}
+
+	q := &Queue{
+		rd:            rd,
+		notifications: zsetqueue.New(rd, o.Prefix, notificationsQueue, o.Visibility, 0),
+		stop:          make(chan struct{}),
+	}
+	// не подтвержденных сообщений каждой очереди не больше Batch, остальные
+	// ждут в redis и не прячутся зря
+	q.dlq = zsetqueue.New(rd, o.Prefix, deadLettersQueue, o.Visibility, o.Batch).
+		Consume(q.stop, o.Batch, o.PollInterval)
+	q.res = zsetqueue.New(rd, o.Prefix, resultsQueue, o.Visibility, o.Batch).
+		Consume(q.stop, o.Batch, o.PollInterval)
+	q.clm = zsetqueue.New(rd, o.Prefix, claimsQueue, o.Visibility, o.Batch).
+		Consume(q.stop, o.Batch, o.PollInterval)
+
+	return q
+}
+
+func (q *Queue) Shutdown() {
+	const op = "internal.storage.redisqueue.Shutdown"
+
+	q.once.Do(func() {
+		close(q.stop)
+		if err := q.rd.Close(); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	})
+}
+
+// Publish кладет сообщение в очередь sender, оно станет видно через d мс
+func (q *Queue) Publish(val []byte, d int64) error {
+	const op = "internal.storage.redisqueue.Publish"
+
+	err := q.notifications.Push(zsetqueue.Envelope{
+		Headers:     amqp091.Table{"x-delay": d},
+		ContentType: "text/plain",
+		Body:        val,
+		Timestamp:   time.Now().UTC(),
+	}, time.Duration(d)*time.Millisecond)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Send кладет сообщение в очередь sender без задержки
+func (q *Queue) Send(val []byte) error {
+	const op = "internal.storage.redisqueue.Send"
+
+	err := q.notifications.Push(zsetqueue.Envelope{
+		ContentType: "text/plain",
+		Body:        val,
+		Timestamp:   time.Now().UTC(),
+	}, 0)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// DeadLetters сообщения DLQ, каждое нужно подтвердить или вернуть
+func (q *Queue) DeadLetters() <-chan amqp091.Delivery {
+	return q.dlq
+}
+
+// Results результаты доставки от sender, каждый нужно подтвердить или вернуть
+func (q *Queue) Results() <-chan amqp091.Delivery {
+	return q.res
+}
+
+// Claims запросы sender перед отправкой, на каждый нужно ответить через Reply
+func (q *Queue) Claims() <-chan amqp091.Delivery {
+	return q.clm
+}
+
+// Reply кладет ответ на запрос msg в его список ответа, sender ждет его
+// через BLPOP
+func (q *Queue) Reply(msg amqp091.Delivery, val []byte) error {
+	const op = "internal.storage.redisqueue.Reply"
+
+	_, err := q.rd.TxPipelined(context.Background(), func(p redis.Pipeliner) error {
+		p.RPush(context.Background(), msg.ReplyTo, val)
+		p.PExpire(context.Background(), msg.ReplyTo, replyTTL)
+		return nil
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
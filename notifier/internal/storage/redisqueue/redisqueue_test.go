@@ -0,0 +1,116 @@
+package redisqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+	"zsetqueue"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	Prefix: "test", Visibility: time.Minute, PollInterval: 10 * time.Millisecond, Batch: 16,
+}
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	m := miniredis.RunT(t)
+	q := New(m.Addr(), "", 0, testOptions)
+	t.Cleanup(q.Shutdown)
+
+	return q
+}
+
+func receive(t *testing.T, ch <-chan amqp091.Delivery) amqp091.Delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message in queue")
+	}
+
+	return amqp091.Delivery{}
+}
+
+func nothing(t *testing.T, ch <-chan amqp091.Delivery) {
+	t.Helper()
+	select {
+	case d := <-ch:
+		t.Fatalf("unexpected message: %s", d.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQueue_Publish(t *testing.T) {
+	q := newTestQueue(t)
+	stop := make(chan struct{})
+	defer close(stop)
+	// сторона sender
+	messages := q.notifications.Consume(stop, 16, 10*time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, q.Publish([]byte("late"), 300))
+	require.NoError(t, q.Publish([]byte("early"), 100))
+	require.NoError(t, q.Send([]byte("now")))
+
+	for _, want := range []string{"now", "early", "late"} {
+		d := receive(t, messages)
+		require.Equal(t, want, string(d.Body))
+		require.False(t, d.Redelivered)
+		require.NoError(t, d.Ack(false))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// подтвержденные сообщения удалены целиком
+	n, err := q.rd.HLen(context.Background(), "test:notifications:messages").Result()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestQueue_Nack(t *testing.T) {
+	q := newTestQueue(t)
+	results := zsetqueue.New(q.rd, "test", resultsQueue, time.Minute, 0)
+	require.NoError(t, results.Push(zsetqueue.Envelope{Body: []byte("r")}, 0))
+
+	d := receive(t, q.Results())
+	require.NoError(t, d.Nack(false, true))
+	d = receive(t, q.Results())
+	require.Equal(t, "r", string(d.Body))
+	require.True(t, d.Redelivered)
+	require.NoError(t, d.Nack(false, false))
+	nothing(t, q.Results())
+}
+
+func TestQueue_Claims(t *testing.T) {
+	q := newTestQueue(t)
+	claims := zsetqueue.New(q.rd, "test", claimsQueue, time.Minute, 0)
+
+	// запрос, который sender уже не ждет, notifier не получает
+	require.NoError(t, claims.Push(zsetqueue.Envelope{
+		Body: []byte("old"), Expiration: 50, Timestamp: time.Now().Add(-time.Second),
+	}, 0))
+	require.NoError(t, claims.Push(zsetqueue.Envelope{
+		Body: []byte("claim"), CorrelationID: "1", ReplyTo: "test:reply:1",
+		Expiration: 10000, Timestamp: time.Now(),
+	}, 0))
+
+	d := receive(t, q.Claims())
+	require.Equal(t, "claim", string(d.Body))
+	require.Equal(t, "1", d.CorrelationId)
+	require.NoError(t, q.Reply(d, []byte("ok")))
+	require.NoError(t, d.Ack(false))
+
+	// ответ, который никто не забрал, не копится в redis
+	ttl, err := q.rd.PTTL(context.Background(), "test:reply:1").Result()
+	require.NoError(t, err)
+	require.Positive(t, ttl)
+	require.LessOrEqual(t, ttl, replyTTL)
+	v, err := q.rd.LPop(context.Background(), "test:reply:1").Result()
+	require.NoError(t, err)
+	require.Equal(t, "ok", v)
+	nothing(t, q.Claims())
+}
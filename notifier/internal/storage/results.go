@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"delayednotifier/internal/entities/event"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// ErrRetryLater результат не записан из-за временной ошибки, сообщение
+// нужно вернуть в очередь
+var ErrRetryLater = errors.New("retry later")
+
+// ConsumeResults передает результаты доставки от sender в apply, пока очередь
+// открыта. Результат с ErrRetryLater возвращается в очередь, нечитаемый или
+// отклоненный - подтверждается и теряется
+func (s *Storage) ConsumeResults(apply func(e event.Delivery) error) {
+	const op = "internal.storage.ConsumeResults"
+
+	for msg := range s.q.Results() {
+		e := event.Delivery{}
+		if err := json.Unmarshal(msg.Body, &e); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			if err := msg.Ack(false); err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+			}
+			continue
+		}
+
+		err := apply(e)
+		if errors.Is(err, ErrRetryLater) {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			time.Sleep(time.Second)
+			if err := msg.Nack(false, true); err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+			}
+			continue
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Fields(map[string]any{
+				"id": e.NotificationID, "channel": e.Channel,
+			}).Msg(op)
+		}
+		if err := msg.Ack(false); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	}
+}
+
+// ConsumeClaims отвечает на запросы sender перед отправкой ответом claim,
+// пока очередь открыта
+func (s *Storage) ConsumeClaims(claim func(c event.Claim) event.ClaimReply) {
+	const op = "internal.storage.ConsumeClaims"
+
+	for msg := range s.q.Claims() {
+		c := event.Claim{}
+		r := event.ClaimReply{}
+		if err := json.Unmarshal(msg.Body, &c); err != nil {
+			r = event.ClaimReply{Result: event.ClaimFailed, Error: err.Error()}
+		} else {
+			r = claim(c)
+		}
+
+		v, err := json.Marshal(r)
+		if err == nil && msg.ReplyTo != "" {
+			err = s.q.Reply(msg, v)
+		}
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+		if err := msg.Ack(false); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	}
+}
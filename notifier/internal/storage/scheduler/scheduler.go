@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/retry"
+	"delayednotifier/internal/storage"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// DB выборка наступивших уведомлений и повторов, см. postgres.DispatchDue
+// и postgres.DispatchRetries
+type DB interface {
+	DispatchDue(limit int, send func(n notification.Notification) error) (int, error)
+	AddRetry(r retry.Retry) error
+	DispatchRetries(limit int, send func(r retry.Retry) error) (int, error)
+}
+
+// Retries очередь повторов sender без отложенного обменника, см.
+// rabbit.Rabbit
+type Retries interface {
+	Retries() <-chan amqp091.Delivery
+	SendRetry(val []byte, headers amqp091.Table) error
+}
+
+// Scheduler реализация storage.Queue без отложенного обменника: время
+// отправки хранит только таблица уведомлений, наступившие уведомления Run
+// забирает из нее и отдает sender через Send брокера. Остальное делает
+// брокер q
+type Scheduler struct {
+	storage.Queue
+	db      DB
+	retries Retries
+	wake    chan struct{}
+}
+
+func New(q storage.Queue, db DB) *Scheduler {
+	return &Scheduler{
+		Queue: q,
+		db:    db,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Publish ничего не публикует: уведомление уже в базе, Run возьмет его в
+// срок. Наступившее уведомление будит Run, чтобы не ждать тика
+func (s *Scheduler) Publish(val []byte, d int64) error {
+	if d <= 0 {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// SetRetries задает очередь, из которой приходят повторы sender. Без нее
+// повторы идут мимо планировщика, например через ZSET очереди в redis
+func (s *Scheduler) SetRetries(r Retries) {
+	s.retries = r
+}
+
+// Run каждые interval отдает sender наступившие уведомления и повторы
+// пачками по batch, пока они есть
+func (s *Scheduler) Run(interval time.Duration, batch int) {
+	const op = "internal.storage.scheduler.Run"
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-s.wake:
+		}
+
+		s.drain(op, batch, s.dispatch)
+		if s.retries != nil {
+			s.drain(op, batch, s.dispatchRetries)
+		}
+	}
+}
+
+// drain вызывает dispatch, пока он отдает полные пачки
+func (s *Scheduler) drain(op string, batch int, dispatch func(batch int) (int, error)) {
+	for {
+		n, err := dispatch(batch)
+		if err != nil {
+			// брокер или база недоступны, повторим на следующем тике
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return
+		}
+		if n < batch {
+			return
+		}
+	}
+}
+
+// dispatch отдает одну пачку, возвращает число отданных
+func (s *Scheduler) dispatch(batch int) (int, error) {
+	return s.db.DispatchDue(batch, func(n notification.Notification) error {
+		v, err := n.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		return s.Send(v)
+	})
+}
+
+// dispatchRetries отдает одну пачку повторов, возвращает число отданных
+func (s *Scheduler) dispatchRetries(batch int) (int, error) {
+	return s.db.DispatchRetries(batch, func(r retry.Retry) error {
+		h := amqp091.Table{}
+		for k, v := range r.Headers {
+			h[k] = v
+		}
+
+		return s.retries.SendRetry(r.Payload, h)
+	})
+}
+
+// ConsumeRetries сохраняет повторы sender в базу до их времени, пока
+// очередь открыта. Несохраненный повтор возвращается в очередь
+func (s *Scheduler) ConsumeRetries() {
+	const op = "internal.storage.scheduler.ConsumeRetries"
+
+	for msg := range s.retries.Retries() {
+		r := toRetry(msg, time.Now())
+		if err := s.db.AddRetry(r); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			time.Sleep(time.Second)
+			if err := msg.Nack(false, true); err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+			}
+			continue
+		}
+		if err := msg.Ack(false); err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+		if !r.DueAt.After(time.Now()) {
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// toRetry повтор из сообщения sender: время - now плюс x-delay в мс,
+// остальные строковые заголовки сохраняются как есть
+func toRetry(msg amqp091.Delivery, now time.Time) retry.Retry {
+	r := retry.Retry{Payload: msg.Body, Headers: map[string]string{}, DueAt: now}
+	for k, v := range msg.Headers {
+		if v, ok := v.(string); ok {
+			r.Headers[k] = v
+		}
+	}
+	switch d := msg.Headers[delayHeader].(type) {
+	case int64:
+		r.DueAt = now.Add(time.Duration(d) * time.Millisecond)
+	case int32:
+		r.DueAt = now.Add(time.Duration(d) * time.Millisecond)
+	}
+
+	return r
+}
+
+// delayHeader заголовок с задержкой в мс, как у отложенного обменника
+const delayHeader = "x-delay"
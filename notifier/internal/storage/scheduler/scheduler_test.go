@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/retry"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+type QueueMock struct {
+	sendF func(val []byte) error
+}
+
+func (q *QueueMock) Publish(val []byte, d int64) error {
+	return errors.New("publish isn't expected")
+}
+
+func (q *QueueMock) Send(val []byte) error {
+	return q.sendF(val)
+}
+
+func (q *QueueMock) DeadLetters() <-chan amqp091.Delivery {
+	return nil
+}
+
+func (q *QueueMock) Results() <-chan amqp091.Delivery {
+	return nil
+}
+
+func (q *QueueMock) Claims() <-chan amqp091.Delivery {
+	return nil
+}
+
+func (q *QueueMock) Reply(msg amqp091.Delivery, val []byte) error {
+	return nil
+}
+
+// DBMock отдает due и retries в send по порядку, как postgres.DispatchDue
+// и postgres.DispatchRetries
+type DBMock struct {
+	due     []notification.Notification
+	retries []retry.Retry
+}
+
+func (d *DBMock) DispatchDue(limit int, send func(n notification.Notification) error) (int, error) {
+	sent := 0
+	for _, n := range d.due[:min(limit, len(d.due))] {
+		if err := send(n); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (d *DBMock) AddRetry(r retry.Retry) error {
+	d.retries = append(d.retries, r)
+	return nil
+}
+
+func (d *DBMock) DispatchRetries(limit int, send func(r retry.Retry) error) (int, error) {
+	sent := 0
+	for _, r := range d.retries[:min(limit, len(d.retries))] {
+		if err := send(r); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+type RetriesMock struct {
+	sendF func(val []byte, headers amqp091.Table) error
+}
+
+func (r *RetriesMock) Retries() <-chan amqp091.Delivery {
+	return nil
+}
+
+func (r *RetriesMock) SendRetry(val []byte, headers amqp091.Table) error {
+	return r.sendF(val, headers)
+}
+
+func TestScheduler_Publish(t *testing.T) {
+	s := New(&QueueMock{}, &DBMock{})
+
+	require.NoError(t, s.Publish([]byte("later"), 60000))
+	require.Empty(t, s.wake)
+	require.NoError(t, s.Publish([]byte("now"), -5))
+	require.NoError(t, s.Publish([]byte("now"), 0))
+	require.Len(t, s.wake, 1)
+}
+
+func TestScheduler_dispatch(t *testing.T) {
+	due := []notification.Notification{
+		{ID: 1, Version: 1, Message: "a"},
+		{ID: 2, Version: 3, Message: "b"},
+		{ID: 3, Version: 1, Message: "c"},
+	}
+	tests := []struct {
+		name     string
+		batch    int
+		failOn   int64
+		wantSent []int64
+		wantErr  bool
+	}{
+		{name: "all", batch: 10, wantSent: []int64{1, 2, 3}},
+		{name: "batch", batch: 2, wantSent: []int64{1, 2}},
+		{name: "broker down", batch: 10, failOn: 2, wantSent: []int64{1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int64{}
+			s := New(&QueueMock{
+				sendF: func(val []byte) error {
+					n := notification.Notification{}
+					require.NoError(t, n.UnmarshalBinary(val))
+					if n.ID == tt.failOn {
+						return errors.New("channel closed")
+					}
+					got = append(got, n.ID)
+					return nil
+				},
+			}, &DBMock{due: due})
+
+			sent, err := s.dispatch(tt.batch)
+			require.Equal(t, tt.wantErr, err != nil)
+			require.Equal(t, len(tt.wantSent), sent)
+			require.Equal(t, tt.wantSent, got)
+		})
+	}
+}
+
+func TestToRetry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		msg  amqp091.Delivery
+		want retry.Retry
+	}{
+		{
+			name: "delayed",
+			msg: amqp091.Delivery{Body: []byte("n"), Headers: amqp091.Table{
+				"x-delay": int64(30000), "x-attempts": `[{"attempt":0}]`,
+			}},
+			want: retry.Retry{
+				Payload: []byte("n"),
+				Headers: map[string]string{"x-attempts": `[{"attempt":0}]`},
+				DueAt:   now.Add(30 * time.Second),
+			},
+		},
+		{
+			name: "no delay",
+			msg:  amqp091.Delivery{Body: []byte("n")},
+			want: retry.Retry{Payload: []byte("n"), Headers: map[string]string{}, DueAt: now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, toRetry(tt.msg, now))
+		})
+	}
+}
+
+func TestScheduler_dispatchRetries(t *testing.T) {
+	got := []string{}
+	s := New(&QueueMock{}, &DBMock{retries: []retry.Retry{
+		{ID: 1, Payload: []byte("a"), Headers: map[string]string{"x-attempts": "[]"}},
+		{ID: 2, Payload: []byte("b")},
+	}})
+	s.SetRetries(&RetriesMock{
+		sendF: func(val []byte, headers amqp091.Table) error {
+			if string(val) == "a" {
+				require.Equal(t, amqp091.Table{"x-attempts": "[]"}, headers)
+			}
+			got = append(got, string(val))
+			return nil
+		},
+	})
+
+	sent, err := s.dispatchRetries(10)
+	require.NoError(t, err)
+	require.Equal(t, 2, sent)
+	require.Equal(t, []string{"a", "b"}, got)
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"errors"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// CreateSeries сохраняет серию с первым срабатыванием, его публикует
+// RelayOutbox. Возвращает id серии и id уведомления
+func (s *Storage) CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error) {
+	const op = "internal.storage.CreateSeries"
+
+	seriesID, id, err := s.db.CreateSeries(sr, n)
+	if errors.Is(err, notification.ErrQuotaExceeded) {
+		return 0, 0, err
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, 0, err
+	}
+	if seriesID < 1 || id < 1 {
+		return 0, 0, ErrDontHaveID
+	}
+	s.wakeRelay()
+
+	return seriesID, id, nil
+}
+
+// CreateOccurrence создает следующее срабатывание серии после prevID, если
+// серия не активна или его уже создали - возвращает ErrNotAffected
+func (s *Storage) CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error) {
+	const op = "internal.storage.CreateOccurrence"
+
+	id, err := s.db.CreateOccurrence(seriesID, prevID, n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotAffected
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	s.wakeRelay()
+
+	return id, nil
+}
+
+func (s *Storage) Series(tenantID, id int64) (series.Series, error) {
+	const op = "internal.storage.Series"
+
+	sr, err := s.db.Series(tenantID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sr, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return sr, err
+	}
+
+	return sr, nil
+}
+
+// UpdateSeriesStatus меняет статус серии, остановленную или завершенную
+// серию менять нельзя - ErrNotEditable
+func (s *Storage) UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error) {
+	const op = "internal.storage.UpdateSeriesStatus"
+
+	sr, err := s.db.UpdateSeriesStatus(tenantID, id, status)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = s.db.Series(tenantID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return sr, ErrNotAffected
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return sr, err
+		}
+		return sr, ErrNotEditable
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return sr, err
+	}
+
+	return sr, nil
+}
@@ -2,11 +2,19 @@ package storage
 
 import (
 	"database/sql"
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/outbox"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
 	"errors"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/zlog"
 )
 
@@ -14,36 +22,96 @@ var (
 	ErrNotFound    = errors.New("not found row")
 	ErrNotAffected = errors.New("no one element didn't was affected")
 	ErrDontHaveID  = errors.New("can't get id after inserting")
+	ErrConflict    = errors.New("idempotency key already used with another request")
+	ErrNotEditable = errors.New("notification isn't pending or has another version")
+	ErrCancelled   = errors.New("notification was cancelled")
 )
 
 type DB interface {
 	CreateNotification(n notification.Notification) (int64, error)
-	Notification(id int64) (notification.Notification, error)
-	UpdateNotificationStatus(status string, id int64) (int64, error)
-	DeleteNotification(id int64) (int64, error)
+	CreateNotifications(ns []notification.Notification) ([]int64, error)
+	CreateNotificationIdempotent(n notification.Notification, key, hash string) (int64, bool, error)
+	IdempotencyKey(tenantID int64, key string) (int64, string, error)
+	Notification(tenantID, id int64) (notification.Notification, error)
+	SetMaxPending(n int64)
+	Notifications(f notification.Filter) ([]notification.Notification, error)
+	UpdateNotification(tenantID, id int64, u notification.Update) (notification.Notification, error)
+	CancelNotification(tenantID, id int64, reason string) (int64, error)
+	PurgeNotifications(tenantID int64, before time.Time) ([]int64, error)
+	RecordDelivery(tenantID, id int64, d notification.Delivery) (int64, error)
+	Deliveries(tenantID, id int64) ([]notification.Delivery, error)
+	RelayOutbox(limit int, publish func(m outbox.Message) error) (int, error)
+	PurgeOutbox(before time.Time) (int64, error)
+	SweepOverdue(before time.Time, maxRepublish, limit int) (notification.Sweep, error)
+	OverdueNotifications(f notification.OverdueFilter) ([]notification.Overdue, error)
+
+	CreateSeries(sr series.Series, n notification.Notification) (int64, int64, error)
+	CreateOccurrence(seriesID, prevID int64, n notification.Notification) (int64, error)
+	Series(tenantID, id int64) (series.Series, error)
+	UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error)
+
+	CreateTemplate(t template.Template) (int64, bool, error)
+	Template(tenantID, id int64) (template.Template, error)
+	Templates(tenantID int64) ([]template.Template, error)
+	UpdateTemplate(t template.Template) (template.Template, error)
+	DeleteTemplate(tenantID, id int64) (int64, error)
+
+	CreateContact(c contact.Contact) (int64, error)
+	Contact(tenantID, id int64) (contact.Contact, error)
+	Contacts(tenantID int64) ([]contact.Contact, error)
+	UpdateContact(c contact.Contact) (contact.Contact, error)
+	DeleteContact(tenantID, id int64) (int64, error)
+
+	CreateAPIKey(k apikey.Key) (int64, error)
+	APIKeyByHash(hash string) (apikey.Key, error)
+	APIKeys(tenantID int64) ([]apikey.Key, error)
+	RevokeAPIKey(tenantID, id int64) (int64, error)
+
+	CreateTenant(t tenant.Tenant) (int64, bool, error)
+	Tenant(id int64) (tenant.Tenant, error)
+	Tenants() ([]tenant.Tenant, error)
+
+	CreateDeadLetter(l deadletter.Letter) (int64, error)
+	DeadLetter(id int64) (deadletter.Letter, error)
+	DeadLetters(f deadletter.Filter) ([]deadletter.Letter, error)
+	DeleteDeadLetter(id int64) (int64, error)
 }
 
+// Cache кеш уведомлений, ключи разных команд не пересекаются
 type Cache interface {
 	AddNotification(n notification.Notification) error
-	GetNotification(id int64) (notification.Notification, error)
-	DeleteNotification(id int64) (int64, error)
+	GetNotification(tenantID, id int64) (notification.Notification, error)
+	DeleteNotification(tenantID, id int64) (int64, error)
+	AddIdempotencyKey(tenantID int64, key string, id int64, hash string) error
+	IdempotencyKey(tenantID int64, key string) (int64, string, error)
+	Allow(client string, rate float64, burst int64) (bool, time.Duration, error)
 }
 
+// Queue брокер сообщений. Publish планирует сообщение уведомления на время
+// отправки, Send отдает сообщение sender сразу
 type Queue interface {
 	Publish(val []byte, d int64) error
+	Send(val []byte) error
+	DeadLetters() <-chan amqp091.Delivery
+	Results() <-chan amqp091.Delivery
+	Claims() <-chan amqp091.Delivery
+	Reply(msg amqp091.Delivery, val []byte) error
 }
 
 type Storage struct {
 	db DB
 	c  Cache
 	q  Queue
+	// outbox будит RelayOutbox после записи нового сообщения
+	outbox chan struct{}
 }
 
 func New(db DB, c Cache, q Queue) *Storage {
 	return &Storage{
-		db: db,
-		c:  c,
-		q:  q,
+		db:     db,
+		c:      c,
+		q:      q,
+		outbox: make(chan struct{}, 1),
 	}
 }
 
@@ -51,52 +119,140 @@ func (s *Storage) CreateNotification(n notification.Notification) (int64, error)
 	const op = "internal.storage.CreateNotification"
 
 	id, err := s.db.CreateNotification(n)
-	if err != nil {
+	if errors.Is(err, notification.ErrQuotaExceeded) {
+		return id, err
+	} else if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return id, err
 	}
 	if id < 1 {
 		return id, ErrDontHaveID
 	}
-	n.ID = id
+	s.wakeRelay()
 
-	v, err := n.MarshalBinary()
-	if err != nil {
+	return id, nil
+}
+
+// CreateNotificationIdempotent создает уведомление один раз на ключ, повтор
+// с тем же содержимым возвращает исходный id, с другим - ErrConflict.
+// Повтор ничего не публикует: сообщение исходного уведомления записано в
+// outbox той же транзакцией и дойдет до брокера через RelayOutbox, даже если
+// при первом запросе брокер был недоступен
+func (s *Storage) CreateNotificationIdempotent(n notification.Notification, key string) (int64, error) {
+	const op = "internal.storage.CreateNotificationIdempotent"
+
+	hash := n.Hash()
+
+	id, stored, err := s.c.IdempotencyKey(n.TenantID, key)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+	}
+	if err == nil {
+		if stored != hash {
+			return 0, ErrConflict
+		}
+		return id, nil
+	}
+
+	id, created, err := s.db.CreateNotificationIdempotent(n, key, hash)
+	if errors.Is(err, notification.ErrQuotaExceeded) {
+		return 0, err
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return 0, err
 	}
+	if !created {
+		id, stored, err = s.db.IdempotencyKey(n.TenantID, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return 0, err
+		}
+		if stored != hash {
+			return 0, ErrConflict
+		}
+	}
+	if id < 1 {
+		return id, ErrDontHaveID
+	}
 
-	err = s.q.Publish(v, n.Date.UnixMilli()-time.Now().UnixMilli())
+	if created {
+		s.wakeRelay()
+	}
+
+	err = s.c.AddIdempotencyKey(n.TenantID, key, id, hash)
 	if err != nil {
-		return 0, err
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
 	}
 
 	return id, nil
 }
 
-func (s *Storage) UpdateNotificationStatus(status string, id int64) error {
-	const op = "internal.storage.UpdateNotification"
+// CreateNotifications сохраняет пачку уведомлений одной транзакцией, в
+// очередь их публикует RelayOutbox
+func (s *Storage) CreateNotifications(ns []notification.Notification) ([]notification.BatchResult, error) {
+	const op = "internal.storage.CreateNotifications"
 
-	affected, err := s.db.UpdateNotificationStatus(status, id)
-	if err != nil {
+	ids, err := s.db.CreateNotifications(ns)
+	if errors.Is(err, notification.ErrQuotaExceeded) {
+		return nil, err
+	} else if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
-		return err
+		return nil, err
 	}
-	if affected == 0 {
-		return ErrNotAffected
+	if len(ids) != len(ns) {
+		return nil, ErrDontHaveID
+	}
+
+	r := make([]notification.BatchResult, len(ns))
+	for i := range ns {
+		r[i].ID = ids[i]
+	}
+	s.wakeRelay()
+
+	return r, nil
+}
+
+// UpdateNotification обновляет уведомление, при изменении содержимого или
+// времени новая версия уходит в outbox, старое сообщение отбросит sender
+func (s *Storage) UpdateNotification(tenantID, id int64, u notification.Update) (notification.Notification, error) {
+	const op = "internal.storage.UpdateNotification"
+
+	n, err := s.db.UpdateNotification(tenantID, id, u)
+	if errors.Is(err, sql.ErrNoRows) {
+		cur, err := s.db.Notification(tenantID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return n, ErrNotAffected
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return n, err
+		}
+		if cur.Status == notification.StatusCancelled {
+			return n, ErrCancelled
+		}
+		return n, ErrNotEditable
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return n, err
 	}
 
-	_, err = s.c.DeleteNotification(id)
+	_, err = s.c.DeleteNotification(tenantID, id)
 	if err != nil && !errors.Is(err, redis.Nil) {
-		return err
+		return n, err
 	}
 
-	return nil
+	if u.HasContent() {
+		s.wakeRelay()
+	}
+
+	return n, nil
 }
 
-func (s *Storage) GetNotification(id int64) (notification.Notification, error) {
+func (s *Storage) GetNotification(tenantID, id int64) (notification.Notification, error) {
 	const op = "internal.storage.GetNotification"
 
-	n, err := s.c.GetNotification(id)
+	n, err := s.c.GetNotification(tenantID, id)
 	if err != nil && !errors.Is(err, redis.Nil) {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return n, err
@@ -105,7 +261,7 @@ func (s *Storage) GetNotification(id int64) (notification.Notification, error) {
 		return n, nil
 	}
 
-	n, err = s.db.Notification(id)
+	n, err = s.db.Notification(tenantID, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return n, ErrNotFound
 	} else if err != nil {
@@ -120,14 +276,74 @@ func (s *Storage) GetNotification(id int64) (notification.Notification, error) {
 	return n, nil
 }
 
-func (s *Storage) DeleteNotification(id int64) error {
-	const op = "internal.storage.DeleteNotification"
-	_, err := s.c.DeleteNotification(id)
+func (s *Storage) Notifications(f notification.Filter) ([]notification.Notification, error) {
+	const op = "internal.storage.Notifications"
+
+	r, err := s.db.Notifications(f)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// CancelNotification отменяет pending уведомление, строка остается в базе
+func (s *Storage) CancelNotification(tenantID, id int64, reason string) error {
+	const op = "internal.storage.CancelNotification"
+
+	affected, err := s.db.CancelNotification(tenantID, id, reason)
 	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+	if affected == 0 {
+		cur, err := s.db.Notification(tenantID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotAffected
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return err
+		}
+		if cur.Status == notification.StatusCancelled {
+			return ErrCancelled
+		}
+		return ErrNotEditable
+	}
+
+	_, err = s.c.DeleteNotification(tenantID, id)
+	if err != nil && !errors.Is(err, redis.Nil) {
 		return err
 	}
 
-	affected, err := s.db.DeleteNotification(id)
+	return nil
+}
+
+// PurgeNotifications окончательно удаляет отмененные до before уведомления
+// команды
+func (s *Storage) PurgeNotifications(tenantID int64, before time.Time) (int64, error) {
+	const op = "internal.storage.PurgeNotifications"
+
+	ids, err := s.db.PurgeNotifications(tenantID, before)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	for _, id := range ids {
+		_, err = s.c.DeleteNotification(tenantID, id)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+		}
+	}
+
+	return int64(len(ids)), nil
+}
+
+// RecordDelivery сохраняет попытку доставки одному получателю
+func (s *Storage) RecordDelivery(tenantID, id int64, d notification.Delivery) error {
+	const op = "internal.storage.RecordDelivery"
+
+	affected, err := s.db.RecordDelivery(tenantID, id, d)
 	if err != nil {
 		zlog.Logger.Error().AnErr("err", err).Msg(op)
 		return err
@@ -136,5 +352,22 @@ func (s *Storage) DeleteNotification(id int64) error {
 		return ErrNotAffected
 	}
 
+	_, err = s.c.DeleteNotification(tenantID, id)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
 	return nil
 }
+
+func (s *Storage) Deliveries(tenantID, id int64) ([]notification.Delivery, error) {
+	const op = "internal.storage.Deliveries"
+
+	r, err := s.db.Deliveries(tenantID, id)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
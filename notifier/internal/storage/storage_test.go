@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/storage/memory"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// QueueMock брокер в памяти, Publish которого не работает, пока down
+type QueueMock struct {
+	*memory.Queue
+	down      bool
+	published [][]byte
+}
+
+func (q *QueueMock) Publish(val []byte, d int64) error {
+	if q.down {
+		return errors.New("connection refused")
+	}
+	q.published = append(q.published, val)
+	return nil
+}
+
+func TestStorage_CreateNotificationIdempotent(t *testing.T) {
+	q := &QueueMock{Queue: memory.NewQueue(), down: true}
+	defer q.Shutdown()
+	s := New(memory.New(), memory.NewCache(), q)
+	n := notification.Notification{
+		TenantID: tenant.DefaultID, Message: "hi", Date: time.Now().Add(time.Hour),
+		Recipients: []notification.Recipient{{
+			Channel: notification.ChannelEmail, Address: "a@b.co",
+		}},
+	}
+
+	// брокер недоступен: уведомление сохранено, но не опубликовано
+	id, err := s.CreateNotificationIdempotent(n, "k")
+	require.NoError(t, err)
+	_, err = s.relayOutbox(10)
+	require.Error(t, err)
+
+	// повтор с тем же ключом не создает новое уведомление, публикация
+	// исходного не теряется: сообщение ждет relay в outbox
+	again, err := s.CreateNotificationIdempotent(n, "k")
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+	q.down = false
+	sent, err := s.relayOutbox(10)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+
+	got := notification.Notification{}
+	require.NoError(t, got.UnmarshalBinary(q.published[0]))
+	require.Equal(t, id, got.ID)
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/template"
+	"errors"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// CreateTemplate сохраняет шаблон, занятое имя - ErrConflict
+func (s *Storage) CreateTemplate(t template.Template) (int64, error) {
+	const op = "internal.storage.CreateTemplate"
+
+	id, created, err := s.db.CreateTemplate(t)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	if !created {
+		return 0, ErrConflict
+	}
+	if id < 1 {
+		return id, ErrDontHaveID
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Template(tenantID, id int64) (template.Template, error) {
+	const op = "internal.storage.Template"
+
+	t, err := s.db.Template(tenantID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return t, err
+	}
+
+	return t, nil
+}
+
+func (s *Storage) Templates(tenantID int64) ([]template.Template, error) {
+	const op = "internal.storage.Templates"
+
+	r, err := s.db.Templates(tenantID)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// UpdateTemplate заменяет шаблон, нет шаблона - ErrNotAffected, имя занято
+// другим шаблоном - ErrConflict
+func (s *Storage) UpdateTemplate(t template.Template) (template.Template, error) {
+	const op = "internal.storage.UpdateTemplate"
+
+	r, err := s.db.UpdateTemplate(t)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = s.db.Template(t.TenantID, t.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return r, ErrNotAffected
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			return r, err
+		}
+		return r, ErrConflict
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return r, err
+	}
+
+	return r, nil
+}
+
+func (s *Storage) DeleteTemplate(tenantID, id int64) error {
+	const op = "internal.storage.DeleteTemplate"
+
+	affected, err := s.db.DeleteTemplate(tenantID, id)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotAffected
+	}
+
+	return nil
+}
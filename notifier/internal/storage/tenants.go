@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"delayednotifier/internal/entities/tenant"
+	"errors"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// CreateTenant создает команду, имя занято - ErrConflict
+func (s *Storage) CreateTenant(t tenant.Tenant) (int64, error) {
+	const op = "internal.storage.CreateTenant"
+
+	id, created, err := s.db.CreateTenant(t)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return 0, err
+	}
+	if !created {
+		return 0, ErrConflict
+	}
+	if id < 1 {
+		return id, ErrDontHaveID
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Tenant(id int64) (tenant.Tenant, error) {
+	const op = "internal.storage.Tenant"
+
+	t, err := s.db.Tenant(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return t, err
+	}
+
+	return t, nil
+}
+
+func (s *Storage) Tenants() ([]tenant.Tenant, error) {
+	const op = "internal.storage.Tenants"
+
+	r, err := s.db.Tenants()
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	return r, nil
+}
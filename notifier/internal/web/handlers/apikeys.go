@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	// TenantHeader задает команду для сервисного ключа, у остальных ключей
+	// команда берется из самого ключа
+	TenantHeader = "X-Tenant-ID"
+	// APIKeyContext ключ gin.Context, под которым лежит apikey.Key запроса
+	APIKeyContext = "api_key"
+	// TenantContext ключ gin.Context с ID команды запроса
+	TenantContext = "tenant_id"
+)
+
+type authenticator interface {
+	Authenticate(token string) (apikey.Key, error)
+}
+
+// token достает ключ из Authorization: Bearer или из X-API-Key
+func token(c *ginext.Context) string {
+	if h := c.GetHeader("Authorization"); h != "" {
+		scheme, t, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(t)
+		}
+	}
+
+	return strings.TrimSpace(c.GetHeader(APIKeyHeader))
+}
+
+// tenantID возвращает команду, которую Auth определил для запроса
+func tenantID(c *ginext.Context) int64 {
+	return c.GetInt64(TenantContext)
+}
+
+// apiKey возвращает ключ, с которым пришел запрос
+func apiKey(c *ginext.Context) apikey.Key {
+	k, _ := c.Get(APIKeyContext)
+	r, _ := k.(apikey.Key)
+	return r
+}
+
+// Auth пропускает запрос дальше только с действующим ключом, в котором
+// есть scope. Нет ключа или он неизвестен - 401, нет scope - 403.
+// Сервисный ключ работает от имени команды из заголовка X-Tenant-ID
+func Auth(s authenticator, scope string) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.Auth"
+
+		k, err := s.Authenticate(token(c))
+		if errors.Is(err, service.ErrUnauthorized) {
+			c.Header("WWW-Authenticate", `Bearer realm="notifier"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+		if !k.Has(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"api key doesn't have scope "+scope,
+			))
+			return
+		}
+
+		tid := k.TenantID
+		if k.Has(apikey.ScopeService) {
+			tid, err = strconv.ParseInt(c.GetHeader(TenantHeader), 10, 64)
+			if err != nil || tid <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, response.Error(
+					TenantHeader+" should be positive numeric value",
+				))
+				return
+			}
+		}
+
+		c.Set(APIKeyContext, k)
+		c.Set(TenantContext, tid)
+		c.Next()
+	}
+}
+
+// issueAPIKey выпускает ключ в команду tenantID от имени ключа запроса
+func issueAPIKey(c *ginext.Context, s notifyer, tenantID int64) {
+	const op = "internal.handlers.issueAPIKey"
+
+	var r request.APIKey
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"wrong data types or fields in json ",
+		))
+		return
+	}
+	r, msg := r.Validate()
+	if msg != "" {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			msg,
+		))
+		return
+	}
+
+	k, t, err := s.IssueAPIKey(apiKey(c), tenantID, r.Name, r.Scopes)
+	if errors.Is(err, service.ErrNotValidData) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			err.Error(),
+		))
+		return
+	} else if errors.Is(err, service.ErrForbidden) {
+		c.JSONP(http.StatusForbidden, response.Error(
+			err.Error(),
+		))
+		return
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return
+	}
+
+	c.JSONP(http.StatusOK, response.OK(
+		response.IssuedKey{ID: k.ID, Key: t, Scopes: k.Scopes},
+	))
+}
+
+// IssueAPIKey godoc
+// @Summary Выпустить ключ API
+// @Description Scopes: create - создание и изменение, read - чтение, cancel - отмена и удаление, admin - все scope и управление ключами, tenants - управление командами. Ключ выпускается в команду вызывающего и не может получить scope, которого у вызывающего нет. Значение ключа возвращается только в этом ответе
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key body request.APIKey true "Имя и scope ключа"
+// @Success 200 {object} response.Response{result=response.IssuedKey}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /keys [post]
+func IssueAPIKey(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		issueAPIKey(c, s, tenantID(c))
+	}
+}
+
+// ListAPIKeys godoc
+// @Summary Получить список ключей API
+// @Description Ключи показываются без значения, только с первыми символами
+// @Tags keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{result=[]apikey.Key}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /keys [get]
+func ListAPIKeys(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListAPIKeys"
+
+		r, err := s.APIKeys(tenantID(c))
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			r,
+		))
+	}
+}
+
+// RevokeAPIKey godoc
+// @Summary Отозвать ключ API
+// @Tags keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID ключа"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /keys/{id} [delete]
+func RevokeAPIKey(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.RevokeAPIKey"
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"id should be positive numeric value",
+			))
+			return
+		}
+
+		err = s.RevokeAPIKey(tenantID(c), id)
+		if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				"key not found or already revoked",
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			id,
+		))
+	}
+}
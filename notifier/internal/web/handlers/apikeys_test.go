@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuth(t *testing.T) {
+	s := &ServiceMock{
+		authF: func(token string) (apikey.Key, error) {
+			switch token {
+			case "dn_reader":
+				return apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}, TenantID: 3}, nil
+			case "dn_admin":
+				return apikey.Key{ID: 2, Scopes: []string{apikey.ScopeAdmin}, TenantID: 1}, nil
+			case "dn_service":
+				return apikey.Key{Scopes: []string{apikey.ScopeRead, apikey.ScopeService}}, nil
+			case "dn_broken":
+				return apikey.Key{}, errors.New("unknown")
+			}
+			return apikey.Key{}, fmt.Errorf("%w: unknown key", service.ErrUnauthorized)
+		},
+	}
+	tests := []struct {
+		name   string
+		scope  string
+		header string
+		value  string
+		tenant string
+		code   int
+		want   string
+	}{
+		{
+			name: "bearer", scope: apikey.ScopeRead,
+			header: "Authorization", value: "Bearer dn_reader",
+			code: http.StatusOK, want: "3",
+		},
+		{
+			name: "x-api-key", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_reader",
+			code: http.StatusOK, want: "3",
+		},
+		{
+			name: "tenant header ignored", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_reader", tenant: "7",
+			code: http.StatusOK, want: "3",
+		},
+		{
+			name: "admin has all", scope: apikey.ScopeCancel,
+			header: APIKeyHeader, value: "dn_admin",
+			code: http.StatusOK, want: "1",
+		},
+		{
+			name: "service with tenant", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_service", tenant: "7",
+			code: http.StatusOK, want: "7",
+		},
+		{
+			name: "service without tenant", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_service",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "service with bad tenant", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_service", tenant: "-1",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "no key", scope: apikey.ScopeRead,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown key", scope: apikey.ScopeRead,
+			header: "Authorization", value: "Bearer dn_other",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong scheme", scope: apikey.ScopeRead,
+			header: "Authorization", value: "Basic dn_reader",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "no scope", scope: apikey.ScopeCreate,
+			header: APIKeyHeader, value: "dn_reader",
+			code: http.StatusForbidden,
+		},
+		{
+			name: "unknown err", scope: apikey.ScopeRead,
+			header: APIKeyHeader, value: "dn_broken",
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/notify", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			if tt.tenant != "" {
+				req.Header.Set(TenantHeader, tt.tenant)
+			}
+
+			g := gin.Default()
+			g.GET("/notify", Auth(s, tt.scope), func(c *gin.Context) {
+				c.String(http.StatusOK, "%d", tenantID(c))
+			})
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Auth() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if tt.code == http.StatusOK && tt.want != rr.Body.String() {
+				t.Errorf("Auth() tenant get=%s, want %s", rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyIssuing(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				issueKF: func(name string, scopes []string) (apikey.Key, string, error) {
+					return apikey.Key{ID: 1, Name: name, Scopes: scopes}, "dn_new", nil
+				},
+			},
+			body: `{"name": "crm", "scopes": ["create", "read"]}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "no scopes",
+			s:    &ServiceMock{},
+			body: `{"name": "crm"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "service scope",
+			s:    &ServiceMock{},
+			body: `{"name": "crm", "scopes": ["service"]}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "scope not granted",
+			s: &ServiceMock{
+				issueKF: func(name string, scopes []string) (apikey.Key, string, error) {
+					return apikey.Key{}, "", fmt.Errorf(
+						"%w: can't grant scope admin", service.ErrForbidden,
+					)
+				},
+			},
+			body: `{"name": "crm", "scopes": ["admin"]}`,
+			code: http.StatusForbidden,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				issueKF: func(name string, scopes []string) (apikey.Key, string, error) {
+					return apikey.Key{}, "", errors.New("unknown")
+				},
+			},
+			body: `{"name": "crm", "scopes": ["read"]}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/keys", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/keys", IssueAPIKey(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"IssueAPIKey() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if tt.code == http.StatusOK && !strings.Contains(rr.Body.String(), "dn_new") {
+				t.Errorf("IssueAPIKey() body doesn't contain key: %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyRevoking(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		code int
+	}{
+		{name: "good", id: "1", code: http.StatusOK},
+		{name: "not found", id: "2", code: http.StatusNotFound},
+		{name: "bad id", id: "a", code: http.StatusBadRequest},
+	}
+	s := &ServiceMock{
+		revokeKF: func(id int64) error {
+			if id != 1 {
+				return service.ErrNotAffected
+			}
+			return nil
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/keys/"+tt.id, nil)
+
+			g := gin.Default()
+			g.DELETE("/keys/:id", RevokeAPIKey(s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"RevokeAPIKey() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
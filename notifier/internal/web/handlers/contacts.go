@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// applyContact подставляет зону контакта, если в запросе она не задана.
+// При ошибке ответ уже записан и возвращается false
+func applyContact(c *ginext.Context, s notifyer, r request.CreateNotification) (request.CreateNotification, bool) {
+	const op = "internal.handlers.applyContact"
+
+	if r.ContactID <= 0 || r.Timezone != "" {
+		return r, true
+	}
+
+	ct, err := s.Contact(tenantID(c), r.ContactID)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			service.ErrNotValidData.Error()+": contact not found",
+		))
+		return r, false
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return r, false
+	}
+	r.Timezone = ct.Timezone
+
+	return r, true
+}
+
+func contactID(c *ginext.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be numeric value",
+		))
+		return 0, false
+	}
+	if id <= 0 {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be positive",
+		))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// CreateContact godoc
+// @Summary Создать контакт
+// @Description Контакт хранит адреса получателя, предпочитаемый канал (telegram, email или пустой - все каналы), локаль и зону (IANA). Уведомление с contact_id отправляется по адресам контакта на момент отправки
+// @Tags contacts
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param contact body request.Contact true "Контакт"
+// @Success 200 {object} response.Response{result=int}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /contacts [post]
+func CreateContact(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.CreateContact"
+
+		var r request.Contact
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		ct, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		id, err := s.CreateContact(tenantID(c), ct)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			id,
+		))
+	}
+}
+
+// ListContacts godoc
+// @Summary Получить список контактов
+// @Tags contacts
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} response.Response{result=[]contact.Contact}
+// @Failure 500 {object} response.Response
+// @Router /contacts [get]
+func ListContacts(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListContacts"
+
+		r, err := s.Contacts(tenantID(c))
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			r,
+		))
+	}
+}
+
+// GetContact godoc
+// @Summary Получить контакт по ID
+// @Tags contacts
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID контакта"
+// @Success 200 {object} response.Response{result=contact.Contact}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /contacts/{id} [get]
+func GetContact(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.GetContact"
+
+		id, ok := contactID(c)
+		if !ok {
+			return
+		}
+
+		ct, err := s.Contact(tenantID(c), id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			ct,
+		))
+	}
+}
+
+// UpdateContact godoc
+// @Summary Изменить контакт
+// @Description Контакт заменяется целиком, новые адреса используются и для уже запланированных уведомлений
+// @Tags contacts
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID контакта"
+// @Param contact body request.Contact true "Контакт"
+// @Success 200 {object} response.Response{result=contact.Contact}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /contacts/{id} [put]
+func UpdateContact(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.UpdateContact"
+
+		id, ok := contactID(c)
+		if !ok {
+			return
+		}
+		var r request.Contact
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		ct, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+		ct.ID = id
+
+		ct, err := s.UpdateContact(tenantID(c), ct)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			ct,
+		))
+	}
+}
+
+// DeleteContact godoc
+// @Summary Удалить контакт
+// @Description Контакт с pending уведомлениями удалить нельзя
+// @Tags contacts
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID контакта"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /contacts/{id} [delete]
+func DeleteContact(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.DeleteContact"
+
+		id, ok := contactID(c)
+		if !ok {
+			return
+		}
+
+		err := s.DeleteContact(tenantID(c), id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			"successfull deleted",
+		))
+	}
+}
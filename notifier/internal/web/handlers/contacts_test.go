@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContactCreating(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				addCF: func(c contact.Contact) (int64, error) {
+					if c.Timezone != "Europe/Moscow" || c.PreferredChannel != "email" {
+						return 0, errors.New("wrong contact")
+					}
+					return 1, nil
+				},
+			},
+			body: `{"name": "Аня", "email": "a@asd.com", "preferred_channel": "email", "locale": "ru-RU", "timezone": "Europe/Moscow"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "no channels",
+			s:    &ServiceMock{},
+			body: `{"name": "Аня"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "wrong timezone",
+			s:    &ServiceMock{},
+			body: `{"name": "Аня", "email": "a@asd.com", "timezone": "Moscow"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				addCF: func(c contact.Contact) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			body: `{"name": "Аня", "email": "a@asd.com"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/contacts", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/contacts", CreateContact(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"CreateContact() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestContactUpdater(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				updateCF: func(c contact.Contact) (contact.Contact, error) {
+					if c.ID != 1 {
+						return c, errors.New("wrong id")
+					}
+					return c, nil
+				},
+			},
+			body: `{"name": "Аня", "telegram_id": "123"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				updateCF: func(c contact.Contact) (contact.Contact, error) {
+					return c, service.ErrNotAffected
+				},
+			},
+			body: `{"name": "Аня", "telegram_id": "123"}`,
+			code: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPut, "/contacts/1", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.PUT("/contacts/:id", UpdateContact(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"UpdateContact() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestContactDeleter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "good", code: http.StatusOK},
+		{name: "not found", err: service.ErrNotAffected, code: http.StatusNotFound},
+		{name: "has pending", err: service.ErrConflict, code: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/contacts/1", nil)
+			s := &ServiceMock{
+				deleteCF: func(id int64) error {
+					return tt.err
+				},
+			}
+
+			g := gin.Default()
+			g.DELETE("/contacts/:id", DeleteContact(s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"DeleteContact() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestCreatingForContact(t *testing.T) {
+	contactF := func(id int64) (contact.Contact, error) {
+		if id != 7 {
+			return contact.Contact{}, service.ErrNotFound
+		}
+		return contact.Contact{ID: 7, Email: "a@asd.com", Timezone: "Asia/Tokyo"}, nil
+	}
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "contact timezone",
+			s: &ServiceMock{
+				getCF: contactF,
+				createF: func(n notification.Notification) (int64, error) {
+					// 09:00 в Токио - 00:00 UTC
+					if n.ContactID != 7 || n.Timezone != "Asia/Tokyo" ||
+						n.Date.Hour() != 0 {
+						return 0, errors.New("contact not applied")
+					}
+					return 1, nil
+				},
+			},
+			body: `{"message": "hi", "contact_id": 7, "date": "3000-12-22T09:00:00"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "own timezone",
+			s: &ServiceMock{
+				createF: func(n notification.Notification) (int64, error) {
+					if n.Timezone != "UTC" {
+						return 0, errors.New("timezone overridden")
+					}
+					return 1, nil
+				},
+			},
+			body: `{"message": "hi", "contact_id": 7, "timezone": "UTC", "date": "3000-12-22T09:00:00"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "unknown contact",
+			s:    &ServiceMock{getCF: contactF},
+			body: `{"message": "hi", "contact_id": 8, "date": "3000-12-22T09:00:00"}`,
+			code: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/notify", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/notify", CreateNotify(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"CreateNotify() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+func deadLetterID(c *ginext.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be numeric value",
+		))
+		return 0, false
+	}
+	if id <= 0 {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be positive",
+		))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// ListDeadLetters godoc
+// @Summary Получить список DLQ
+// @Description Сообщения, которые sender не смог доставить: попытки кончились, ошибка постоянная или сообщение не разобрано. Записи всех команд, без исходных сообщений
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Produce json
+// @Param tenant_id query int false "ID команды"
+// @Param notification_id query int false "ID уведомления"
+// @Param channel query string false "канал: telegram, email, webhook"
+// @Param reason query string false "причина: unreadable, permanent_error, retries_exhausted"
+// @Param limit query int false "размер страницы, по умолчанию 20, максимум 100"
+// @Param cursor query string false "курсор следующей страницы из next_cursor"
+// @Success 200 {object} response.Response{result=response.Page{items=[]deadletter.Letter}}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters [get]
+func ListDeadLetters(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListDeadLetters"
+
+		var r request.ListDeadLetters
+		if err := c.ShouldBindQuery(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong query params",
+			))
+			return
+		}
+		f, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		ls, next, err := s.DeadLetters(f)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			response.Page{Items: ls, NextCursor: next},
+		))
+	}
+}
+
+// GetDeadLetter godoc
+// @Summary Получить запись DLQ по ID
+// @Description Вместе с разобранным уведомлением, если сообщение читается. Исходное сообщение и секрет webhook не отдаются
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {object} response.Response{result=response.DeadLetter}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters/{id} [get]
+func GetDeadLetter(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.GetDeadLetter"
+
+		id, ok := deadLetterID(c)
+		if !ok {
+			return
+		}
+
+		l, err := s.DeadLetter(id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			response.NewDeadLetter(l),
+		))
+	}
+}
+
+// deadLetterAction replay или discard одной записи из пути запроса
+func deadLetterAction(c *ginext.Context, op string, do func(id int64) error, done string) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	err := do(id)
+	if errors.Is(err, service.ErrNotValidData) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			err.Error(),
+		))
+		return
+	} else if errors.Is(err, service.ErrNotAffected) {
+		c.JSONP(http.StatusNotFound, response.Error(
+			err.Error(),
+		))
+		return
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return
+	}
+
+	c.JSONP(http.StatusOK, response.OK(
+		done,
+	))
+}
+
+// ReplayDeadLetter godoc
+// @Summary Отправить запись DLQ заново
+// @Description Сообщение сразу публикуется в очередь, запись удаляется. Уведомление уходит как повтор и получает попытки заново
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters/{id}/replay [post]
+func ReplayDeadLetter(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		deadLetterAction(c, "internal.handlers.ReplayDeadLetter", s.ReplayDeadLetter, "successfull replayed")
+	}
+}
+
+// DiscardDeadLetter godoc
+// @Summary Удалить запись DLQ без отправки
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters/{id} [delete]
+func DiscardDeadLetter(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		deadLetterAction(c, "internal.handlers.DiscardDeadLetter", s.DiscardDeadLetter, "successfull discarded")
+	}
+}
+
+// deadLetterBulk массовый replay или discard, результат для каждой записи
+func deadLetterBulk(c *ginext.Context, op string, do func(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error)) {
+	var r request.DeadLetterBulk
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"wrong data types or fields in json ",
+		))
+		return
+	}
+	ids, f, msg := r.Validate()
+	if msg != "" {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			msg,
+		))
+		return
+	}
+
+	res, err := do(ids, f)
+	if errors.Is(err, service.ErrNotValidData) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			err.Error(),
+		))
+		return
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return
+	}
+
+	items := make([]response.DeadLetterItem, len(res))
+	for i, rs := range res {
+		items[i].ID = rs.ID
+		switch {
+		case rs.Err == nil:
+		case errors.Is(rs.Err, service.ErrNotValidData),
+			errors.Is(rs.Err, service.ErrNotAffected):
+			items[i].Error = rs.Err.Error()
+		default:
+			zlog.Logger.Error().AnErr("err", rs.Err).Int64("id", rs.ID).Msg(op)
+			items[i].Error = "internal server error on our service"
+		}
+	}
+
+	c.JSONP(http.StatusOK, response.OK(
+		items,
+	))
+}
+
+// ReplayDeadLetters godoc
+// @Summary Отправить заново несколько записей DLQ
+// @Description Записи задаются списком ids или all с необязательным фильтром, за раз обрабатывается до 1000 записей. Результат возвращается для каждой записи
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param bulk body request.DeadLetterBulk true "Записи"
+// @Success 200 {object} response.Response{result=[]response.DeadLetterItem}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters/replay [post]
+func ReplayDeadLetters(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		deadLetterBulk(c, "internal.handlers.ReplayDeadLetters", s.ReplayDeadLetters)
+	}
+}
+
+// DiscardDeadLetters godoc
+// @Summary Удалить несколько записей DLQ без отправки
+// @Description Записи задаются списком ids или all с необязательным фильтром, за раз обрабатывается до 1000 записей. Результат возвращается для каждой записи
+// @Tags deadletters
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param bulk body request.DeadLetterBulk true "Записи"
+// @Success 200 {object} response.Response{result=[]response.DeadLetterItem}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /deadletters/discard [post]
+func DiscardDeadLetters(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		deadLetterBulk(c, "internal.handlers.DiscardDeadLetters", s.DiscardDeadLetters)
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/deadletter"
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeadLettersListing(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     notifyer
+		query string
+		code  int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				listDF: func(f deadletter.Filter) ([]deadletter.Letter, string, error) {
+					if f.TenantID != 2 || f.Reason != deadletter.ReasonExhausted ||
+						f.After != 10 || f.Limit != 5 {
+						return nil, "", errors.New("wrong filter")
+					}
+					return []deadletter.Letter{{ID: 11}}, "11", nil
+				},
+			},
+			query: "?tenant_id=2&reason=retries_exhausted&cursor=10&limit=5",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "wrong reason",
+			s:     &ServiceMock{},
+			query: "?reason=lost",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "wrong cursor",
+			s:     &ServiceMock{},
+			query: "?cursor=abc",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				listDF: func(f deadletter.Filter) ([]deadletter.Letter, string, error) {
+					return nil, "", errors.New("unknown")
+				},
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodGet, "/deadletters"+tt.query, nil,
+			)
+
+			g := gin.Default()
+			g.GET("/deadletters", ListDeadLetters(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"ListDeadLetters() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestDeadLetterGetter(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		id   string
+		code int
+		want string
+		hide string
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				getDF: func(id int64) (deadletter.Letter, error) {
+					return deadletter.Letter{ID: id, Payload: []byte("raw")}, nil
+				},
+			},
+			id:   "1",
+			code: http.StatusOK,
+			want: `"id":1`,
+			hide: "payload",
+		},
+		{
+			name: "webhook secret",
+			s: &ServiceMock{
+				getDF: func(id int64) (deadletter.Letter, error) {
+					v, err := notification.Notification{
+						ID: 7, Message: "hi", WebhookSecret: "s3cret",
+					}.MarshalBinary()
+					return deadletter.Letter{ID: id, Payload: v}, err
+				},
+			},
+			id:   "1",
+			code: http.StatusOK,
+			want: `"notification":{"ID":7`,
+			hide: "s3cret",
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				getDF: func(id int64) (deadletter.Letter, error) {
+					return deadletter.Letter{}, service.ErrNotFound
+				},
+			},
+			id:   "1",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "wrong id",
+			s:    &ServiceMock{},
+			id:   "abc",
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodGet, "/deadletters/"+tt.id, nil,
+			)
+
+			g := gin.Default()
+			g.GET("/deadletters/:id", GetDeadLetter(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"GetDeadLetter() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("GetDeadLetter() body %s, want %s", rr.Body.String(), tt.want)
+			}
+			if tt.hide != "" && strings.Contains(rr.Body.String(), tt.hide) {
+				t.Errorf("GetDeadLetter() body %s, has %s", rr.Body.String(), tt.hide)
+			}
+		})
+	}
+}
+
+func TestDeadLetterReplaying(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		id   string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				replayDF: func(id int64) error {
+					return nil
+				},
+			},
+			id:   "1",
+			code: http.StatusOK,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				replayDF: func(id int64) error {
+					return service.ErrNotAffected
+				},
+			},
+			id:   "1",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "negative id",
+			s:    &ServiceMock{},
+			id:   "-1",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				replayDF: func(id int64) error {
+					return errors.New("unknown")
+				},
+			},
+			id:   "1",
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/deadletters/"+tt.id+"/replay", nil,
+			)
+
+			g := gin.Default()
+			g.POST("/deadletters/:id/replay", ReplayDeadLetter(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"ReplayDeadLetter() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestDeadLettersBulk(t *testing.T) {
+	results := func(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error) {
+		if len(ids) == 0 && f.Channel != "email" {
+			return nil, errors.New("wrong filter")
+		}
+		return []service.DeadLetterResult{
+			{ID: 1},
+			{ID: 2, Err: service.ErrNotAffected},
+			{ID: 3, Err: errors.New("unknown")},
+		}, nil
+	}
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+		want string
+	}{
+		{
+			name: "ids",
+			s:    &ServiceMock{discardBF: results},
+			body: `{"ids": [1, 2, 3]}`,
+			code: http.StatusOK,
+			want: `{"id":3,"error":"internal server error on our service"}`,
+		},
+		{
+			name: "all by filter",
+			s:    &ServiceMock{discardBF: results},
+			body: `{"all": true, "channel": "email"}`,
+			code: http.StatusOK,
+			want: `{"id":1}`,
+		},
+		{
+			name: "ids and all",
+			s:    &ServiceMock{},
+			body: `{"ids": [1], "all": true}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "nothing",
+			s:    &ServiceMock{},
+			body: `{}`,
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/deadletters/discard", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/deadletters/discard", DiscardDeadLetters(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"DiscardDeadLetters() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("DiscardDeadLetters() body %s, want %s", rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,77 @@
 package handlers
 
 import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
 	"delayednotifier/internal/entities/request"
 	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
 	"delayednotifier/internal/service"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wb-go/wbf/ginext"
 	"github.com/wb-go/wbf/zlog"
 )
 
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type notifyer interface {
-	CreateNotification(n notification.Notification) (int64, error)
-	Notification(id int64) (notification.Notification, error)
-	DeleteNotification(id int64) error
-	UpdateNotificationStatus(status string, id int64) error
+	CreateNotification(tenantID int64, n notification.Notification) (int64, error)
+	CreateNotifications(tenantID int64, ns []notification.Notification) ([]notification.BatchResult, error)
+	CreateNotificationIdempotent(tenantID int64, n notification.Notification, key string) (int64, error)
+	Notification(tenantID, id int64) (notification.Notification, error)
+	Notifications(tenantID int64, f notification.Filter) ([]notification.Notification, string, error)
+	CancelNotification(tenantID, id int64, reason string) error
+	PurgeNotifications(tenantID int64, before time.Time) (int64, error)
+	UpdateNotification(tenantID, id int64, u notification.Update) error
+	Deliveries(tenantID, id int64) ([]notification.Delivery, error)
+
+	CreateSeries(tenantID int64, sr series.Series, n notification.Notification) (int64, int64, error)
+	Series(tenantID, id int64) (series.Series, error)
+	SeriesOccurrences(tenantID, id int64, f notification.Filter) ([]notification.Notification, string, error)
+	UpdateSeriesStatus(tenantID, id int64, status string) (series.Series, error)
+
+	CreateTemplate(tenantID int64, t template.Template) (int64, error)
+	Template(tenantID, id int64) (template.Template, error)
+	Templates(tenantID int64) ([]template.Template, error)
+	UpdateTemplate(tenantID int64, t template.Template) (template.Template, error)
+	DeleteTemplate(tenantID, id int64) error
+	ApplyTemplate(tenantID int64, n notification.Notification, templateID int64, params map[string]any) (notification.Notification, error)
+
+	CreateContact(tenantID int64, c contact.Contact) (int64, error)
+	Contact(tenantID, id int64) (contact.Contact, error)
+	Contacts(tenantID int64) ([]contact.Contact, error)
+	UpdateContact(tenantID int64, c contact.Contact) (contact.Contact, error)
+	DeleteContact(tenantID, id int64) error
+
+	Authenticate(token string) (apikey.Key, error)
+	IssueAPIKey(issuer apikey.Key, tenantID int64, name string, scopes []string) (apikey.Key, string, error)
+	APIKeys(tenantID int64) ([]apikey.Key, error)
+	RevokeAPIKey(tenantID, id int64) error
+
+	CreateTenant(name string) (int64, error)
+	Tenant(id int64) (tenant.Tenant, error)
+	Tenants() ([]tenant.Tenant, error)
+
+	DeadLetters(f deadletter.Filter) ([]deadletter.Letter, string, error)
+	Overdue(f notification.OverdueFilter) ([]notification.Overdue, string, error)
+	DeadLetter(id int64) (deadletter.Letter, error)
+	ReplayDeadLetter(id int64) error
+	DiscardDeadLetter(id int64) error
+	ReplayDeadLetters(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error)
+	DiscardDeadLetters(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error)
+
+	Allow(keyID int64) (time.Duration, error)
+	AllowIP(ip string) (time.Duration, error)
 }
 
 // Main godoc
@@ -35,13 +88,18 @@ func Main(s notifyer) gin.HandlerFunc {
 
 // CreateNotify godoc
 // @Summary Создать уведомление
-// @Description Создание нового уведомления в очереди, date: RFC3339 UTC
+// @Description Создание нового уведомления в очереди, получатели: telegram_id/email, списки telegram_ids/emails и/или contact_id (адреса контакта берутся при отправке, его зона - зона по умолчанию). date: RFC3339, без смещения трактуется в зоне timezone (IANA, по умолчанию UTC). Вместо message можно передать template_id и params, текст рендерится по шаблону при создании. Если задано recurrence (cron из 5 полей), создается серия и возвращаются series_id и id первого срабатывания, date для серии необязательна
 // @Tags notifications
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param notify body request.CreateNotify true "Данные уведомления"
+// @Param Idempotency-Key header string false "ключ идемпотентности, повтор с тем же ключом вернет исходный id"
+// @Param notify body request.CreateNotification true "Данные уведомления"
 // @Success 200 {object} response.Response
+// @Success 200 {object} response.Response{result=response.SeriesCreated}
 // @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure 504 {object} response.Response
 // @Router /notify [post]
@@ -56,6 +114,15 @@ func CreateNotify(s notifyer) gin.HandlerFunc {
 			))
 			return
 		}
+		r, ok := applyContact(c, s, r)
+		if !ok {
+			return
+		}
+
+		if r.Recurring() {
+			createSeries(c, s, r)
+			return
+		}
 
 		n, msg := r.Validate()
 		if msg != "" {
@@ -64,13 +131,35 @@ func CreateNotify(s notifyer) gin.HandlerFunc {
 			))
 			return
 		}
+		n, ok = applyTemplate(c, s, r, n)
+		if !ok {
+			return
+		}
 
-		id, err := s.CreateNotification(n)
+		var (
+			id  int64
+			err error
+		)
+		if key := c.GetHeader(IdempotencyKeyHeader); key != "" {
+			id, err = s.CreateNotificationIdempotent(tenantID(c), n, key)
+		} else {
+			id, err = s.CreateNotification(tenantID(c), n)
+		}
 		if errors.Is(err, service.ErrNotValidData) {
 			c.JSONP(http.StatusServiceUnavailable, response.Error(
 				err.Error(),
 			))
 			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSONP(http.StatusTooManyRequests, response.Error(
+				err.Error(),
+			))
+			return
 		} else if err != nil {
 			zlog.Logger.Error().AnErr("err", err).Msg(op)
 			c.JSONP(http.StatusInternalServerError, response.Error(
@@ -85,14 +174,120 @@ func CreateNotify(s notifyer) gin.HandlerFunc {
 	}
 }
 
+// CreateNotifyBatch godoc
+// @Summary Создать пачку уведомлений
+// @Description Создание нескольких уведомлений одним запросом, результат возвращается для каждого элемента, date: RFC3339, без смещения трактуется в зоне timezone
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param notify body []request.CreateNotification true "Список уведомлений"
+// @Success 200 {object} response.Response{result=[]response.BatchItem}
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /notify/batch [post]
+func CreateNotifyBatch(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.CreateNotifyBatch"
+
+		var r []request.CreateNotification
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		if len(r) == 0 || len(r) > request.MaxBatchSize {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				fmt.Sprintf("batch size should be in range 1..%d", request.MaxBatchSize),
+			))
+			return
+		}
+
+		items := make([]response.BatchItem, len(r))
+		ns := make([]notification.Notification, 0, len(r))
+		idx := make([]int, 0, len(r))
+		for i := range r {
+			items[i].Index = i
+			if r[i].Recurring() {
+				items[i].Error = "recurrence isn't supported in batch"
+				continue
+			}
+			if r[i].ContactID > 0 && r[i].Timezone == "" {
+				ct, err := s.Contact(tenantID(c), r[i].ContactID)
+				if errors.Is(err, service.ErrNotFound) {
+					items[i].Error = service.ErrNotValidData.Error() + ": contact not found"
+					continue
+				} else if err != nil {
+					zlog.Logger.Error().AnErr("err", err).Msg(op)
+					items[i].Error = "internal server error on our service"
+					continue
+				}
+				r[i].Timezone = ct.Timezone
+			}
+			n, msg := r[i].Validate()
+			if msg != "" {
+				items[i].Error = msg
+				continue
+			}
+			if r[i].TemplateID != 0 {
+				var err error
+				n, err = s.ApplyTemplate(tenantID(c), n, r[i].TemplateID, r[i].Params)
+				if errors.Is(err, service.ErrNotValidData) {
+					items[i].Error = err.Error()
+					continue
+				} else if err != nil {
+					zlog.Logger.Error().AnErr("err", err).Msg(op)
+					items[i].Error = "internal server error on our service"
+					continue
+				}
+			}
+			ns = append(ns, n)
+			idx = append(idx, i)
+		}
+
+		if len(ns) != 0 {
+			res, err := s.CreateNotifications(tenantID(c), ns)
+			if errors.Is(err, service.ErrQuotaExceeded) {
+				c.JSONP(http.StatusTooManyRequests, response.Error(
+					err.Error(),
+				))
+				return
+			} else if err != nil {
+				zlog.Logger.Error().AnErr("err", err).Msg(op)
+				c.JSONP(http.StatusInternalServerError, response.Error(
+					"internal server error on our service",
+				))
+				return
+			}
+			for i, v := range res {
+				item := &items[idx[i]]
+				item.ID = v.ID
+				if errors.Is(v.Err, service.ErrNotValidData) {
+					item.Error = v.Err.Error()
+				} else if v.Err != nil {
+					zlog.Logger.Error().AnErr("err", v.Err).Msg(op)
+					item.Error = "internal server error on our service"
+				}
+			}
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			items,
+		))
+	}
+}
+
 // GetNotify godoc
 // @Summary Получить уведомление по ID
-// @Description Получение информации о конкретном уведомлении
+// @Description Получение информации о конкретном уведомлении, Date в UTC, LocalDate в зоне Timezone, Recipients - получатели со статусом доставки (pending, retrying, sent, failed), Deliveries - история попыток. Status после взятия sender: sending, sent, partially_failed или failed
 // @Tags notifications
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "ID уведомления"
-// @Success 200 {object} response.Response
+// @Success 200 {object} response.Response{result=response.Notification}
 // @Failure 400 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -117,7 +312,7 @@ func GetNotify(s notifyer) gin.HandlerFunc {
 			return
 		}
 
-		n, err := s.Notification(id)
+		n, err := s.Notification(tenantID(c), id)
 		if errors.Is(err, service.ErrNotValidData) {
 			c.JSONP(http.StatusServiceUnavailable, response.Error(
 				err.Error(),
@@ -136,28 +331,105 @@ func GetNotify(s notifyer) gin.HandlerFunc {
 			return
 		}
 
+		ds, err := s.Deliveries(tenantID(c), id)
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+		r := response.NewNotification(n)
+		r.Deliveries = ds
+
 		c.JSONP(http.StatusOK, response.OK(
-			n,
+			r,
+		))
+	}
+}
+
+// ListNotify godoc
+// @Summary Получить список уведомлений
+// @Description Поиск уведомлений по фильтрам с курсорной пагинацией, from/to: RFC3339
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Param status query string false "pending || sending || sent || partially_failed || failed || cancelled"
+// @Param channel query string false "telegram || email"
+// @Param recipient query string false "telegram_id или email получателя"
+// @Param text query string false "подстрока текста уведомления"
+// @Param from query string false "dt не раньше"
+// @Param to query string false "dt не позже"
+// @Param sort query string false "dt || id"
+// @Param order query string false "asc || desc"
+// @Param limit query int false "размер страницы (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "курсор следующей страницы"
+// @Success 200 {object} response.Response{result=response.Page}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /notify [get]
+func ListNotify(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListNotify"
+
+		var r request.ListNotifications
+		if err := c.ShouldBindQuery(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong query params",
+			))
+			return
+		}
+		f, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		ns, next, err := s.Notifications(tenantID(c), f)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			response.Page{
+				Items: response.NewNotifications(ns), NextCursor: next,
+			},
 		))
 	}
 }
 
 // DeleteNotify godoc
-// @Summary удалить уведомление по ID
-// @Description удаление конкретного уведомления из очереди
+// @Summary отменить уведомление по ID
+// @Description перевод pending уведомления в статус cancelled, запись остается до очистки
 // @Tags notifications
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "ID уведомления"
+// @Param reason query string false "причина отмены"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 410 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure 503 {object} response.Response
 // @Router /notify/{id} [delete]
 func DeleteNotify(s notifyer) gin.HandlerFunc {
 	return func(c *ginext.Context) {
-		const op = "internal.handlers.GetNotify"
+		const op = "internal.handlers.DeleteNotify"
 
 		idTmp := c.Param("id")
 		id, err := strconv.ParseInt(idTmp, 10, 64)
@@ -174,7 +446,7 @@ func DeleteNotify(s notifyer) gin.HandlerFunc {
 			return
 		}
 
-		err = s.DeleteNotification(id)
+		err = s.CancelNotification(tenantID(c), id, c.Query("reason"))
 		if errors.Is(err, service.ErrNotValidData) {
 			c.JSONP(http.StatusServiceUnavailable, response.Error(
 				err.Error(),
@@ -185,6 +457,68 @@ func DeleteNotify(s notifyer) gin.HandlerFunc {
 				err.Error(),
 			))
 			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrCancelled) {
+			c.JSONP(http.StatusGone, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			"successfull cancelled",
+		))
+	}
+}
+
+// PurgeNotify godoc
+// @Summary удалить отмененные уведомления
+// @Description окончательное удаление уведомлений, отмененных раньше before (RFC3339)
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param purge body request.PurgeNotifications true "Граница времени отмены"
+// @Success 200 {object} response.Response{result=int}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /notify/purge [post]
+func PurgeNotify(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.PurgeNotify"
+
+		var r request.PurgeNotifications
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		before, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		n, err := s.PurgeNotifications(tenantID(c), before)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
 		} else if err != nil {
 			zlog.Logger.Error().AnErr("err", err).Msg(op)
 			c.JSONP(http.StatusInternalServerError, response.Error(
@@ -194,22 +528,25 @@ func DeleteNotify(s notifyer) gin.HandlerFunc {
 		}
 
 		c.JSONP(http.StatusOK, response.OK(
-			"successfull deleted",
+			n,
 		))
 	}
 }
 
 // Update godoc
-// @Summary обновить статус по ID
-// @Description обновить конкретное уведомление из очереди
+// @Summary обновить уведомление по ID
+// @Description обновить текст, время или получателей pending уведомления, version - ожидаемая версия. Статус выводится из доставки, запрос со status отклоняется
 // @Tags notifications
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "ID уведомления"
-// @Param status body string true "complete || pending"
+// @Param notify body request.UpdateNotification true "Изменения уведомления"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 410 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure 503 {object} response.Response
 // @Router /notify/{id} [patch]
@@ -238,7 +575,7 @@ func UpdateNotify(s notifyer) gin.HandlerFunc {
 			))
 			return
 		}
-		status, msg := r.Validate()
+		u, msg := r.Validate()
 		if msg != "" {
 			c.JSONP(http.StatusBadRequest, response.Error(
 				msg,
@@ -246,7 +583,7 @@ func UpdateNotify(s notifyer) gin.HandlerFunc {
 			return
 		}
 
-		err = s.UpdateNotificationStatus(status, id)
+		err = s.UpdateNotification(tenantID(c), id, u)
 		if errors.Is(err, service.ErrNotValidData) {
 			c.JSONP(http.StatusServiceUnavailable, response.Error(
 				err.Error(),
@@ -257,6 +594,16 @@ func UpdateNotify(s notifyer) gin.HandlerFunc {
 				err.Error(),
 			))
 			return
+		} else if errors.Is(err, service.ErrCancelled) {
+			c.JSONP(http.StatusGone, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
 		} else if err != nil {
 			zlog.Logger.Error().AnErr("err", err).Msg(op)
 			c.JSONP(http.StatusInternalServerError, response.Error(
@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/contact"
+	"delayednotifier/internal/entities/deadletter"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/entities/tenant"
 	"delayednotifier/internal/service"
 	"errors"
 	"fmt"
@@ -11,31 +17,219 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ServiceMock struct {
 	createF func(n notification.Notification) (int64, error)
+	batchF  func(ns []notification.Notification) ([]notification.BatchResult, error)
+	idemF   func(n notification.Notification, key string) (int64, error)
 	getF    func(id int64) (notification.Notification, error)
-	deleteF func(id int64) error
-	updateF func(status string, id int64) error
+	listF   func(f notification.Filter) ([]notification.Notification, string, error)
+	cancelF func(id int64, reason string) error
+	purgeF  func(before time.Time) (int64, error)
+	updateF func(id int64, u notification.Update) error
+	dlvsF   func(id int64) ([]notification.Delivery, error)
+
+	createSF    func(sr series.Series, n notification.Notification) (int64, int64, error)
+	seriesF     func(id int64) (series.Series, error)
+	occurrenceF func(id int64, f notification.Filter) ([]notification.Notification, string, error)
+	sStatusF    func(id int64, status string) (series.Series, error)
+
+	createTF func(t template.Template) (int64, error)
+	getTF    func(id int64) (template.Template, error)
+	listTF   func() ([]template.Template, error)
+	updateTF func(t template.Template) (template.Template, error)
+	deleteTF func(id int64) error
+	applyTF  func(n notification.Notification, templateID int64, params map[string]any) (notification.Notification, error)
+
+	addCF    func(c contact.Contact) (int64, error)
+	getCF    func(id int64) (contact.Contact, error)
+	listCF   func() ([]contact.Contact, error)
+	updateCF func(c contact.Contact) (contact.Contact, error)
+	deleteCF func(id int64) error
+
+	authF    func(token string) (apikey.Key, error)
+	issueKF  func(name string, scopes []string) (apikey.Key, string, error)
+	listKF   func() ([]apikey.Key, error)
+	revokeKF func(id int64) error
+
+	createTnF func(name string) (int64, error)
+	getTnF    func(id int64) (tenant.Tenant, error)
+	listTnF   func() ([]tenant.Tenant, error)
+
+	listDF    func(f deadletter.Filter) ([]deadletter.Letter, string, error)
+	overF     func(f notification.OverdueFilter) ([]notification.Overdue, string, error)
+	getDF     func(id int64) (deadletter.Letter, error)
+	replayDF  func(id int64) error
+	discardDF func(id int64) error
+	replayBF  func(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error)
+	discardBF func(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error)
+
+	allowF   func(keyID int64) (time.Duration, error)
+	allowIPF func(ip string) (time.Duration, error)
 }
 
-func (sm *ServiceMock) CreateNotification(n notification.Notification) (int64, error) {
+func (sm *ServiceMock) CreateNotification(_ int64, n notification.Notification) (int64, error) {
 	return sm.createF(n)
 }
 
-func (sm *ServiceMock) Notification(id int64) (notification.Notification, error) {
+func (sm *ServiceMock) CreateNotifications(_ int64, ns []notification.Notification) ([]notification.BatchResult, error) {
+	return sm.batchF(ns)
+}
+
+func (sm *ServiceMock) CreateNotificationIdempotent(_ int64, n notification.Notification, key string) (int64, error) {
+	return sm.idemF(n, key)
+}
+
+func (sm *ServiceMock) Notification(_, id int64) (notification.Notification, error) {
 	return sm.getF(id)
 }
 
-func (sm *ServiceMock) DeleteNotification(id int64) error {
-	return sm.deleteF(id)
+func (sm *ServiceMock) Notifications(_ int64, f notification.Filter) ([]notification.Notification, string, error) {
+	return sm.listF(f)
+}
+
+func (sm *ServiceMock) CancelNotification(_, id int64, reason string) error {
+	return sm.cancelF(id, reason)
+}
+
+func (sm *ServiceMock) PurgeNotifications(_ int64, before time.Time) (int64, error) {
+	return sm.purgeF(before)
+}
+
+func (sm *ServiceMock) UpdateNotification(_, id int64, u notification.Update) error {
+	return sm.updateF(id, u)
+}
+
+func (sm *ServiceMock) Deliveries(_, id int64) ([]notification.Delivery, error) {
+	return sm.dlvsF(id)
+}
+
+func (sm *ServiceMock) CreateSeries(_ int64, sr series.Series, n notification.Notification) (int64, int64, error) {
+	return sm.createSF(sr, n)
+}
+
+func (sm *ServiceMock) Series(_, id int64) (series.Series, error) {
+	return sm.seriesF(id)
+}
+
+func (sm *ServiceMock) SeriesOccurrences(_, id int64, f notification.Filter) ([]notification.Notification, string, error) {
+	return sm.occurrenceF(id, f)
+}
+
+func (sm *ServiceMock) UpdateSeriesStatus(_, id int64, status string) (series.Series, error) {
+	return sm.sStatusF(id, status)
+}
+
+func (sm *ServiceMock) CreateTemplate(_ int64, t template.Template) (int64, error) {
+	return sm.createTF(t)
+}
+
+func (sm *ServiceMock) Template(_, id int64) (template.Template, error) {
+	return sm.getTF(id)
+}
+
+func (sm *ServiceMock) Templates(_ int64) ([]template.Template, error) {
+	return sm.listTF()
+}
+
+func (sm *ServiceMock) UpdateTemplate(_ int64, t template.Template) (template.Template, error) {
+	return sm.updateTF(t)
+}
+
+func (sm *ServiceMock) DeleteTemplate(_, id int64) error {
+	return sm.deleteTF(id)
+}
+
+func (sm *ServiceMock) ApplyTemplate(_ int64, n notification.Notification, templateID int64, params map[string]any) (notification.Notification, error) {
+	return sm.applyTF(n, templateID, params)
+}
+
+func (sm *ServiceMock) CreateContact(_ int64, c contact.Contact) (int64, error) {
+	return sm.addCF(c)
+}
+
+func (sm *ServiceMock) Contact(_, id int64) (contact.Contact, error) {
+	return sm.getCF(id)
+}
+
+func (sm *ServiceMock) Contacts(_ int64) ([]contact.Contact, error) {
+	return sm.listCF()
+}
+
+func (sm *ServiceMock) UpdateContact(_ int64, c contact.Contact) (contact.Contact, error) {
+	return sm.updateCF(c)
+}
+
+func (sm *ServiceMock) DeleteContact(_, id int64) error {
+	return sm.deleteCF(id)
+}
+
+func (sm *ServiceMock) Authenticate(token string) (apikey.Key, error) {
+	return sm.authF(token)
+}
+
+func (sm *ServiceMock) IssueAPIKey(_ apikey.Key, _ int64, name string, scopes []string) (apikey.Key, string, error) {
+	return sm.issueKF(name, scopes)
+}
+
+func (sm *ServiceMock) APIKeys(_ int64) ([]apikey.Key, error) {
+	return sm.listKF()
+}
+
+func (sm *ServiceMock) RevokeAPIKey(_, id int64) error {
+	return sm.revokeKF(id)
+}
+
+func (sm *ServiceMock) CreateTenant(name string) (int64, error) {
+	return sm.createTnF(name)
+}
+
+func (sm *ServiceMock) Tenant(id int64) (tenant.Tenant, error) {
+	return sm.getTnF(id)
+}
+
+func (sm *ServiceMock) Tenants() ([]tenant.Tenant, error) {
+	return sm.listTnF()
+}
+
+func (sm *ServiceMock) DeadLetters(f deadletter.Filter) ([]deadletter.Letter, string, error) {
+	return sm.listDF(f)
+}
+
+func (sm *ServiceMock) Overdue(f notification.OverdueFilter) ([]notification.Overdue, string, error) {
+	return sm.overF(f)
+}
+
+func (sm *ServiceMock) DeadLetter(id int64) (deadletter.Letter, error) {
+	return sm.getDF(id)
+}
+
+func (sm *ServiceMock) ReplayDeadLetter(id int64) error {
+	return sm.replayDF(id)
+}
+
+func (sm *ServiceMock) DiscardDeadLetter(id int64) error {
+	return sm.discardDF(id)
+}
+
+func (sm *ServiceMock) ReplayDeadLetters(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error) {
+	return sm.replayBF(ids, f)
 }
 
-func (sm *ServiceMock) UpdateNotificationStatus(status string, id int64) error {
-	return sm.updateF(status, id)
+func (sm *ServiceMock) DiscardDeadLetters(ids []int64, f deadletter.Filter) ([]service.DeadLetterResult, error) {
+	return sm.discardBF(ids, f)
+}
+
+func (sm *ServiceMock) Allow(keyID int64) (time.Duration, error) {
+	return sm.allowF(keyID)
+}
+
+func (sm *ServiceMock) AllowIP(ip string) (time.Duration, error) {
+	return sm.allowIPF(ip)
 }
 
 func TestMain(t *testing.T) {
@@ -110,6 +304,18 @@ func TestCreating(t *testing.T) {
 			body: `{"message": "hi", "telegram_id": "123", "date": "2000-12-22T15:00:00.000Z"}`,
 			code: http.StatusOK,
 		},
+		{
+			name: "quota exceeded",
+			args: args{
+				s: &ServiceMock{
+					createF: func(n notification.Notification) (int64, error) {
+						return 0, fmt.Errorf("%w: 10 of 10", service.ErrQuotaExceeded)
+					},
+				},
+			},
+			body: `{"message": "hi", "telegram_id": "123", "date": "2000-12-22T15:00:00.000Z"}`,
+			code: http.StatusTooManyRequests,
+		},
 		{
 			name: "empty body",
 			args: args{
@@ -197,6 +403,188 @@ func TestCreating(t *testing.T) {
 	}
 }
 
+func TestIdempotentCreating(t *testing.T) {
+	body := `{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"}`
+	tests := []struct {
+		name string
+		s    notifyer
+		key  string
+		code int
+	}{
+		{
+			name: "without key",
+			s: &ServiceMock{
+				createF: func(n notification.Notification) (int64, error) {
+					return 1, nil
+				},
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "with key",
+			s: &ServiceMock{
+				idemF: func(n notification.Notification, key string) (int64, error) {
+					if key != "abc" {
+						return 0, errors.New("wrong key")
+					}
+					return 1, nil
+				},
+			},
+			key:  "abc",
+			code: http.StatusOK,
+		},
+		{
+			name: "key reused with another body",
+			s: &ServiceMock{
+				idemF: func(n notification.Notification, key string) (int64, error) {
+					return 0, service.ErrConflict
+				},
+			},
+			key:  "abc",
+			code: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/main", strings.NewReader(body),
+			)
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+
+			g := gin.Default()
+			g.POST("/main", CreateNotify(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestBatchCreating(t *testing.T) {
+	type args struct {
+		s notifyer
+	}
+	tests := []struct {
+		name string
+		args
+		body     string
+		code     int
+		wantErrs int
+	}{
+		{
+			name: "good",
+			args: args{
+				s: &ServiceMock{
+					batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+						r := make([]notification.BatchResult, len(ns))
+						for i := range r {
+							r[i].ID = int64(i + 1)
+						}
+						return r, nil
+					},
+				},
+			},
+			body: `[{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"},
+				{"message": "hi", "email": "asd@asd.com", "date": "3000-12-22T15:00:00.000Z"}]`,
+			code: http.StatusOK,
+		},
+		{
+			name: "partial failure",
+			args: args{
+				s: &ServiceMock{
+					batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+						return []notification.BatchResult{
+							{ID: 1},
+							{Err: service.ErrNotValidData},
+						}, nil
+					},
+				},
+			},
+			body: `[{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"},
+				{"message": "", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"},
+				{"message": "hi", "telegram_id": "123", "date": "1000-12-22T15:00:00.000Z"}]`,
+			code:     http.StatusOK,
+			wantErrs: 2,
+		},
+		{
+			name: "recurrence in batch",
+			args: args{
+				s: &ServiceMock{
+					batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+						return []notification.BatchResult{{ID: 1}}, nil
+					},
+				},
+			},
+			body: `[{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"},
+				{"message": "hi", "telegram_id": "123", "recurrence": "@daily"}]`,
+			code:     http.StatusOK,
+			wantErrs: 1,
+		},
+		{
+			name: "empty batch",
+			args: args{
+				s: &ServiceMock{},
+			},
+			body: `[]`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "bad json",
+			args: args{
+				s: &ServiceMock{},
+			},
+			body: `{"message": "hi"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "internal error",
+			args: args{
+				s: &ServiceMock{
+					batchF: func(ns []notification.Notification) ([]notification.BatchResult, error) {
+						return nil, errors.New("unknown")
+					},
+				},
+			},
+			body: `[{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00.000Z"}]`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/main", strings.NewReader(tt.body),
+			)
+			h := CreateNotifyBatch(tt.s)
+
+			g := gin.Default()
+			g.POST("/main", h)
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			if got := strings.Count(rr.Body.String(), `"error"`); got != tt.wantErrs {
+				t.Errorf("CreateNotifyBatch() item errors get=%d, want %d", got, tt.wantErrs)
+			}
+		})
+	}
+}
+
 func TestGetter(t *testing.T) {
 	type args struct {
 		s notifyer
@@ -214,11 +602,32 @@ func TestGetter(t *testing.T) {
 					getF: func(id int64) (notification.Notification, error) {
 						return notification.Notification{}, nil
 					},
+					dlvsF: func(id int64) ([]notification.Delivery, error) {
+						return []notification.Delivery{{
+							Channel: notification.ChannelEmail, Address: "a@asd.com",
+							Status: notification.RecipientSent,
+						}}, nil
+					},
 				},
 			},
 			code: http.StatusOK,
 			id:   "1",
 		},
+		{
+			name: "deliveries err",
+			args: args{
+				s: &ServiceMock{
+					getF: func(id int64) (notification.Notification, error) {
+						return notification.Notification{}, nil
+					},
+					dlvsF: func(id int64) ([]notification.Delivery, error) {
+						return nil, errors.New("unknown")
+					},
+				},
+			},
+			code: http.StatusInternalServerError,
+			id:   "1",
+		},
 		{
 			name: "not numeric id",
 			args: args{
@@ -316,7 +725,7 @@ func TestDeleter(t *testing.T) {
 			name: "good",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return nil
 					},
 				},
@@ -328,7 +737,7 @@ func TestDeleter(t *testing.T) {
 			name: "not numeric id",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return nil
 					},
 				},
@@ -340,7 +749,7 @@ func TestDeleter(t *testing.T) {
 			name: "not valid id",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return service.ErrNotValidData
 					},
 				},
@@ -352,7 +761,7 @@ func TestDeleter(t *testing.T) {
 			name: "not found notification",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return service.ErrNotAffected
 					},
 				},
@@ -364,7 +773,7 @@ func TestDeleter(t *testing.T) {
 			name: "unknown err",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return errors.New("unknown")
 					},
 				},
@@ -376,7 +785,7 @@ func TestDeleter(t *testing.T) {
 			name: "business err",
 			args: args{
 				s: &ServiceMock{
-					deleteF: func(id int64) error {
+					cancelF: func(id int64, reason string) error {
 						return service.ErrNotValidData
 					},
 				},
@@ -384,6 +793,30 @@ func TestDeleter(t *testing.T) {
 			code: http.StatusServiceUnavailable,
 			id:   "10000000",
 		},
+		{
+			name: "already cancelled",
+			args: args{
+				s: &ServiceMock{
+					cancelF: func(id int64, reason string) error {
+						return service.ErrCancelled
+					},
+				},
+			},
+			code: http.StatusGone,
+			id:   "1",
+		},
+		{
+			name: "already sent",
+			args: args{
+				s: &ServiceMock{
+					cancelF: func(id int64, reason string) error {
+						return service.ErrConflict
+					},
+				},
+			},
+			code: http.StatusConflict,
+			id:   "1?reason=test",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -407,6 +840,71 @@ func TestDeleter(t *testing.T) {
 	}
 }
 
+func TestPurger(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				purgeF: func(before time.Time) (int64, error) {
+					return 2, nil
+				},
+			},
+			body: `{"before": "2000-12-22T15:00:00.000Z"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "wrong before",
+			s:    &ServiceMock{},
+			body: `{"before": "2000-12-22 15:00"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "business err",
+			s: &ServiceMock{
+				purgeF: func(before time.Time) (int64, error) {
+					return 0, service.ErrNotValidData
+				},
+			},
+			body: `{"before": "3000-12-22T15:00:00.000Z"}`,
+			code: http.StatusServiceUnavailable,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				purgeF: func(before time.Time) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			body: `{"before": "2000-12-22T15:00:00.000Z"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/purge", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/purge", PurgeNotify(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
 func TestUpdater(t *testing.T) {
 	type args struct {
 		s notifyer
@@ -422,85 +920,98 @@ func TestUpdater(t *testing.T) {
 			name: "good",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return nil
 					},
 				},
 			},
 			code: http.StatusOK,
-			body: `{"status": "complete"}`,
+			body: `{"message": "hi"}`,
 			id:   "1",
 		},
 		{
 			name: "not numeric id",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return nil
 					},
 				},
 			},
 			code: http.StatusBadRequest,
-			body: `{"status": "complete"}`,
+			body: `{"message": "hi"}`,
 			id:   "haha",
 		},
 		{
 			name: "not valid id",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return service.ErrNotValidData
 					},
 				},
 			},
 			code: http.StatusBadRequest,
-			body: `{"status": "complete"}`,
+			body: `{"message": "hi"}`,
 			id:   "-1",
 		},
 		{
 			name: "not found notification",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return service.ErrNotAffected
 					},
 				},
 			},
 			code: http.StatusNotFound,
-			body: `{"status": "complete"}`,
+			body: `{"message": "hi"}`,
 			id:   "10000000",
 		},
 		{
 			name: "unknown err",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return errors.New("unknown")
 					},
 				},
 			},
 			code: http.StatusInternalServerError,
-			body: `{"status": "complete"}`,
+			body: `{"message": "hi"}`,
+			id:   "10000000",
+		},
+		{
+			name: "status",
+			args: args{
+				s: &ServiceMock{
+					updateF: func(id int64, u notification.Update) error {
+						return nil
+					},
+				},
+			},
+			code: http.StatusBadRequest,
+			body: `{"status": "sending"}`,
 			id:   "10000000",
 		},
 		{
-			name: "unknown status",
+			name: "back to pending",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return nil
 					},
 				},
 			},
 			code: http.StatusBadRequest,
-			body: `{"status": "test"}`,
+			body: `{"message": "hi", "status": "pending"}`,
 			id:   "10000000",
 		},
 		{
 			name: "wrong json",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return nil
 					},
 				},
@@ -513,15 +1024,75 @@ func TestUpdater(t *testing.T) {
 			name: "wrong json",
 			args: args{
 				s: &ServiceMock{
-					updateF: func(status string, id int64) error {
+					updateF: func(id int64, u notification.Update) error {
 						return service.ErrNotValidData
 					},
 				},
 			},
 			code: http.StatusServiceUnavailable,
-			body: `{"status": "pending"}`,
+			body: `{"message": "hi"}`,
 			id:   "10000000",
 		},
+		{
+			name: "reschedule",
+			args: args{
+				s: &ServiceMock{
+					updateF: func(id int64, u notification.Update) error {
+						if u.Message == nil || u.Date == nil || u.Version != 2 {
+							return errors.New("wrong update")
+						}
+						return nil
+					},
+				},
+			},
+			code: http.StatusOK,
+			body: `{"message": "new", "date": "3000-12-22T15:00:00.000Z", "version": 2}`,
+			id:   "1",
+		},
+		{
+			name: "wrong date",
+			args: args{
+				s: &ServiceMock{},
+			},
+			code: http.StatusBadRequest,
+			body: `{"date": "3000-12-22 15:00"}`,
+			id:   "1",
+		},
+		{
+			name: "empty update",
+			args: args{
+				s: &ServiceMock{},
+			},
+			code: http.StatusBadRequest,
+			body: `{}`,
+			id:   "1",
+		},
+		{
+			name: "not pending or stale version",
+			args: args{
+				s: &ServiceMock{
+					updateF: func(id int64, u notification.Update) error {
+						return service.ErrConflict
+					},
+				},
+			},
+			code: http.StatusConflict,
+			body: `{"message": "new"}`,
+			id:   "1",
+		},
+		{
+			name: "cancelled",
+			args: args{
+				s: &ServiceMock{
+					updateF: func(id int64, u notification.Update) error {
+						return service.ErrCancelled
+					},
+				},
+			},
+			code: http.StatusGone,
+			body: `{"message": "hi", "version": 1}`,
+			id:   "1",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -544,3 +1115,107 @@ func TestUpdater(t *testing.T) {
 		})
 	}
 }
+
+func TestLister(t *testing.T) {
+	type args struct {
+		s notifyer
+	}
+	tests := []struct {
+		name string
+		args
+		code  int
+		query string
+	}{
+		{
+			name: "good",
+			args: args{
+				s: &ServiceMock{
+					listF: func(f notification.Filter) ([]notification.Notification, string, error) {
+						return []notification.Notification{{ID: 1}}, "next", nil
+					},
+				},
+			},
+			code:  http.StatusOK,
+			query: "",
+		},
+		{
+			name: "good with filters",
+			args: args{
+				s: &ServiceMock{
+					listF: func(f notification.Filter) ([]notification.Notification, string, error) {
+						return nil, "", nil
+					},
+				},
+			},
+			code:  http.StatusOK,
+			query: "?status=pending&channel=email&text=hi&from=2000-12-22T15:00:00Z&sort=id&order=desc&limit=5",
+		},
+		{
+			name: "wrong channel",
+			args: args{
+				s: &ServiceMock{},
+			},
+			code:  http.StatusBadRequest,
+			query: "?channel=sms",
+		},
+		{
+			name: "wrong limit",
+			args: args{
+				s: &ServiceMock{},
+			},
+			code:  http.StatusBadRequest,
+			query: "?limit=haha",
+		},
+		{
+			name: "wrong cursor",
+			args: args{
+				s: &ServiceMock{},
+			},
+			code:  http.StatusBadRequest,
+			query: "?cursor=haha",
+		},
+		{
+			name: "business err",
+			args: args{
+				s: &ServiceMock{
+					listF: func(f notification.Filter) ([]notification.Notification, string, error) {
+						return nil, "", service.ErrNotValidData
+					},
+				},
+			},
+			code:  http.StatusServiceUnavailable,
+			query: "?limit=1000",
+		},
+		{
+			name: "unknown err",
+			args: args{
+				s: &ServiceMock{
+					listF: func(f notification.Filter) ([]notification.Notification, string, error) {
+						return nil, "", errors.New("unknown")
+					},
+				},
+			},
+			code:  http.StatusInternalServerError,
+			query: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			url := "/endpoint"
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, url+tt.query, nil)
+
+			g := gin.Default()
+			h := ListNotify(tt.s)
+			g.GET(url, h)
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
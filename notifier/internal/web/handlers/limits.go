@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type limiter interface {
+	Allow(keyID int64) (time.Duration, error)
+	AllowIP(ip string) (time.Duration, error)
+}
+
+// retryAfter выставляет Retry-After в целых секундах, не меньше одной
+func retryAfter(c *ginext.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1)))
+}
+
+// limit отвечает 429 с Retry-After, если запрос сверх лимита. Если
+// лимитер недоступен, запрос пропускается, чтобы сбой redis не
+// останавливал API
+func limit(c *ginext.Context, op string, wait time.Duration, err error) {
+	if errors.Is(err, service.ErrRateLimited) {
+		retryAfter(c, wait)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, response.Error(
+			err.Error(),
+		))
+		return
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+	}
+
+	c.Next()
+}
+
+// RateLimit ограничивает частоту запросов ключа, ставится после Auth.
+// Сервисный ключ sender не ограничивается
+func RateLimit(s limiter) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.RateLimit"
+
+		if apiKey(c).Has(apikey.ScopeService) {
+			c.Next()
+			return
+		}
+
+		wait, err := s.Allow(apiKey(c).ID)
+		limit(c, op, wait, err)
+	}
+}
+
+// RateLimitIP ограничивает частоту запросов с одного адреса, ставится до
+// Auth, так ограничены и запросы с неверным ключом. X-Forwarded-For
+// учитывается, только если router доверяет прокси (SetTrustedProxies)
+func RateLimitIP(s limiter) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.RateLimitIP"
+
+		wait, err := s.AllowIP(c.ClientIP())
+		limit(c, op, wait, err)
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        *apikey.Key
+		allowF     func(keyID int64) (time.Duration, error)
+		code       int
+		retryAfter string
+	}{
+		{
+			name: "allowed",
+			key:  &apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}},
+			allowF: func(keyID int64) (time.Duration, error) {
+				if keyID != 1 {
+					return time.Second, service.ErrRateLimited
+				}
+				return 0, nil
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "limited",
+			key:  &apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}},
+			allowF: func(keyID int64) (time.Duration, error) {
+				return 1500 * time.Millisecond, fmt.Errorf("%w: retry after 1.5s", service.ErrRateLimited)
+			},
+			code:       http.StatusTooManyRequests,
+			retryAfter: "2",
+		},
+		{
+			name: "limited less than second",
+			key:  &apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}},
+			allowF: func(keyID int64) (time.Duration, error) {
+				return 10 * time.Millisecond, service.ErrRateLimited
+			},
+			code:       http.StatusTooManyRequests,
+			retryAfter: "1",
+		},
+		{
+			name: "service key not limited",
+			key:  &apikey.Key{Scopes: []string{apikey.ScopeService}},
+			allowF: func(keyID int64) (time.Duration, error) {
+				return time.Second, service.ErrRateLimited
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "limiter down",
+			key:  &apikey.Key{ID: 1, Scopes: []string{apikey.ScopeRead}},
+			allowF: func(keyID int64) (time.Duration, error) {
+				return 0, fmt.Errorf("%w -> %w", service.ErrStorageInternal, errors.New("unknown"))
+			},
+			code: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/notify", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			g := gin.Default()
+			g.GET("/notify", func(c *gin.Context) {
+				if tt.key != nil {
+					c.Set(APIKeyContext, *tt.key)
+				}
+			}, RateLimit(&ServiceMock{allowF: tt.allowF}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"RateLimit() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("RateLimit() Retry-After get=%q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimitIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowIPF   func(ip string) (time.Duration, error)
+		code       int
+		retryAfter string
+	}{
+		{
+			name: "allowed",
+			allowIPF: func(ip string) (time.Duration, error) {
+				if ip != "192.0.2.1" {
+					return time.Second, service.ErrRateLimited
+				}
+				return 0, nil
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "limited",
+			allowIPF: func(ip string) (time.Duration, error) {
+				return 3 * time.Second, service.ErrRateLimited
+			},
+			code:       http.StatusTooManyRequests,
+			retryAfter: "3",
+		},
+		{
+			name: "limiter down",
+			allowIPF: func(ip string) (time.Duration, error) {
+				return 0, fmt.Errorf("%w -> %w", service.ErrStorageInternal, errors.New("unknown"))
+			},
+			code: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/notify", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			g := gin.Default()
+			g.GET("/notify", RateLimitIP(&ServiceMock{allowIPF: tt.allowIPF}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"RateLimitIP() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("RateLimitIP() Retry-After get=%q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimitIP_Forwarded(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		codes   []int
+	}{
+		{
+			name:  "spoofed header",
+			codes: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+		{
+			name:    "trusted proxy",
+			proxies: []string{"192.0.2.0/24"},
+			codes:   []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			// на каждый адрес один запрос
+			seen := map[string]bool{}
+			s := &ServiceMock{allowIPF: func(ip string) (time.Duration, error) {
+				if seen[ip] {
+					return time.Second, service.ErrRateLimited
+				}
+				seen[ip] = true
+				return 0, nil
+			}}
+
+			g := gin.New()
+			if err := g.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatal(err)
+			}
+			g.GET("/notify", RateLimitIP(s), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			for i, code := range tt.codes {
+				rr := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/notify", nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+				g.ServeHTTP(rr, req)
+				if code != rr.Result().StatusCode {
+					t.Errorf(
+						"RateLimitIP() request %d status code get=%d, want %d",
+						i, rr.Result().StatusCode, code,
+					)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// ListOverdue godoc
+// @Summary Отчет о просроченных уведомлениях
+// @Description Ожидающие уведомления всех команд, время которых прошло больше чем на sweeper.grace: отложенное сообщение, скорее всего, потеряно. republished - сколько раз уведомление опубликовано заново, overdue_at - когда повторы кончились
+// @Tags admin
+// @Security ApiKeyAuth
+// @Produce json
+// @Param tenant_id query int false "ID команды"
+// @Param flagged query bool false "только уведомления, повторы которых кончились"
+// @Param limit query int false "размер страницы, по умолчанию 20, максимум 100"
+// @Param cursor query string false "курсор следующей страницы из next_cursor"
+// @Success 200 {object} response.Response{result=response.Page{items=[]notification.Overdue}}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /admin/overdue [get]
+func ListOverdue(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListOverdue"
+
+		var r request.ListOverdue
+		if err := c.ShouldBindQuery(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong query params",
+			))
+			return
+		}
+		f, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		ns, next, err := s.Overdue(f)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			response.Page{Items: ns, NextCursor: next},
+		))
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOverdueListing(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     notifyer
+		query string
+		code  int
+		want  string
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, string, error) {
+					if f.TenantID != 2 || !f.Flagged || f.After != 10 || f.Limit != 5 {
+						return nil, "", errors.New("wrong filter")
+					}
+					return []notification.Overdue{{ID: 11, Republished: 3}}, "11", nil
+				},
+			},
+			query: "?tenant_id=2&flagged=true&cursor=10&limit=5",
+			code:  http.StatusOK,
+			want:  `"republished":3`,
+		},
+		{
+			name:  "wrong flagged",
+			s:     &ServiceMock{},
+			query: "?flagged=maybe",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "wrong cursor",
+			s:     &ServiceMock{},
+			query: "?cursor=-1",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name: "wrong limit",
+			s: &ServiceMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, string, error) {
+					return nil, "", service.ErrNotValidData
+				},
+			},
+			query: "?limit=1000",
+			code:  http.StatusServiceUnavailable,
+		},
+		{
+			name: "internal error",
+			s: &ServiceMock{
+				overF: func(f notification.OverdueFilter) ([]notification.Overdue, string, error) {
+					return nil, "", service.ErrStorageInternal
+				},
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodGet, "/admin/overdue"+tt.query, nil,
+			)
+
+			g := gin.Default()
+			g.GET("/admin/overdue", ListOverdue(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"ListOverdue() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("ListOverdue() body %s, want %s", rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+func createSeries(c *ginext.Context, s notifyer, r request.CreateNotification) {
+	const op = "internal.handlers.createSeries"
+
+	if c.GetHeader(IdempotencyKeyHeader) != "" {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"idempotency key isn't supported for recurring notifications",
+		))
+		return
+	}
+
+	sr, n, msg := r.ValidateSeries()
+	if msg != "" {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			msg,
+		))
+		return
+	}
+	n, ok := applyTemplate(c, s, r, n)
+	if !ok {
+		return
+	}
+
+	seriesID, id, err := s.CreateSeries(tenantID(c), sr, n)
+	if errors.Is(err, service.ErrNotValidData) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			err.Error(),
+		))
+		return
+	} else if errors.Is(err, service.ErrQuotaExceeded) {
+		c.JSONP(http.StatusTooManyRequests, response.Error(
+			err.Error(),
+		))
+		return
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return
+	}
+
+	c.JSONP(http.StatusOK, response.OK(
+		response.SeriesCreated{SeriesID: seriesID, ID: id},
+	))
+}
+
+func seriesID(c *ginext.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be numeric value",
+		))
+		return 0, false
+	}
+	if id <= 0 {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be positive",
+		))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// GetSeries godoc
+// @Summary Получить серию по ID
+// @Description Получение правила повторения, ограничений и статуса серии
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID серии"
+// @Success 200 {object} response.Response{result=series.Series}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /series/{id} [get]
+func GetSeries(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.GetSeries"
+
+		id, ok := seriesID(c)
+		if !ok {
+			return
+		}
+
+		sr, err := s.Series(tenantID(c), id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			sr,
+		))
+	}
+}
+
+// ListSeriesOccurrences godoc
+// @Summary Получить срабатывания серии
+// @Description Список уведомлений серии, фильтры и пагинация как у GET /notify
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID серии"
+// @Param status query string false "pending || sending || sent || partially_failed || failed || cancelled"
+// @Param from query string false "dt не раньше"
+// @Param to query string false "dt не позже"
+// @Param sort query string false "dt || id"
+// @Param order query string false "asc || desc"
+// @Param limit query int false "размер страницы (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "курсор следующей страницы"
+// @Success 200 {object} response.Response{result=response.Page}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /series/{id}/occurrences [get]
+func ListSeriesOccurrences(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListSeriesOccurrences"
+
+		id, ok := seriesID(c)
+		if !ok {
+			return
+		}
+		var r request.ListNotifications
+		if err := c.ShouldBindQuery(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong query params",
+			))
+			return
+		}
+		f, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		ns, next, err := s.SeriesOccurrences(tenantID(c), id, f)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			response.Page{
+				Items: response.NewNotifications(ns), NextCursor: next,
+			},
+		))
+	}
+}
+
+// UpdateSeries godoc
+// @Summary Изменить статус серии
+// @Description paused - пауза, active - возобновить, stopped - остановить навсегда. При паузе и остановке ожидающее срабатывание отменяется
+// @Tags series
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID серии"
+// @Param series body request.UpdateSeries true "Новый статус"
+// @Success 200 {object} response.Response{result=series.Series}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /series/{id} [patch]
+func UpdateSeries(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.UpdateSeries"
+
+		id, ok := seriesID(c)
+		if !ok {
+			return
+		}
+		var r request.UpdateSeries
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		status, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		sr, err := s.UpdateSeriesStatus(tenantID(c), id, status)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			sr,
+		))
+	}
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSeriesCreating(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		key  string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				createSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					if sr.Rule != "0 9 * * 1" || sr.Count != 3 || !n.Date.IsZero() {
+						return 0, 0, errors.New("wrong series")
+					}
+					return 1, 2, nil
+				},
+			},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "0 9 * * 1", "recurrence_count": 3}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "with date and until",
+			s: &ServiceMock{
+				createSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					if sr.Until.IsZero() || n.Date.IsZero() {
+						return 0, 0, errors.New("wrong series")
+					}
+					return 1, 2, nil
+				},
+			},
+			body: `{"message": "hi", "email": "a@b.c", "date": "3000-12-22T15:00:00Z", "recurrence": "@daily", "recurrence_until": "3001-01-01T00:00:00Z"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "wrong rule",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "every monday"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "count without rule",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "telegram_id": "123", "date": "3000-12-22T15:00:00Z", "recurrence_count": 3}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "negative count",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "@daily", "recurrence_count": -1}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "with idempotency key",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "@daily"}`,
+			key:  "abc",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "business err",
+			s: &ServiceMock{
+				createSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					return 0, 0, service.ErrNotValidData
+				},
+			},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "0 0 30 2 *"}`,
+			code: http.StatusServiceUnavailable,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				createSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					return 0, 0, errors.New("unknown")
+				},
+			},
+			body: `{"message": "hi", "telegram_id": "123", "recurrence": "@daily"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/main", strings.NewReader(tt.body),
+			)
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+
+			g := gin.Default()
+			g.POST("/main", CreateNotify(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestSeriesGetter(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		id   string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				seriesF: func(id int64) (series.Series, error) {
+					return series.Series{ID: id, Rule: "@daily"}, nil
+				},
+			},
+			id:   "1",
+			code: http.StatusOK,
+		},
+		{
+			name: "bad id",
+			s:    &ServiceMock{},
+			id:   "haha",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "negative id",
+			s:    &ServiceMock{},
+			id:   "-1",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				seriesF: func(id int64) (series.Series, error) {
+					return series.Series{}, service.ErrNotFound
+				},
+			},
+			id:   "1",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				seriesF: func(id int64) (series.Series, error) {
+					return series.Series{}, errors.New("unknown")
+				},
+			},
+			id:   "1",
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/series/"+tt.id, nil)
+
+			g := gin.Default()
+			g.GET("/series/:id", GetSeries(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestSeriesOccurrencesLister(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     notifyer
+		id    string
+		query string
+		code  int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				occurrenceF: func(id int64, f notification.Filter) ([]notification.Notification, string, error) {
+					return []notification.Notification{{ID: 1, SeriesID: id}}, "", nil
+				},
+			},
+			id:    "1",
+			query: "?status=sent&limit=5",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "wrong status",
+			s:     &ServiceMock{},
+			id:    "1",
+			query: "?status=haha",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				occurrenceF: func(id int64, f notification.Filter) ([]notification.Notification, string, error) {
+					return nil, "", service.ErrNotFound
+				},
+			},
+			id:   "1",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				occurrenceF: func(id int64, f notification.Filter) ([]notification.Notification, string, error) {
+					return nil, "", errors.New("unknown")
+				},
+			},
+			id:   "1",
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodGet, "/series/"+tt.id+"/occurrences"+tt.query, nil,
+			)
+
+			g := gin.Default()
+			g.GET("/series/:id/occurrences", ListSeriesOccurrences(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestSeriesUpdater(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "pause",
+			s: &ServiceMock{
+				sStatusF: func(id int64, status string) (series.Series, error) {
+					return series.Series{ID: id, Status: status}, nil
+				},
+			},
+			body: `{"status": "paused"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "wrong status",
+			s:    &ServiceMock{},
+			body: `{"status": "finished"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "bad json",
+			s:    &ServiceMock{},
+			body: `{`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				sStatusF: func(id int64, status string) (series.Series, error) {
+					return series.Series{}, service.ErrNotAffected
+				},
+			},
+			body: `{"status": "stopped"}`,
+			code: http.StatusNotFound,
+		},
+		{
+			name: "already stopped",
+			s: &ServiceMock{
+				sStatusF: func(id int64, status string) (series.Series, error) {
+					return series.Series{}, service.ErrConflict
+				},
+			},
+			body: `{"status": "active"}`,
+			code: http.StatusConflict,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				sStatusF: func(id int64, status string) (series.Series, error) {
+					return series.Series{}, errors.New("unknown")
+				},
+			},
+			body: `{"status": "active"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPatch, "/series/1", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.PATCH("/series/:id", UpdateSeries(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
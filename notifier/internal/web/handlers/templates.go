@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// applyTemplate рендерит текст уведомления, если в запросе задан шаблон.
+// При ошибке ответ уже записан и возвращается false
+func applyTemplate(c *ginext.Context, s notifyer, r request.CreateNotification, n notification.Notification) (notification.Notification, bool) {
+	const op = "internal.handlers.applyTemplate"
+
+	if r.TemplateID == 0 {
+		return n, true
+	}
+
+	n, err := s.ApplyTemplate(tenantID(c), n, r.TemplateID, r.Params)
+	if errors.Is(err, service.ErrNotValidData) {
+		c.JSONP(http.StatusServiceUnavailable, response.Error(
+			err.Error(),
+		))
+		return n, false
+	} else if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		c.JSONP(http.StatusInternalServerError, response.Error(
+			"internal server error on our service",
+		))
+		return n, false
+	}
+
+	return n, true
+}
+
+func templateID(c *ginext.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be numeric value",
+		))
+		return 0, false
+	}
+	if id <= 0 {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be positive",
+		))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// CreateTemplate godoc
+// @Summary Создать шаблон сообщения
+// @Description Переменные в формате text/template: {{.name}}. body - текст по умолчанию, telegram_body, email_subject и email_body - необязательные варианты для каналов
+// @Tags templates
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param template body request.Template true "Шаблон"
+// @Success 200 {object} response.Response{result=int}
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /templates [post]
+func CreateTemplate(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.CreateTemplate"
+
+		var r request.Template
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		t, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		id, err := s.CreateTemplate(tenantID(c), t)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			id,
+		))
+	}
+}
+
+// ListTemplates godoc
+// @Summary Получить список шаблонов
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} response.Response{result=[]template.Template}
+// @Failure 500 {object} response.Response
+// @Router /templates [get]
+func ListTemplates(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListTemplates"
+
+		r, err := s.Templates(tenantID(c))
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			r,
+		))
+	}
+}
+
+// GetTemplate godoc
+// @Summary Получить шаблон по ID
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Success 200 {object} response.Response{result=template.Template}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /templates/{id} [get]
+func GetTemplate(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.GetTemplate"
+
+		id, ok := templateID(c)
+		if !ok {
+			return
+		}
+
+		t, err := s.Template(tenantID(c), id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			t,
+		))
+	}
+}
+
+// UpdateTemplate godoc
+// @Summary Изменить шаблон
+// @Description Шаблон заменяется целиком, уже созданные уведомления не меняются
+// @Tags templates
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Param template body request.Template true "Шаблон"
+// @Success 200 {object} response.Response{result=template.Template}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /templates/{id} [put]
+func UpdateTemplate(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.UpdateTemplate"
+
+		id, ok := templateID(c)
+		if !ok {
+			return
+		}
+		var r request.Template
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		t, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+		t.ID = id
+
+		t, err := s.UpdateTemplate(tenantID(c), t)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			t,
+		))
+	}
+}
+
+// DeleteTemplate godoc
+// @Summary Удалить шаблон
+// @Description Созданные по шаблону уведомления остаются без изменений
+// @Tags templates
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /templates/{id} [delete]
+func DeleteTemplate(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.DeleteTemplate"
+
+		id, ok := templateID(c)
+		if !ok {
+			return
+		}
+
+		err := s.DeleteTemplate(tenantID(c), id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotAffected) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			"successfull deleted",
+		))
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/series"
+	"delayednotifier/internal/entities/template"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTemplateCreating(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				createTF: func(t template.Template) (int64, error) {
+					return 1, nil
+				},
+			},
+			body: `{"name": "meeting", "body": "Привет, {{.name}}", "email_subject": "Встреча"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "empty name",
+			s:    &ServiceMock{},
+			body: `{"body": "hi"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "wrong syntax",
+			s:    &ServiceMock{},
+			body: `{"name": "meeting", "body": "hi {{.name"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "name used",
+			s: &ServiceMock{
+				createTF: func(t template.Template) (int64, error) {
+					return 0, service.ErrConflict
+				},
+			},
+			body: `{"name": "meeting", "body": "hi"}`,
+			code: http.StatusConflict,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				createTF: func(t template.Template) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			body: `{"name": "meeting", "body": "hi"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/templates", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/templates", CreateTemplate(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestTemplateGetter(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		id   string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				getTF: func(id int64) (template.Template, error) {
+					return template.Template{ID: id, Name: "meeting"}, nil
+				},
+			},
+			id:   "1",
+			code: http.StatusOK,
+		},
+		{
+			name: "bad id",
+			s:    &ServiceMock{},
+			id:   "haha",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				getTF: func(id int64) (template.Template, error) {
+					return template.Template{}, service.ErrNotFound
+				},
+			},
+			id:   "1",
+			code: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/templates/"+tt.id, nil)
+
+			g := gin.Default()
+			g.GET("/templates/:id", GetTemplate(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestTemplateUpdater(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				updateTF: func(t template.Template) (template.Template, error) {
+					if t.ID != 1 {
+						return t, errors.New("wrong id")
+					}
+					return t, nil
+				},
+			},
+			body: `{"name": "meeting", "body": "hi"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				updateTF: func(t template.Template) (template.Template, error) {
+					return t, service.ErrNotAffected
+				},
+			},
+			body: `{"name": "meeting", "body": "hi"}`,
+			code: http.StatusNotFound,
+		},
+		{
+			name: "name used",
+			s: &ServiceMock{
+				updateTF: func(t template.Template) (template.Template, error) {
+					return t, service.ErrConflict
+				},
+			},
+			body: `{"name": "meeting", "body": "hi"}`,
+			code: http.StatusConflict,
+		},
+		{
+			name: "empty body",
+			s:    &ServiceMock{},
+			body: `{"name": "meeting"}`,
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPut, "/templates/1", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.PUT("/templates/:id", UpdateTemplate(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestTemplateDeleter(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				deleteTF: func(id int64) error {
+					return nil
+				},
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "not found",
+			s: &ServiceMock{
+				deleteTF: func(id int64) error {
+					return service.ErrNotAffected
+				},
+			},
+			code: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/templates/1", nil)
+
+			g := gin.Default()
+			g.DELETE("/templates/:id", DeleteTemplate(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestCreatingFromTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				applyTF: func(n notification.Notification, id int64, params map[string]any) (notification.Notification, error) {
+					if id != 1 || params["name"] != "Bob" {
+						return n, errors.New("wrong template args")
+					}
+					n.Message = "hi Bob"
+					return n, nil
+				},
+				createF: func(n notification.Notification) (int64, error) {
+					if n.Message != "hi Bob" {
+						return 0, errors.New("template not applied")
+					}
+					return 1, nil
+				},
+			},
+			body: `{"template_id": 1, "params": {"name": "Bob"}, "telegram_id": "123", "date": "3000-12-22T15:00:00Z"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "message with template",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "template_id": 1, "telegram_id": "123", "date": "3000-12-22T15:00:00Z"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "params without template",
+			s:    &ServiceMock{},
+			body: `{"message": "hi", "params": {"name": "Bob"}, "telegram_id": "123", "date": "3000-12-22T15:00:00Z"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "missing param",
+			s: &ServiceMock{
+				applyTF: func(n notification.Notification, id int64, params map[string]any) (notification.Notification, error) {
+					return n, service.ErrNotValidData
+				},
+			},
+			body: `{"template_id": 1, "telegram_id": "123", "date": "3000-12-22T15:00:00Z"}`,
+			code: http.StatusServiceUnavailable,
+		},
+		{
+			name: "series from template",
+			s: &ServiceMock{
+				applyTF: func(n notification.Notification, id int64, params map[string]any) (notification.Notification, error) {
+					n.Message = "hi"
+					return n, nil
+				},
+				createSF: func(sr series.Series, n notification.Notification) (int64, int64, error) {
+					if n.Message != "hi" {
+						return 0, 0, errors.New("template not applied")
+					}
+					return 1, 2, nil
+				},
+			},
+			body: `{"template_id": 1, "telegram_id": "123", "recurrence": "@daily"}`,
+			code: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/main", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/main", CreateNotify(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"Main() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/request"
+	"delayednotifier/internal/entities/response"
+	"delayednotifier/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+func pathTenantID(c *ginext.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be numeric value",
+		))
+		return 0, false
+	}
+	if id <= 0 {
+		c.JSONP(http.StatusBadRequest, response.Error(
+			"id should be positive",
+		))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// CreateTenant godoc
+// @Summary Создать команду
+// @Description Данные команд не пересекаются: уведомления, серии, шаблоны, контакты и ключи видны только внутри своей команды
+// @Tags tenants
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param tenant body request.Tenant true "Имя команды"
+// @Success 200 {object} response.Response{result=int}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /tenants [post]
+func CreateTenant(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.CreateTenant"
+
+		var r request.Tenant
+		if err := c.ShouldBindJSON(&r); err != nil {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				"wrong data types or fields in json ",
+			))
+			return
+		}
+		name, msg := r.Validate()
+		if msg != "" {
+			c.JSONP(http.StatusBadRequest, response.Error(
+				msg,
+			))
+			return
+		}
+
+		id, err := s.CreateTenant(name)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrConflict) {
+			c.JSONP(http.StatusConflict, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			id,
+		))
+	}
+}
+
+// ListTenants godoc
+// @Summary Получить список команд
+// @Tags tenants
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} response.Response{result=[]tenant.Tenant}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /tenants [get]
+func ListTenants(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.ListTenants"
+
+		r, err := s.Tenants()
+		if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			r,
+		))
+	}
+}
+
+// GetTenant godoc
+// @Summary Получить команду по ID
+// @Tags tenants
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "ID команды"
+// @Success 200 {object} response.Response{result=tenant.Tenant}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /tenants/{id} [get]
+func GetTenant(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		const op = "internal.handlers.GetTenant"
+
+		id, ok := pathTenantID(c)
+		if !ok {
+			return
+		}
+
+		t, err := s.Tenant(id)
+		if errors.Is(err, service.ErrNotValidData) {
+			c.JSONP(http.StatusServiceUnavailable, response.Error(
+				err.Error(),
+			))
+			return
+		} else if errors.Is(err, service.ErrNotFound) {
+			c.JSONP(http.StatusNotFound, response.Error(
+				err.Error(),
+			))
+			return
+		} else if err != nil {
+			zlog.Logger.Error().AnErr("err", err).Msg(op)
+			c.JSONP(http.StatusInternalServerError, response.Error(
+				"internal server error on our service",
+			))
+			return
+		}
+
+		c.JSONP(http.StatusOK, response.OK(
+			t,
+		))
+	}
+}
+
+// IssueTenantAPIKey godoc
+// @Summary Выпустить ключ API в команду
+// @Description Первый ключ новой команды выпускается здесь. Ключ не может получить scope, которого нет у вызывающего
+// @Tags tenants
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID команды"
+// @Param key body request.APIKey true "Имя и scope ключа"
+// @Success 200 {object} response.Response{result=response.IssuedKey}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /tenants/{id}/keys [post]
+func IssueTenantAPIKey(s notifyer) gin.HandlerFunc {
+	return func(c *ginext.Context) {
+		id, ok := pathTenantID(c)
+		if !ok {
+			return
+		}
+
+		issueAPIKey(c, s, id)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"delayednotifier/internal/entities/apikey"
+	"delayednotifier/internal/entities/tenant"
+	"delayednotifier/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantCreating(t *testing.T) {
+	tests := []struct {
+		name string
+		s    notifyer
+		body string
+		code int
+	}{
+		{
+			name: "good",
+			s: &ServiceMock{
+				createTnF: func(name string) (int64, error) {
+					return 2, nil
+				},
+			},
+			body: `{"name": "billing"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "empty name",
+			s:    &ServiceMock{},
+			body: `{"name": "  "}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "wrong json",
+			s:    &ServiceMock{},
+			body: `{"name": 1}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "name used",
+			s: &ServiceMock{
+				createTnF: func(name string) (int64, error) {
+					return 0, fmt.Errorf("%w: tenant name already used", service.ErrConflict)
+				},
+			},
+			body: `{"name": "billing"}`,
+			code: http.StatusConflict,
+		},
+		{
+			name: "unknown err",
+			s: &ServiceMock{
+				createTnF: func(name string) (int64, error) {
+					return 0, errors.New("unknown")
+				},
+			},
+			body: `{"name": "billing"}`,
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/tenants", strings.NewReader(tt.body),
+			)
+
+			g := gin.Default()
+			g.POST("/tenants", CreateTenant(tt.s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"CreateTenant() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestTenantGetter(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		code int
+	}{
+		{name: "good", id: "1", code: http.StatusOK},
+		{name: "not found", id: "2", code: http.StatusNotFound},
+		{name: "bad id", id: "a", code: http.StatusBadRequest},
+		{name: "negative id", id: "-1", code: http.StatusBadRequest},
+	}
+	s := &ServiceMock{
+		getTnF: func(id int64) (tenant.Tenant, error) {
+			if id != 1 {
+				return tenant.Tenant{}, service.ErrNotFound
+			}
+			return tenant.Tenant{ID: 1, Name: "default"}, nil
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/tenants/"+tt.id, nil)
+
+			g := gin.Default()
+			g.GET("/tenants/:id", GetTenant(s))
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"GetTenant() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestTenantAPIKeyIssuing(t *testing.T) {
+	var got int64
+	s := &tenantIssuer{
+		ServiceMock: ServiceMock{
+			authF: func(token string) (apikey.Key, error) {
+				return apikey.Key{
+					ID: 1, Scopes: []string{apikey.ScopeTenants}, TenantID: 1,
+				}, nil
+			},
+		},
+		issue: func(issuer apikey.Key, tenantID int64) {
+			got = tenantID
+		},
+	}
+	tests := []struct {
+		name string
+		id   string
+		code int
+		want int64
+	}{
+		{name: "good", id: "5", code: http.StatusOK, want: 5},
+		{name: "bad id", id: "a", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = 0
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost, "/tenants/"+tt.id+"/keys",
+				strings.NewReader(`{"name": "ops", "scopes": ["admin"]}`),
+			)
+			req.Header.Set(APIKeyHeader, "dn_root")
+
+			g := gin.Default()
+			g.POST(
+				"/tenants/:id/keys",
+				Auth(s, apikey.ScopeTenants), IssueTenantAPIKey(s),
+			)
+			g.ServeHTTP(rr, req)
+			if tt.code != rr.Result().StatusCode {
+				t.Errorf(
+					"IssueTenantAPIKey() status code get=%d, want %d, body %s",
+					rr.Result().StatusCode, tt.code, rr.Body.String(),
+				)
+			}
+			if got != tt.want {
+				t.Errorf("IssueTenantAPIKey() tenant get=%d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// tenantIssuer запоминает, от чьего имени и в какую команду выпущен ключ
+type tenantIssuer struct {
+	ServiceMock
+	issue func(issuer apikey.Key, tenantID int64)
+}
+
+func (ti *tenantIssuer) IssueAPIKey(issuer apikey.Key, tenantID int64, name string, scopes []string) (apikey.Key, string, error) {
+	ti.issue(issuer, tenantID)
+	return apikey.Key{ID: 2, Name: name, Scopes: scopes, TenantID: tenantID}, "dn_new", nil
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"delayednotifier/internal/entities/apikey"
 	"delayednotifier/internal/service"
 	"delayednotifier/internal/web/handlers"
 
@@ -22,8 +23,58 @@ func SetRoutes(router *ginext.Engine, s *service.Service) {
 
 	router.GET("/", handlers.Main(s))
 
-	router.POST("/notify", handlers.CreateNotify(s))
-	router.GET("/notify/:id", handlers.GetNotify(s))
-	router.PATCH("/notify/:id", handlers.UpdateNotify(s))
-	router.DELETE("/notify/:id", handlers.DeleteNotify(s))
+	// лимит по IP идет до Auth, чтобы перебор ключей тоже ограничивался
+	ip := handlers.RateLimitIP(s)
+	// изменение - create, чтение - read, отмена и удаление - cancel.
+	// Отметки о доставке приходят от sender только через брокер
+	create := handlers.Auth(s, apikey.ScopeCreate)
+	read := handlers.Auth(s, apikey.ScopeRead)
+	cancel := handlers.Auth(s, apikey.ScopeCancel)
+	admin := handlers.Auth(s, apikey.ScopeAdmin)
+	tenants := handlers.Auth(s, apikey.ScopeTenants)
+	ops := handlers.Auth(s, apikey.ScopeOps)
+	// лимит считается по ключу, поэтому идет после Auth
+	limit := handlers.RateLimit(s)
+
+	router.POST("/notify", ip, create, limit, handlers.CreateNotify(s))
+	router.POST("/notify/batch", ip, create, limit, handlers.CreateNotifyBatch(s))
+	router.POST("/notify/purge", ip, cancel, limit, handlers.PurgeNotify(s))
+	router.GET("/notify", ip, read, limit, handlers.ListNotify(s))
+	router.GET("/notify/:id", ip, read, limit, handlers.GetNotify(s))
+	router.PATCH("/notify/:id", ip, create, limit, handlers.UpdateNotify(s))
+	router.DELETE("/notify/:id", ip, cancel, limit, handlers.DeleteNotify(s))
+
+	router.GET("/series/:id", ip, read, limit, handlers.GetSeries(s))
+	router.GET("/series/:id/occurrences", ip, read, limit, handlers.ListSeriesOccurrences(s))
+	router.PATCH("/series/:id", ip, create, limit, handlers.UpdateSeries(s))
+
+	router.POST("/templates", ip, create, limit, handlers.CreateTemplate(s))
+	router.GET("/templates", ip, read, limit, handlers.ListTemplates(s))
+	router.GET("/templates/:id", ip, read, limit, handlers.GetTemplate(s))
+	router.PUT("/templates/:id", ip, create, limit, handlers.UpdateTemplate(s))
+	router.DELETE("/templates/:id", ip, cancel, limit, handlers.DeleteTemplate(s))
+
+	router.POST("/contacts", ip, create, limit, handlers.CreateContact(s))
+	router.GET("/contacts", ip, read, limit, handlers.ListContacts(s))
+	router.GET("/contacts/:id", ip, read, limit, handlers.GetContact(s))
+	router.PUT("/contacts/:id", ip, create, limit, handlers.UpdateContact(s))
+	router.DELETE("/contacts/:id", ip, cancel, limit, handlers.DeleteContact(s))
+
+	router.POST("/keys", ip, admin, limit, handlers.IssueAPIKey(s))
+	router.GET("/keys", ip, admin, limit, handlers.ListAPIKeys(s))
+	router.DELETE("/keys/:id", ip, admin, limit, handlers.RevokeAPIKey(s))
+
+	router.POST("/tenants", ip, tenants, limit, handlers.CreateTenant(s))
+	router.GET("/tenants", ip, tenants, limit, handlers.ListTenants(s))
+	router.GET("/tenants/:id", ip, tenants, limit, handlers.GetTenant(s))
+	router.POST("/tenants/:id/keys", ip, tenants, limit, handlers.IssueTenantAPIKey(s))
+
+	router.GET("/deadletters", ip, ops, limit, handlers.ListDeadLetters(s))
+	router.GET("/deadletters/:id", ip, ops, limit, handlers.GetDeadLetter(s))
+	router.POST("/deadletters/:id/replay", ip, ops, limit, handlers.ReplayDeadLetter(s))
+	router.DELETE("/deadletters/:id", ip, ops, limit, handlers.DiscardDeadLetter(s))
+	router.POST("/deadletters/replay", ip, ops, limit, handlers.ReplayDeadLetters(s))
+	router.POST("/deadletters/discard", ip, ops, limit, handlers.DiscardDeadLetters(s))
+
+	router.GET("/admin/overdue", ip, ops, limit, handlers.ListOverdue(s))
 }
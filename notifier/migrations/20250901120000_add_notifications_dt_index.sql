@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+create index notifications_dt_id_idx on notifications (dt, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index notifications_dt_id_idx;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications
+    add column idempotency_key varchar(255) unique,
+    add column request_hash varchar(64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    drop column idempotency_key,
+    drop column request_hash;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications add column version bigint not null default 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications drop column version;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications
+    add column cancelled_at timestamp,
+    add column cancel_reason text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    drop column cancelled_at,
+    drop column cancel_reason;
+-- +goose StatementEnd
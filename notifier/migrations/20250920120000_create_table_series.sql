@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table series(
+    id serial primary key,
+    rule varchar(255) not null,
+    until timestamp,
+    max_count bigint not null default 0,
+    fired bigint not null default 0,
+    status varchar(50) not null default ('active'),
+    last_occurrence_id bigint not null default 0,
+    created_at timestamp not null default (now() at time zone 'utc')
+);
+
+alter table notifications
+    add column series_id bigint references series (id) on delete set null;
+
+create index notifications_series_id_idx on notifications (series_id, dt, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications drop column series_id;
+drop table series;
+-- +goose StatementEnd
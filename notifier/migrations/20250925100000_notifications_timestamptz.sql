@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- до миграции время писалось в UTC без зоны
+alter table notifications
+    alter column dt type timestamptz using dt at time zone 'UTC',
+    alter column cancelled_at type timestamptz using cancelled_at at time zone 'UTC',
+    add column timezone varchar(64) not null default ('UTC');
+
+alter table series
+    alter column until type timestamptz using until at time zone 'UTC',
+    alter column created_at drop default,
+    alter column created_at type timestamptz using created_at at time zone 'UTC',
+    alter column created_at set default now();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table series
+    alter column created_at drop default,
+    alter column created_at type timestamp using created_at at time zone 'UTC',
+    alter column created_at set default (now() at time zone 'utc'),
+    alter column until type timestamp using until at time zone 'UTC';
+
+alter table notifications
+    drop column timezone,
+    alter column cancelled_at type timestamp using cancelled_at at time zone 'UTC',
+    alter column dt type timestamp using dt at time zone 'UTC';
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+create table templates(
+    id serial primary key,
+    name varchar(255) not null unique,
+    body text not null,
+    telegram_body text not null default '',
+    email_subject text not null default '',
+    email_body text not null default '',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+alter table notifications
+    add column telegram_message text not null default '',
+    add column email_subject text not null default '',
+    add column email_message text not null default '',
+    add column template_id bigint references templates (id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    drop column telegram_message,
+    drop column email_subject,
+    drop column email_message,
+    drop column template_id;
+
+drop table templates;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+create table recipients(
+    id serial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    channel varchar(20) not null,
+    address varchar(255) not null,
+    status varchar(50) not null default ('pending'),
+    error text not null default '',
+    updated_at timestamptz not null default now(),
+    unique (notification_id, channel, address)
+);
+
+-- до появления статусов доставки complete означало, что уведомление ушло
+insert into recipients (notification_id, channel, address, status)
+select id, 'telegram', telegram_id::text,
+    case when status = 'complete' then 'sent' else 'pending' end
+from notifications where coalesce(telegram_id, 0) <> 0;
+
+insert into recipients (notification_id, channel, address, status)
+select id, 'email', email,
+    case when status = 'complete' then 'sent' else 'pending' end
+from notifications where coalesce(email, '') <> '';
+
+alter table notifications
+    drop column telegram_id,
+    drop column email;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    add column telegram_id bigint,
+    add column email varchar(255);
+
+update notifications n set telegram_id = r.address::bigint
+from (
+    select distinct on (notification_id) notification_id, address
+    from recipients where channel = 'telegram' order by notification_id, id
+) r where r.notification_id = n.id;
+
+update notifications n set email = r.address
+from (
+    select distinct on (notification_id) notification_id, address
+    from recipients where channel = 'email' order by notification_id, id
+) r where r.notification_id = n.id;
+
+drop table recipients;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table contacts(
+    id serial primary key,
+    name varchar(255) not null,
+    telegram_id varchar(64) not null default '',
+    email varchar(255) not null default '',
+    preferred_channel varchar(20) not null default '',
+    locale varchar(35) not null default '',
+    timezone varchar(64) not null default ('UTC'),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+alter table notifications
+    add column contact_id bigint references contacts (id) on delete set null;
+
+create index notifications_contact_id_idx on notifications (contact_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index notifications_contact_id_idx;
+
+alter table notifications drop column contact_id;
+
+drop table contacts;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys(
+    id serial primary key,
+    name varchar(255) not null,
+    shown varchar(32) not null,
+    hash varchar(64) not null unique,
+    scopes text[] not null,
+    created_at timestamptz not null default now(),
+    revoked_at timestamptz
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table api_keys;
+-- +goose StatementEnd
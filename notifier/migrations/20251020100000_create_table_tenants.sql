@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+create table tenants(
+    id serial primary key,
+    name varchar(255) not null unique,
+    created_at timestamptz not null default now()
+);
+
+-- все данные, созданные до появления команд, достаются команде по умолчанию
+insert into tenants (id, name) values (1, 'default');
+select setval('tenants_id_seq', 1);
+
+alter table notifications
+    add column tenant_id bigint not null default 1 references tenants (id);
+alter table series
+    add column tenant_id bigint not null default 1 references tenants (id);
+alter table templates
+    add column tenant_id bigint not null default 1 references tenants (id);
+alter table contacts
+    add column tenant_id bigint not null default 1 references tenants (id);
+alter table api_keys
+    add column tenant_id bigint not null default 1 references tenants (id);
+
+alter table notifications alter column tenant_id drop default;
+alter table series alter column tenant_id drop default;
+alter table templates alter column tenant_id drop default;
+alter table contacts alter column tenant_id drop default;
+alter table api_keys alter column tenant_id drop default;
+
+alter table notifications
+    drop constraint notifications_idempotency_key_key,
+    add constraint notifications_tenant_idempotency_key_key
+        unique (tenant_id, idempotency_key);
+alter table templates
+    drop constraint templates_name_key,
+    add constraint templates_tenant_name_key unique (tenant_id, name);
+
+create index notifications_tenant_dt_id_idx on notifications (tenant_id, dt, id);
+create index contacts_tenant_id_idx on contacts (tenant_id);
+create index api_keys_tenant_id_idx on api_keys (tenant_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index api_keys_tenant_id_idx;
+drop index contacts_tenant_id_idx;
+drop index notifications_tenant_dt_id_idx;
+
+alter table templates
+    drop constraint templates_tenant_name_key,
+    add constraint templates_name_key unique (name);
+alter table notifications
+    drop constraint notifications_tenant_idempotency_key_key,
+    add constraint notifications_idempotency_key_key unique (idempotency_key);
+
+alter table api_keys drop column tenant_id;
+alter table contacts drop column tenant_id;
+alter table templates drop column tenant_id;
+alter table series drop column tenant_id;
+alter table notifications drop column tenant_id;
+
+drop table tenants;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications
+    add column webhook_headers jsonb not null default '{}',
+    add column webhook_secret varchar(255) not null default '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    drop column webhook_headers,
+    drop column webhook_secret;
+-- +goose StatementEnd
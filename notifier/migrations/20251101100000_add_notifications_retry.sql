@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications
+    add column retry_max_attempts int not null default 0,
+    add column retry_backoff int not null default 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications
+    drop column retry_max_attempts,
+    drop column retry_backoff;
+-- +goose StatementEnd
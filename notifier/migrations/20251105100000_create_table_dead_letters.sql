@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table dead_letters(
+    id bigserial primary key,
+    tenant_id bigint,
+    notification_id bigint,
+    channel varchar(20) not null default '',
+    address varchar(255) not null default '',
+    reason varchar(32) not null,
+    error text not null default '',
+    attempts jsonb not null default '[]',
+    payload bytea not null,
+    failed_at timestamptz not null,
+    created_at timestamptz not null default now()
+);
+create index dead_letters_tenant_idx on dead_letters (tenant_id, id);
+create index dead_letters_notification_idx on dead_letters (notification_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table dead_letters;
+-- +goose StatementEnd
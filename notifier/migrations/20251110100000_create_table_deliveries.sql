@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+create table deliveries(
+    id bigserial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    channel varchar(20) not null,
+    address varchar(255) not null,
+    attempt bigint not null default 0,
+    status varchar(50) not null,
+    response text not null default '',
+    error text not null default '',
+    started_at timestamptz not null,
+    finished_at timestamptz not null
+);
+create index deliveries_notification_id_idx on deliveries (notification_id, id);
+
+-- complete ставил sender до отправки, статус выводится из получателей
+update notifications n set status = case
+    when r.waiting > 0 then 'sending'
+    when r.failed = 0 then 'sent'
+    when r.sent = 0 then 'failed'
+    else 'partially_failed'
+end
+from (
+    select notification_id,
+        count(*) filter (where status not in ('sent', 'failed')) as waiting,
+        count(*) filter (where status = 'sent') as sent,
+        count(*) filter (where status = 'failed') as failed
+    from recipients group by notification_id
+) r where r.notification_id = n.id and n.status = 'complete';
+
+update notifications set status = 'sending' where status = 'complete';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+update notifications set status = 'complete'
+where status in ('sending', 'sent', 'partially_failed', 'failed');
+
+drop table deliveries;
+-- +goose StatementEnd
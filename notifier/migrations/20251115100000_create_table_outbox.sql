@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+create table outbox(
+    id bigserial primary key,
+    notification_id bigint not null references notifications (id) on delete cascade,
+    payload bytea not null,
+    publish_at timestamptz not null,
+    attempts integer not null default 0,
+    last_error text not null default '',
+    created_at timestamptz not null default now(),
+    sent_at timestamptz
+);
+create index outbox_unsent_idx on outbox (id) where sent_at is null;
+create index outbox_sent_at_idx on outbox (sent_at) where sent_at is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table outbox;
+-- +goose StatementEnd
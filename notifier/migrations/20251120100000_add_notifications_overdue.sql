@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications
+    add column republished integer not null default 0,
+    add column republished_at timestamptz,
+    add column overdue_at timestamptz;
+create index notifications_pending_dt_idx on notifications (dt, id) where status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index notifications_pending_dt_idx;
+alter table notifications
+    drop column republished,
+    drop column republished_at,
+    drop column overdue_at;
+-- +goose StatementEnd
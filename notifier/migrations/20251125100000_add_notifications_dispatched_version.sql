@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table notifications add column dispatched_version bigint not null default 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table notifications drop column dispatched_version;
+-- +goose StatementEnd
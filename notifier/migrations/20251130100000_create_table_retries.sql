@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+create table retries(
+    id bigserial primary key,
+    payload bytea not null,
+    headers jsonb not null default '{}',
+    due_at timestamptz not null,
+    created_at timestamptz not null default now()
+);
+create index retries_due_at_idx on retries (due_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table retries;
+-- +goose StatementEnd
@@ -8,6 +8,10 @@ document.addEventListener("DOMContentLoaded", function () {
   const telegramConfirmed = document.getElementById("telegram-confirmed");
   const confirmError = document.getElementById("confirm-error");
   const submitButton = document.querySelector(".apple-button");
+  const apiKeyInput = document.getElementById("api_key");
+
+  // Ключ API запоминается в браузере, чтобы не вводить его каждый раз
+  apiKeyInput.value = localStorage.getItem("api_key") || "";
 
   // Авто-высота textarea
   textarea.addEventListener("input", function () {
@@ -100,6 +104,7 @@ document.addEventListener("DOMContentLoaded", function () {
           : true, // всегда true если не используется
         email_set: isEmailChecked,
         email: emailValue,
+        timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
       });
 
       showSuccess("Уведомление успешно создано!");
@@ -172,11 +177,18 @@ document.addEventListener("DOMContentLoaded", function () {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
+        "X-API-Key": apiKeyInput.value.trim(),
       },
       body: JSON.stringify(data),
     });
     const rspClose = response.clone();
     console.log(rspClose.text);
+    if (response.ok) {
+      localStorage.setItem("api_key", apiKeyInput.value.trim());
+    }
+    if (response.status === 401 || response.status === 403) {
+      throw new Error("Неверный ключ API или у ключа нет права create");
+    }
     if (!response.ok) {
       const errorData = await response.json().catch(() => ({}));
       throw new Error(errorData.message || `Ошибка: ${response.status}`);
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"delayednotifier/internal/entities/notification"
+	"delayednotifier/internal/entities/tenant"
 	"delayednotifier/internal/service"
 	"delayednotifier/internal/storage"
 	"delayednotifier/internal/storage/postgres"
@@ -40,6 +41,10 @@ const (
 	RabbitQueue      = "test"
 	RabbitExchange   = "test_ex"
 	RabbitRoutingKey = "test_rk"
+	RabbitDLQ        = "test_dlq"
+	RabbitResults    = "test_results"
+	RabbitClaims     = "test_claims"
+	RabbitRetries    = "test_retries"
 
 	DBMapped      = "5432"
 	RedisMapped   = "6379"
@@ -203,7 +208,8 @@ func TestNotifyEnd2End(t *testing.T) {
 
 	rb := rabbit.New(
 		RabbitUser, RabbitPassword, fmt.Sprintf("%s:%s", host, port.Port()),
-		RabbitQueue, RabbitExchange, RabbitRoutingKey,
+		RabbitQueue, RabbitExchange, RabbitRoutingKey, RabbitDLQ,
+		RabbitResults, RabbitClaims, RabbitRetries,
 	)
 
 	str := storage.New(p, rd, rb)
@@ -211,6 +217,10 @@ func TestNotifyEnd2End(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	g := gin.Default()
+	// Auth здесь не подключен, запросы идут от команды по умолчанию
+	g.Use(func(c *gin.Context) {
+		c.Set(handlers.TenantContext, tenant.DefaultID)
+	})
 
 	// -------------------- CREATING NOTIFICATION -------------------------
 	/*
@@ -228,7 +238,7 @@ func TestNotifyEnd2End(t *testing.T) {
 	t.Log(rr.Body.String())
 	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
 
-	n, err := str.GetNotification(1)
+	n, err := str.GetNotification(tenant.DefaultID, 1)
 	require.NoError(t, err)
 	if n.ID == 0 {
 		t.Error("can't find notififcation after adding")
@@ -250,17 +260,17 @@ func TestNotifyEnd2End(t *testing.T) {
 	t.Log(rr.Body.String())
 	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
 
-	_, err = rd.GetNotification(n.ID)
+	_, err = rd.GetNotification(n.TenantID, n.ID)
 	require.NoError(t, err)
 	// --------------------------------------------------------------------
 
 	// ------------------ UPDATING NOTIFICATION ---------------------------
 	/*
-		send request and check what status of notification was updated
+		send request and check what text of notification was updated
 	*/
 	g.PATCH("/notify/:id", handlers.UpdateNotify(srv))
 
-	body = `{"status": "complete"}`
+	body = `{"message": "hello"}`
 	rr = httptest.NewRecorder()
 	req = httptest.NewRequest(
 		http.MethodPatch, "/notify/1", strings.NewReader(body),
@@ -270,31 +280,123 @@ func TestNotifyEnd2End(t *testing.T) {
 	t.Log(rr.Body.String())
 	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
 
-	n, err = str.GetNotification(1)
+	n, err = str.GetNotification(tenant.DefaultID, 1)
 	require.NoError(t, err)
-	if n.Status != notification.StatusComplete {
-		t.Error("status don't changed after handler")
+	if n.Message != "hello" {
+		t.Error("message don't changed after handler")
 	}
 	// --------------------------------------------------------------------
 
-	// ------------------- DELETING NOTIFICATION --------------------------
+	// ------------------- CANCELLING NOTIFICATION ------------------------
 	/*
-		send request and check what notification was deleted
+		create new notification, cancel it and check what status was changed
 	*/
 	g.DELETE("/notify/:id", handlers.DeleteNotify(srv))
 
+	body = `{"message": "bye", "email": "asd@asd.com", "date": "3000-12-22T15:00:00.000Z"}`
 	rr = httptest.NewRecorder()
 	req = httptest.NewRequest(
-		http.MethodDelete, "/notify/1", nil,
+		http.MethodPost, "/notify", strings.NewReader(body),
+	)
+	g.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(
+		http.MethodDelete, "/notify/2?reason=test", nil,
 	)
 	g.ServeHTTP(rr, req)
 
 	t.Log(rr.Body.String())
 	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
 
-	n, err = str.GetNotification(1)
+	n, err = str.GetNotification(tenant.DefaultID, 2)
+	require.NoError(t, err)
+	require.Equal(t, notification.StatusCancelled, n.Status)
+	require.Equal(t, "test", n.CancelReason)
+
+	body = `{"message": "hello"}`
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(
+		http.MethodPatch, "/notify/2", strings.NewReader(body),
+	)
+	g.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusGone, rr.Result().StatusCode)
+	// --------------------------------------------------------------------
+
+	// ------------------- PURGING NOTIFICATION ---------------------------
+	/*
+		send request and check what cancelled notification was deleted
+	*/
+	g.POST("/notify/purge", handlers.PurgeNotify(srv))
+
+	time.Sleep(time.Second)
+	body = fmt.Sprintf(`{"before": "%s"}`, time.Now().UTC().Format(time.RFC3339))
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(
+		http.MethodPost, "/notify/purge", strings.NewReader(body),
+	)
+	g.ServeHTTP(rr, req)
+
+	t.Log(rr.Body.String())
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	_, err = str.GetNotification(tenant.DefaultID, 2)
 	if !errors.Is(err, storage.ErrNotFound) {
-		t.Error("found notification after deleting")
+		t.Error("found notification after purging")
 	}
 	// --------------------------------------------------------------------
+
+	// ------------------- DELIVERY TO RECIPIENTS -------------------------
+	/*
+		create notification for several recipients, report delivery result
+		for one of them and check per recipient statuses
+	*/
+	body = `{"message": "all", "telegram_ids": ["123", "456"],
+		"emails": ["a@asd.com", "b@asd.com"], "date": "3000-12-22T15:00:00.000Z"}`
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(
+		http.MethodPost, "/notify", strings.NewReader(body),
+	)
+	g.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	err = srv.RecordDelivery(tenant.DefaultID, 3, notification.Delivery{
+		Channel: notification.ChannelEmail, Address: "b@asd.com",
+		Status: notification.RecipientFailed, Error: "mailbox not found",
+	})
+	require.NoError(t, err)
+
+	n, err = str.GetNotification(tenant.DefaultID, 3)
+	require.NoError(t, err)
+	require.Len(t, n.Recipients, 4)
+	require.Equal(t, []string{"123", "456"}, n.Addresses(notification.ChannelTelegram))
+	require.Equal(t, notification.RecipientFailed, n.Recipients[3].Status)
+	require.Equal(t, "mailbox not found", n.Recipients[3].Error)
+	require.Equal(t, notification.RecipientPending, n.Recipients[2].Status)
+	require.Equal(t, notification.StatusPending, n.Status)
+
+	/*
+		after sender took notification its status is derived from recipients
+	*/
+	sending := notification.StatusSending
+	err = srv.UpdateNotification(tenant.DefaultID, 3, notification.Update{Status: &sending})
+	require.NoError(t, err)
+
+	for _, rc := range n.Recipients[:3] {
+		err = srv.RecordDelivery(tenant.DefaultID, 3, notification.Delivery{
+			Channel: rc.Channel, Address: rc.Address,
+			Status: notification.RecipientSent, Response: "200 OK",
+		})
+		require.NoError(t, err)
+	}
+
+	n, err = str.GetNotification(tenant.DefaultID, 3)
+	require.NoError(t, err)
+	require.Equal(t, notification.StatusPartiallyFailed, n.Status)
+	ds, err := str.Deliveries(tenant.DefaultID, 3)
+	require.NoError(t, err)
+	require.Len(t, ds, 4)
+	require.Equal(t, "200 OK", ds[3].Response)
+	// --------------------------------------------------------------------
 }
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
-	"sender/internal/service"
-	"sender/internal/storage"
 	"sender/internal/storage/rabbit"
+	"sender/internal/storage/redisqueue"
+	"sender/worker"
+	"strconv"
 	"syscall"
 
 	"github.com/wb-go/wbf/config"
@@ -20,19 +22,66 @@ func main() {
 		panic(err)
 	}
 
+	c, err := worker.Load(cfg)
+	if err != nil {
+		panic(err)
+	}
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
-	rb := rabbit.New(
-		cfg.GetString("rabbit.host"), cfg.GetString("rabbit.username"),
-		os.Getenv("RABBIT_PASSWORD"), cfg.GetString("rabbit.queue"),
-	)
-	str := storage.New(rb)
-	srv := service.New(str, cfg.GetString("sender.email_username"))
-
-	go srv.Start()
-	zlog.Logger.Info().Msg("start receive messages from queue")
+	q, err := dial(cfg, c.Prefetch())
+	if err != nil {
+		panic(err)
+	}
+	w := worker.New(q, c)
+	go w.Start()
+	zlog.Logger.Info().Strs("channels", c.Channels()).
+		Msg("start receive messages from queue")
 
 	<-sig
-	str.Shutdown()
+	zlog.Logger.Info().Msg("shutdown starting...")
+	// новые сообщения не берем, начатые доотправляем, неподтвержденные
+	// вернутся в очередь при закрытии канала
+	w.Stop()
+}
+
+// dial подключается к брокеру из queue.type: rabbit (по умолчанию) или
+// redis, тот же, что у notifier
+func dial(cfg *config.Config, prefetch int) (worker.Messager, error) {
+	switch v := cfg.GetString("queue.type"); v {
+	case "", "rabbit":
+		// при scheduler.type postgres у notifier нет отложенного обменника,
+		// повторы хранит его база
+		exchanger := cfg.GetString("rabbit.exchanger")
+		switch s := cfg.GetString("scheduler.type"); s {
+		case "", "rabbit":
+		case "postgres":
+			exchanger = ""
+		default:
+			return nil, fmt.Errorf("scheduler.type: wrong value %q (rabbit or postgres)", s)
+		}
+		return rabbit.New(
+			cfg.GetString("rabbit.host"), cfg.GetString("rabbit.username"),
+			os.Getenv("RABBIT_PASSWORD"), cfg.GetString("rabbit.queue"),
+			exchanger, cfg.GetString("rabbit.routing_key"),
+			cfg.GetString("rabbit.dead_letter_queue"), cfg.GetString("rabbit.results_queue"),
+			cfg.GetString("rabbit.claims_queue"), cfg.GetString("rabbit.retries_queue"),
+			prefetch,
+		), nil
+	case "redis":
+		o, err := worker.LoadRedisQueue(cfg)
+		if err != nil {
+			return nil, err
+		}
+		db, err := strconv.Atoi(cfg.GetString("redis.db"))
+		if err != nil {
+			return nil, fmt.Errorf("redis.db: %w", err)
+		}
+		return redisqueue.New(
+			cfg.GetString("redis.addr"), os.Getenv("REDIS_PASSWORD"), db, o, prefetch,
+		), nil
+	default:
+		return nil, fmt.Errorf("queue.type: wrong value %q (rabbit or redis)", v)
+	}
 }
@@ -0,0 +1,59 @@
+package contact
+
+import "sender/internal/entities/notification"
+
+// Contact получатель из справочника notifier. Поля совпадают с JSON
+// контакта в notifier
+type Contact struct {
+	ID               int64
+	Name             string
+	TelegramID       string
+	Email            string
+	PreferredChannel string
+	Locale           string
+	Timezone         string
+}
+
+// Recipients адреса контакта для отправки. Если задан предпочитаемый канал -
+// только он, иначе все заданные каналы
+func (c Contact) Recipients() []notification.Recipient {
+	addrs := []struct{ channel, address string }{
+		{notification.ChannelTelegram, c.TelegramID},
+		{notification.ChannelEmail, c.Email},
+	}
+
+	rs := []notification.Recipient{}
+	for _, a := range addrs {
+		if a.address == "" {
+			continue
+		}
+		if c.PreferredChannel != "" && c.PreferredChannel != a.channel {
+			continue
+		}
+		rs = append(rs, notification.Recipient{
+			Channel: a.channel,
+			Address: a.address,
+			Status:  notification.RecipientPending,
+		})
+	}
+
+	return rs
+}
+
+// Merge добавляет к rs адреса контакта, которых там еще нет
+func (c Contact) Merge(rs []notification.Recipient) []notification.Recipient {
+	for _, cr := range c.Recipients() {
+		found := false
+		for _, r := range rs {
+			if r.Channel == cr.Channel && r.Address == cr.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rs = append(rs, cr)
+		}
+	}
+
+	return rs
+}
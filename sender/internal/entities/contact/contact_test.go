@@ -0,0 +1,54 @@
+package contact
+
+import (
+	"sender/internal/entities/notification"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Contact
+		want []notification.Recipient
+	}{
+		{
+			name: "all channels",
+			c:    Contact{TelegramID: "1", Email: "a@b.c"},
+			want: []notification.Recipient{
+				{Channel: notification.ChannelTelegram, Address: "1", Status: notification.RecipientPending},
+				{Channel: notification.ChannelEmail, Address: "a@b.c", Status: notification.RecipientPending},
+			},
+		},
+		{
+			name: "preferred",
+			c:    Contact{TelegramID: "1", Email: "a@b.c", PreferredChannel: notification.ChannelEmail},
+			want: []notification.Recipient{
+				{Channel: notification.ChannelEmail, Address: "a@b.c", Status: notification.RecipientPending},
+			},
+		},
+		{
+			name: "no address",
+			c:    Contact{},
+			want: []notification.Recipient{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.c.Recipients())
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	c := Contact{TelegramID: "1", Email: "a@b.c"}
+	rs := []notification.Recipient{
+		{Channel: notification.ChannelEmail, Address: "a@b.c", Status: notification.RecipientSent},
+	}
+
+	got := c.Merge(rs)
+	require.Len(t, got, 2)
+	require.Equal(t, notification.RecipientSent, got[0].Status)
+	require.Equal(t, notification.ChannelTelegram, got[1].Channel)
+}
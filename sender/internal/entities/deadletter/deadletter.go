@@ -0,0 +1,35 @@
+package deadletter
+
+import "time"
+
+// Причины, по которым сообщение уходит в DLQ. Совпадают с notifier
+const (
+	// ReasonUnreadable сообщение очереди не удалось разобрать
+	ReasonUnreadable = "unreadable"
+	// ReasonPermanent отправка не удалась и повтор не поможет
+	ReasonPermanent = "permanent_error"
+	// ReasonExhausted временные ошибки, попытки кончились
+	ReasonExhausted = "retries_exhausted"
+)
+
+// Attempt неудачная попытка отправки, Attempt - ее номер, 0 - первая
+type Attempt struct {
+	Attempt int64     `json:"attempt"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
+
+// Letter недоставленное сообщение, notifier принимает его из DLQ в JSON.
+// Payload - сообщение очереди, для доставки одному получателю в нем
+// только он
+type Letter struct {
+	TenantID       int64     `json:"tenant_id"`
+	NotificationID int64     `json:"notification_id"`
+	Channel        string    `json:"channel"`
+	Address        string    `json:"address"`
+	Reason         string    `json:"reason"`
+	Error          string    `json:"error"`
+	Attempts       []Attempt `json:"attempts"`
+	Payload        []byte    `json:"payload"`
+	FailedAt       time.Time `json:"failed_at"`
+}
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"sender/internal/entities/contact"
+	"sender/internal/entities/notification"
+)
+
+// Claim запрос к notifier перед отправкой: уведомление отмечается взятым,
+// если его версия совпадает с версией в сообщении. Совпадает с notifier
+type Claim struct {
+	TenantID       int64 `json:"tenant_id"`
+	NotificationID int64 `json:"notification_id"`
+	Version        int64 `json:"version"`
+	ContactID      int64 `json:"contact_id,omitempty"`
+}
+
+// Ответы на Claim
+const (
+	ClaimOK         = "ok"
+	ClaimCancelled  = "cancelled"
+	ClaimSuperseded = "superseded"
+	ClaimNotFound   = "not_found"
+	// ClaimFailed notifier не смог обработать запрос
+	ClaimFailed = "failed"
+)
+
+// ClaimReply ответ notifier на Claim. Contact - адреса контакта уведомления
+// на момент отправки, Error - почему контакта нет или почему запрос не
+// обработан. Recorded - получатели, результат доставки которым уже записан,
+// им повторное сообщение не отправляется
+type ClaimReply struct {
+	Result   string           `json:"result"`
+	Error    string           `json:"error,omitempty"`
+	Contact  *contact.Contact `json:"contact,omitempty"`
+	Recorded []Recipient      `json:"recorded,omitempty"`
+}
+
+// Recipient получатель и записанный статус его доставки
+type Recipient struct {
+	Channel string `json:"channel"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
+}
+
+// Delivery результат попытки доставки одному получателю
+type Delivery struct {
+	TenantID       int64 `json:"tenant_id"`
+	NotificationID int64 `json:"notification_id"`
+	notification.Delivery
+}
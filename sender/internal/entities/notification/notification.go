@@ -3,28 +3,118 @@ package notification
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
 	"time"
 )
 
 // Notify модель отложенного уведомления
 type Notification struct {
-	ID         int64     `db:"id"`
-	TelegramID int64     `db:"telegram_id"`
-	Message    string    `db:"message"`
-	Email      string    `db:"email"`
-	Status     string    `db:"status"`
-	Date       time.Time `db:"dt"`
+	ID           int64     `db:"id"`
+	Message      string    `db:"message"`
+	Status       string    `db:"status"`
+	Date         time.Time `db:"dt"`
+	Version      int64     `db:"version"`
+	CancelledAt  time.Time `db:"cancelled_at"`
+	CancelReason string    `db:"cancel_reason"`
+	SeriesID     int64     `db:"series_id"`
+	Timezone     string    `db:"timezone"`
+	// текст для отдельных каналов, пустой - используется Message
+	TelegramMessage string `db:"telegram_message"`
+	EmailSubject    string `db:"email_subject"`
+	EmailMessage    string `db:"email_message"`
+	TemplateID      int64  `db:"template_id"`
+	Recipients      []Recipient
+	// ContactID сохраненный контакт, его адреса sender берет при отправке
+	ContactID int64 `db:"contact_id"`
+	// TenantID команда, которой принадлежит уведомление
+	TenantID int64 `db:"tenant_id"`
+	// WebhookHeaders дополнительные заголовки запроса в канал webhook,
+	// WebhookSecret - ключ подписи его тела
+	WebhookHeaders map[string]string `db:"webhook_headers"`
+	WebhookSecret  string            `db:"webhook_secret"`
+	// RetryMaxAttempts, RetryBackoff политика повтора неудачной отправки:
+	// попыток всего и первая пауза в секундах, 0 - настройки sender
+	RetryMaxAttempts int64 `db:"retry_max_attempts"`
+	RetryBackoff     int64 `db:"retry_backoff"`
+	// Attempt номер повтора в сообщении очереди, 0 - первая отправка
+	Attempt int64 `db:"-" json:"-"`
 }
 
+// Recipient получатель уведомления в одном канале, Address - chat id для
+// Telegram, адрес почты или URL для webhook
+type Recipient struct {
+	Channel string `db:"channel"`
+	Address string `db:"address"`
+	Status  string `db:"status"`
+	Error   string `db:"error"`
+}
+
+// Delivery попытка доставки одному получателю. Attempt - номер попытки, 0 - первая, Response - ответ провайдера канала
+type Delivery struct {
+	Channel    string    `json:"channel"`
+	Address    string    `json:"address"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+	Attempt    int64     `json:"attempt"`
+	Response   string    `json:"response"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// DefaultEmailSubject тема письма, если уведомление создано без нее
+const DefaultEmailSubject = "Новое уведомление"
+
 const (
 	DateLayout = time.RFC3339
 
-	StatusPending  = "pending"
-	StatusComplete = "complete"
+	StatusPending   = "pending"
+	StatusCancelled = "cancelled"
+	// StatusSending уведомление взято sender, дальше статус выводит notifier
+	StatusSending = "sending"
+
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+
+	RecipientPending = "pending"
+	RecipientSent    = "sent"
+	RecipientFailed  = "failed"
+	// RecipientRetrying отправка не удалась, повтор запланирован
+	RecipientRetrying = "retrying"
 )
 
-func (n Notification) DT() string {
-	return n.Date.Format(time.DateTime)
+// TelegramText текст для Telegram, вариант канала или общий текст
+func (n Notification) TelegramText() string {
+	if n.TelegramMessage != "" {
+		return n.TelegramMessage
+	}
+	return n.Message
+}
+
+// EmailText тема и текст письма
+func (n Notification) EmailText() (string, string) {
+	subject, body := n.EmailSubject, n.EmailMessage
+	if subject == "" {
+		subject = DefaultEmailSubject
+	}
+	if body == "" {
+		body = n.Message
+	}
+	return subject, body
+}
+
+// webhookHeaderNames имена заголовков webhook по порядку, чтобы отпечаток и
+// бинарный вид не зависели от обхода map
+func (n Notification) webhookHeaderNames() []string {
+	r := make([]string, 0, len(n.WebhookHeaders))
+	for k := range n.WebhookHeaders {
+		r = append(r, k)
+	}
+	slices.Sort(r)
+
+	return r
 }
 
 func (n Notification) MarshalBinary() ([]byte, error) {
@@ -34,11 +124,8 @@ func (n Notification) MarshalBinary() ([]byte, error) {
 	if err := binary.Write(b, binary.LittleEndian, n.ID); err != nil {
 		return nil, err
 	}
-	if err := binary.Write(b, binary.LittleEndian, n.TelegramID); err != nil {
-		return nil, err
-	}
 
-	strFields := []string{n.Message, n.Email, n.Status}
+	strFields := []string{n.Message, n.Status}
 	for _, f := range strFields {
 		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
 			return nil, err
@@ -57,6 +144,78 @@ func (n Notification) MarshalBinary() ([]byte, error) {
 	if err := binary.Write(b, binary.LittleEndian, d); err != nil {
 		return nil, err
 	}
+	if err := binary.Write(b, binary.LittleEndian, n.Version); err != nil {
+		return nil, err
+	}
+	c, err := n.CancelledAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range [][]byte{c, []byte(n.CancelReason)} {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.SeriesID); err != nil {
+		return nil, err
+	}
+	channelFields := []string{
+		n.Timezone, n.TelegramMessage, n.EmailSubject, n.EmailMessage,
+	}
+	for _, f := range channelFields {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.TemplateID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, int32(len(n.Recipients))); err != nil {
+		return nil, err
+	}
+	for _, rc := range n.Recipients {
+		for _, f := range []string{rc.Channel, rc.Address, rc.Status, rc.Error} {
+			if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+				return nil, err
+			}
+			if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.ContactID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, n.TenantID); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b, binary.LittleEndian, int32(len(n.WebhookHeaders))); err != nil {
+		return nil, err
+	}
+	webhookFields := make([]string, 0, 2*len(n.WebhookHeaders)+1)
+	for _, k := range n.webhookHeaderNames() {
+		webhookFields = append(webhookFields, k, n.WebhookHeaders[k])
+	}
+	webhookFields = append(webhookFields, n.WebhookSecret)
+	for _, f := range webhookFields {
+		if err := binary.Write(b, binary.LittleEndian, int32(len(f))); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(b, binary.LittleEndian, []byte(f)); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []int64{n.RetryMaxAttempts, n.RetryBackoff, n.Attempt} {
+		if err := binary.Write(b, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
 	return b.Bytes(), nil
 }
 
@@ -72,38 +231,156 @@ func (n *Notification) UnmarshalBinary(data []byte) error {
 		return err
 	}
 	n.ID = ID
-	var TelegramID int64
-	if err := binary.Read(b, binary.LittleEndian, &TelegramID); err != nil {
-		return err
-	}
-	n.TelegramID = TelegramID
 
-	strFields := []*string{&n.Message, &n.Email, &n.Status}
+	strFields := []*string{&n.Message, &n.Status}
 	for _, f := range strFields {
-		var l int32
-		if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+		bf, err := readBytes(b)
+		if err != nil {
 			return err
 		}
-		bf := make([]byte, l)
-		if err := binary.Read(b, binary.LittleEndian, bf); err != nil {
+		*f = string(bf)
+	}
+	t := time.Time{}
+	bf, err := readBytes(b)
+	if err != nil {
+		return err
+	}
+	err = t.UnmarshalBinary(bf)
+	if err != nil {
+		return err
+	}
+	n.Date = t
+	var Version int64
+	if err := binary.Read(b, binary.LittleEndian, &Version); err != nil {
+		return err
+	}
+	n.Version = Version
+	var tail [2][]byte
+	for i := range tail {
+		bf, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		tail[i] = bf
+	}
+	if err := n.CancelledAt.UnmarshalBinary(tail[0]); err != nil {
+		return err
+	}
+	n.CancelReason = string(tail[1])
+	var SeriesID int64
+	if err := binary.Read(b, binary.LittleEndian, &SeriesID); err != nil {
+		return err
+	}
+	n.SeriesID = SeriesID
+	channelFields := []*string{
+		&n.Timezone, &n.TelegramMessage, &n.EmailSubject, &n.EmailMessage,
+	}
+	for _, f := range channelFields {
+		bf, err := readBytes(b)
+		if err != nil {
 			return err
 		}
 		*f = string(bf)
 	}
-	t := time.Time{}
-	var l int32
-	if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+	var TemplateID int64
+	if err := binary.Read(b, binary.LittleEndian, &TemplateID); err != nil {
 		return err
 	}
-	bf := make([]byte, l)
-	if err := binary.Read(b, binary.LittleEndian, bf); err != nil {
+	n.TemplateID = TemplateID
+	// у получателя 4 строки, у каждой длина не меньше 4 байт
+	count, err := readCount(b, 16)
+	if err != nil {
+		return err
+	}
+	n.Recipients = nil
+	for i := int32(0); i < count; i++ {
+		rc := Recipient{}
+		for _, f := range []*string{&rc.Channel, &rc.Address, &rc.Status, &rc.Error} {
+			bf, err := readBytes(b)
+			if err != nil {
+				return err
+			}
+			*f = string(bf)
+		}
+		n.Recipients = append(n.Recipients, rc)
+	}
+	var ContactID int64
+	if err := binary.Read(b, binary.LittleEndian, &ContactID); err != nil {
 		return err
 	}
-	err := t.UnmarshalBinary(bf)
+	n.ContactID = ContactID
+	var TenantID int64
+	if err := binary.Read(b, binary.LittleEndian, &TenantID); err != nil {
+		return err
+	}
+	n.TenantID = TenantID
+	headers, err := readCount(b, 8)
 	if err != nil {
 		return err
 	}
-	n.Date = t
+	readString := func() (string, error) {
+		bf, err := readBytes(b)
+		return string(bf), err
+	}
+	n.WebhookHeaders = nil
+	for i := int32(0); i < headers; i++ {
+		k, err := readString()
+		if err != nil {
+			return err
+		}
+		v, err := readString()
+		if err != nil {
+			return err
+		}
+		if n.WebhookHeaders == nil {
+			n.WebhookHeaders = make(map[string]string, headers)
+		}
+		n.WebhookHeaders[k] = v
+	}
+	secret, err := readString()
+	if err != nil {
+		return err
+	}
+	n.WebhookSecret = secret
+	for _, f := range []*int64{&n.RetryMaxAttempts, &n.RetryBackoff, &n.Attempt} {
+		if err := binary.Read(b, binary.LittleEndian, f); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// ErrCorrupted бинарное представление уведомления повреждено: длина поля
+// отрицательная или больше оставшихся данных
+var ErrCorrupted = errors.New("corrupted notification binary")
+
+// readBytes читает поле с длиной впереди. Длина проверяется до выделения
+// памяти, иначе поврежденное сообщение из очереди роняет процесс
+func readBytes(b *bytes.Reader) ([]byte, error) {
+	var l int32
+	if err := binary.Read(b, binary.LittleEndian, &l); err != nil {
+		return nil, err
+	}
+	if l < 0 || int64(l) > int64(b.Len()) {
+		return nil, ErrCorrupted
+	}
+	bf := make([]byte, l)
+	if _, err := io.ReadFull(b, bf); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// readCount читает число элементов, каждый из которых занимает не меньше
+// size байт
+func readCount(b *bytes.Reader, size int64) (int32, error) {
+	var n int32
+	if err := binary.Read(b, binary.LittleEndian, &n); err != nil {
+		return 0, err
+	}
+	if n < 0 || int64(n)*size > int64(b.Len()) {
+		return 0, ErrCorrupted
+	}
+	return n, nil
+}
@@ -1,21 +1,38 @@
 package notification
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"reflect"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
+// to получатели по адресам: числовой адрес - Telegram, остальные - почта
+func to(addrs ...string) []Recipient {
+	r := make([]Recipient, 0, len(addrs))
+	for _, a := range addrs {
+		ch := ChannelEmail
+		if _, err := strconv.ParseInt(a, 10, 64); err == nil {
+			ch = ChannelTelegram
+		}
+		r = append(r, Recipient{Channel: ch, Address: a, Status: RecipientPending})
+	}
+
+	return r
+}
+
 func BenchmarkBinary(b *testing.B) {
 	tm, _ := time.Parse(DateLayout, "2000-12-22 15:00")
 
 	for i := 0; i < b.N; i++ {
 		m := Notification{
-			ID: 1, TelegramID: 123, Message: "hihi",
-			Email: "asd@asad.com", Status: "pending", Date: tm,
+			ID: 1, Message: "hihi", Recipients: to("123", "asd@asad.com"),
+			Status: "pending", Date: tm,
 		}
 		b, _ := m.MarshalBinary()
 		t := Notification{}
@@ -27,8 +44,8 @@ func BenchmarkJSON(b *testing.B) {
 	tm, _ := time.Parse(DateLayout, "2000-12-22 15:00")
 	for i := 0; i < b.N; i++ {
 		m := Notification{
-			ID: 1, TelegramID: 123, Message: "hihi",
-			Email: "asd@asad.com", Status: "pending", Date: tm,
+			ID: 1, Message: "hihi", Recipients: to("123", "asd@asad.com"),
+			Status: "pending", Date: tm,
 		}
 		v, _ := json.Marshal(m)
 		t := Notification{}
@@ -42,8 +59,8 @@ func BenchmarkJSONBinary(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 
 		m := Notification{
-			ID: 1, TelegramID: 123, Message: "hihi",
-			Email: "asd@asad.com", Status: "pending", Date: tm,
+			ID: 1, Message: "hihi", Recipients: to("123", "asd@asad.com"),
+			Status: "pending", Date: tm,
 		}
 		b, _ := m.MarshalBinary()
 		v, _ := json.Marshal(b)
@@ -64,12 +81,100 @@ func TestMarshalUnmarshal(t *testing.T) {
 		{
 			name: "good",
 			data: Notification{
-				ID: 1, TelegramID: 123, Message: "hihi",
-				Email: "asd@asad.com", Status: "pending", Date: tm,
+				ID: 1, Message: "hihi", Recipients: to("123", "asd@asad.com"),
+				Status: "pending", Date: tm, Version: 2,
+				Timezone: "Europe/Moscow",
+			},
+			want: Notification{
+				ID: 1, Message: "hihi", Recipients: to("123", "asd@asad.com"),
+				Status: "pending", Date: tm, Version: 2,
+				Timezone: "Europe/Moscow",
+			},
+		},
+		{
+			name: "cancelled",
+			data: Notification{
+				ID: 1, Recipients: to("123"), Message: "hihi", Status: "cancelled",
+				Date: tm, Version: 1, CancelledAt: tm, CancelReason: "test",
+				SeriesID: 3,
+			},
+			want: Notification{
+				ID: 1, Recipients: to("123"), Message: "hihi", Status: "cancelled",
+				Date: tm, Version: 1, CancelledAt: tm, CancelReason: "test",
+				SeriesID: 3,
+			},
+		},
+		{
+			name: "channel variants",
+			data: Notification{
+				ID: 1, Recipients: to("123"), Message: "hihi", Status: "pending",
+				Date: tm, Version: 1, TelegramMessage: "hi",
+				EmailSubject: "Hello", EmailMessage: "hello\nthere", TemplateID: 7,
+			},
+			want: Notification{
+				ID: 1, Recipients: to("123"), Message: "hihi", Status: "pending",
+				Date: tm, Version: 1, TelegramMessage: "hi",
+				EmailSubject: "Hello", EmailMessage: "hello\nthere", TemplateID: 7,
+			},
+		},
+		{
+			name: "delivery status",
+			data: Notification{
+				ID: 1, Message: "hihi", Status: "complete", Date: tm, Version: 1,
+				Recipients: []Recipient{
+					{Channel: ChannelTelegram, Address: "123", Status: RecipientSent},
+					{Channel: ChannelEmail, Address: "a@b.c", Status: RecipientFailed, Error: "timeout"},
+				},
+			},
+			want: Notification{
+				ID: 1, Message: "hihi", Status: "complete", Date: tm, Version: 1,
+				Recipients: []Recipient{
+					{Channel: ChannelTelegram, Address: "123", Status: RecipientSent},
+					{Channel: ChannelEmail, Address: "a@b.c", Status: RecipientFailed, Error: "timeout"},
+				},
+			},
+		},
+		{
+			name: "contact and tenant",
+			data: Notification{
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				ContactID: 4, TenantID: 2,
 			},
 			want: Notification{
-				ID: 1, TelegramID: 123, Message: "hihi",
-				Email: "asd@asad.com", Status: "pending", Date: tm,
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				ContactID: 4, TenantID: 2,
+			},
+		},
+		{
+			name: "webhook",
+			data: Notification{
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				Recipients: []Recipient{{
+					Channel: ChannelWebhook, Address: "https://example.com/hook",
+					Status: RecipientPending,
+				}},
+				WebhookHeaders: map[string]string{"X-Source": "crm", "Authorization": "Token 1"},
+				WebhookSecret:  "s3cret",
+			},
+			want: Notification{
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				Recipients: []Recipient{{
+					Channel: ChannelWebhook, Address: "https://example.com/hook",
+					Status: RecipientPending,
+				}},
+				WebhookHeaders: map[string]string{"X-Source": "crm", "Authorization": "Token 1"},
+				WebhookSecret:  "s3cret",
+			},
+		},
+		{
+			name: "retry",
+			data: Notification{
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				Recipients: to("123"), RetryMaxAttempts: 5, RetryBackoff: 30, Attempt: 2,
+			},
+			want: Notification{
+				ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+				Recipients: to("123"), RetryMaxAttempts: 5, RetryBackoff: 30, Attempt: 2,
 			},
 		},
 	}
@@ -85,3 +190,61 @@ func TestMarshalUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalBinary_Corrupted(t *testing.T) {
+	tm, _ := time.Parse(DateLayout, "2000-12-22 15:00")
+	good, err := Notification{
+		ID: 1, Message: "hihi", Status: "pending", Date: tm, Version: 1,
+	}.MarshalBinary()
+	require.NoError(t, err)
+	// id и длина сообщения l
+	head := func(l int32) []byte {
+		b := binary.LittleEndian.AppendUint64(nil, 1)
+		return binary.LittleEndian.AppendUint32(b, uint32(l))
+	}
+	// count записан перед 48 байтами: contact, tenant, число заголовков,
+	// секрет и политика повтора
+	count := func(c int32) []byte {
+		b := slices.Clone(good)
+		binary.LittleEndian.PutUint32(b[len(b)-52:], uint32(c))
+		return b
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "truncated", data: good[:len(good)-3]},
+		{name: "truncated field", data: append(head(10), "hihi"...)},
+		{name: "negative length", data: append(head(-1), "hihi"...), wantErr: ErrCorrupted},
+		{name: "oversized length", data: append(head(1<<30), "hihi"...), wantErr: ErrCorrupted},
+		{name: "negative count", data: count(-1), wantErr: ErrCorrupted},
+		{name: "oversized count", data: count(1 << 30), wantErr: ErrCorrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Notification{}
+			err := n.UnmarshalBinary(tt.data)
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+	n := Notification{}
+	require.NoError(t, n.UnmarshalBinary(count(0)))
+}
+
+func TestChannelText(t *testing.T) {
+	n := Notification{Message: "hihi"}
+	require.Equal(t, "hihi", n.TelegramText())
+	subject, body := n.EmailText()
+	require.Equal(t, DefaultEmailSubject, subject)
+	require.Equal(t, "hihi", body)
+
+	n.TelegramMessage, n.EmailSubject, n.EmailMessage = "hi", "Hello", "hello"
+	require.Equal(t, "hi", n.TelegramText())
+	subject, body = n.EmailText()
+	require.Equal(t, "Hello", subject)
+	require.Equal(t, "hello", body)
+}
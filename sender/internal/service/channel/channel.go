@@ -0,0 +1,120 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sender/internal/entities/notification"
+	"sort"
+)
+
+var (
+	ErrUnknownChannel = errors.New("unknown channel")
+	ErrDuplicate      = errors.New("channel already registered")
+	ErrWrongAddress   = errors.New("wrong address for channel")
+)
+
+// Channel способ доставки уведомления. Name совпадает с именем канала у
+// получателя, Validate проверяет адрес до отправки
+type Channel interface {
+	Name() string
+	Validate(address string) error
+	Send(ctx context.Context, n notification.Notification, address string) Result
+}
+
+// Result итог отправки одному получателю. Retryable - ошибка временная
+// (сеть, 5xx, 429), повтор отправки может пройти. Response - ответ
+// провайдера канала, если он есть
+type Result struct {
+	Status    string
+	Err       error
+	Retryable bool
+	Response  string
+}
+
+// MaxResponseLen ответ провайдера обрезается до этой длины
+const MaxResponseLen = 512
+
+// WithResponse итог с ответом провайдера
+func (r Result) WithResponse(resp string) Result {
+	if len(resp) > MaxResponseLen {
+		resp = resp[:MaxResponseLen]
+	}
+	r.Response = resp
+
+	return r
+}
+
+// Sent успешная отправка
+func Sent() Result {
+	return Result{Status: notification.RecipientSent}
+}
+
+// Failed неудачная отправка
+func Failed(err error, retryable bool) Result {
+	return Result{Status: notification.RecipientFailed, Err: err, Retryable: retryable}
+}
+
+// Registry включенные каналы по имени
+type Registry struct {
+	chs map[string]Channel
+}
+
+func NewRegistry() *Registry {
+	return &Registry{chs: map[string]Channel{}}
+}
+
+// Register добавляет канал, имя должно быть уникальным
+func (r *Registry) Register(c Channel) error {
+	if _, ok := r.chs[c.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicate, c.Name())
+	}
+	r.chs[c.Name()] = c
+
+	return nil
+}
+
+// Get канал по имени
+func (r *Registry) Get(name string) (Channel, error) {
+	c, ok := r.chs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, name)
+	}
+
+	return c, nil
+}
+
+// Names имена зарегистрированных каналов по алфавиту
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.chs))
+	for name := range r.chs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Send отправляет уведомление получателю через его канал. Неизвестный канал
+// и неверный адрес - неудача без повтора
+func (r *Registry) Send(ctx context.Context, n notification.Notification, rc notification.Recipient) Result {
+	c, err := r.Get(rc.Channel)
+	if err != nil {
+		return Failed(err, false)
+	}
+	if err := c.Validate(rc.Address); err != nil {
+		return Failed(err, false)
+	}
+
+	return c.Send(ctx, n, rc.Address)
+}
+
+// HTTPResult итог запроса по коду ответа: 2xx - отправлено, 429 и 5xx -
+// временная ошибка
+func HTTPResult(code int, err error) Result {
+	if code >= 200 && code <= 299 {
+		return Sent()
+	}
+
+	return Failed(err, code == 429 || code >= 500)
+}
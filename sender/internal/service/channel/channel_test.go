@@ -0,0 +1,104 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sender/internal/entities/notification"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type ChannelMock struct {
+	name  string
+	sendF func(n notification.Notification, address string) Result
+}
+
+func (c ChannelMock) Name() string {
+	return c.name
+}
+
+func (c ChannelMock) Validate(address string) error {
+	if address == "" {
+		return ErrWrongAddress
+	}
+	return nil
+}
+
+func (c ChannelMock) Send(ctx context.Context, n notification.Notification, address string) Result {
+	return c.sendF(n, address)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	sent := ChannelMock{name: "sms", sendF: func(n notification.Notification, address string) Result {
+		return Sent()
+	}}
+	down := ChannelMock{name: "push", sendF: func(n notification.Notification, address string) Result {
+		return Failed(errors.New("down"), true)
+	}}
+	require.NoError(t, r.Register(sent))
+	require.NoError(t, r.Register(down))
+	require.ErrorIs(t, r.Register(sent), ErrDuplicate)
+	require.Equal(t, []string{"push", "sms"}, r.Names())
+
+	tests := []struct {
+		name          string
+		rc            notification.Recipient
+		wantStatus    string
+		wantErr       error
+		wantRetryable bool
+	}{
+		{
+			name:       "sent",
+			rc:         notification.Recipient{Channel: "sms", Address: "1"},
+			wantStatus: notification.RecipientSent,
+		},
+		{
+			name:          "failed",
+			rc:            notification.Recipient{Channel: "push", Address: "1"},
+			wantStatus:    notification.RecipientFailed,
+			wantRetryable: true,
+		},
+		{
+			name:       "unknown channel",
+			rc:         notification.Recipient{Channel: "fax", Address: "1"},
+			wantStatus: notification.RecipientFailed,
+			wantErr:    ErrUnknownChannel,
+		},
+		{
+			name:       "wrong address",
+			rc:         notification.Recipient{Channel: "sms"},
+			wantStatus: notification.RecipientFailed,
+			wantErr:    ErrWrongAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := r.Send(context.Background(), notification.Notification{}, tt.rc)
+			require.Equal(t, tt.wantStatus, res.Status)
+			require.Equal(t, tt.wantRetryable, res.Retryable)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, res.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPResult(t *testing.T) {
+	err := errors.New("bad")
+	require.Equal(t, Sent(), HTTPResult(204, err))
+	require.True(t, HTTPResult(503, err).Retryable)
+	require.True(t, HTTPResult(429, err).Retryable)
+	require.False(t, HTTPResult(400, err).Retryable)
+	require.Equal(t, notification.RecipientFailed, HTTPResult(404, err).Status)
+}
+
+func TestResult_WithResponse(t *testing.T) {
+	r := Failed(errors.New("bad"), true).WithResponse("503 busy")
+	require.Equal(t, "503 busy", r.Response)
+	require.True(t, r.Retryable)
+
+	long := make([]byte, MaxResponseLen+10)
+	require.Len(t, Sent().WithResponse(string(long)).Response, MaxResponseLen)
+}
@@ -1,28 +1,66 @@
 package email
 
 import (
-	"os"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
 	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
 
-	"github.com/wb-go/wbf/zlog"
 	gomail "gopkg.in/mail.v2"
 )
 
-func Send(n notification.Notification, emailUsername string) {
-	const op = "internal.service.email.Send"
+// Config параметры SMTP сервера, Username - адрес отправителя
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
 
-	m := gomail.NewMessage()
-	m.SetHeader("From", emailUsername)
-	m.SetHeader("To", n.Email)
-	m.SetHeader("Subject", "Новое уведомление")
-	m.SetBody("text/plain", n.Message)
+// Email канал доставки письмом, адрес - почта получателя
+type Email struct {
+	cfg Config
+}
+
+func New(cfg Config) *Email {
+	return &Email{cfg: cfg}
+}
+
+func (e *Email) Name() string {
+	return notification.ChannelEmail
+}
 
-	d := gomail.NewDialer(
-		"smtp.gmail.com", 587, emailUsername,
-		os.Getenv("EMAIL_PASSWORD"),
-	)
+func (e *Email) Validate(to string) error {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("%w: %s %q", channel.ErrWrongAddress, e.Name(), to)
+	}
 
+	return nil
+}
+
+// Send отправляет уведомление письмом на адрес to. Ответ SMTP 5xx -
+// постоянная ошибка, остальные можно повторить
+func (e *Email) Send(ctx context.Context, n notification.Notification, to string) channel.Result {
+	// gomail не принимает контекст, поэтому отмена проверяется до отправки
+	if err := ctx.Err(); err != nil {
+		return channel.Failed(err, true)
+	}
+	subject, body := n.EmailText()
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", e.cfg.Username)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", body)
+
+	d := gomail.NewDialer(e.cfg.Host, e.cfg.Port, e.cfg.Username, e.cfg.Password)
 	if err := d.DialAndSend(m); err != nil {
-		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).Send()
+		var tpErr *textproto.Error
+		return channel.Failed(err, !errors.As(err, &tpErr) || tpErr.Code < 500)
 	}
+
+	return channel.Sent()
 }
@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
+	"time"
+)
+
+// Policy повтор неудачной отправки. MaxAttempts - попыток всего, 0 и 1 -
+// без повторов. Пауза перед k-м повтором Backoff*2^(k-1), не больше
+// MaxBackoff, и отклоняется на случайную долю до Jitter в обе стороны
+type Policy struct {
+	MaxAttempts int64
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Jitter      float64
+}
+
+// For политика для уведомления, заданные в нем поля заменяют настройки
+// sender
+func (p Policy) For(n notification.Notification) Policy {
+	if n.RetryMaxAttempts > 0 {
+		p.MaxAttempts = n.RetryMaxAttempts
+	}
+	if n.RetryBackoff > 0 {
+		p.Backoff = time.Duration(n.RetryBackoff) * time.Second
+		p.MaxBackoff = max(p.MaxBackoff, p.Backoff)
+	}
+
+	return p
+}
+
+// Delay пауза перед повтором retry (1 - первый повтор), rnd - случайное
+// число из [0, 1)
+func (p Policy) Delay(retry int64, rnd float64) time.Duration {
+	d := p.Backoff
+	for i := int64(1); i < retry && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rnd - 1))
+	}
+
+	return max(d, 0)
+}
+
+// Next решает, повторять ли отправку после попытки attempt (0 - первая) с
+// результатом res, и возвращает паузу перед повтором
+func (p Policy) Next(attempt int64, res channel.Result) (time.Duration, bool) {
+	if res.Err == nil || !res.Retryable || attempt+1 >= p.MaxAttempts {
+		return 0, false
+	}
+
+	return p.Delay(attempt+1, rand.Float64()), true
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"errors"
+	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	tests := []struct {
+		name   string
+		jitter float64
+		retry  int64
+		rnd    float64
+		want   time.Duration
+	}{
+		{name: "first", retry: 1, want: time.Second},
+		{name: "third", retry: 3, want: 4 * time.Second},
+		{name: "capped", retry: 8, want: 10 * time.Second},
+		{name: "jitter down", jitter: 0.5, retry: 2, rnd: 0, want: time.Second},
+		{name: "jitter up", jitter: 0.5, retry: 2, rnd: 0.75, want: 2500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := p
+			p.Jitter = tt.jitter
+			require.Equal(t, tt.want, p.Delay(tt.retry, tt.rnd))
+		})
+	}
+}
+
+func TestPolicy_Next(t *testing.T) {
+	p := Policy{MaxAttempts: 3, Backoff: time.Second}
+	temporary := channel.Failed(errors.New("timeout"), true)
+
+	_, ok := p.Next(0, channel.Sent())
+	require.False(t, ok)
+	_, ok = p.Next(0, channel.Failed(errors.New("bad request"), false))
+	require.False(t, ok)
+	d, ok := p.Next(0, temporary)
+	require.True(t, ok)
+	require.Equal(t, time.Second, d)
+	d, ok = p.Next(1, temporary)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, d)
+	_, ok = p.Next(2, temporary)
+	require.False(t, ok)
+
+	_, ok = Policy{}.Next(0, temporary)
+	require.False(t, ok)
+}
+
+func TestPolicy_For(t *testing.T) {
+	p := Policy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	require.Equal(t, p, p.For(notification.Notification{}))
+
+	got := p.For(notification.Notification{RetryMaxAttempts: 1})
+	require.Equal(t, int64(1), got.MaxAttempts)
+
+	got = p.For(notification.Notification{RetryBackoff: 120})
+	require.Equal(t, 2*time.Minute, got.Backoff)
+	require.Equal(t, 2*time.Minute, got.MaxBackoff)
+}
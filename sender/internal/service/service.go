@@ -1,84 +1,338 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"sender/internal/entities/deadletter"
+	"sender/internal/entities/event"
 	"sender/internal/entities/notification"
-	"sender/internal/service/email"
-	"sender/internal/service/telegram"
+	"sender/internal/service/channel"
+	"sender/internal/service/retry"
 	"sender/internal/storage"
-	"strings"
+	"sync"
+	"time"
 
+	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/zlog"
 )
 
 type Service struct {
-	str   *storage.Storage
-	email string
+	str          *storage.Storage
+	channels     *channel.Registry
+	retry        retry.Policy
+	claimTimeout time.Duration
+	// inflight сообщения в обработке, Start ждет их перед выходом
+	inflight sync.WaitGroup
 }
 
-func New(str *storage.Storage, email string) *Service {
+func New(str *storage.Storage, channels *channel.Registry) *Service {
 	return &Service{
-		str:   str,
-		email: email,
+		str:      str,
+		channels: channels,
 	}
 }
 
+// SetRetry задает политику повтора по умолчанию, без нее неудачная отправка
+// не повторяется
+func (s *Service) SetRetry(p retry.Policy) {
+	s.retry = p
+}
+
 var (
-	ErrWrongStatusCode = errors.New("status code of request not 200")
+	ErrNotFound        = errors.New("notification not found in notifier")
+	ErrSuperseded      = errors.New("notification was edited after publishing")
+	ErrCancelled       = errors.New("notification was cancelled")
+	ErrContactNotFound = errors.New("contact not found in notifier")
+	ErrClaimFailed     = errors.New("notifier failed to claim notification")
 )
 
-func UpdateStatus(id int64) error {
-	port := os.Getenv("NOTIFIER_PORT")
-	body := fmt.Sprintf(`{"status": "%s"}`, notification.StatusComplete)
-	req, err := http.NewRequest(http.MethodPatch,
-		fmt.Sprintf("http://notifier:%s/notify/%d", port, id),
-		strings.NewReader(body),
-	)
+// DefaultClaimTimeout сколько ждать ответ notifier перед отправкой
+const DefaultClaimTimeout = 10 * time.Second
+
+// SetClaimTimeout задает время ожидания ответа notifier перед отправкой
+func (s *Service) SetClaimTimeout(d time.Duration) {
+	s.claimTimeout = d
+}
+
+// claim отмечает, что sender взял уведомление в отправку. notifier
+// отклоняет сообщение, если версия в нем не совпадает с текущей (уведомление
+// изменили и сообщение устарело) или уведомление уже отправлено. Если
+// уведомление уже взято, в ответе получатели с записанным результатом, так
+// повторное сообщение не дублирует отправку. Для уведомления с контактом в
+// ответе его адреса на момент отправки
+func (s *Service) claim(n notification.Notification) (event.ClaimReply, error) {
+	timeout := s.claimTimeout
+	if timeout == 0 {
+		timeout = DefaultClaimTimeout
+	}
+	r, err := s.str.Claim(event.Claim{
+		TenantID:       n.TenantID,
+		NotificationID: n.ID,
+		Version:        n.Version,
+		ContactID:      n.ContactID,
+	}, timeout)
+	if err != nil {
+		return r, err
+	}
+
+	switch r.Result {
+	case event.ClaimOK:
+	case event.ClaimCancelled:
+		return r, ErrCancelled
+	case event.ClaimSuperseded:
+		return r, ErrSuperseded
+	case event.ClaimNotFound:
+		return r, ErrNotFound
+	default:
+		return r, fmt.Errorf("%w: %s", ErrClaimFailed, r.Error)
+	}
+	if n.ContactID != 0 && r.Contact == nil {
+		return r, fmt.Errorf("%w: %s", ErrContactNotFound, r.Error)
+	}
+
+	return r, nil
+}
+
+// skipRecorded ставит получателям статус, уже записанный в notifier, тогда
+// они не ждут отправки и повторное сообщение их пропускает
+func skipRecorded(rs []notification.Recipient, recorded []event.Recipient) {
+	for _, r := range recorded {
+		for i := range rs {
+			if rs[i].Channel == r.Channel && rs[i].Address == r.Address {
+				rs[i].Status = r.Status
+			}
+		}
+	}
+}
+
+// AttemptsHeader заголовок повтора с историей неудачных попыток в JSON
+const AttemptsHeader = "x-attempts"
+
+// attempts история попыток из заголовка сообщения
+func attempts(msg amqp091.Delivery) []deadletter.Attempt {
+	r := []deadletter.Attempt{}
+	if v, ok := msg.Headers[AttemptsHeader].(string); ok {
+		_ = json.Unmarshal([]byte(v), &r)
+	}
+
+	return r
+}
+
+// deliver отправляет уведомление одному получателю и сообщает результат.
+// Временная ошибка планирует повтор, если попытки кончились или ошибка
+// постоянная - сообщение уходит в DLQ. Ошибка - результат не записан. Если
+// запись DLQ не сохранилась, результат не сообщается, иначе повторное
+// сообщение пропустит получателя и запись потеряется
+func (s *Service) deliver(n notification.Notification, rc notification.Recipient, history []deadletter.Attempt) error {
+	const op = "internal.service.deliver"
+
+	started := time.Now().UTC()
+	res := s.channels.Send(context.Background(), n, rc)
+	d := notification.Delivery{
+		Channel: rc.Channel, Address: rc.Address, Attempt: n.Attempt,
+		Response: res.Response, StartedAt: started, FinishedAt: time.Now().UTC(),
+	}
+	rc.Status, rc.Error = res.Status, ""
+	if res.Err != nil {
+		zlog.Logger.Error().Err(res.Err).Fields(map[string]any{
+			"op": op, "id": n.ID, "channel": rc.Channel, "attempt": n.Attempt,
+		}).Send()
+		rc.Error = res.Err.Error()
+		history = append(history, deadletter.Attempt{
+			Attempt: n.Attempt, Error: rc.Error, At: time.Now().UTC(),
+		})
+		delay, ok := s.retry.For(n).Next(n.Attempt, res)
+		if ok {
+			if err := s.requeue(n, rc, delay, history); err != nil {
+				zlog.Logger.Error().Err(err).Fields(map[string]any{
+					"op": op, "id": n.ID, "channel": rc.Channel,
+				}).Send()
+				ok = false
+			} else {
+				rc.Status = notification.RecipientRetrying
+				rc.Error = fmt.Sprintf("%s (retry %d in %s)", rc.Error, n.Attempt+1, delay.Round(time.Second))
+			}
+		}
+		if !ok {
+			reason := deadletter.ReasonPermanent
+			if res.Retryable {
+				reason = deadletter.ReasonExhausted
+			}
+			if err := s.deadLetter(n, rc, reason, history); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.Status, d.Error = rc.Status, rc.Error
+	err := s.str.ReportDelivery(event.Delivery{
+		TenantID: n.TenantID, NotificationID: n.ID, Delivery: d,
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{
+			"op": op, "id": n.ID, "channel": rc.Channel,
+		}).Send()
+	}
+
+	return err
+}
+
+// single уведомление только с одним получателем, ожидающим отправки
+func single(n notification.Notification, rc notification.Recipient) notification.Notification {
+	rc.Status, rc.Error = notification.RecipientPending, ""
+	n.Recipients = []notification.Recipient{rc}
+
+	return n
+}
+
+// requeue публикует повтор отправки одному получателю через отложенный
+// обменник или базу notifier, поэтому повтор переживает перезапуск sender
+func (s *Service) requeue(n notification.Notification, rc notification.Recipient, d time.Duration, history []deadletter.Attempt) error {
+	n = single(n, rc)
+	n.Attempt++
+	v, err := n.MarshalBinary()
 	if err != nil {
 		return err
 	}
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	h, err := json.Marshal(history)
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode != http.StatusOK {
-		b := new(bytes.Buffer)
-		_, _ = io.Copy(b, resp.Body)
-		zlog.Logger.Info().Fields(map[string]any{"body": b.String()}).
-			Send()
-		return ErrWrongStatusCode
+
+	return s.str.Publish(v, d.Milliseconds(), amqp091.Table{AttemptsHeader: string(h)})
+}
+
+// deadLetter отправляет в DLQ сообщение для одного получателя, доставить
+// которому не удалось
+func (s *Service) deadLetter(n notification.Notification, rc notification.Recipient, reason string, history []deadletter.Attempt) error {
+	const op = "internal.service.deadLetter"
+
+	v, err := single(n, rc).MarshalBinary()
+	if err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op, "id": n.ID}).Send()
+		return err
+	}
+	err = s.str.DeadLetter(deadletter.Letter{
+		TenantID:       n.TenantID,
+		NotificationID: n.ID,
+		Channel:        rc.Channel,
+		Address:        rc.Address,
+		Reason:         reason,
+		Error:          rc.Error,
+		Attempts:       history,
+		Payload:        v,
+		FailedAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{
+			"op": op, "id": n.ID, "channel": rc.Channel,
+		}).Send()
 	}
 
-	return nil
+	return err
 }
 
+// requeueDelay пауза перед возвратом сообщения в очередь, чтобы не крутить
+// его, пока notifier или брокер недоступны
+var requeueDelay = time.Second
+
+// Start обрабатывает сообщения, пока очередь открыта, и ждет уже начатые
 func (s *Service) Start() {
-	const op = "internal.servce.MainCycle"
 	for msg := range s.str.Receiver() {
-		n := notification.Notification{}
-		err := n.UnmarshalBinary(msg.Body)
-		if err != nil {
+		s.inflight.Add(1)
+		go func() {
+			defer s.inflight.Done()
+			s.handle(msg)
+		}()
+	}
+	s.inflight.Wait()
+}
+
+// handle подтверждает сообщение, когда результат по всем получателям
+// записан. При временной ошибке сообщение возвращается в очередь и придет
+// снова, получатели с записанным результатом при этом пропускаются. Еще раз
+// уведомление может прийти только тому, чей результат записать не удалось
+func (s *Service) handle(msg amqp091.Delivery) {
+	const op = "internal.service.handle"
+
+	if err := s.process(msg); err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).
+			Msg("requeue message")
+		time.Sleep(requeueDelay)
+		if err := msg.Nack(false, true); err != nil {
 			zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).
 				Send()
-			continue
 		}
-		err = UpdateStatus(n.ID)
-		if err != nil {
-			zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).
+		return
+	}
+	if err := msg.Ack(false); err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).
+			Send()
+	}
+}
+
+// process отправляет уведомление из сообщения. Ошибка - сообщение нужно
+// обработать еще раз
+func (s *Service) process(msg amqp091.Delivery) error {
+	const op = "internal.service.process"
+
+	n := notification.Notification{}
+	err := n.UnmarshalBinary(msg.Body)
+	if err != nil {
+		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).
+			Send()
+		return s.str.DeadLetter(deadletter.Letter{
+			Reason:   deadletter.ReasonUnreadable,
+			Error:    err.Error(),
+			Attempts: attempts(msg),
+			Payload:  msg.Body,
+			FailedAt: time.Now().UTC(),
+		})
+	}
+	// повтор: статус уже отмечен, получатели уже известны
+	if n.Attempt == 0 {
+		r, err := s.claim(n)
+		if errors.Is(err, ErrCancelled) {
+			zlog.Logger.Info().
+				Fields(map[string]any{"op": op, "id": n.ID}).
+				Msg("drop cancelled notification")
+			return nil
+		} else if errors.Is(err, ErrSuperseded) || errors.Is(err, ErrNotFound) {
+			zlog.Logger.Info().Err(err).
+				Fields(map[string]any{"op": op, "id": n.ID, "version": n.Version}).
+				Msg("skip outdated message")
+			return nil
+		} else if errors.Is(err, ErrContactNotFound) {
+			// уведомление взято, отправляем по его собственным адресам
+			zlog.Logger.Error().Err(err).
+				Fields(map[string]any{"op": op, "id": n.ID, "contact_id": n.ContactID}).
 				Send()
-			continue
+		} else if err != nil {
+			return err
 		}
-		if n.TelegramID != 0 {
-			go telegram.Send(n)
+		if r.Contact != nil {
+			// адреса контакта берутся на момент отправки
+			n.Recipients = r.Contact.Merge(n.Recipients)
 		}
-		if n.Email != "" {
-			go email.Send(n, s.email)
+		skipRecorded(n.Recipients, r.Recorded)
+	}
+
+	history := attempts(msg)
+	errs := make([]error, len(n.Recipients))
+	wg := sync.WaitGroup{}
+	for i, rc := range n.Recipients {
+		if rc.Status != notification.RecipientPending {
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.deliver(n, rc, history)
+		}()
 	}
+	wg.Wait()
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sender/internal/entities/contact"
+	"sender/internal/entities/event"
+	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
+	"sender/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+type MessagerMock struct {
+	claimF  func(c event.Claim) (event.ClaimReply, error)
+	resultF func(e event.Delivery) error
+	deadF   func(val []byte) error
+}
+
+func (m *MessagerMock) Channel() <-chan amqp091.Delivery {
+	return nil
+}
+
+func (m *MessagerMock) Cancel() error {
+	return nil
+}
+
+func (m *MessagerMock) Publish(val []byte, d int64, headers amqp091.Table) error {
+	return nil
+}
+
+func (m *MessagerMock) DeadLetter(val []byte) error {
+	return m.deadF(val)
+}
+
+func (m *MessagerMock) Result(val []byte) error {
+	e := event.Delivery{}
+	if err := json.Unmarshal(val, &e); err != nil {
+		return err
+	}
+	return m.resultF(e)
+}
+
+func (m *MessagerMock) Claim(val []byte, timeout time.Duration) ([]byte, error) {
+	c := event.Claim{}
+	if err := json.Unmarshal(val, &c); err != nil {
+		return nil, err
+	}
+	r, err := m.claimF(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(r)
+}
+
+func (m *MessagerMock) Shutdown() {}
+
+func TestService_claim(t *testing.T) {
+	reply := func(r event.ClaimReply) func(c event.Claim) (event.ClaimReply, error) {
+		return func(c event.Claim) (event.ClaimReply, error) {
+			if c.NotificationID != 7 || c.Version != 2 {
+				return event.ClaimReply{}, errors.New("wrong claim")
+			}
+			return r, nil
+		}
+	}
+	tests := []struct {
+		name        string
+		claimF      func(c event.Claim) (event.ClaimReply, error)
+		contactID   int64
+		wantErr     error
+		wantContact bool
+	}{
+		{name: "ok", claimF: reply(event.ClaimReply{Result: event.ClaimOK})},
+		{
+			name:      "contact",
+			claimF:    reply(event.ClaimReply{Result: event.ClaimOK, Contact: &contact.Contact{Email: "a@b.c"}}),
+			contactID: 3, wantContact: true,
+		},
+		{
+			name:      "contact not found",
+			claimF:    reply(event.ClaimReply{Result: event.ClaimOK, Error: "not found"}),
+			contactID: 3, wantErr: ErrContactNotFound,
+		},
+		{name: "cancelled", claimF: reply(event.ClaimReply{Result: event.ClaimCancelled}), wantErr: ErrCancelled},
+		{name: "superseded", claimF: reply(event.ClaimReply{Result: event.ClaimSuperseded}), wantErr: ErrSuperseded},
+		{name: "not found", claimF: reply(event.ClaimReply{Result: event.ClaimNotFound}), wantErr: ErrNotFound},
+		{name: "failed", claimF: reply(event.ClaimReply{Result: event.ClaimFailed}), wantErr: ErrClaimFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(storage.New(&MessagerMock{claimF: tt.claimF}), nil)
+			r, err := s.claim(notification.Notification{
+				ID: 7, Version: 2, ContactID: tt.contactID,
+			})
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantContact, r.Contact != nil)
+		})
+	}
+}
+
+type ChannelMock struct{}
+
+func (c ChannelMock) Name() string {
+	return notification.ChannelWebhook
+}
+
+func (c ChannelMock) Validate(address string) error {
+	return nil
+}
+
+func (c ChannelMock) Send(ctx context.Context, n notification.Notification, address string) channel.Result {
+	return channel.Sent().WithResponse("200 ok")
+}
+
+func TestService_deliverReports(t *testing.T) {
+	chs := channel.NewRegistry()
+	require.NoError(t, chs.Register(ChannelMock{}))
+	var got event.Delivery
+	s := New(storage.New(&MessagerMock{
+		resultF: func(e event.Delivery) error {
+			got = e
+			return nil
+		},
+	}), chs)
+
+	n := notification.Notification{ID: 7, TenantID: 2, Attempt: 1}
+	err := s.deliver(n, notification.Recipient{
+		Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+	}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(7), got.NotificationID)
+	require.Equal(t, int64(2), got.TenantID)
+	require.Equal(t, int64(1), got.Attempt)
+	require.Equal(t, notification.RecipientSent, got.Status)
+	require.Equal(t, "200 ok", got.Response)
+	require.False(t, got.StartedAt.After(got.FinishedAt))
+}
+
+// AckMock запоминает, подтверждено сообщение или возвращено в очередь
+type AckMock struct {
+	acked, requeued bool
+}
+
+func (a *AckMock) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *AckMock) Nack(tag uint64, multiple, requeue bool) error {
+	a.requeued = requeue
+	return nil
+}
+
+func (a *AckMock) Reject(tag uint64, requeue bool) error {
+	a.requeued = requeue
+	return nil
+}
+
+func TestService_handle(t *testing.T) {
+	requeueDelay = 0
+	n := notification.Notification{ID: 7, Version: 2, Recipients: []notification.Recipient{{
+		Channel: notification.ChannelWebhook, Address: "https://example.com/hook",
+		Status: notification.RecipientPending,
+	}}}
+	body, err := n.MarshalBinary()
+	require.NoError(t, err)
+
+	ok := func(e event.Delivery) error {
+		return nil
+	}
+	claimed := func(c event.Claim) (event.ClaimReply, error) {
+		return event.ClaimReply{Result: event.ClaimOK}, nil
+	}
+	tests := []struct {
+		name         string
+		m            *MessagerMock
+		body         []byte
+		wantRequeued bool
+	}{
+		{
+			name: "sent",
+			m:    &MessagerMock{claimF: claimed, resultF: ok},
+			body: body,
+		},
+		{
+			name: "cancelled",
+			m: &MessagerMock{
+				claimF: func(c event.Claim) (event.ClaimReply, error) {
+					return event.ClaimReply{Result: event.ClaimCancelled}, nil
+				},
+			},
+			body: body,
+		},
+		{
+			name: "claim timeout",
+			m: &MessagerMock{
+				claimF: func(c event.Claim) (event.ClaimReply, error) {
+					return event.ClaimReply{}, errors.New("no reply in time")
+				},
+			},
+			body:         body,
+			wantRequeued: true,
+		},
+		{
+			name: "result not published",
+			m: &MessagerMock{
+				claimF: claimed,
+				resultF: func(e event.Delivery) error {
+					return errors.New("channel closed")
+				},
+			},
+			body:         body,
+			wantRequeued: true,
+		},
+		{
+			name: "unreadable",
+			m: &MessagerMock{
+				deadF: func(val []byte) error {
+					return nil
+				},
+			},
+			body: []byte("{"),
+		},
+		{
+			name: "unreadable not dead lettered",
+			m: &MessagerMock{
+				deadF: func(val []byte) error {
+					return errors.New("channel closed")
+				},
+			},
+			body:         []byte("{"),
+			wantRequeued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chs := channel.NewRegistry()
+			require.NoError(t, chs.Register(ChannelMock{}))
+			s := New(storage.New(tt.m), chs)
+
+			a := &AckMock{}
+			s.handle(amqp091.Delivery{Acknowledger: a, Body: tt.body})
+			require.Equal(t, !tt.wantRequeued, a.acked)
+			require.Equal(t, tt.wantRequeued, a.requeued)
+		})
+	}
+}
+
+func TestService_processClaimedAgain(t *testing.T) {
+	n := notification.Notification{ID: 7, Version: 2, Recipients: []notification.Recipient{
+		{
+			Channel: notification.ChannelWebhook, Address: "https://example.com/a",
+			Status: notification.RecipientPending,
+		},
+		{
+			Channel: notification.ChannelWebhook, Address: "https://example.com/b",
+			Status: notification.RecipientPending,
+		},
+	}}
+	body, err := n.MarshalBinary()
+	require.NoError(t, err)
+
+	var reported []string
+	chs := channel.NewRegistry()
+	require.NoError(t, chs.Register(ChannelMock{}))
+	s := New(storage.New(&MessagerMock{
+		// результат для a записан до того, как сообщение вернулось в очередь
+		claimF: func(c event.Claim) (event.ClaimReply, error) {
+			return event.ClaimReply{Result: event.ClaimOK, Recorded: []event.Recipient{{
+				Channel: notification.ChannelWebhook, Address: "https://example.com/a",
+				Status: notification.RecipientSent,
+			}}}, nil
+		},
+		resultF: func(e event.Delivery) error {
+			reported = append(reported, e.Address)
+			return nil
+		},
+	}), chs)
+
+	require.NoError(t, s.process(amqp091.Delivery{Body: body, Redelivered: true}))
+	require.Equal(t, []string{"https://example.com/b"}, reported)
+}
@@ -2,48 +2,108 @@ package telegram
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wb-go/wbf/zlog"
 )
 
+// DefaultAPIURL адрес Bot API, если в конфиге не задан другой
+const DefaultAPIURL = "https://api.telegram.org"
+
+var (
+	ErrWrongStatusCode = errors.New("wrong status code on request")
+	ErrRequest         = errors.New("telegram api request failed")
+)
+
 type SendMessage struct {
 	TelegramID string `json:"chat_id"`
 	Message    string `json:"text"`
 }
 
-func Send(n notification.Notification) {
+// Telegram канал доставки через бота, адрес - chat id
+type Telegram struct {
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+func New(token, apiURL string, timeout time.Duration) *Telegram {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Telegram{
+		token:  token,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Telegram) Name() string {
+	return notification.ChannelTelegram
+}
+
+func (t *Telegram) Validate(chatID string) error {
+	if _, err := strconv.ParseInt(chatID, 10, 64); err != nil {
+		return fmt.Errorf("%w: %s %q", channel.ErrWrongAddress, t.Name(), chatID)
+	}
+
+	return nil
+}
+
+// Send отправляет уведомление в чат chatID
+func (t *Telegram) Send(ctx context.Context, n notification.Notification, chatID string) channel.Result {
 	const op = "internal.service.telegram.send"
-	url := fmt.Sprintf(
-		"https://api.telegram.org/bot%s/sendMessage", os.Getenv("BOT_TOKEN"),
-	)
-	body := fmt.Sprintf(
-		`{"chat_id": "%s", "text": "%s"}`,
-		strconv.FormatInt(n.TelegramID, 10), n.Message,
-	)
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	u := fmt.Sprintf("%s/bot%s/sendMessage", t.apiURL, t.token)
+	// текст из шаблона может содержать кавычки и переводы строк
+	body, err := json.Marshal(SendMessage{
+		TelegramID: chatID,
+		Message:    n.TelegramText(),
+	})
+	if err != nil {
+		return channel.Failed(err, false)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
 	if err != nil {
-		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).Send()
-		return
+		return channel.Failed(redact(err), false)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
-		zlog.Logger.Error().Err(err).Fields(map[string]any{"op": op}).Send()
-		return
+		return channel.Failed(redact(err), true)
 	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	b := new(bytes.Buffer)
+	_, _ = io.Copy(b, io.LimitReader(resp.Body, channel.MaxResponseLen))
 	if resp.StatusCode != http.StatusOK {
-		b := new(bytes.Buffer)
-		_, _ = io.Copy(b, resp.Body)
-		zlog.Logger.Error().Err(errors.New("wrong status code on request")).
+		zlog.Logger.Error().Err(ErrWrongStatusCode).
 			Fields(map[string]any{"op": op, "body": b.String()}).Send()
 	}
+
+	return channel.HTTPResult(
+		resp.StatusCode, fmt.Errorf("%w: %d", ErrWrongStatusCode, resp.StatusCode),
+	).WithResponse(fmt.Sprintf("%d %s", resp.StatusCode, strings.TrimSpace(b.String())))
+}
+
+// redact убирает из ошибки http клиента адрес запроса: в нем токен бота, а
+// ошибка уходит в notifier и видна команде
+func redact(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return fmt.Errorf("%w: %s: %w", ErrRequest, ue.Op, ue.Err)
+	}
+
+	return err
 }
@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sender/internal/entities/notification"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_TokenNotReported(t *testing.T) {
+	const token = "SECRET123"
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name   string
+		apiURL string
+	}{
+		{name: "connection refused", apiURL: "http://127.0.0.1:1"},
+		{name: "timeout", apiURL: slow.URL},
+		{name: "wrong url", apiURL: "http://[::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := New(token, tt.apiURL, 50*time.Millisecond)
+			res := tg.Send(context.Background(), notification.Notification{Message: "hi"}, "1")
+			require.Error(t, res.Err)
+			require.False(t, strings.Contains(res.Err.Error(), token), res.Err.Error())
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+var (
+	ErrWrongStatusCode = errors.New("wrong status code on request")
+	ErrForbiddenHost   = errors.New("webhook host is not allowed")
+)
+
+// Webhook канал доставки POST запросом на URL получателя
+type Webhook struct {
+	client *http.Client
+	allow  []netip.Prefix
+}
+
+// New канал с таймаутом на запрос. Адреса тенантов не должны вести во
+// внутреннюю сеть, поэтому loopback, частные, link-local и unspecified IP
+// запрещены, кроме подсетей из allow. IP проверяется при каждом соединении
+// уже после разрешения имени, так что подмена DNS между проверкой и
+// запросом не помогает
+func New(timeout time.Duration, allow []netip.Prefix) *Webhook {
+	w := &Webhook{allow: allow}
+	dialer := &net.Dialer{Timeout: timeout, Control: w.control}
+	w.client = &http.Client{
+		Timeout: timeout,
+		// через прокси соединение шло бы к прокси, а не к получателю
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+
+	return w
+}
+
+// ParseAllow подсети через запятую, в которые webhook можно слать несмотря
+// на запрет внутренних адресов
+func ParseAllow(s string) ([]netip.Prefix, error) {
+	var allow []netip.Prefix
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		allow = append(allow, p.Masked())
+	}
+
+	return allow, nil
+}
+
+// allowed можно ли слать запрос на ip
+func (w *Webhook) allowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range w.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast())
+}
+
+// control проверяет IP, к которому открывается соединение
+func (w *Webhook) control(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenHost, address)
+	}
+	if !w.allowed(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenHost, ap.Addr())
+	}
+
+	return nil
+}
+
+func (w *Webhook) Name() string {
+	return notification.ChannelWebhook
+}
+
+func (w *Webhook) Validate(address string) error {
+	u, err := url.Parse(address)
+	if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: %s %q", channel.ErrWrongAddress, w.Name(), address)
+	}
+	// IP в адресе проверяется сразу, имя - при соединении
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		host = "127.0.0.1"
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !w.allowed(ip) {
+		return fmt.Errorf("%w: %w %q", channel.ErrWrongAddress, ErrForbiddenHost, address)
+	}
+
+	return nil
+}
+
+// Payload тело запроса в webhook
+type Payload struct {
+	ID       int64     `json:"id"`
+	TenantID int64     `json:"tenant_id"`
+	SeriesID int64     `json:"series_id,omitempty"`
+	Message  string    `json:"message"`
+	Date     time.Time `json:"date"`
+	Timezone string    `json:"timezone,omitempty"`
+}
+
+// Sign подпись тела: hex HMAC-SHA256 по строке "<timestamp>.<body>"
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send отправляет уведомление POST запросом на address, ответ не из 2xx -
+// неудачная доставка
+func (w *Webhook) Send(ctx context.Context, n notification.Notification, address string) channel.Result {
+	const op = "internal.service.webhook.send"
+
+	body, err := json.Marshal(Payload{
+		ID:       n.ID,
+		TenantID: n.TenantID,
+		SeriesID: n.SeriesID,
+		Message:  n.Message,
+		Date:     n.Date,
+		Timezone: n.Timezone,
+	})
+	if err != nil {
+		return channel.Failed(err, false)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return channel.Failed(err, false)
+	}
+	// служебные заголовки ставятся после пользовательских и не
+	// перезаписываются ими
+	for k, v := range n.WebhookHeaders {
+		req.Header.Set(k, v)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(n.WebhookSecret, ts, body))
+
+	resp, err := w.client.Do(req)
+	if errors.Is(err, ErrForbiddenHost) {
+		return channel.Failed(err, false)
+	}
+	if err != nil {
+		return channel.Failed(err, true)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	b := new(bytes.Buffer)
+	_, _ = io.Copy(b, io.LimitReader(resp.Body, channel.MaxResponseLen))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		zlog.Logger.Error().Err(ErrWrongStatusCode).
+			Fields(map[string]any{"op": op, "body": b.String()}).Send()
+	}
+
+	return channel.HTTPResult(
+		resp.StatusCode, fmt.Errorf("%w: %d", ErrWrongStatusCode, resp.StatusCode),
+	).WithResponse(fmt.Sprintf("%d %s", resp.StatusCode, strings.TrimSpace(b.String())))
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sender/internal/entities/notification"
+	"sender/internal/service/channel"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	tm, _ := time.Parse(notification.DateLayout, "2000-12-22T15:00:00Z")
+	n := notification.Notification{
+		ID: 7, TenantID: 2, Message: "hihi", Date: tm,
+		WebhookHeaders: map[string]string{
+			"Authorization": "Token 1", "X-Webhook-Signature": "fake",
+		},
+		WebhookSecret: "s3cret",
+	}
+
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       error
+		wantRetryable bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{
+			name: "server error", status: http.StatusInternalServerError,
+			wantErr: ErrWrongStatusCode, wantRetryable: true,
+		},
+		{name: "bad request", status: http.StatusBadRequest, wantErr: ErrWrongStatusCode},
+		{name: "redirect", status: http.StatusNotModified, wantErr: ErrWrongStatusCode},
+	}
+	// httptest слушает loopback
+	w := New(time.Second, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.Equal(t, "Token 1", r.Header.Get("Authorization"))
+				ts := r.Header.Get(TimestampHeader)
+				require.NotEmpty(t, ts)
+				require.Equal(t, Sign("s3cret", ts, body), r.Header.Get(SignatureHeader))
+
+				p := Payload{}
+				require.NoError(t, json.Unmarshal(body, &p))
+				require.Equal(t, int64(7), p.ID)
+				require.Equal(t, int64(2), p.TenantID)
+				require.Equal(t, "hihi", p.Message)
+				require.True(t, tm.Equal(p.Date))
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("ok\n"))
+			}))
+			defer srv.Close()
+
+			res := w.Send(context.Background(), n, srv.URL)
+			require.ErrorIs(t, res.Err, tt.wantErr)
+			require.Equal(t, tt.wantRetryable, res.Retryable)
+			if tt.status != http.StatusNotModified {
+				require.Equal(t, strconv.Itoa(tt.status)+" ok", res.Response)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	a := Sign("s3cret", "1", []byte("{}"))
+	require.Equal(t, a, Sign("s3cret", "1", []byte("{}")))
+	require.NotEqual(t, a, Sign("other", "1", []byte("{}")))
+	require.NotEqual(t, a, Sign("s3cret", "2", []byte("{}")))
+	require.NotEqual(t, a, Sign("s3cret", "1", []byte("{ }")))
+}
+
+func TestValidate(t *testing.T) {
+	w := New(time.Second, nil)
+	require.NoError(t, w.Validate("https://example.com/hook"))
+	require.NoError(t, w.Validate("http://93.184.216.34/hook"))
+	require.ErrorIs(t, w.Validate("ftp://example.com"), channel.ErrWrongAddress)
+	require.ErrorIs(t, w.Validate("/hook"), channel.ErrWrongAddress)
+	for _, address := range []string{
+		"http://127.0.0.1:15672/api", "http://localhost/admin", "http://10.0.0.1/",
+		"http://192.168.1.1/", "http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0:8080/", "http://[::1]/", "http://[::ffff:127.0.0.1]/",
+	} {
+		err := w.Validate(address)
+		require.ErrorIs(t, err, channel.ErrWrongAddress, address)
+		require.ErrorIs(t, err, ErrForbiddenHost, address)
+	}
+
+	allowed := New(time.Second, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")})
+	require.NoError(t, allowed.Validate("http://10.1.2.3/hook"))
+	require.ErrorIs(t, allowed.Validate("http://10.2.0.1/hook"), ErrForbiddenHost)
+}
+
+func TestSend_Forbidden(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	// имя, которое ведет на loopback, проверяется при соединении
+	address := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	res := New(time.Second, nil).Send(context.Background(), notification.Notification{}, address)
+	require.ErrorIs(t, res.Err, ErrForbiddenHost)
+	require.False(t, res.Retryable)
+	require.False(t, called)
+}
+
+func TestParseAllow(t *testing.T) {
+	allow, err := ParseAllow(" 10.1.0.0/16, ,127.0.0.1/8")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("127.0.0.0/8"),
+	}, allow)
+
+	_, err = ParseAllow("10.1.0.0")
+	require.Error(t, err)
+}
@@ -1,17 +1,40 @@
 package rabbit
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/wb-go/wbf/zlog"
 )
 
+// replyTo псевдо-очередь RabbitMQ для ответов без отдельной очереди
+const replyTo = "amq.rabbitmq.reply-to"
+
+// consumer тег подписки на очередь уведомлений, по нему подписка отменяется
+const consumer = "sender"
+
+var ErrTimeout = errors.New("no reply in time")
+
 type Queue struct {
 	conn     *amqp091.Connection
 	ch       *amqp091.Channel
 	q        amqp091.Queue
 	messages <-chan amqp091.Delivery
+	ex       string
+	key      string
+	dlq      string
+	results  string
+	claims   string
+	retries  string
+
+	seq     atomic.Uint64
+	mu      sync.Mutex
+	waiting map[string]chan []byte
 }
 
 func (r *Queue) Shutdown() {
@@ -26,8 +49,15 @@ func (r *Queue) Shutdown() {
 	}
 }
 
-func New(addr, user, password, queue string) *Queue {
-	r := &Queue{}
+// New подключается к очереди queue. Повторы публикуются в отложенный обменник
+// ex с ключом key, тот же, через который уведомления публикует notifier.
+// Пустой ex - без обменника и плагина, повторы уходят в очередь retries, до
+// их времени их хранит notifier. Недоставленные сообщения идут в очередь
+// dlq, результаты доставки - в очередь results, запросы перед отправкой - в
+// очередь claims, их разбирает notifier. prefetch - сколько
+// неподтвержденных сообщений sender берет одновременно, 0 - без ограничения
+func New(addr, user, password, queue, ex, key, dlq, results, claims, retries string, prefetch int) *Queue {
+	r := &Queue{waiting: map[string]chan []byte{}}
 	conn, err := amqp091.Dial(
 		fmt.Sprintf("amqp://%s:%s@%s", user, password, addr),
 	)
@@ -50,7 +80,59 @@ func New(addr, user, password, queue string) *Queue {
 	}
 	r.q = q
 
-	messages, err := ch.Consume(q.Name, "", true, false, false, false, nil)
+	if ex != "" {
+		err = ch.ExchangeDeclare(
+			ex, "x-delayed-message", true, false, false, false,
+			amqp091.Table{"x-delayed-type": "direct"},
+		)
+		if err != nil {
+			panic(err)
+		}
+		err = ch.QueueBind(q.Name, key, ex, false, nil)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		_, err = ch.QueueDeclare(retries, true, false, false, false, nil)
+		if err != nil {
+			panic(err)
+		}
+	}
+	r.ex = ex
+	r.key = key
+	r.retries = retries
+
+	// DLQ переживает перезапуск брокера, ее разбирает notifier
+	_, err = ch.QueueDeclare(dlq, true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	r.dlq = dlq
+
+	for _, name := range []string{results, claims} {
+		_, err = ch.QueueDeclare(name, true, false, false, false, nil)
+		if err != nil {
+			panic(err)
+		}
+	}
+	r.results = results
+	r.claims = claims
+
+	// ответы приходят в этот же канал, поэтому слушать их нужно до первого
+	// запроса
+	replies, err := ch.Consume(replyTo, "", true, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	go r.dispatch(replies)
+
+	err = ch.Qos(prefetch, 0, false)
+	if err != nil {
+		panic(err)
+	}
+	// подтверждение после записи результата, иначе при остановке sender
+	// сообщение вернется в очередь
+	messages, err := ch.Consume(q.Name, consumer, false, false, false, false, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -62,3 +144,131 @@ func New(addr, user, password, queue string) *Queue {
 func (r *Queue) Channel() <-chan amqp091.Delivery {
 	return r.messages
 }
+
+// Cancel отменяет подписку на очередь уведомлений: новые сообщения не
+// приходят, канал Channel закрывается после уже полученных
+func (r *Queue) Cancel() error {
+	const op = "internal.storage.rabbit.Cancel"
+
+	err := r.ch.Cancel(consumer, false)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Publish публикует сообщение, которое придет в очередь через d мс. Без
+// обменника сообщение уходит в очередь повторов notifier, задержка
+// передается тем же заголовком x-delay
+func (r *Queue) Publish(val []byte, d int64, headers amqp091.Table) error {
+	const op = "internal.storage.rabbit.Publish"
+
+	h := amqp091.Table{}
+	for k, v := range headers {
+		h[k] = v
+	}
+	h["x-delay"] = d
+	msg := amqp091.Publishing{
+		Headers:     h,
+		ContentType: "text/plain",
+		Body:        val,
+	}
+	ex, key := r.ex, r.key
+	if ex == "" {
+		key = r.retries
+		msg.DeliveryMode = amqp091.Persistent
+	}
+	err := r.ch.Publish(ex, key, true, false, msg)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// DeadLetter кладет недоставленное сообщение в DLQ
+func (r *Queue) DeadLetter(val []byte) error {
+	const op = "internal.storage.rabbit.DeadLetter"
+
+	err := r.ch.Publish("", r.dlq, true, false, amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		Body:         val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Result публикует результат доставки, сообщение переживает перезапуск
+// брокера и notifier
+func (r *Queue) Result(val []byte) error {
+	const op = "internal.storage.rabbit.Result"
+
+	err := r.ch.Publish("", r.results, true, false, amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		Body:         val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Claim отправляет запрос в очередь claims и ждет ответ не дольше timeout.
+// Запрос живет в очереди столько же, сколько его ждут, поэтому notifier не
+// обработает запрос, от которого sender уже отказался
+func (r *Queue) Claim(val []byte, timeout time.Duration) ([]byte, error) {
+	const op = "internal.storage.rabbit.Claim"
+
+	id := strconv.FormatUint(r.seq.Add(1), 10)
+	reply := make(chan []byte, 1)
+	r.mu.Lock()
+	r.waiting[id] = reply
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.waiting, id)
+		r.mu.Unlock()
+	}()
+
+	err := r.ch.Publish("", r.claims, true, false, amqp091.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: id,
+		ReplyTo:       replyTo,
+		Expiration:    strconv.FormatInt(timeout.Milliseconds(), 10),
+		Body:          val,
+	})
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	select {
+	case v := <-reply:
+		return v, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// dispatch передает ответы ожидающим запросам, опоздавшие отбрасываются
+func (r *Queue) dispatch(replies <-chan amqp091.Delivery) {
+	for msg := range replies {
+		r.mu.Lock()
+		reply, ok := r.waiting[msg.CorrelationId]
+		r.mu.Unlock()
+		if ok {
+			reply <- msg.Body
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package redisqueue брокер на Redis вместо RabbitMQ: каждая очередь -
+// ZSET id сообщений по времени, когда они станут видны, поэтому повторам
+// не нужен плагин отложенных сообщений. Очередь из общего модуля zsetqueue,
+// ключи те же у notifier
+package redisqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+	"zsetqueue"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Имена очередей после префикса, те же у notifier
+const (
+	notificationsQueue = "notifications"
+	deadLettersQueue   = "dead_letters"
+	resultsQueue       = "results"
+	claimsQueue        = "claims"
+)
+
+var ErrTimeout = errors.New("no reply in time")
+
+// Options настройки очередей
+type Options struct {
+	// Prefix начало всех ключей, одинаковое у notifier и sender
+	Prefix string
+	// Visibility на сколько выданное сообщение прячется от других
+	// получателей, не подтвержденное за это время выдается снова
+	Visibility time.Duration
+	// PollInterval как часто искать наступившие сообщения
+	PollInterval time.Duration
+	// Batch сколько сообщений забирать за раз
+	Batch int
+}
+
+// Queue реализация storage.Messager поверх redis
+type Queue struct {
+	rd            *redis.Client
+	prefix        string
+	notifications *zsetqueue.Queue
+	deadLetters   *zsetqueue.Queue
+	results       *zsetqueue.Queue
+	claims        *zsetqueue.Queue
+	messages      <-chan amqp091.Delivery
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New подключается к redis и начинает разбирать очередь уведомлений.
+// prefetch - сколько неподтвержденных сообщений sender берет одновременно,
+// 0 - без ограничения. Visibility должно быть больше времени обработки
+// одного сообщения, иначе его получит другой sender
+func New(addr, password string, db int, o Options, prefetch int) *Queue {
+	rd := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+	if err := rd.Ping(context.Background()).Err(); err != nil {
+		panic(err)
+	}
+
+	q := &Queue{
+		rd:            rd,
+		prefix:        o.Prefix,
+		notifications: zsetqueue.New(rd, o.Prefix, notificationsQueue, o.Visibility, prefetch),
+		deadLetters:   zsetqueue.New(rd, o.Prefix, deadLettersQueue, o.Visibility, 0),
+		results:       zsetqueue.New(rd, o.Prefix, resultsQueue, o.Visibility, 0),
+		claims:        zsetqueue.New(rd, o.Prefix, claimsQueue, o.Visibility, 0),
+		stop:          make(chan struct{}),
+	}
+	q.messages = q.notifications.Consume(q.stop, o.Batch, o.PollInterval)
+
+	return q
+}
+
+func (q *Queue) Shutdown() {
+	const op = "internal.storage.redisqueue.Shutdown"
+
+	_ = q.Cancel()
+	if err := q.rd.Close(); err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+	}
+}
+
+func (q *Queue) Channel() <-chan amqp091.Delivery {
+	return q.messages
+}
+
+// Cancel перестает забирать уведомления: канал Channel закрывается, уже
+// забранные, но не отданные сообщения возвращаются в очередь
+func (q *Queue) Cancel() error {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+
+	return nil
+}
+
+// Publish кладет сообщение, которое станет видно через d мс
+func (q *Queue) Publish(val []byte, d int64, headers amqp091.Table) error {
+	const op = "internal.storage.redisqueue.Publish"
+
+	h := amqp091.Table{}
+	for k, v := range headers {
+		h[k] = v
+	}
+	h["x-delay"] = d
+	err := q.notifications.Push(zsetqueue.Envelope{
+		Headers:     h,
+		ContentType: "text/plain",
+		Body:        val,
+		Timestamp:   time.Now().UTC(),
+	}, time.Duration(d)*time.Millisecond)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// DeadLetter кладет недоставленное сообщение в DLQ
+func (q *Queue) DeadLetter(val []byte) error {
+	const op = "internal.storage.redisqueue.DeadLetter"
+
+	err := q.deadLetters.Push(zsetqueue.Envelope{
+		ContentType: "application/json",
+		Body:        val,
+		Timestamp:   time.Now().UTC(),
+	}, 0)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Result кладет результат доставки для notifier
+func (q *Queue) Result(val []byte) error {
+	const op = "internal.storage.redisqueue.Result"
+
+	err := q.results.Push(zsetqueue.Envelope{
+		ContentType: "application/json",
+		Body:        val,
+		Timestamp:   time.Now().UTC(),
+	}, 0)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return err
+	}
+
+	return nil
+}
+
+// Claim кладет запрос в очередь claims и ждет ответ в своем списке не
+// дольше timeout. Запрос живет в очереди столько же, сколько его ждут,
+// поэтому notifier не обработает запрос, от которого sender уже отказался
+func (q *Queue) Claim(val []byte, timeout time.Duration) ([]byte, error) {
+	const op = "internal.storage.redisqueue.Claim"
+
+	// ответы всех sender в одном redis, поэтому id случайный, а не счетчик
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	replyTo := q.prefix + ":reply:" + id
+
+	err := q.claims.Push(zsetqueue.Envelope{
+		ContentType:   "application/json",
+		CorrelationID: id,
+		ReplyTo:       replyTo,
+		Expiration:    timeout.Milliseconds(),
+		Timestamp:     time.Now().UTC(),
+		Body:          val,
+	}, 0)
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	r, err := q.rd.BLPop(context.Background(), timeout, replyTo).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTimeout
+	}
+	if err != nil {
+		zlog.Logger.Error().AnErr("err", err).Msg(op)
+		return nil, err
+	}
+
+	// BLPOP отдает имя списка и значение
+	return []byte(r[1]), nil
+}
@@ -0,0 +1,83 @@
+package redisqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	Prefix: "test", Visibility: time.Minute, PollInterval: 10 * time.Millisecond, Batch: 16,
+}
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	m := miniredis.RunT(t)
+	q := New(m.Addr(), "", 0, testOptions, 0)
+	t.Cleanup(q.Shutdown)
+
+	return q
+}
+
+func receive(t *testing.T, ch <-chan amqp091.Delivery) amqp091.Delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message in queue")
+	}
+
+	return amqp091.Delivery{}
+}
+
+func TestQueue_Publish(t *testing.T) {
+	q := newTestQueue(t)
+
+	start := time.Now()
+	require.NoError(t, q.Publish([]byte("retry"), 200, amqp091.Table{"x-attempts": `[{"attempt":0}]`}))
+	d := receive(t, q.Channel())
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, "retry", string(d.Body))
+	require.Equal(t, `[{"attempt":0}]`, d.Headers["x-attempts"])
+	require.NoError(t, d.Ack(false))
+}
+
+func TestQueue_Claim(t *testing.T) {
+	q := newTestQueue(t)
+	stop := make(chan struct{})
+	defer close(stop)
+	// сторона notifier
+	claims := q.claims.Consume(stop, 16, 10*time.Millisecond)
+	go func() {
+		d := <-claims
+		_ = q.rd.RPush(context.Background(), d.ReplyTo, append([]byte("re: "), d.Body...)).Err()
+		_ = d.Ack(false)
+	}()
+
+	v, err := q.Claim([]byte("claim"), 2*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "re: claim", string(v))
+
+	_, err = q.Claim([]byte("late"), time.Second)
+	require.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	q := newTestQueue(t)
+
+	require.NoError(t, q.Result([]byte("r")))
+	require.NoError(t, q.Cancel())
+	_, ok := <-q.Channel()
+	require.False(t, ok)
+
+	// результаты ждут notifier в redis и после остановки sender
+	ds, err := q.results.Claim(10)
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	require.Equal(t, "r", string(ds[0].Body))
+}
@@ -1,11 +1,21 @@
 package storage
 
 import (
+	"encoding/json"
+	"sender/internal/entities/deadletter"
+	"sender/internal/entities/event"
+	"time"
+
 	"github.com/rabbitmq/amqp091-go"
 )
 
 type Messager interface {
 	Channel() <-chan amqp091.Delivery
+	Cancel() error
+	Publish(val []byte, d int64, headers amqp091.Table) error
+	DeadLetter(val []byte) error
+	Result(val []byte) error
+	Claim(val []byte, timeout time.Duration) ([]byte, error)
 	Shutdown()
 }
 
@@ -23,6 +33,55 @@ func (s *Storage) Receiver() <-chan amqp091.Delivery {
 	return s.q.Channel()
 }
 
+// StopReceiving перестает получать новые сообщения, Receiver закрывается
+// после уже полученных
+func (s *Storage) StopReceiving() error {
+	return s.q.Cancel()
+}
+
+// Publish возвращает сообщение в очередь через отложенный обменник, d - мс
+func (s *Storage) Publish(val []byte, d int64, headers amqp091.Table) error {
+	return s.q.Publish(val, d, headers)
+}
+
+// DeadLetter отправляет недоставленное сообщение в DLQ
+func (s *Storage) DeadLetter(l deadletter.Letter) error {
+	v, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	return s.q.DeadLetter(v)
+}
+
+// ReportDelivery публикует результат попытки доставки для notifier
+func (s *Storage) ReportDelivery(e event.Delivery) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.q.Result(v)
+}
+
+// Claim просит notifier отметить уведомление взятым и ждет ответ
+func (s *Storage) Claim(c event.Claim, timeout time.Duration) (event.ClaimReply, error) {
+	v, err := json.Marshal(c)
+	if err != nil {
+		return event.ClaimReply{}, err
+	}
+	b, err := s.q.Claim(v, timeout)
+	if err != nil {
+		return event.ClaimReply{}, err
+	}
+	r := event.ClaimReply{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return event.ClaimReply{}, err
+	}
+
+	return r, nil
+}
+
 func (s *Storage) Shutdown() {
 	s.q.Shutdown()
 }
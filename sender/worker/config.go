@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"sender/internal/service"
+	"sender/internal/service/channel"
+	"sender/internal/service/email"
+	"sender/internal/service/retry"
+	"sender/internal/service/telegram"
+	"sender/internal/service/webhook"
+	"sender/internal/storage/redisqueue"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/config"
+)
+
+const (
+	// defaultTimeout время на один запрос канала, если в конфиге не задано
+	defaultTimeout = 10 * time.Second
+	// defaultPrefetch сколько сообщений обрабатывается одновременно
+	defaultPrefetch = 16
+	// defaultShutdownTimeout сколько ждать начатые отправки при остановке
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Config настройки sender из секции sender конфига
+type Config struct {
+	channels        *channel.Registry
+	retry           retry.Policy
+	claimTimeout    time.Duration
+	prefetch        int
+	shutdownTimeout time.Duration
+}
+
+// Prefetch сколько неподтвержденных сообщений брать у брокера одновременно,
+// 0 - без ограничения
+func (c Config) Prefetch() int {
+	return c.prefetch
+}
+
+// Channels имена включенных каналов
+func (c Config) Channels() []string {
+	return c.channels.Names()
+}
+
+// Load читает настройки sender, пустое значение - по умолчанию
+func Load(cfg *config.Config) (Config, error) {
+	c := Config{prefetch: defaultPrefetch}
+	var err error
+	if c.channels, err = loadChannels(cfg); err != nil {
+		return c, err
+	}
+	if c.retry, err = loadRetry(cfg); err != nil {
+		return c, err
+	}
+	c.claimTimeout, err = duration(cfg, "sender.claim_timeout", service.DefaultClaimTimeout)
+	if err != nil {
+		return c, err
+	}
+	c.shutdownTimeout, err = duration(cfg, "sender.shutdown_timeout", defaultShutdownTimeout)
+	if err != nil {
+		return c, err
+	}
+	if v := cfg.GetString("sender.prefetch"); v != "" {
+		if c.prefetch, err = strconv.Atoi(v); err != nil || c.prefetch < 0 {
+			return c, fmt.Errorf("sender.prefetch: wrong value %q", v)
+		}
+	}
+
+	return c, nil
+}
+
+// factories конструкторы каналов по имени, новый канал добавляется сюда и
+// включается в sender.channels
+var factories = map[string]func(cfg *config.Config) (channel.Channel, error){
+	"telegram": func(cfg *config.Config) (channel.Channel, error) {
+		timeout, err := duration(cfg, "sender.telegram.timeout", defaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return telegram.New(
+			os.Getenv("BOT_TOKEN"), cfg.GetString("sender.telegram.api_url"), timeout,
+		), nil
+	},
+	"email": func(cfg *config.Config) (channel.Channel, error) {
+		port, err := strconv.Atoi(cfg.GetString("sender.email.port"))
+		if err != nil {
+			return nil, fmt.Errorf("sender.email.port: %w", err)
+		}
+		return email.New(email.Config{
+			Host:     cfg.GetString("sender.email.host"),
+			Port:     port,
+			Username: cfg.GetString("sender.email_username"),
+			Password: os.Getenv("EMAIL_PASSWORD"),
+		}), nil
+	},
+	"webhook": func(cfg *config.Config) (channel.Channel, error) {
+		timeout, err := duration(cfg, "sender.webhook.timeout", defaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+		allow, err := webhook.ParseAllow(cfg.GetString("sender.webhook.allow"))
+		if err != nil {
+			return nil, fmt.Errorf("sender.webhook.allow: %w", err)
+		}
+		return webhook.New(timeout, allow), nil
+	},
+}
+
+// loadChannels собирает каналы, перечисленные через запятую в
+// sender.channels
+func loadChannels(cfg *config.Config) (*channel.Registry, error) {
+	r := channel.NewRegistry()
+	for _, name := range strings.Split(cfg.GetString("sender.channels"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		f, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("sender.channels: %w: %s", channel.ErrUnknownChannel, name)
+		}
+		c, err := f(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Register(c); err != nil {
+			return nil, fmt.Errorf("sender.channels: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// loadRetry политика повтора из sender.retry, незаданные поля - без повторов
+func loadRetry(cfg *config.Config) (retry.Policy, error) {
+	var (
+		p   retry.Policy
+		err error
+	)
+	if v := cfg.GetString("sender.retry.max_attempts"); v != "" {
+		if p.MaxAttempts, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return p, fmt.Errorf("sender.retry.max_attempts: %w", err)
+		}
+	}
+	if p.Backoff, err = duration(cfg, "sender.retry.backoff", time.Second); err != nil {
+		return p, err
+	}
+	if p.MaxBackoff, err = duration(cfg, "sender.retry.max_backoff", time.Hour); err != nil {
+		return p, err
+	}
+	if v := cfg.GetString("sender.retry.jitter"); v != "" {
+		if p.Jitter, err = strconv.ParseFloat(v, 64); err != nil {
+			return p, fmt.Errorf("sender.retry.jitter: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+func duration(cfg *config.Config, key string, def time.Duration) (time.Duration, error) {
+	v := cfg.GetString(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, nil
+}
+
+// LoadRedisQueue читает queue.redis для брокера redis, пустое значение - по
+// умолчанию. Значения по умолчанию те же, что у notifier
+func LoadRedisQueue(cfg *config.Config) (redisqueue.Options, error) {
+	o := redisqueue.Options{
+		Prefix: "delayednotifier", Visibility: 5 * time.Minute,
+		PollInterval: 100 * time.Millisecond, Batch: 16,
+	}
+	var err error
+	if v := cfg.GetString("queue.redis.prefix"); v != "" {
+		o.Prefix = v
+	}
+	if v := cfg.GetString("queue.redis.visibility_timeout"); v != "" {
+		if o.Visibility, err = time.ParseDuration(v); err != nil || o.Visibility <= 0 {
+			return o, fmt.Errorf("queue.redis.visibility_timeout: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("queue.redis.poll_interval"); v != "" {
+		if o.PollInterval, err = time.ParseDuration(v); err != nil || o.PollInterval <= 0 {
+			return o, fmt.Errorf("queue.redis.poll_interval: wrong value %q", v)
+		}
+	}
+	if v := cfg.GetString("queue.redis.batch"); v != "" {
+		if o.Batch, err = strconv.Atoi(v); err != nil || o.Batch <= 0 {
+			return o, fmt.Errorf("queue.redis.batch: wrong value %q", v)
+		}
+	}
+
+	return o, nil
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"sender/internal/storage/redisqueue"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/config"
+)
+
+// load конфиг из текста yaml
+func load(t *testing.T, yml string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(yml), 0o600))
+	cfg := config.New()
+	require.NoError(t, cfg.Load(path))
+	return cfg
+}
+
+func TestLoadRedisQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		yml     string
+		want    redisqueue.Options
+		wantErr bool
+	}{
+		{
+			name: "default",
+			yml:  "queue:\n  type: \"redis\"\n",
+			want: redisqueue.Options{
+				Prefix: "delayednotifier", Visibility: 5 * time.Minute,
+				PollInterval: 100 * time.Millisecond, Batch: 16,
+			},
+		},
+		{
+			name: "custom",
+			yml: "queue:\n  redis:\n    prefix: \"dn\"\n" +
+				"    visibility_timeout: \"1m\"\n    poll_interval: \"1s\"\n    batch: 4\n",
+			want: redisqueue.Options{
+				Prefix: "dn", Visibility: time.Minute, PollInterval: time.Second, Batch: 4,
+			},
+		},
+		{
+			name:    "wrong visibility",
+			yml:     "queue:\n  redis:\n    visibility_timeout: \"soon\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong poll interval",
+			yml:     "queue:\n  redis:\n    poll_interval: \"0s\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong batch",
+			yml:     "queue:\n  redis:\n    batch: -1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRedisQueue(load(t, tt.yml))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
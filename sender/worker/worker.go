@@ -0,0 +1,62 @@
+// Package worker собирает sender поверх брокера: каналы доставки и политика
+// повтора из конфига, цикл обработки сообщений и остановка. Пакет не
+// internal, чтобы модуль dev в корне репозитория запускал sender в одном
+// процессе с notifier
+package worker
+
+import (
+	"sender/internal/service"
+	"sender/internal/storage"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Messager брокер, из которого sender берет уведомления и куда публикует
+// повторы, недоставленные сообщения, результаты и запросы перед отправкой
+type Messager = storage.Messager
+
+type Worker struct {
+	str             *storage.Storage
+	srv             *service.Service
+	shutdownTimeout time.Duration
+	done            chan struct{}
+}
+
+// New собирает sender поверх брокера q с настройками c
+func New(q Messager, c Config) *Worker {
+	str := storage.New(q)
+	srv := service.New(str, c.channels)
+	srv.SetRetry(c.retry)
+	srv.SetClaimTimeout(c.claimTimeout)
+
+	return &Worker{
+		str:             str,
+		srv:             srv,
+		shutdownTimeout: c.shutdownTimeout,
+		done:            make(chan struct{}),
+	}
+}
+
+// Start обрабатывает сообщения, пока очередь открыта, и ждет уже начатые
+func (w *Worker) Start() {
+	defer close(w.done)
+	w.srv.Start()
+}
+
+// Stop перестает брать новые сообщения, ждет начатые не дольше
+// sender.shutdown_timeout и закрывает брокер. Неподтвержденные сообщения
+// вернутся в очередь
+func (w *Worker) Stop() {
+	const op = "worker.Stop"
+
+	if err := w.str.StopReceiving(); err == nil {
+		select {
+		case <-w.done:
+		case <-time.After(w.shutdownTimeout):
+			zlog.Logger.Error().Str("op", op).
+				Msg("in-flight messages aren't finished on shutdown")
+		}
+	}
+	w.str.Shutdown()
+}
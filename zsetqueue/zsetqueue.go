@@ -0,0 +1,311 @@
+// Package zsetqueue очередь на Redis для брокера redis notifier и sender:
+// ZSET id сообщений по времени, когда они станут видны, хеш тел и хеш
+// номеров выдачи. Модуль общий, формат сообщений у обеих сторон один
+package zsetqueue
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Envelope сообщение в хеше очереди: тело и свойства, которые у RabbitMQ
+// хранит брокер
+type Envelope struct {
+	Body          []byte        `json:"body"`
+	Headers       amqp091.Table `json:"headers,omitempty"`
+	ContentType   string        `json:"content_type,omitempty"`
+	CorrelationID string        `json:"correlation_id,omitempty"`
+	ReplyTo       string        `json:"reply_to,omitempty"`
+	// Expiration срок жизни в мс от Timestamp, 0 - бессрочно
+	Expiration int64     `json:"expiration,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// pushScript кладет сообщение ARGV[1] в очередь: тело в хеш KEYS[2] под
+// новым id из счетчика KEYS[3], id в KEYS[1] со временем, когда оно станет
+// видно, через ARGV[2] мс. Время берется у redis, поэтому у notifier и
+// sender одни и те же часы
+var pushScript = redis.NewScript(`
+local id = redis.call("INCR", KEYS[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("HSET", KEYS[2], id, ARGV[1])
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), id)
+return id
+`)
+
+// claimScript забирает до ARGV[1] наступивших сообщений из KEYS[1] и
+// прячет их на ARGV[2] мс: если получатель упадет, не подтвердив сообщение,
+// оно снова станет видно. Номер выдачи считается в KEYS[3]. Возвращает
+// плоский список {id, тело, номер выдачи}
+var claimScript = redis.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", now, "LIMIT", 0, tonumber(ARGV[1]))
+local r = {}
+for _, id in ipairs(ids) do
+	local body = redis.call("HGET", KEYS[2], id)
+	if body then
+		redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), id)
+		local n = redis.call("HINCRBY", KEYS[3], id, 1)
+		table.insert(r, id)
+		table.insert(r, body)
+		table.insert(r, n)
+	else
+		redis.call("ZREM", KEYS[1], id)
+		redis.call("HDEL", KEYS[3], id)
+	end
+end
+return r
+`)
+
+// requeueScript делает выданное сообщение ARGV[1] видимым сразу, если оно
+// еще в очереди KEYS[1]
+var requeueScript = redis.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+return redis.call("ZADD", KEYS[1], "XX", now, ARGV[1])
+`)
+
+// Queue одна очередь в redis: ZSET id по времени, когда сообщение станет
+// видно, хеш тел, хеш номеров выдачи и счетчик id. Реализует
+// amqp091.Acknowledger, DeliveryTag выданного сообщения - его id
+type Queue struct {
+	rd         *redis.Client
+	key        string
+	messages   string
+	deliveries string
+	seq        string
+	visibility time.Duration
+	// prefetch сколько выданных сообщений может быть не подтверждено, 0 -
+	// без ограничения
+	prefetch int
+
+	mu       sync.Mutex
+	inflight map[uint64]struct{}
+	// freed будит consume, когда подтверждено выданное сообщение
+	freed chan struct{}
+}
+
+// New очередь name с ключами под prefix. visibility - на сколько выданное
+// сообщение прячется от других получателей, prefetch - сколько выданных
+// сообщений может быть не подтверждено, 0 - без ограничения
+func New(rd *redis.Client, prefix, name string, visibility time.Duration, prefetch int) *Queue {
+	key := prefix + ":" + name
+
+	return &Queue{
+		rd:         rd,
+		key:        key,
+		messages:   key + ":messages",
+		deliveries: key + ":deliveries",
+		seq:        key + ":seq",
+		visibility: visibility,
+		prefetch:   prefetch,
+		inflight:   map[uint64]struct{}{},
+		freed:      make(chan struct{}, 1),
+	}
+}
+
+// Push кладет сообщение, которое станет видно через delay
+func (q *Queue) Push(e Envelope, delay time.Duration) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return pushScript.Run(
+		context.Background(), q.rd, []string{q.key, q.messages, q.seq},
+		v, delay.Milliseconds(),
+	).Err()
+}
+
+// Claim забирает до limit наступивших сообщений
+func (q *Queue) Claim(limit int) ([]amqp091.Delivery, error) {
+	r, err := claimScript.Run(
+		context.Background(), q.rd, []string{q.key, q.messages, q.deliveries},
+		limit, q.visibility.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	ds := make([]amqp091.Delivery, 0, len(r)/3)
+	for i := 0; i+2 < len(r); i += 3 {
+		id, _ := r[i].(string)
+		body, _ := r[i+1].(string)
+		n, _ := r[i+2].(int64)
+		tag, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		var e Envelope
+		if err := json.Unmarshal([]byte(body), &e); err != nil {
+			// битое сообщение не разобрать никогда, оно только мешает
+			_ = q.Ack(tag, false)
+			continue
+		}
+		ds = append(ds, amqp091.Delivery{
+			Acknowledger:  q,
+			DeliveryTag:   tag,
+			MessageId:     id,
+			Redelivered:   n > 1,
+			Headers:       e.Headers,
+			ContentType:   e.ContentType,
+			CorrelationId: e.CorrelationID,
+			ReplyTo:       e.ReplyTo,
+			Expiration:    expiration(e.Expiration),
+			Timestamp:     e.Timestamp,
+			Body:          e.Body,
+		})
+	}
+
+	return ds, nil
+}
+
+// free сколько сообщений можно выдать сейчас
+func (q *Queue) free(batch int) int {
+	if q.prefetch == 0 {
+		return batch
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return min(batch, q.prefetch-len(q.inflight))
+}
+
+func (q *Queue) take(tag uint64) {
+	q.mu.Lock()
+	q.inflight[tag] = struct{}{}
+	q.mu.Unlock()
+}
+
+func (q *Queue) release(tag uint64) {
+	q.mu.Lock()
+	delete(q.inflight, tag)
+	q.mu.Unlock()
+	select {
+	case q.freed <- struct{}{}:
+	default:
+	}
+}
+
+// Consume раз в poll забирает наступившие сообщения пачками по batch и
+// отдает их в канал, пока не закрыт stop. Сообщения, которые не успели
+// отдать, сразу возвращаются в очередь
+func (q *Queue) Consume(stop <-chan struct{}, batch int, poll time.Duration) <-chan amqp091.Delivery {
+	const op = "zsetqueue.Consume"
+
+	out := make(chan amqp091.Delivery)
+	go func() {
+		defer close(out)
+		tick := time.NewTicker(poll)
+		defer tick.Stop()
+		for {
+			n := q.free(batch)
+			var ds []amqp091.Delivery
+			if n > 0 {
+				var err error
+				ds, err = q.Claim(n)
+				if err != nil {
+					zlog.Logger.Error().AnErr("err", err).Str("queue", q.key).Msg(op)
+				}
+			}
+			for i, d := range ds {
+				if expired(d) {
+					_ = q.Ack(d.DeliveryTag, false)
+					continue
+				}
+				q.take(d.DeliveryTag)
+				select {
+				case out <- d:
+				case <-stop:
+					for _, d := range ds[i:] {
+						_ = q.Reject(d.DeliveryTag, true)
+					}
+					return
+				}
+			}
+			// полная пачка - в очереди может быть еще
+			if n > 0 && len(ds) == n {
+				select {
+				case <-stop:
+					return
+				default:
+					continue
+				}
+			}
+			// все выданные не подтверждены - ждать подтверждения, иначе тика
+			wake := q.freed
+			if n > 0 {
+				wake = nil
+			}
+			select {
+			case <-tick.C:
+			case <-wake:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func expiration(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(ms, 10)
+}
+
+// expired истек ли срок жизни сообщения, как у RabbitMQ
+func expired(d amqp091.Delivery) bool {
+	if d.Expiration == "" {
+		return false
+	}
+	ms, err := strconv.ParseInt(d.Expiration, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Since(d.Timestamp) > time.Duration(ms)*time.Millisecond
+}
+
+// Ack удаляет сообщение tag из очереди, multiple не поддерживается
+func (q *Queue) Ack(tag uint64, multiple bool) error {
+	q.release(tag)
+	id := strconv.FormatUint(tag, 10)
+	_, err := q.rd.TxPipelined(context.Background(), func(p redis.Pipeliner) error {
+		p.ZRem(context.Background(), q.key, id)
+		p.HDel(context.Background(), q.messages, id)
+		p.HDel(context.Background(), q.deliveries, id)
+		return nil
+	})
+
+	return err
+}
+
+// Nack отклоняет сообщение tag, с requeue оно сразу станет видно снова
+func (q *Queue) Nack(tag uint64, multiple bool, requeue bool) error {
+	return q.Reject(tag, requeue)
+}
+
+// Reject отклоняет сообщение tag, без requeue удаляет его
+func (q *Queue) Reject(tag uint64, requeue bool) error {
+	if !requeue {
+		return q.Ack(tag, false)
+	}
+	q.release(tag)
+
+	return requeueScript.Run(
+		context.Background(), q.rd, []string{q.key}, strconv.FormatUint(tag, 10),
+	).Err()
+}
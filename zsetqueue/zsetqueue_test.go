@@ -0,0 +1,113 @@
+package zsetqueue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+// wire сообщение в хеше очереди так, как его пишет другая сторона
+const wire = `{
+	"body": "aGk=",
+	"headers": {"x-attempts": "[]"},
+	"content_type": "application/json",
+	"correlation_id": "7",
+	"reply_to": "amq.rabbitmq.reply-to",
+	"expiration": 5000,
+	"timestamp": "2026-01-01T12:00:00Z"
+}`
+
+func TestEnvelope_Wire(t *testing.T) {
+	ts := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := Envelope{
+		Body: []byte("hi"), Headers: amqp091.Table{"x-attempts": "[]"},
+		ContentType: "application/json", CorrelationID: "7",
+		ReplyTo: "amq.rabbitmq.reply-to", Expiration: 5000, Timestamp: ts,
+	}
+	v, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.JSONEq(t, wire, string(v))
+
+	m := miniredis.RunT(t)
+	rd := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	defer rd.Close()
+	q := New(rd, "test", "wire", time.Minute, 0)
+	err = pushScript.Run(
+		context.Background(), rd, []string{q.key, q.messages, q.seq}, wire, 0,
+	).Err()
+	require.NoError(t, err)
+
+	ds, err := q.Claim(10)
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	d := ds[0]
+	require.Equal(t, "hi", string(d.Body))
+	require.Equal(t, amqp091.Table{"x-attempts": "[]"}, d.Headers)
+	require.Equal(t, "application/json", d.ContentType)
+	require.Equal(t, "7", d.CorrelationId)
+	require.Equal(t, "amq.rabbitmq.reply-to", d.ReplyTo)
+	require.Equal(t, "5000", d.Expiration)
+	require.Equal(t, ts, d.Timestamp)
+}
+
+func TestQueue_Visibility(t *testing.T) {
+	m := miniredis.RunT(t)
+	rd := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	defer rd.Close()
+	q := New(rd, "test", "results", 100*time.Millisecond, 0)
+	require.NoError(t, q.Push(Envelope{Body: []byte("r")}, 0))
+
+	ds, err := q.Claim(10)
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	// выданное сообщение спрятано, пока не истечет visibility
+	ds, err = q.Claim(10)
+	require.NoError(t, err)
+	require.Empty(t, ds)
+
+	time.Sleep(150 * time.Millisecond)
+	ds, err = q.Claim(10)
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	require.True(t, ds[0].Redelivered)
+}
+
+func TestQueue_Prefetch(t *testing.T) {
+	m := miniredis.RunT(t)
+	rd := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	defer rd.Close()
+	q := New(rd, "test", "notifications", time.Minute, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	messages := q.Consume(stop, 16, 10*time.Millisecond)
+
+	require.NoError(t, q.Push(Envelope{Body: []byte("a")}, 0))
+	require.NoError(t, q.Push(Envelope{Body: []byte("b")}, 0))
+	var d amqp091.Delivery
+	select {
+	case d = <-messages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message in queue")
+	}
+	require.Equal(t, "a", string(d.Body))
+	// выдано prefetch сообщений, пока их не подтвердят, новых нет
+	select {
+	case d := <-messages:
+		t.Fatalf("unexpected message: %s", d.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, d.Ack(false))
+	select {
+	case d = <-messages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message in queue")
+	}
+	require.Equal(t, "b", string(d.Body))
+}